@@ -19,8 +19,11 @@ package gwu
 
 import (
 	"html"
+	"log"
 	"net/http"
+	"sort"
 	"strconv"
+	"time"
 )
 
 // Container interface defines a component that can contain other components.
@@ -45,6 +48,10 @@ type Container interface {
 
 	// Clear clears the container, removes all child components.
 	Clear()
+
+	// children returns the child components.
+	// The returned slice must not be modified.
+	children() []Comp
 }
 
 // Comp interface: the base of all UI components.
@@ -88,9 +95,54 @@ type Comp interface {
 	// SetToolTip sets the tool tip of the component.
 	SetToolTip(toolTip string)
 
+	// TabIndex returns the tab index of the component,
+	// and whether it is set explicitly.
+	TabIndex() (index int, set bool)
+
+	// SetTabIndex sets the tab index of the component (rendered as
+	// the tabindex HTML attribute). 0 makes the component focusable in
+	// the document order, -1 makes it focusable only programmatically
+	// (e.g. by Event.SetFocusedComp()).
+	//
+	// Positive values are allowed but a warning is logged: they move the
+	// component before all others in the tab order, which is an accessibility
+	// anti-pattern. Prefer ordering components in the document instead.
+	//
+	// To remove an explicitly set tab index, use SetAttr("tabindex", "").
+	SetTabIndex(index int)
+
+	// SetEnabledWhen makes the enabled state of the component depend on the
+	// value of the src component, at the client side: the enabled state is
+	// updated whenever the value of src changes in the browser (without
+	// a server round trip), and when the component is rendered.
+	//
+	// pred tells if the component is enabled, based on the value of src
+	// (for check boxes and radio buttons the value is "true" if checked,
+	// an empty string otherwise), e.g.:
+	//     EnabledPred{Op: EnPredEquals, Value: "other"}
+	// The form controls inside the component are enabled / disabled too.
+	//
+	// Note that this does not change the server side enabled state
+	// (HasEnabled.Enabled()). Pass nil as src to remove the dependency.
+	SetEnabledWhen(src Comp, pred EnabledPred)
+
 	// Style returns the Style builder of the component.
 	Style() Style
 
+	// VisibleAt returns the viewport width range in which the component is visible.
+	// 0 values mean no bound.
+	VisibleAt() (minWidth, maxWidth int)
+
+	// SetVisibleAt sets the viewport width range (in pixels, inclusive) in which
+	// the component is visible, e.g. a hamburger menu only on small screens,
+	// full navigation only on large screens. Pass 0 for no lower or upper bound,
+	// pass 0 for both to make the component visible at all widths.
+	//
+	// This is rendered as media queries into a style block scoped to the
+	// component (just like responsive widths, see Style.SetResponsiveWidth()),
+	// so it works without server round trips when the viewport is resized.
+	SetVisibleAt(minWidth, maxWidth int)
+
 	// DescendantOf tells if this component is a descendant of the specified another component.
 	DescendantOf(c2 Comp) bool
 
@@ -100,17 +152,116 @@ type Comp interface {
 	// AddEHandlerFunc adds a new event handler generated from a handler function.
 	AddEHandlerFunc(hf func(e Event), etypes ...EventType)
 
+	// AddKeyHandler adds an ETypeKeyDown event handler which is only called
+	// if the pressed key matches the specified key code and the states of the
+	// modifier keys match the specified modifier key mask exactly
+	// (pass 0 for no modifier keys), e.g. Enter in a search box.
+	//
+	// If all ETypeKeyDown handlers of the component are key handlers,
+	// keys are also matched at the client side: only the matching keys
+	// generate events, and the default action of the matching keys is prevented.
+	AddKeyHandler(keyCode Key, modKeys ModKey, handler EventHandler)
+
+	// AddEHandlerNS adds a new event handler in the specified namespace.
+	// Handlers of a namespace can be removed without affecting other handlers
+	// (see RemoveEHandlerFuncNS()), so reusable widgets can manage their own
+	// handlers on components which may also have application handlers.
+	AddEHandlerNS(ns string, handler EventHandler, etypes ...EventType)
+
+	// AddEHandlerFuncNS adds a new event handler generated from a handler
+	// function in the specified namespace.
+	AddEHandlerFuncNS(ns string, hf func(e Event), etypes ...EventType)
+
+	// RemoveEHandlerFuncNS removes the event handlers of the specified
+	// namespace added for the specified event type.
+	// Handlers of other namespaces and handlers without namespace are kept.
+	RemoveEHandlerFuncNS(ns string, etype EventType)
+
 	// HandlersCount returns the number of added handlers.
 	HandlersCount(etype EventType) int
 
 	// SyncOnETypes returns the event types on which to synchronize component value
-	// from browser to the server.
+	// from browser to the server, in ascending order.
+	// nil is returned if the component does not sync its value.
 	SyncOnETypes() []EventType
 
 	// AddSyncOnETypes adds additional event types on which to synchronize
 	// component value from browser to the server.
 	AddSyncOnETypes(etypes ...EventType)
 
+	// MinEventInterval returns the minimum interval between events
+	// sent to the server by the component, in milliseconds.
+	MinEventInterval() int
+
+	// SetMinEventInterval sets the minimum interval between events
+	// sent to the server by the component (per event type), in milliseconds.
+	// Events occurring within the interval are dropped at the client side,
+	// except the last one which is sent when the interval elapses
+	// (so the final state always reaches the server).
+	// This is useful for components generating events continuously
+	// (e.g. while dragging). Pass 0 to send all events.
+	SetMinEventInterval(ms int)
+
+	// EventDebounce returns the debounce delay of the events of the
+	// specified type sent to the server by the component.
+	EventDebounce(etype EventType) time.Duration
+
+	// SetEventDebounce sets the debounce delay of the events of the
+	// specified type sent to the server by the component: an event is
+	// only sent when no further event of the type occurs within the delay,
+	// so rapidly occurring events are coalesced into the last one.
+	// This is useful for high-frequency events, e.g. ETypeInput (to search
+	// as the user types), ETypeWheel or ETypeMouseMove.
+	// Debouncing takes precedence over the min event interval.
+	// Note that the default action of debounced ETypeContextMenu
+	// events can't be prevented (see Event.PreventDefault()).
+	// Pass 0 to send the events immediately.
+	SetEventDebounce(etype EventType, d time.Duration)
+
+	// WheelPreventDefault tells if the default action (scrolling)
+	// of the ETypeWheel events of the component is prevented.
+	WheelPreventDefault() bool
+
+	// SetWheelPreventDefault sets if the default action (scrolling) of the
+	// ETypeWheel events of the component is prevented at the client,
+	// e.g. to zoom instead of scrolling the page. Since wheel events are
+	// sent asynchronously, this can't be decided by the event handler.
+	// Only has effect if the component has an ETypeWheel handler.
+	//
+	// Wheel handlers are rendered as element attributes which are
+	// non-passive listeners, so browsers allow preventing the default
+	// action; but browsers can't start scrolling until such listeners
+	// return, which may hurt scrolling performance.
+	// Default is false.
+	SetWheelPreventDefault(prevent bool)
+
+	// Buffered tells if value changes of the component are buffered.
+	Buffered() bool
+
+	// SetBuffered sets whether value changes of the component are buffered.
+	// If buffered, the events on which the component syncs its value
+	// (see SyncOnETypes()) are not sent to the server immediately, they
+	// are buffered at the client side (only the last value per event type
+	// is kept), and sent together with the next non-buffered event
+	// (e.g. the click on an "Apply" button). Buffered changes are processed
+	// (component values are updated and event handlers are called) before
+	// the event they are sent with.
+	// Useful for optimistic UIs where several changes are to be applied at once.
+	SetBuffered(buffered bool)
+
+	// EventDecoder returns the event decoder of the component.
+	EventDecoder() func(r *http.Request) interface{}
+
+	// SetEventDecoder sets an event decoder which turns the raw form values
+	// of events of the component into a typed value (e.g. a struct).
+	// The decoder is called for each event of the component after the
+	// component preprocessed the event (see preprocessEvent()) and before
+	// event handlers are called. Its result is available to event handlers
+	// via Event.DecodedValue().
+	// The component value can be acquired with r.FormValue("cval").
+	// Pass nil to remove the decoder.
+	SetEventDecoder(decoder func(r *http.Request) interface{})
+
 	// PreprocessEvent preprocesses an incoming event before it is dispatched.
 	// This gives the opportunity for components to update their new value
 	// before event handlers are called for example.
@@ -123,6 +274,26 @@ type Comp interface {
 	Render(w Writer)
 }
 
+// Operator of an enabled predicate.
+type EnPredOp int
+
+// Enabled predicate operators.
+const (
+	EnPredEquals    EnPredOp = iota // Value equals to the operand
+	EnPredNotEquals                 // Value does not equal to the operand
+	EnPredNotEmpty                  // Value is not empty (check box is checked)
+	EnPredEmpty                     // Value is empty (check box is not checked)
+	EnPredMatches                   // Value matches the operand, a JavaScript regular expression
+)
+
+// EnabledPred is a predicate of Comp.SetEnabledWhen(), evaluated at the
+// client side. Predicates are evaluated by the Gowut JavaScript without
+// evaluating code, so they also work under a content security policy.
+type EnabledPred struct {
+	Op    EnPredOp // Operator
+	Value string   // Operand of EnPredEquals, EnPredNotEquals and EnPredMatches
+}
+
 // Comp implementation.
 type compImpl struct {
 	id     ID        // The component id
@@ -131,9 +302,14 @@ type compImpl struct {
 	attrs     map[string]string // Explicitly set HTML attributes for the component's wrapper tag.
 	styleImpl *styleImpl        // Style builder.
 
-	handlers        map[EventType][]EventHandler // Event handlers mapped from event type. Lazily initialized.
-	valueProviderJs []byte                       // If the HTML representation of the component has a value, this JavaScript code code must provide it. It will be automatically sent as the paramCompId parameter.
-	syncOnETypes    map[EventType]bool           // Tells on which event types should comp value sync happen.
+	handlers        map[EventType][]EventHandler      // Event handlers mapped from event type. Lazily initialized.
+	valueProviderJs []byte                            // If the HTML representation of the component has a value, this JavaScript code code must provide it. It will be automatically sent as the paramCompId parameter.
+	syncOnETypes    map[EventType]bool                // Tells on which event types should comp value sync happen.
+	minEventIntv    int                               // Minimum interval between sent events in milliseconds, 0 if not limited.
+	debounces       map[EventType]time.Duration       // Debounce delays of event types. Lazily initialized.
+	buffered        bool                              // Tells if value changes are buffered at the client side.
+	wheelPd         bool                              // Tells if the default action of wheel events is prevented.
+	eventDecoder    func(r *http.Request) interface{} // Optional event decoder.
 }
 
 // newCompImpl creates a new compImpl.
@@ -200,10 +376,48 @@ func (c *compImpl) SetToolTip(toolTip string) {
 	c.SetAttr("title", html.EscapeString(toolTip))
 }
 
+func (c *compImpl) TabIndex() (index int, set bool) {
+	index, err := strconv.Atoi(c.Attr("tabindex"))
+	return index, err == nil
+}
+
+func (c *compImpl) SetTabIndex(index int) {
+	if index > 0 {
+		log.Printf("WARNING: positive tab index (%d) set for component %s, this overrides the document order of tab navigation.", index, c.id)
+	}
+	c.SetIAttr("tabindex", index)
+}
+
+func (c *compImpl) SetEnabledWhen(src Comp, pred EnabledPred) {
+	if src == nil {
+		c.SetAttr("data-gwuenwhen", "")
+		c.SetAttr("data-gwuenpred", "")
+		c.SetAttr("data-gwuenval", "")
+		return
+	}
+	c.SetAttr("data-gwuenwhen", src.Id().String())
+	c.SetAttr("data-gwuenpred", strconv.Itoa(int(pred.Op)))
+	c.SetAttr("data-gwuenval", html.EscapeString(pred.Value))
+}
+
 func (c *compImpl) Style() Style {
 	return c.styleImpl
 }
 
+func (c *compImpl) VisibleAt() (minWidth, maxWidth int) {
+	return c.styleImpl.visMin, c.styleImpl.visMax
+}
+
+func (c *compImpl) SetVisibleAt(minWidth, maxWidth int) {
+	if minWidth < 0 {
+		minWidth = 0
+	}
+	if maxWidth < 0 {
+		maxWidth = 0
+	}
+	c.styleImpl.visMin, c.styleImpl.visMax = minWidth, maxWidth
+}
+
 func (c *compImpl) DescendantOf(c2 Comp) bool {
 	for parent := c.parent; parent != nil; parent = parent.Parent() {
 		// Always compare components by id, because Comp.Parent()
@@ -225,6 +439,42 @@ func (c *compImpl) renderAttrsAndStyle(w Writer) {
 	c.styleImpl.render(w)
 }
 
+// renderComp renders the specified component, preceded by the style block
+// of its responsive styles (see Style.SetResponsiveWidth(), Comp.SetVisibleAt())
+// if it has any. Containers must render their child components with this.
+func renderComp(w Writer, c Comp) {
+	c.Style().renderMedia(w, c.Id())
+	c.Render(w)
+}
+
+var (
+	strPrintStaticOp = []byte(`<span class="gwu-PrintStatic" id="`) // `<span class="gwu-PrintStatic" id="`
+	strPrintStaticId = []byte(`_ps">`)                              // `_ps">`
+)
+
+// renderPrintStatic renders the specified text (the value of an input component)
+// wrapped in a span which is only displayed when printing, if print-static inputs
+// are enabled in the window of the component (see Window.SetPrintStaticInputs()).
+// It must be called before rendering the input tag, because the print CSS hides
+// the next sibling of the span.
+func (c *compImpl) renderPrintStatic(w Writer, text string) {
+	var root Container
+	for parent := c.parent; parent != nil; parent = parent.Parent() {
+		root = parent
+	}
+	// Window's children have the window's embedded panelImpl as their parent,
+	// so check the attribute instead of asserting windowImpl.
+	if root == nil || root.Attr(attrPrintStatic) == "" {
+		return
+	}
+
+	w.Write(strPrintStaticOp)
+	w.Writev(int(c.id))
+	w.Write(strPrintStaticId)
+	w.Writees(text)
+	w.Write(strSpanCl)
+}
+
 func (c *compImpl) AddEHandler(handler EventHandler, etypes ...EventType) {
 	if c.handlers == nil {
 		c.handlers = make(map[EventType][]EventHandler)
@@ -238,6 +488,39 @@ func (c *compImpl) AddEHandlerFunc(hf func(e Event), etypes ...EventType) {
 	c.AddEHandler(handlerFuncWrapper{hf}, etypes...)
 }
 
+func (c *compImpl) AddKeyHandler(keyCode Key, modKeys ModKey, handler EventHandler) {
+	c.AddEHandler(keyEHandler{keyCode: keyCode, modKeys: modKeys, handler: handler}, ETypeKeyDown)
+}
+
+func (c *compImpl) AddEHandlerNS(ns string, handler EventHandler, etypes ...EventType) {
+	c.AddEHandler(nsEHandler{ns: ns, handler: handler}, etypes...)
+}
+
+func (c *compImpl) AddEHandlerFuncNS(ns string, hf func(e Event), etypes ...EventType) {
+	c.AddEHandlerNS(ns, handlerFuncWrapper{hf}, etypes...)
+}
+
+func (c *compImpl) RemoveEHandlerFuncNS(ns string, etype EventType) {
+	handlers := c.handlers[etype]
+	kept := handlers[:0]
+	for _, handler := range handlers {
+		if nh, ok := handler.(nsEHandler); !ok || nh.ns != ns {
+			kept = append(kept, handler)
+		}
+	}
+	// Clear the removed tail so removed handlers can be garbage collected
+	for i := len(kept); i < len(handlers); i++ {
+		handlers[i] = nil
+	}
+
+	if len(kept) == 0 {
+		// No handlers left, so the event is not rendered anymore
+		delete(c.handlers, etype)
+	} else {
+		c.handlers[etype] = kept
+	}
+}
+
 func (c *compImpl) HandlersCount(etype EventType) int {
 	return len(c.handlers[etype])
 }
@@ -253,6 +536,8 @@ func (c *compImpl) SyncOnETypes() []EventType {
 		etypes[i] = etype
 		i++
 	}
+	// Sort for deterministic output:
+	sort.Slice(etypes, func(i, j int) bool { return etypes[i] < etypes[j] })
 	return etypes
 }
 
@@ -268,13 +553,87 @@ func (c *compImpl) AddSyncOnETypes(etypes ...EventType) {
 	}
 }
 
+func (c *compImpl) MinEventInterval() int {
+	return c.minEventIntv
+}
+
+func (c *compImpl) SetMinEventInterval(ms int) {
+	if ms < 0 {
+		ms = 0
+	}
+	c.minEventIntv = ms
+}
+
+func (c *compImpl) EventDebounce(etype EventType) time.Duration {
+	return c.debounces[etype]
+}
+
+func (c *compImpl) SetEventDebounce(etype EventType, d time.Duration) {
+	if d <= 0 {
+		delete(c.debounces, etype)
+		return
+	}
+	if c.debounces == nil {
+		c.debounces = make(map[EventType]time.Duration, 1)
+	}
+	c.debounces[etype] = d
+}
+
+func (c *compImpl) WheelPreventDefault() bool {
+	return c.wheelPd
+}
+
+func (c *compImpl) SetWheelPreventDefault(prevent bool) {
+	c.wheelPd = prevent
+}
+
+func (c *compImpl) Buffered() bool {
+	return c.buffered
+}
+
+func (c *compImpl) SetBuffered(buffered bool) {
+	c.buffered = buffered
+}
+
+func (c *compImpl) EventDecoder() func(r *http.Request) interface{} {
+	return c.eventDecoder
+}
+
+func (c *compImpl) SetEventDecoder(decoder func(r *http.Request) interface{}) {
+	c.eventDecoder = decoder
+}
+
 var (
-	strSePrefix = []byte(`="se(event,`) // `="se(event,`
-	strSeSuffix = []byte(`)"`)          // `)"`
+	strSbufPrefix   = []byte("sbuf(")                   // "sbuf("
+	strSePrefix     = []byte("se(event,")               // "se(event,"
+	strSeSuffix     = []byte(`)"`)                      // `)"`
+	strSeMinPrefix  = []byte("seMin(")                  // "seMin("
+	strSeSyncPrefix = []byte("return seSync(event,")    // "return seSync(event,"
+	strSeDebPrefix  = []byte("seDeb(")                  // "seDeb("
+	strPreventDef   = []byte("event.preventDefault();") // "event.preventDefault();"
+	strCommaEvent   = []byte(",event,")                 // ",event,"
+	strKeyMatchOp   = []byte("if(keyMatch(event,[")     // "if(keyMatch(event,["
+	strKeyMatchCl   = []byte("]))")                     // "]))"
 )
 
 // rendrenderEventHandlers renders the event handlers as attributes.
 func (c *compImpl) renderEHandlers(w Writer) {
+	c.renderEHandlersInput(w, nil)
+}
+
+// renderEHandlersInput renders the event handlers as attributes, and
+// the oninput attribute with the specified JavaScript code of the component
+// itself if not empty, which is run before sending ETypeInput events
+// (an element can't have multiple oninput attributes).
+func (c *compImpl) renderEHandlersInput(w Writer, inputJs []byte) {
+	if len(inputJs) > 0 && len(c.handlers[ETypeInput]) == 0 {
+		w.Write(strSpace)
+		w.Write(etypeAttrs[ETypeInput])
+		w.Write(strEqQuote)
+		w.Write(inputJs)
+		w.Write(strQuote)
+	}
+
 	for etype, _ := range c.handlers {
 		etypeAttr := etypeAttrs[etype]
 		if len(etypeAttr) == 0 { // Only general events are added to the etypeAttrs map
@@ -283,13 +642,47 @@ func (c *compImpl) renderEHandlers(w Writer) {
 
 		// To render                 : ` <etypeAttr>="se(event,etype,compId,value)"`
 		// Example (checkbox onclick): ` onclick="se(event,0,4327,this.checked)"`
+		// With min event interval   : ` onclick="seMin(300,event,0,4327,this.checked)"`
+		// Debounced                 : ` oninput="seDeb(300,event,16,4327,encodeURIComponent(this.value))"`
+		// Buffered                  : ` onclick="sbuf(0,4327,this.checked)"`
+		// With key handlers         : ` onkeydown="if(keyMatch(event,[13,0]))se(event,7,4327)"`
+		// Context menu              : ` oncontextmenu="return seSync(event,14,4327)"`
+		// Wheel, default prevented  : ` onwheel="event.preventDefault();se(event,15,4327)"`
+		// With input JS             : ` oninput="maskInput(this);se(event,16,4327,encodeURIComponent(this.value))"`
 		w.Write(strSpace)
 		w.Write(etypeAttr)
-		w.Write(strSePrefix)
+		w.Write(strEqQuote)
+		if etype == ETypeInput && len(inputJs) > 0 {
+			w.Write(inputJs)
+			w.Write(strSemicol)
+		}
+		if etype == ETypeKeyDown {
+			c.renderKeyMatch(w)
+		}
+		if etype == ETypeWheel && c.wheelPd {
+			w.Write(strPreventDef)
+		}
+		// Input events always carry the (in-progress) value
+		sync := len(c.valueProviderJs) > 0 && (etype == ETypeInput || c.syncOnETypes != nil && c.syncOnETypes[etype])
+		if d := c.debounces[etype]; d > 0 {
+			w.Write(strSeDebPrefix)
+			w.Writev(int(d / time.Millisecond))
+			w.Write(strCommaEvent)
+		} else if etype == ETypeContextMenu {
+			w.Write(strSeSyncPrefix)
+		} else if c.buffered && sync {
+			w.Write(strSbufPrefix)
+		} else if c.minEventIntv > 0 {
+			w.Write(strSeMinPrefix)
+			w.Writev(c.minEventIntv)
+			w.Write(strCommaEvent)
+		} else {
+			w.Write(strSePrefix)
+		}
 		w.Writev(int(etype))
 		w.Write(strComma)
 		w.Writev(int(c.id))
-		if len(c.valueProviderJs) > 0 && c.syncOnETypes != nil && c.syncOnETypes[etype] {
+		if sync {
 			w.Write(strComma)
 			w.Write(c.valueProviderJs)
 		}
@@ -297,6 +690,28 @@ func (c *compImpl) renderEHandlers(w Writer) {
 	}
 }
 
+// renderKeyMatch renders the client side key matching condition
+// if all ETypeKeyDown handlers are key handlers (see AddKeyHandler()).
+func (c *compImpl) renderKeyMatch(w Writer) {
+	handlers := c.handlers[ETypeKeyDown]
+	for _, handler := range handlers {
+		if _, isKeyHandler := handler.(keyEHandler); !isKeyHandler {
+			return
+		}
+	}
+
+	// Key codes and modifier key masks in pairs
+	w.Write(strKeyMatchOp)
+	for i, handler := range handlers {
+		if i > 0 {
+			w.Write(strComma)
+		}
+		kh := handler.(keyEHandler)
+		w.Writevs(int(kh.keyCode), strComma, int(kh.modKeys))
+	}
+	w.Write(strKeyMatchCl)
+}
+
 // THIS IS AN EMPTY IMPLEMENTATION AS NOT ALL COMPONENTS NEED THIS.
 // THOSE WHO DO SHOULD DEFINE THEIR OWN.
 func (b *compImpl) preprocessEvent(event Event, r *http.Request) {
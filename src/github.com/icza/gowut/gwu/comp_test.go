@@ -0,0 +1,281 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Test helpers shared by the component tests.
+
+package gwu
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// renderString renders the component and returns the result as a string.
+func renderString(c Comp) string {
+	b := &bytes.Buffer{}
+	c.Render(NewWriter(b))
+	return b.String()
+}
+
+// newCompValueReq creates a request carrying the specified component value
+// the same way the client side sends it with events.
+func newCompValueReq(value string) *http.Request {
+	return &http.Request{Form: url.Values{paramCompValue: {value}}}
+}
+
+func TestMinEventIntervalRender(t *testing.T) {
+	tb := NewTextBox("")
+	tb.AddEHandlerFunc(func(e Event) {}, ETypeChange)
+
+	id := tb.Id().String()
+	if s := renderString(tb); !strings.Contains(s, ` onchange="se(event,`+ETypeChange.String()+`,`+id+`,encodeURIComponent(this.value))"`) {
+		t.Errorf("Event handler not rendered properly: %s", s)
+	}
+
+	tb.SetMinEventInterval(300)
+	if s := renderString(tb); !strings.Contains(s, ` onchange="seMin(300,event,`+ETypeChange.String()+`,`+id+`,encodeURIComponent(this.value))"`) {
+		t.Errorf("Interval guard not rendered properly: %s", s)
+	}
+}
+
+func TestEventDebounceRender(t *testing.T) {
+	tb := NewTextBox("")
+	tb.AddEHandlerFunc(func(e Event) {}, ETypeInput, ETypeChange)
+	tb.SetMinEventInterval(100)
+	tb.SetEventDebounce(ETypeInput, 300*time.Millisecond)
+	if d := tb.EventDebounce(ETypeInput); d != 300*time.Millisecond {
+		t.Errorf("Got debounce %v", d)
+	}
+
+	id := tb.Id().String()
+	s := renderString(tb)
+	if !strings.Contains(s, ` oninput="seDeb(300,event,`+ETypeInput.String()+`,`+id+`,encodeURIComponent(this.value))"`) {
+		t.Errorf("Debounce not rendered properly: %s", s)
+	}
+	if !strings.Contains(s, ` onchange="seMin(100,event,`) {
+		t.Errorf("Debounce applied to other event type: %s", s)
+	}
+
+	tb.SetEventDebounce(ETypeInput, 0)
+	if s := renderString(tb); strings.Contains(s, "seDeb(") || tb.EventDebounce(ETypeInput) != 0 {
+		t.Errorf("Debounce not removed: %s", s)
+	}
+	checkStaticJsFuncs(t, "seDeb")
+}
+
+func TestTabIndex(t *testing.T) {
+	l := NewLabel("")
+	if _, set := l.TabIndex(); set {
+		t.Error("Tab index is set by default")
+	}
+
+	l.SetTabIndex(0)
+	if s := renderString(l); !strings.Contains(s, ` tabindex="0"`) {
+		t.Errorf("Tab index not rendered: %s", s)
+	}
+	l.SetTabIndex(-1)
+	if s := renderString(l); !strings.Contains(s, ` tabindex="-1"`) {
+		t.Errorf("Tab index not rendered: %s", s)
+	}
+	if index, set := l.TabIndex(); index != -1 || !set {
+		t.Errorf("Got tab index %d (set: %v), want -1", index, set)
+	}
+
+	// Positive tab index is rendered, but a warning is logged
+	b := &bytes.Buffer{}
+	log.SetOutput(b)
+	defer log.SetOutput(os.Stderr)
+	l.SetTabIndex(3)
+	if s := renderString(l); !strings.Contains(s, ` tabindex="3"`) {
+		t.Errorf("Tab index not rendered: %s", s)
+	}
+	if !strings.Contains(b.String(), "WARNING: positive tab index (3)") {
+		t.Errorf("No warning logged for positive tab index: %q", b.String())
+	}
+
+	l.SetAttr("tabindex", "")
+	if s := renderString(l); strings.Contains(s, "tabindex") {
+		t.Errorf("Removed tab index rendered: %s", s)
+	}
+}
+
+func TestSyncOnETypes(t *testing.T) {
+	l := NewLabel("")
+	if etypes := l.SyncOnETypes(); etypes != nil {
+		t.Errorf("Got %v, want nil", etypes)
+	}
+
+	l.AddSyncOnETypes(ETypeChange)
+	if etypes := l.SyncOnETypes(); len(etypes) != 1 || etypes[0] != ETypeChange {
+		t.Errorf("Got %v, want [%v]", etypes, ETypeChange)
+	}
+
+	l.AddSyncOnETypes(ETypeBlur, ETypeChange, ETypeClick)
+	etypes := l.SyncOnETypes()
+	if len(etypes) != 3 || etypes[0] != ETypeClick || etypes[1] != ETypeBlur || etypes[2] != ETypeChange {
+		t.Errorf("Got %v, want sorted [%v %v %v]", etypes, ETypeClick, ETypeBlur, ETypeChange)
+	}
+}
+
+func TestKeyHandlerRender(t *testing.T) {
+	tb := NewTextBox("")
+	tb.AddKeyHandler(KeyEnter, 0, EmptyEHandler)
+	tb.AddKeyHandler(KeyEscape, ModKeyCtrl|ModKeyShift, EmptyEHandler)
+
+	prefix := ` onkeydown="`
+	suffix := `se(event,` + ETypeKeyDown.String() + `,` + tb.Id().String() + `)"`
+	want := prefix + `if(keyMatch(event,[13,0,27,` + strconv.Itoa(int(ModKeyCtrl|ModKeyShift)) + `]))` + suffix
+	if s := renderString(tb); !strings.Contains(s, want) {
+		t.Errorf("Rendered output does not contain %s: %s", want, s)
+	}
+
+	// A general key down handler needs all keys: no client side matching
+	tb.AddEHandlerFunc(func(e Event) {}, ETypeKeyDown)
+	if s := renderString(tb); !strings.Contains(s, prefix+suffix) {
+		t.Errorf("Rendered output does not contain %s: %s", prefix+suffix, s)
+	}
+}
+
+func TestVisibleAtRender(t *testing.T) {
+	l := NewLabel("menu")
+	render := func() string {
+		b := &bytes.Buffer{}
+		renderComp(NewWriter(b), l)
+		return b.String()
+	}
+	if s := render(); strings.Contains(s, "<style") {
+		t.Errorf("Media rendered without visibility range: %s", s)
+	}
+
+	id := l.Id().String()
+	sel := `){[id="` + id + `"]{display:none !important;}}`
+	cases := []struct {
+		min, max int
+		want     string
+	}{
+		{0, 767, `@media not all and (max-width:767px` + sel},
+		{768, 0, `@media not all and (min-width:768px` + sel},
+		{480, 1023, `@media not all and (min-width:480px` + sel + `@media not all and (max-width:1023px` + sel},
+	}
+	for _, c := range cases {
+		l.SetVisibleAt(c.min, c.max)
+		if min, max := l.VisibleAt(); min != c.min || max != c.max {
+			t.Errorf("Got visible at %d..%d, want %d..%d", min, max, c.min, c.max)
+		}
+		want := `<style id="` + id + `_media">` + c.want + `</style><span id="` + id + `"`
+		if s := render(); !strings.HasPrefix(s, want) {
+			t.Errorf("Rendered output does not start with %s: %s", want, s)
+		}
+	}
+
+	// Combined with responsive widths
+	l.SetVisibleAt(768, 0)
+	l.Style().SetResponsiveWidth(map[int]string{1200: "50%"})
+	want := `<style id="` + id + `_media">@media (min-width:1200px){[id="` + id + `"]{width:50%;}}@media not all and (min-width:768px` + sel + `</style>`
+	if s := render(); !strings.HasPrefix(s, want) {
+		t.Errorf("Rendered output does not start with %s: %s", want, s)
+	}
+
+	// Rendered along with the component by containers
+	p := NewPanel()
+	p.Add(l)
+	if s := renderString(p); !strings.Contains(s, want+`<span id="`+id+`"`) {
+		t.Errorf("Rendered output does not contain %s: %s", want, s)
+	}
+
+	l.Style().SetResponsiveWidth(nil)
+	l.SetVisibleAt(0, 0)
+	if s := render(); strings.Contains(s, "<style") {
+		t.Errorf("Media rendered after removing visibility range: %s", s)
+	}
+}
+
+func TestRemoveEHandlerFuncNS(t *testing.T) {
+	b := NewButton("")
+	var calls []string
+	handler := func(name string) func(e Event) {
+		return func(e Event) { calls = append(calls, name) }
+	}
+	b.AddEHandlerFunc(handler("app"), ETypeClick)
+	b.AddEHandlerFuncNS("widget", handler("widget"), ETypeClick, ETypeFocus)
+	b.AddEHandlerFuncNS("other", handler("other"), ETypeClick)
+	b.AddEHandlerFuncNS("widget", handler("widget2"), ETypeClick)
+
+	b.RemoveEHandlerFuncNS("widget", ETypeClick)
+	if n := b.HandlersCount(ETypeClick); n != 2 {
+		t.Errorf("Got %d click handlers, want 2", n)
+	}
+	if n := b.HandlersCount(ETypeFocus); n != 1 {
+		t.Errorf("Handler of other event type removed, got %d focus handlers", n)
+	}
+
+	b.dispatchEvent(newEventImpl(ETypeClick, b, nil, nil))
+	if got := strings.Join(calls, ","); got != "app,other" {
+		t.Errorf("Got calls %s, want app,other", got)
+	}
+
+	b.RemoveEHandlerFuncNS("widget", ETypeFocus)
+	if s := renderString(b); strings.Contains(s, "onfocus") {
+		t.Errorf("Removed handler rendered: %s", s)
+	}
+}
+
+func TestEnabledWhenRender(t *testing.T) {
+	lb := NewListBox([]string{"a", "other"})
+	tb := NewTextBox("")
+	tb.SetEnabledWhen(lb, EnabledPred{Op: EnPredMatches, Value: `^"other"$`})
+
+	s := renderString(tb)
+	for _, want := range []string{` data-gwuenwhen="` + lb.Id().String() + `"`,
+		` data-gwuenpred="` + strconv.Itoa(int(EnPredMatches)) + `"`, ` data-gwuenval="^&#34;other&#34;$"`} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Rendered %s does not contain %s", s, want)
+		}
+	}
+
+	tb.SetEnabledWhen(nil, EnabledPred{})
+	if s := renderString(tb); strings.Contains(s, "data-gwuen") {
+		t.Errorf("Dependency not removed: %s", s)
+	}
+}
+
+func TestInputEvent(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	tb := NewTextBox("")
+	var texts []string
+	tb.AddEHandlerFunc(func(e Event) { texts = append(texts, tb.Text()) }, ETypeInput)
+	win.Add(tb)
+
+	id := tb.Id().String()
+	if html := renderString(tb); !strings.Contains(html, ` oninput="se(event,`+ETypeInput.String()+`,`+id+`,encodeURIComponent(this.value))"`) {
+		t.Errorf("Input handler not rendered with value: %s", html)
+	}
+
+	for _, v := range []string{"g", "go", ""} {
+		sendEvent(s, &s.sessionImpl, win, url.Values{paramCompId: {id}, paramEventType: {ETypeInput.String()}, paramCompValue: {v}})
+	}
+	if want := []string{"g", "go", ""}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("Got texts %q, want %q", texts, want)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Countdown component interface and implementation.
+
+package gwu
+
+import (
+	"net/http"
+	"time"
+)
+
+// Countdown interface defines a component which displays the remaining
+// time until a target time, ticking down at the client side, e.g. for
+// auctions and sales.
+//
+// When the countdown reaches zero, an ETypeStateChange event is generated
+// (once).
+//
+// The remaining time is recomputed from the target time on each tick
+// (and when the page becomes visible again), so it does not drift even if
+// the browser throttles timers of hidden pages. The target time is sent to
+// the client as a remaining duration, so the clock of the client does not
+// need to be in sync with the server's.
+//
+// Default style classes: "gwu-Countdown", "gwu-Countdown-Zero"
+type Countdown interface {
+	// Countdown is a component.
+	Comp
+
+	// Target returns the target time.
+	Target() time.Time
+
+	// SetTarget sets the target time.
+	// This also resets the reached zero state (see ReachedZero()).
+	SetTarget(target time.Time)
+
+	// ReachedZero tells if the countdown reported reaching zero
+	// (the ETypeStateChange event has been generated).
+	ReachedZero() bool
+
+	// SetJsConverter sets the Javascript function name which converts
+	// a float second remaining time value to a displayable string.
+	// The default value is "convertCountdown" which formats the remaining
+	// time as "HH:MM:SS", prefixed with the days if there are any ("2d 03:04:05").
+	SetJsConverter(jsFuncName string)
+
+	// JsConverter returns the name of the Javascript function which converts
+	// float second remaining time values to displayable strings.
+	JsConverter() string
+}
+
+// Countdown implementation
+type countdownImpl struct {
+	timerImpl // Timer implementation, used to tick
+
+	target      time.Time // The target time
+	reachedZero bool      // Tells if the countdown reported reaching zero
+}
+
+// NewCountdown creates a new Countdown.
+// The displayed remaining time is refreshed every second.
+func NewCountdown(target time.Time) Countdown {
+	c := &countdownImpl{
+		timerImpl: timerImpl{compImpl: newCompImpl(nil), timeout: time.Second, active: true, repeat: true},
+		target:    target,
+	}
+	c.Style().AddClass("gwu-Countdown")
+	c.SetJsConverter("convertCountdown")
+	return c
+}
+
+func (c *countdownImpl) Target() time.Time {
+	return c.target
+}
+
+func (c *countdownImpl) SetTarget(target time.Time) {
+	c.target = target
+	c.reachedZero = false
+}
+
+func (c *countdownImpl) ReachedZero() bool {
+	return c.reachedZero
+}
+
+func (c *countdownImpl) SetJsConverter(jsFuncName string) {
+	c.SetAttr("gwuJsFuncName", jsFuncName)
+}
+
+func (c *countdownImpl) JsConverter() string {
+	return c.Attr("gwuJsFuncName")
+}
+
+func (c *countdownImpl) preprocessEvent(event Event, r *http.Request) {
+	if event.Type() == ETypeStateChange {
+		c.reachedZero = true
+	}
+}
+
+var (
+	strCountdownRem  = []byte(` data-gwuremaining="`) // ` data-gwuremaining="`
+	strCountdownZero = []byte(` data-gwuzero`)        // ` data-gwuzero`
+	strJsCountdownOp = []byte("countdownTick(")       // "countdownTick("
+)
+
+func (c *countdownImpl) Render(w Writer) {
+	// To render: <span id="compId" class="gwu-Countdown" data-gwuremaining="ms"><span></span><script>setupTimer(...);countdownTick(compId,etype);</script></span>
+	w.Write(strSpanOp)
+	c.renderAttrsAndStyle(w)
+	c.renderEHandlers(w)
+	remaining := time.Until(c.target)
+	if remaining < 0 {
+		remaining = 0
+	}
+	w.Write(strCountdownRem)
+	w.Writev(int(remaining / time.Millisecond))
+	w.Write(strQuote)
+	if c.reachedZero {
+		w.Write(strCountdownZero)
+	}
+	w.Write(strGT)
+
+	w.Write(strEmptySpan) // Placeholder for the remaining time
+
+	writeScriptOp(w)
+	c.renderSetupTimerJs(w, strJsCountdownOp, int(c.id), strComma, int(ETypeStateChange), strParenCl)
+	// Tick right away:
+	w.Write(strJsCountdownOp)
+	w.Writevs(int(c.id), strComma, int(ETypeStateChange))
+	w.Write(strJsFuncCl)
+	w.Write(strScriptCl)
+
+	w.Write(strSpanCl)
+}
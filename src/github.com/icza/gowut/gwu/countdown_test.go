@@ -0,0 +1,76 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCountdownRender(t *testing.T) {
+	c := NewCountdown(time.Now().Add(time.Hour))
+	id, et := c.Id().String(), ETypeStateChange.String()
+
+	s := renderString(c)
+	for _, want := range []string{
+		` gwuJsFuncName="convertCountdown"`,
+		`<span></span><script>setupTimer(` + id + `,function(){countdownTick(` + id + `,` + et + `)},1000,true,true,0);countdownTick(` + id + `,` + et + `);</script>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Rendered output does not contain %s: %s", want, s)
+		}
+	}
+
+	// Remaining time is rendered in milliseconds
+	i := strings.Index(s, ` data-gwuremaining="`)
+	if i < 0 {
+		t.Fatalf("Remaining time not rendered: %s", s)
+	}
+	ms, err := strconv.Atoi(s[i+20 : i+20+strings.IndexByte(s[i+20:], '"')])
+	if err != nil || ms <= 59*60*1000 || ms > 60*60*1000 {
+		t.Errorf("Got remaining ms %d (err: %v)", ms, err)
+	}
+
+	c.SetTarget(time.Now().Add(-time.Minute))
+	if s = renderString(c); !strings.Contains(s, ` data-gwuremaining="0"`) {
+		t.Errorf("Past target not rendered as zero remaining: %s", s)
+	}
+}
+
+func TestCountdownZeroEvent(t *testing.T) {
+	c := NewCountdown(time.Now())
+	if c.ReachedZero() || strings.Contains(renderString(c), "data-gwuzero") {
+		t.Error("Reached zero initially")
+	}
+
+	c.(*countdownImpl).preprocessEvent(newEventImpl(ETypeStateChange, c, nil, nil), newCompValueReq(""))
+	if !c.ReachedZero() {
+		t.Error("Zero event not processed")
+	}
+	// Zero event must not be generated again when re-rendered
+	if s := renderString(c); !strings.Contains(s, ` data-gwuzero>`) {
+		t.Errorf("Reached zero state not rendered: %s", s)
+	}
+
+	c.SetTarget(time.Now().Add(time.Minute))
+	if c.ReachedZero() {
+		t.Error("Reached zero state not reset by SetTarget()")
+	}
+
+	checkStaticJsFuncs(t, "setupTimer", "countdownTick")
+}
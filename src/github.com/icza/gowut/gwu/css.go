@@ -49,10 +49,16 @@ body {font-family:Arial}
 .gwu-Table {}
 
 .gwu-Label {}
+.gwu-Label-Editable {cursor:text}
 
 .gwu-Link {}
 
 .gwu-Image {}
+.gwu-Image[data-gwusrc] {filter:blur(4px)}
+.gwu-Image-FadeIn {animation:gwu-fadein 0.6s}
+@keyframes gwu-fadein {from {opacity:0} to {opacity:1}}
+
+.gwu-Iframe {}
 
 .gwu-Button {}
 
@@ -63,13 +69,56 @@ body {font-family:Arial}
 .gwu-RadioButton-Disabled {color:#888}
 
 .gwu-ListBox {}
+.gwu-ListBox-Filter {display:block; box-sizing:border-box}
 
 .gwu-TextBox {}
 
 .gwu-PasswBox {}
 
+.gwu-TimePicker {}
+
+.gwu-MaskedInput {}
+
+.gwu-DateTimePicker {}
+
+.gwu-ValueMirror {}
+
+.gwu-DataTable {}
+.gwu-DataTable-Table {border-collapse:collapse}
+.gwu-DataTable-Table td, .gwu-DataTable-Table th {border:1px solid #a0a0a0; padding:2px 5px}
+.gwu-DataTable-Header {background:#d0d0d0}
+.gwu-DataTable-Pager {padding-top:3px}
+.gwu-DataTable-PageInfo {padding:0px 8px}
+
+.gwu-SegmentedControl {display:inline-block; white-space:nowrap}
+.gwu-SegmentedControl-Segment {margin:0px; border:1px solid #7070ff; background:#ffffff; color:#0000a0; padding:2px 10px}
+.gwu-SegmentedControl-Segment:first-child {border-radius:5px 0px 0px 5px}
+.gwu-SegmentedControl-Segment:last-child {border-radius:0px 5px 5px 0px}
+.gwu-SegmentedControl-Active {background:#7070ff; color:#ffffff}
+
+.gwu-TagInput {display:inline-block; border:1px solid #a0a0a0; padding:1px}
+.gwu-TagInput-Tag {display:inline-block; margin:1px; padding:0px 4px; border-radius:3px; background:#c0c0ff}
+.gwu-TagInput-Remove {margin-left:4px; cursor:pointer}
+.gwu-TagInput-Input {border:0px; outline:none}
+
+.gwu-NumberSpinner {white-space:nowrap}
+.gwu-NumberSpinner-Button {min-width:2em}
+.gwu-NumberSpinner-Value {display:inline-block; min-width:3em; text-align:center}
+
 .gwu-Html {}
 
+.gwu-Rating {white-space:nowrap}
+.gwu-Rating-Star {font-size:120%; cursor:pointer}
+.gwu-Rating-Full {color:#f0b000}
+.gwu-Rating-Half {background:linear-gradient(to right, #f0b000 50%, #c0c0c0 50%); -webkit-background-clip:text; background-clip:text; color:transparent}
+.gwu-Rating-Empty {color:#c0c0c0}
+
+.gwu-RichText {display:inline-block; border:1px solid #a0a0a0}
+.gwu-RichText-Toolbar {background:#e0e0e0; padding:2px}
+.gwu-RichText-Button {min-width:2em; margin-right:2px}
+.gwu-RichText-Editor {min-width:200px; min-height:4em; padding:3px; background:#ffffff}
+.gwu-RichText-Editor[contenteditable=false] {background:#f0f0f0}
+
 .gwu-SwitchButton {}
 .gwu-SwitchButton-On-Active {background:#00a000; color:#d0ffd0}
 .gwu-SwitchButton-Off-Active {background:#d03030; color:#ffd0d0}
@@ -78,6 +127,19 @@ body {font-family:Arial}
 .gwu-SwitchButton-On-Active, .gwu-SwitchButton-Off-Active, .gwu-SwitchButton-On-Inactive, .gwu-SwitchButton-Off-Inactive {margin:0px;border: 0px; width:100%}
 .gwu-SwitchButton-On-Active:disabled, .gwu-SwitchButton-Off-Active:disabled, .gwu-SwitchButton-On-Inactive:disabled, .gwu-SwitchButton-Off-Inactive:disabled {color:black}
 
+.gwu-SplitPanel {width:100%; height:100%}
+.gwu-SplitPanel-Divider {background:#c0c0ff}
+.gwu-SplitPanel-Divider-H {width:5px; cursor:col-resize}
+.gwu-SplitPanel-Divider-V {height:5px; cursor:row-resize}
+
+.gwu-FieldSet {}
+.gwu-FieldSet-Legend {}
+
+.gwu-ShadowHost {}
+
+.gwu-Disclosure {}
+.gwu-Disclosure-Summary {cursor:pointer; font-weight:bold}
+
 .gwu-Expander {}
 .gwu-Expander-Header, .gwu-Expander-Header-Expanded {cursor:pointer}
 .gwu-Expander-Header, .gwu-Expander-Header-Expanded, .gwu-Expander-Content {padding-left:19px}
@@ -92,8 +154,44 @@ body {font-family:Arial}
 .gwu-TabPanel {}
 .gwu-TabPanel-Content {border:1px solid #8080f8; width:100%; height:100%}
 
+.gwu-Wizard {}
+.gwu-Wizard-Progress {margin-bottom:5px}
+.gwu-Wizard-Step {display:inline-block; padding:2px 8px; color:#808080; border-bottom:3px solid #d0d0d0}
+.gwu-Wizard-Step-Done {color:#000000; border-bottom-color:#8080f8}
+.gwu-Wizard-Step-Active {color:#000000; font-weight:bold; border-bottom-color:#0000d0}
+.gwu-Wizard-Content {padding:5px}
+.gwu-Wizard-Nav {text-align:right}
+.gwu-Wizard-Button {min-width:5em; margin-left:5px}
+
+.gwu-ScrollSpy {}
+.gwu-ScrollSpy-Header {position:sticky; top:0px; z-index:1; background:white}
+.gwu-ScrollSpy-Body {display:flex; align-items:flex-start}
+.gwu-ScrollSpy-Nav {position:sticky; top:0px; display:flex; flex-direction:column; padding:5px; border-right:3px solid #d0d0d0}
+.gwu-ScrollSpy-Link {border:none; background:none; text-align:left; padding:2px 8px; color:#808080; cursor:pointer}
+.gwu-ScrollSpy-Link-Active {color:#000000; font-weight:bold; border-left:3px solid #0000d0}
+.gwu-ScrollSpy-Content {flex:1; padding:5px}
+.gwu-ScrollSpy-Section {}
+
 .gwu-SessMonitor {}
 .gwu-SessMonitor-Expired, .gwu-SessMonitor-Error {color:red}
+
+.gwu-Countdown {font-family:monospace}
+.gwu-Countdown-Zero {color:red}
+
+.gwu-Spinner {display:inline-block; box-sizing:border-box; vertical-align:middle; border:3px solid #c0c0ff; border-top-color:#8080f8; border-radius:50%; animation:gwu-spin 0.8s linear infinite}
+@keyframes gwu-spin {to {transform:rotate(360deg)}}
+
+.gwu-SkipLink {position:absolute; left:-10000px; top:0px; width:1px; height:1px; overflow:hidden}
+.gwu-SkipLink:focus {left:0px; width:auto; height:auto; padding:5px; background:white; z-index:1000}
+
+.gwu-Busy {cursor:progress}
+
+.gwu-PrintStatic {display:none; white-space:pre-wrap}
+@media print {
+[data-gwuprintstatic] .gwu-PrintStatic {display:inline}
+[data-gwuprintstatic] .gwu-PrintStatic + * {display:none}
+[data-gwuprintstatic] .gwu-ListBox-Filter {display:none}
+}
 `)
 
 	staticCss[resNameStaticCss(ThemeDebug)] = []byte(string(staticCss[resNameStaticCss(ThemeDefault)]) +
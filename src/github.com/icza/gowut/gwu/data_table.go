@@ -0,0 +1,290 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// DataTable component interface and implementation.
+
+package gwu
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Row is a row of tabular data provided by a DataSource.
+type Row []string
+
+// DataSource interface defines a source of tabular data which is queried
+// page by page, so the data does not have to be materialized at once
+// (e.g. it may be backed by a database table having millions of rows).
+type DataSource interface {
+	// Count returns the number of rows.
+	Count() int
+
+	// Rows returns at most limit rows starting at the specified offset.
+	// Less rows (or no rows at all) may be returned if the offset
+	// is near or beyond the end of the data.
+	Rows(offset, limit int) []Row
+}
+
+// SliceDataSource is a DataSource backed by a slice of rows.
+type SliceDataSource []Row
+
+// Count returns the number of rows.
+func (s SliceDataSource) Count() int {
+	return len(s)
+}
+
+// Rows returns at most limit rows starting at the specified offset.
+// Out of range offsets and limits are clamped to the valid range.
+func (s SliceDataSource) Rows(offset, limit int) []Row {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(s) || limit <= 0 {
+		return nil
+	}
+	end := offset + limit
+	if end > len(s) || end < offset { // end < offset if overflows
+		end = len(s)
+	}
+	return s[offset:end]
+}
+
+// DataTable interface defines a component which displays tabular data
+// of a DataSource page by page. Only the rows of the current page are
+// queried from the data source when the component is rendered,
+// and no components are created for the rows.
+//
+// Navigation buttons are rendered below the table to step between pages.
+// Navigation sends an ETypeStateChange event to the server, which
+// changes the page and marks the DataTable dirty automatically.
+// You can register ETypeStateChange event handlers which will be called
+// when the user navigates to another page.
+//
+// Default style classes: "gwu-DataTable", "gwu-DataTable-Table",
+// "gwu-DataTable-Header", "gwu-DataTable-Pager", "gwu-DataTable-PageInfo"
+type DataTable interface {
+	// DataTable is a component.
+	Comp
+
+	// DataSource returns the data source.
+	DataSource() DataSource
+
+	// SetDataSource sets the data source, and navigates to the first page.
+	SetDataSource(ds DataSource)
+
+	// Header returns the column headers.
+	Header() []string
+
+	// SetHeader sets the column headers.
+	// Pass nil to not render a header row.
+	SetHeader(header []string)
+
+	// PageSize returns the number of rows displayed on a page.
+	PageSize() int
+
+	// SetPageSize sets the number of rows displayed on a page.
+	// Values less than 1 are ignored.
+	SetPageSize(pageSize int)
+
+	// Page returns the current (zero-based) page index.
+	Page() int
+
+	// SetPage sets the current (zero-based) page index,
+	// clamped to the valid range.
+	SetPage(page int)
+
+	// PageCount returns the number of pages (at least 1).
+	PageCount() int
+
+	// PageRows queries the rows of the current page from the data source.
+	PageRows() []Row
+}
+
+// DataTable implementation.
+type dataTableImpl struct {
+	compImpl // Component implementation
+
+	ds       DataSource // Data source
+	header   []string   // Column headers
+	pageSize int        // Number of rows on a page
+	page     int        // Current page index
+}
+
+// NewDataTable creates a new DataTable.
+// Default page size is 20.
+func NewDataTable(ds DataSource) DataTable {
+	c := &dataTableImpl{compImpl: newCompImpl(nil), ds: ds, pageSize: 20}
+	c.Style().AddClass("gwu-DataTable")
+	return c
+}
+
+func (c *dataTableImpl) DataSource() DataSource {
+	return c.ds
+}
+
+func (c *dataTableImpl) SetDataSource(ds DataSource) {
+	c.ds = ds
+	c.page = 0
+}
+
+func (c *dataTableImpl) Header() []string {
+	return c.header
+}
+
+func (c *dataTableImpl) SetHeader(header []string) {
+	c.header = header
+}
+
+func (c *dataTableImpl) PageSize() int {
+	return c.pageSize
+}
+
+func (c *dataTableImpl) SetPageSize(pageSize int) {
+	if pageSize < 1 {
+		return
+	}
+	c.pageSize = pageSize
+	c.SetPage(c.page)
+}
+
+func (c *dataTableImpl) Page() int {
+	return c.page
+}
+
+func (c *dataTableImpl) SetPage(page int) {
+	c.setPage(page, c.PageCount())
+}
+
+// setPage sets the current page clamped to the specified page count.
+func (c *dataTableImpl) setPage(page, pageCount int) {
+	if max := pageCount - 1; page > max {
+		page = max
+	}
+	if page < 0 {
+		page = 0
+	}
+	c.page = page
+}
+
+func (c *dataTableImpl) PageCount() int {
+	if c.ds == nil {
+		return 1
+	}
+	if count := (c.ds.Count() + c.pageSize - 1) / c.pageSize; count > 1 {
+		return count
+	}
+	return 1
+}
+
+func (c *dataTableImpl) PageRows() []Row {
+	// Data source might have shrunk since the page was set
+	c.SetPage(c.page)
+	return c.pageRows()
+}
+
+// pageRows returns the rows of the current page without clamping the page.
+func (c *dataTableImpl) pageRows() []Row {
+	if c.ds == nil {
+		return nil
+	}
+	return c.ds.Rows(c.page*c.pageSize, c.pageSize)
+}
+
+func (c *dataTableImpl) preprocessEvent(event Event, r *http.Request) {
+	if event.Type() != ETypeStateChange {
+		return
+	}
+
+	// Navigation buttons send the target page index
+	page, err := strconv.Atoi(r.FormValue(paramCompValue))
+	if err != nil {
+		return
+	}
+	c.SetPage(page)
+	event.MarkDirty(c)
+}
+
+var (
+	strDataTableOp     = []byte(`<table class="gwu-DataTable-Table">`)       // `<table class="gwu-DataTable-Table">`
+	strDataTableHeadOp = []byte(`<tr class="gwu-DataTable-Header">`)         // `<tr class="gwu-DataTable-Header">`
+	strTH              = []byte("<th>")                                      // "<th>"
+	strTHCl            = []byte("</th>")                                     // "</th>"
+	strTDCl            = []byte("</td>")                                     // "</td>"
+	strTRCl            = []byte("</tr>")                                     // "</tr>"
+	strDataTablePager  = []byte(`</table><div class="gwu-DataTable-Pager">`) // `</table><div class="gwu-DataTable-Pager">`
+	strDataTableBtnOp  = []byte(`<button type="button" onclick="se(event,`)  // `<button type="button" onclick="se(event,`
+	strDataTableBtnCl  = []byte(`);event.stopPropagation()"`)                // `);event.stopPropagation()"`
+	strDataTablePageOp = []byte(`<span class="gwu-DataTable-PageInfo">`)     // `<span class="gwu-DataTable-PageInfo">`
+	strDivOp           = []byte("<div")                                      // "<div"
+	strDivCl           = []byte("</div>")                                    // "</div>"
+)
+
+func (c *dataTableImpl) Render(w Writer) {
+	// Count the rows once per render (counting may be expensive),
+	// the data source might have shrunk since the page was set.
+	pageCount := c.PageCount()
+	c.setPage(c.page, pageCount)
+	rows := c.pageRows()
+
+	w.Write(strDivOp)
+	c.renderAttrsAndStyle(w)
+	c.renderEHandlers(w)
+	w.Write(strGT)
+
+	w.Write(strDataTableOp)
+	if c.header != nil {
+		w.Write(strDataTableHeadOp)
+		for _, h := range c.header {
+			w.Write(strTH)
+			w.Writees(h)
+			w.Write(strTHCl)
+		}
+		w.Write(strTRCl)
+	}
+	for _, row := range rows {
+		w.Write(strTR)
+		for _, cell := range row {
+			w.Write(strTD)
+			w.Writees(cell)
+			w.Write(strTDCl)
+		}
+		w.Write(strTRCl)
+	}
+
+	w.Write(strDataTablePager)
+	c.renderNavButton(w, "&lt;", c.page-1, c.page == 0)
+	w.Write(strDataTablePageOp)
+	w.Writevs(c.page+1, " / ", pageCount)
+	w.Write(strSpanCl)
+	c.renderNavButton(w, "&gt;", c.page+1, c.page >= pageCount-1)
+	w.Write(strDivCl)
+
+	w.Write(strDivCl)
+}
+
+// renderNavButton renders a navigation button which navigates to the specified page.
+func (c *dataTableImpl) renderNavButton(w Writer, text string, page int, disabled bool) {
+	// To render: <button type="button" onclick="se(event,etype,compId,page);event.stopPropagation()">text</button>
+	w.Write(strDataTableBtnOp)
+	w.Writevs(int(ETypeStateChange), strComma, int(c.id), strComma, page)
+	w.Write(strDataTableBtnCl)
+	if disabled {
+		w.Write(strDisabled)
+	}
+	w.Write(strGT)
+	w.Writes(text)
+	w.Write(strButtonCl)
+}
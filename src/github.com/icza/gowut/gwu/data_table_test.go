@@ -0,0 +1,165 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// countingDataSource is a DataSource which records the queries.
+type countingDataSource struct {
+	count   int
+	counts  int // Number of Count() calls
+	queries []string
+}
+
+func (ds *countingDataSource) Count() int {
+	ds.counts++
+	return ds.count
+}
+
+func (ds *countingDataSource) Rows(offset, limit int) []Row {
+	ds.queries = append(ds.queries, strconv.Itoa(offset)+":"+strconv.Itoa(limit))
+	var rows []Row
+	for i := offset; i < offset+limit && i < ds.count; i++ {
+		rows = append(rows, Row{strconv.Itoa(i)})
+	}
+	return rows
+}
+
+func TestSliceDataSource(t *testing.T) {
+	ds := SliceDataSource{{"a"}, {"b"}, {"c"}}
+	cases := []struct {
+		offset, limit int
+		want          []Row
+	}{
+		{0, 2, []Row{{"a"}, {"b"}}},
+		{1, 5, []Row{{"b"}, {"c"}}},
+		{-1, 1, []Row{{"a"}}},
+		{3, 2, nil},
+		{100, 2, nil},
+		{0, 0, nil},
+		{2, int(^uint(0) >> 1), []Row{{"c"}}}, // Overflowing end
+	}
+	for _, c := range cases {
+		if rows := ds.Rows(c.offset, c.limit); !reflect.DeepEqual(rows, c.want) {
+			t.Errorf("Rows(%d, %d): got %q, want %q", c.offset, c.limit, rows, c.want)
+		}
+	}
+}
+
+func TestDataTablePaging(t *testing.T) {
+	ds := &countingDataSource{count: 1000000}
+	dt := NewDataTable(ds)
+	dt.SetPageSize(10)
+
+	if n := dt.PageCount(); n != 100000 {
+		t.Errorf("Got page count %d", n)
+	}
+
+	dt.SetPage(3)
+	rows := dt.PageRows()
+	if len(rows) != 10 || rows[0][0] != "30" {
+		t.Errorf("Got rows %q", rows)
+	}
+	if !reflect.DeepEqual(ds.queries, []string{"30:10"}) {
+		t.Errorf("Got queries %q", ds.queries)
+	}
+
+	// Out of range pages are clamped
+	dt.SetPage(-5)
+	if dt.Page() != 0 {
+		t.Errorf("Got page %d", dt.Page())
+	}
+	dt.SetPage(200000)
+	if dt.Page() != 99999 {
+		t.Errorf("Got page %d", dt.Page())
+	}
+
+	// Data source shrinks: page is clamped when queried
+	ds.count = 25
+	ds.queries = nil
+	if rows := dt.PageRows(); len(rows) != 5 || rows[0][0] != "20" {
+		t.Errorf("Got rows %q", rows)
+	}
+	if !reflect.DeepEqual(ds.queries, []string{"20:10"}) {
+		t.Errorf("Got queries %q", ds.queries)
+	}
+
+	ds.count = 0
+	if n := dt.PageCount(); n != 1 {
+		t.Errorf("Got page count %d for empty data source", n)
+	}
+	if rows := dt.PageRows(); len(rows) != 0 || dt.Page() != 0 {
+		t.Errorf("Got rows %q, page %d", rows, dt.Page())
+	}
+}
+
+func TestDataTableNavigation(t *testing.T) {
+	dt := NewDataTable(&countingDataSource{count: 45})
+	dt.SetPageSize(10)
+
+	steps := []struct {
+		value string
+		page  int
+		dirty bool
+	}{
+		{"1", 1, true},
+		{"4", 4, true},
+		{"5", 4, true}, // Clamped
+		{"-1", 0, true},
+		{"x", 0, false},
+	}
+	for i, s := range steps {
+		e := newEventImpl(ETypeStateChange, dt, nil, nil)
+		dt.preprocessEvent(e, newCompValueReq(s.value))
+		if dt.Page() != s.page {
+			t.Errorf("Step %d: got page %d, want %d", i, dt.Page(), s.page)
+		}
+		if dirty := e.shared.dirty(dt); dirty != s.dirty {
+			t.Errorf("Step %d: dirty = %v", i, dirty)
+		}
+	}
+}
+
+func TestDataTableRender(t *testing.T) {
+	ds := &countingDataSource{count: 15}
+	dt := NewDataTable(ds)
+	dt.SetPageSize(10)
+	dt.SetHeader([]string{"<N>"})
+	dt.SetPage(1)
+	ds.counts = 0
+
+	id, et := dt.Id().String(), ETypeStateChange.String()
+	s := renderString(dt)
+	for _, want := range []string{
+		`<table class="gwu-DataTable-Table"><tr class="gwu-DataTable-Header"><th>&lt;N&gt;</th></tr><tr><td>10</td></tr>`,
+		`<tr><td>14</td></tr></table>`,
+		`<button type="button" onclick="se(event,` + et + `,` + id + `,0);event.stopPropagation()">&lt;</button>`,
+		`<span class="gwu-DataTable-PageInfo">2 / 2</span>`,
+		`<button type="button" onclick="se(event,` + et + `,` + id + `,2);event.stopPropagation()" disabled="disabled">&gt;</button>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Rendered output does not contain %s: %s", want, s)
+		}
+	}
+	if len(ds.queries) != 1 || ds.counts != 1 {
+		t.Errorf("Got queries %q, %d counts", ds.queries, ds.counts)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// DateTimePicker component interface and implementation.
+
+package gwu
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DateTimePicker interface defines a component for picking a date and
+// a time of the day. It is rendered as an HTML5 datetime-local input.
+//
+// The value of a datetime-local input is "naive": it carries no time zone
+// information, it is the wall clock time the user entered. DateTime()
+// interprets it in the local time zone of the server (time.Local),
+// and SetDateTime(), SetMin() and SetMax() use the wall clock of the passed
+// time in its own location. If the time zone of the user differs,
+// convert times with Time.In() before setting them, and re-interpret the
+// wall clock of the returned time as needed.
+//
+// Suggested event type to handle changes: ETypeChange
+//
+// Default style class: "gwu-DateTimePicker"
+type DateTimePicker interface {
+	// DateTimePicker is a component.
+	Comp
+
+	// DateTimePicker can be enabled/disabled.
+	HasEnabled
+
+	// DateTime returns the picked date and time, interpreted in time.Local.
+	// The zero Time is returned (with nil error) if no value is picked.
+	// An error is returned if the value sent by the client is malformed.
+	DateTime() (time.Time, error)
+
+	// SetDateTime sets the picked date and time.
+	// Pass the zero Time to clear the value.
+	SetDateTime(t time.Time)
+
+	// Min returns the minimum date and time that can be picked.
+	// The zero Time is returned if there is no minimum.
+	Min() time.Time
+
+	// SetMin sets the minimum date and time that can be picked.
+	// Pass the zero Time for no minimum.
+	SetMin(min time.Time)
+
+	// Max returns the maximum date and time that can be picked.
+	// The zero Time is returned if there is no maximum.
+	Max() time.Time
+
+	// SetMax sets the maximum date and time that can be picked.
+	// Pass the zero Time for no maximum.
+	SetMax(max time.Time)
+}
+
+// Layout of the value of a datetime-local input.
+// The seconds part is optional in values sent by the client.
+const (
+	dateTimeLocalLayout    = "2006-01-02T15:04"
+	dateTimeLocalLayoutSec = "2006-01-02T15:04:05"
+)
+
+// DateTimePicker implementation.
+type dateTimePickerImpl struct {
+	compImpl       // Component implementation
+	hasEnabledImpl // Has enabled implementation
+
+	value    string    // Raw value as sent by the client, empty string if no value
+	min, max time.Time // Min and max, zero Time if not set
+}
+
+// NewDateTimePicker creates a new DateTimePicker.
+// Pass the zero Time for an initially empty picker.
+func NewDateTimePicker(t time.Time) DateTimePicker {
+	c := &dateTimePickerImpl{compImpl: newCompImpl(strEncURIThisV), hasEnabledImpl: newHasEnabledImpl()}
+	c.SetDateTime(t)
+	c.AddSyncOnETypes(ETypeChange)
+	c.Style().AddClass("gwu-DateTimePicker")
+	return c
+}
+
+func (c *dateTimePickerImpl) DateTime() (time.Time, error) {
+	if c.value == "" {
+		return time.Time{}, nil
+	}
+	return parseDateTimeLocal(c.value)
+}
+
+func (c *dateTimePickerImpl) SetDateTime(t time.Time) {
+	c.value = formatDateTimeLocal(t)
+}
+
+func (c *dateTimePickerImpl) Min() time.Time {
+	return c.min
+}
+
+func (c *dateTimePickerImpl) SetMin(min time.Time) {
+	c.min = min
+}
+
+func (c *dateTimePickerImpl) Max() time.Time {
+	return c.max
+}
+
+func (c *dateTimePickerImpl) SetMax(max time.Time) {
+	c.max = max
+}
+
+func (c *dateTimePickerImpl) preprocessEvent(event Event, r *http.Request) {
+	c.value = r.FormValue(paramCompValue)
+}
+
+// parseDateTimeLocal parses a datetime-local value ("T" separated date and time,
+// with optional seconds and fraction of seconds) in time.Local.
+func parseDateTimeLocal(value string) (time.Time, error) {
+	layout := dateTimeLocalLayout
+	if len(value) > len(dateTimeLocalLayout) {
+		layout = dateTimeLocalLayoutSec
+	}
+	return time.ParseInLocation(layout, value, time.Local)
+}
+
+// formatDateTimeLocal formats a time as a datetime-local value.
+// Empty string is returned for the zero Time.
+func formatDateTimeLocal(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	if t.Second() != 0 {
+		return t.Format(dateTimeLocalLayoutSec)
+	}
+	return t.Format(dateTimeLocalLayout)
+}
+
+var (
+	strDateTimeInputOp = []byte(`<input type="datetime-local" value="`) // `<input type="datetime-local" value="`
+	strMin             = []byte(` min="`)                               // ` min="`
+	strMax             = []byte(` max="`)                               // ` max="`
+)
+
+func (c *dateTimePickerImpl) Render(w Writer) {
+	text := strings.Replace(c.value, "T", " ", 1)
+	if locale := writerLocale(w); locale != "" {
+		if t, err := parseDateTimeLocal(c.value); err == nil {
+			text = FormatDateTime(locale, t)
+		}
+	}
+	c.renderPrintStatic(w, text)
+
+	w.Write(strDateTimeInputOp)
+	w.Writees(c.value)
+	w.Write(strQuote)
+	if !c.min.IsZero() {
+		w.Write(strMin)
+		w.Writes(formatDateTimeLocal(c.min))
+		w.Write(strQuote)
+	}
+	if !c.max.IsZero() {
+		w.Write(strMax)
+		w.Writes(formatDateTimeLocal(c.max))
+		w.Write(strQuote)
+	}
+	c.renderAttrsAndStyle(w)
+	c.renderEnabled(w)
+	c.renderEHandlers(w)
+	w.Write(strInputCl2)
+}
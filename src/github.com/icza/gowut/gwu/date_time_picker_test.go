@@ -0,0 +1,82 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDateTimeLocal(t *testing.T) {
+	valid := []struct {
+		value string
+		t     time.Time
+	}{
+		{"2016-03-15T09:05", time.Date(2016, 3, 15, 9, 5, 0, 0, time.Local)},
+		{"2016-03-15T23:59:30", time.Date(2016, 3, 15, 23, 59, 30, 0, time.Local)},
+		{"2016-03-15T23:59:30.500", time.Date(2016, 3, 15, 23, 59, 30, 500e6, time.Local)},
+	}
+	for _, c := range valid {
+		if got, err := parseDateTimeLocal(c.value); err != nil || !got.Equal(c.t) {
+			t.Errorf("parseDateTimeLocal(%q) = %v, %v; want %v", c.value, got, err, c.t)
+		}
+	}
+
+	invalid := []string{"2016-03-15", "2016-03-15 09:05", "2016-03-15T25:00", "2016-13-01T10:00", "foo"}
+	for _, v := range invalid {
+		if _, err := parseDateTimeLocal(v); err == nil {
+			t.Errorf("parseDateTimeLocal(%q) expected to fail", v)
+		}
+	}
+}
+
+func TestDateTimePickerDateTime(t *testing.T) {
+	dtp := NewDateTimePicker(time.Time{})
+	if got, err := dtp.DateTime(); err != nil || !got.IsZero() {
+		t.Errorf("Got %v, %v; want zero Time", got, err)
+	}
+
+	dtp.(*dateTimePickerImpl).preprocessEvent(nil, newCompValueReq("2016-03-15T09:05"))
+	want := time.Date(2016, 3, 15, 9, 5, 0, 0, time.Local)
+	if got, err := dtp.DateTime(); err != nil || !got.Equal(want) {
+		t.Errorf("Got %v, %v; want %v", got, err, want)
+	}
+
+	dtp.(*dateTimePickerImpl).preprocessEvent(nil, newCompValueReq("garbage"))
+	if _, err := dtp.DateTime(); err == nil {
+		t.Errorf("Expected error for malformed value")
+	}
+}
+
+func TestDateTimePickerRender(t *testing.T) {
+	dtp := NewDateTimePicker(time.Date(2016, 3, 15, 9, 5, 0, 0, time.UTC))
+
+	s := renderString(dtp)
+	if !strings.Contains(s, `value="2016-03-15T09:05"`) {
+		t.Errorf("Value not rendered properly: %s", s)
+	}
+	if strings.Contains(s, "min=") || strings.Contains(s, "max=") {
+		t.Errorf("Min or max rendered when not set: %s", s)
+	}
+
+	dtp.SetMin(time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC))
+	dtp.SetMax(time.Date(2016, 12, 31, 23, 59, 30, 0, time.UTC))
+	s = renderString(dtp)
+	if !strings.Contains(s, ` min="2016-01-01T00:00"`) || !strings.Contains(s, ` max="2016-12-31T23:59:30"`) {
+		t.Errorf("Min or max not rendered properly: %s", s)
+	}
+}
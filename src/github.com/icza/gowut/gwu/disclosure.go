@@ -0,0 +1,174 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Disclosure component interface and implementation.
+
+package gwu
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Disclosure interface defines a lightweight collapsible container,
+// rendered as an HTML5 details element: the content component is shown
+// or hidden by clicking on the summary text, without a server round trip.
+//
+// The open state is synchronized to the server, and you can register
+// ETypeStateChange event handlers which will be called when the user
+// opens or closes the disclosure.
+//
+// Default style classes: "gwu-Disclosure", "gwu-Disclosure-Summary"
+type Disclosure interface {
+	// Disclosure is a container.
+	Container
+
+	// Disclosure has text which is its summary.
+	HasText
+
+	// Content returns the content component.
+	Content() Comp
+
+	// SetContent sets the content component.
+	SetContent(c Comp)
+
+	// Open tells if the disclosure is open (the content is visible).
+	Open() bool
+
+	// SetOpen sets whether the disclosure is open.
+	SetOpen(open bool)
+}
+
+// Disclosure implementation.
+type disclosureImpl struct {
+	compImpl    // Component implementation
+	hasTextImpl // Has text implementation
+
+	content Comp // Content component
+	open    bool // Tells if the disclosure is open
+}
+
+// NewDisclosure creates a new Disclosure.
+// By default disclosures are closed.
+func NewDisclosure(summary string) Disclosure {
+	c := &disclosureImpl{compImpl: newCompImpl(nil), hasTextImpl: newHasTextImpl(summary)}
+	c.Style().AddClass("gwu-Disclosure")
+	return c
+}
+
+func (c *disclosureImpl) Remove(c2 Comp) bool {
+	if c.content == nil || !c.content.Equals(c2) {
+		return false
+	}
+	c2.setParent(nil)
+	c.content = nil
+	return true
+}
+
+func (c *disclosureImpl) ById(id ID) Comp {
+	if c.id == id {
+		return c
+	}
+
+	if c.content != nil {
+		if c.content.Id() == id {
+			return c.content
+		}
+		if c2, isContainer := c.content.(Container); isContainer {
+			return c2.ById(id)
+		}
+	}
+
+	return nil
+}
+
+func (c *disclosureImpl) children() []Comp {
+	if c.content == nil {
+		return nil
+	}
+	return []Comp{c.content}
+}
+
+func (c *disclosureImpl) Clear() {
+	if c.content != nil {
+		c.content.setParent(nil)
+		c.content = nil
+	}
+}
+
+func (c *disclosureImpl) Content() Comp {
+	return c.content
+}
+
+func (c *disclosureImpl) SetContent(content Comp) {
+	content.makeOrphan()
+	c.content = content
+	content.setParent(c)
+}
+
+func (c *disclosureImpl) Open() bool {
+	return c.open
+}
+
+func (c *disclosureImpl) SetOpen(open bool) {
+	c.open = open
+}
+
+func (c *disclosureImpl) preprocessEvent(event Event, r *http.Request) {
+	if event.Type() != ETypeStateChange {
+		return
+	}
+	// The client already displays the new state, no need to mark dirty
+	if open, err := strconv.ParseBool(r.FormValue(paramCompValue)); err == nil {
+		c.open = open
+	}
+}
+
+var (
+	strDetailsOp  = []byte("<details")                                  // "<details"
+	strOpen       = []byte(" open")                                     // " open"
+	strDataGwuOpn = []byte(` data-gwuopen="`)                           // ` data-gwuopen="`
+	strOnToggleOp = []byte(` ontoggle="detailsToggle(this,`)            // ` ontoggle="detailsToggle(this,`
+	strSummaryOp  = []byte(`><summary class="gwu-Disclosure-Summary">`) // `><summary class="gwu-Disclosure-Summary">`
+	strSummaryCl  = []byte("</summary>")                                // "</summary>"
+	strDetailsCl  = []byte("</details>")                                // "</details>"
+)
+
+func (c *disclosureImpl) Render(w Writer) {
+	// To render: <details id="compId" data-gwuopen="false" ontoggle="detailsToggle(this,etype)" ...><summary class="gwu-Disclosure-Summary">text</summary>content</details>
+	w.Write(strDetailsOp)
+	if c.open {
+		w.Write(strOpen)
+	}
+	c.renderAttrsAndStyle(w)
+	c.renderEHandlers(w)
+	// The client only reports actual state changes (toggle is also fired
+	// when an open details element is rendered).
+	w.Write(strDataGwuOpn)
+	w.Writev(c.open)
+	w.Write(strQuote)
+	w.Write(strOnToggleOp)
+	w.Writev(int(ETypeStateChange))
+	w.Write(strSeSuffix)
+	w.Write(strSummaryOp)
+	c.renderText(w)
+	w.Write(strSummaryCl)
+
+	if c.content != nil {
+		renderComp(w, c.content)
+	}
+
+	w.Write(strDetailsCl)
+}
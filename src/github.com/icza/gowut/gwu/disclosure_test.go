@@ -0,0 +1,77 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDisclosureRender(t *testing.T) {
+	d := NewDisclosure("More <info>")
+	d.SetContent(NewLabel("Details"))
+	id, et := d.Id().String(), ETypeStateChange.String()
+
+	s := renderString(d)
+	if !strings.HasPrefix(s, `<details id="`+id+`"`) {
+		t.Errorf("Closed disclosure rendered with open attribute: %s", s)
+	}
+	for _, want := range []string{
+		` data-gwuopen="false" ontoggle="detailsToggle(this,` + et + `)">`,
+		`<summary class="gwu-Disclosure-Summary">More &lt;info&gt;</summary>`,
+		`>Details</span></details>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Rendered output does not contain %s: %s", want, s)
+		}
+	}
+
+	d.SetOpen(true)
+	s = renderString(d)
+	if !strings.HasPrefix(s, `<details open id="`+id+`"`) || !strings.Contains(s, ` data-gwuopen="true"`) {
+		t.Errorf("Open disclosure rendered without open attribute: %s", s)
+	}
+}
+
+func TestDisclosureToggle(t *testing.T) {
+	d := NewDisclosure("Summary")
+	for _, c := range []struct {
+		value string
+		open  bool
+	}{
+		{"true", true}, {"x", true}, {"false", false},
+	} {
+		d.preprocessEvent(newEventImpl(ETypeStateChange, d, nil, nil), newCompValueReq(c.value))
+		if d.Open() != c.open {
+			t.Errorf("Value %q: got open %v", c.value, d.Open())
+		}
+	}
+}
+
+func TestDisclosureContainer(t *testing.T) {
+	d := NewDisclosure("Summary")
+	p := NewPanel()
+	l := NewLabel("x")
+	p.Add(l)
+	d.SetContent(p)
+
+	if d.ById(l.Id()) != l || d.ById(d.Id()) != d {
+		t.Error("ById() did not find the components")
+	}
+	if d.Remove(l) || !d.Remove(p) || d.Content() != nil || p.Parent() != nil {
+		t.Error("Remove() failed")
+	}
+}
@@ -93,7 +93,8 @@ platform solution.
 Starting the GUI server with a non-local address gives you
 the possibility to view the GUI from a remote computer.
 The server can be configured to run in normal mode (HTTP) or in secure
-mode (HTTPS).
+mode (HTTPS), using certificate and key files (NewServerTLS()) or a TLS
+configuration (NewServerTLSConfig()).
 
 The GUI server also has Session management. By default windows added to the
 server are public windows, and shared between all users (clients). This
@@ -205,33 +206,56 @@ definitions of the built-in style classes. For example you can define the
 "gwu-Button" style class to have red background, and the result will be that all
 Buttons will have red background without having to change their style individually.
 
+While events are being processed (sent to the server but not yet responded),
+the "gwu-Busy" style class is added to the document body, so a loading indicator
+(e.g. a progress cursor or a spinner) can be styled with CSS.
+
 
 Component palette
 
 Containers to group and lay out components:
+	Disclosure - shows and hides its content when clicking on the summary (client side)
 	Expander  - shows and hides a content comp when clicking on the header comp
+	FieldSet  - groups related components with a legend (e.g. radio buttons)
 	(Link)    - allows only one optional child
 	Panel     - it has configurable layout
+	ScrollSpy - sections with a sticky navigation highlighting the section scrolled to
+	ShadowHost - renders its content into a shadow DOM, isolated from page styles
+	SplitPanel - displays 2 comps separated by a draggable divider
 	Table     - it is dynamic and flexible
 	TabPanel  - for tabbed displaying components (only 1 is visible at a time)
 	Window    - top of component hierarchy, it is an extension of the Panel
+	Wizard    - guides through ordered steps with validated Next/Back/Finish navigation
 
 Input components to get data from users:
 	CheckBox
+	DateTimePicker
 	ListBox     (it's either a drop-down list or a multi-line/multi-select list box)
+	MaskedInput (applies a mask pattern as the user types, sends the raw value)
+	NumberSpinner
 	TextBox     (it's either a one-line text box or a multi-line text area)
 	PasswBox
 	RadioButton
+	Rating      (clickable stars, optionally with half stars)
+	RichText    (edits formatted text, sanitizes the HTML sent by the client)
+	SegmentedControl
 	SwitchButton
+	TagInput
+	TimePicker
 
 Other components:
 	Button
+	Countdown   (displays the remaining time until a target time)
+	DataTable   (displays data of a DataSource page by page)
 	Html
+	Iframe
 	Image
 	Label
 	Link
 	SessMonitor
+	Spinner
 	Timer
+	ValueMirror (displays the formatted value of another component)
 
 
 Full application example
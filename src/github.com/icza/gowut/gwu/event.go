@@ -18,7 +18,10 @@
 package gwu
 
 import (
+	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 )
 
 // Event type (kind) type.
@@ -52,6 +55,18 @@ const (
 
 	// Internal events, generated and dispatched internally while processing another event
 	ETypeStateChange // State change
+
+	// Event types added later are appended (regardless of their category)
+	// so the values of the existing event types do not change.
+
+	ETypeWinIdle      // Window event: window idle (no user activity for a period of time, see Window.SetIdleTimeout())
+	ETypePaste        // General event: paste (clipboard content pasted, see Table.Pasted())
+	ETypeGeolocation  // Internal event: geolocation queried (see Event.RequestGeolocation())
+	ETypeScriptLoaded // Internal event: script loaded (see Window.LoadScript())
+	ETypeSessExpired  // Internal event: session expired (see SessMonitor.SetOnExpired())
+	ETypeContextMenu  // General event: context menu (e.g. right click, see Event.PreventDefault())
+	ETypeWheel        // General event: mouse wheel (see Event.WheelDeltaY())
+	ETypeInput        // General event: input (value of a text component changed, fired on every keystroke)
 )
 
 // Event type category.
@@ -69,11 +84,11 @@ const (
 // Category returns the event type category.
 func (etype EventType) Category() EventCategory {
 	switch {
-	case etype >= ETypeClick && etype <= ETypeFocus:
+	case etype >= ETypeClick && etype <= ETypeFocus, etype == ETypePaste, etype >= ETypeContextMenu && etype <= ETypeInput:
 		return ECatGeneral
-	case etype >= ETypeWinLoad && etype <= ETypeWinUnload:
+	case etype >= ETypeWinLoad && etype <= ETypeWinUnload, etype == ETypeWinIdle:
 		return ECatWindow
-	case etype >= ETypeStateChange && etype <= ETypeStateChange:
+	case etype == ETypeStateChange, etype >= ETypeGeolocation && etype <= ETypeSessExpired:
 		return ECatInternal
 	}
 
@@ -82,19 +97,23 @@ func (etype EventType) Category() EventCategory {
 
 // Attribute names for the general event types; only for the general event types.
 var etypeAttrs map[EventType][]byte = map[EventType][]byte{
-	ETypeClick:     []byte("onclick"),
-	ETypeDblClick:  []byte("ondblclick"),
-	ETypeMousedown: []byte("onmousedown"),
-	ETypeMouseMove: []byte("onmousemove"),
-	ETypeMouseOver: []byte("onmouseover"),
-	ETypeMouseOut:  []byte("onmouseout"),
-	ETypeMouseUp:   []byte("onmouseup"),
-	ETypeKeyDown:   []byte("onkeydown"),
-	ETypeKeyPress:  []byte("onkeypress"),
-	ETypeKeyUp:     []byte("onkeyup"),
-	ETypeBlur:      []byte("onblur"),
-	ETypeChange:    []byte("onchange"),
-	ETypeFocus:     []byte("onfocus")}
+	ETypeClick:       []byte("onclick"),
+	ETypeDblClick:    []byte("ondblclick"),
+	ETypeMousedown:   []byte("onmousedown"),
+	ETypeMouseMove:   []byte("onmousemove"),
+	ETypeMouseOver:   []byte("onmouseover"),
+	ETypeMouseOut:    []byte("onmouseout"),
+	ETypeMouseUp:     []byte("onmouseup"),
+	ETypeKeyDown:     []byte("onkeydown"),
+	ETypeKeyPress:    []byte("onkeypress"),
+	ETypeKeyUp:       []byte("onkeyup"),
+	ETypeBlur:        []byte("onblur"),
+	ETypeChange:      []byte("onchange"),
+	ETypeFocus:       []byte("onfocus"),
+	ETypePaste:       []byte("onpaste"),
+	ETypeContextMenu: []byte("oncontextmenu"),
+	ETypeWheel:       []byte("onwheel"),
+	ETypeInput:       []byte("oninput")}
 
 // Function names for window event types.
 var etypeFuncs map[EventType][]byte = map[EventType][]byte{
@@ -214,6 +233,8 @@ type Event interface {
 	Parent() Event
 
 	// Mouse returns the mouse x and y coordinates relative to the component.
+	// For touch events the coordinates of the first touch point are returned
+	// (and MouseBtn() reports MouseBtnLeft).
 	// If no mouse coordinate info is available, (-1, -1) is returned.
 	Mouse() (x, y int)
 
@@ -225,6 +246,16 @@ type Event interface {
 	// If no mouse button info is available, MouseBtnUnknown is returned.
 	MouseBtn() MouseBtn
 
+	// WheelDeltaX returns the horizontal scroll amount of an ETypeWheel event
+	// in pixels (positive is to the right); 0 for other event types.
+	WheelDeltaX() int
+
+	// WheelDeltaY returns the vertical scroll amount of an ETypeWheel event
+	// in pixels (positive is downward); 0 for other event types.
+	// Wheel deltas reported in lines or pages by the browser are converted
+	// to pixels.
+	WheelDeltaY() int
+
 	// ModKeys returns the states of the modifier keys.
 	// The returned value contains the states of all modifier keys,
 	// constants of type ModKey can be used to test a specific modifier key,
@@ -237,11 +268,90 @@ type Event interface {
 	// Key code returns the key code.
 	KeyCode() Key
 
+	// Geolocation returns the geolocation (latitude and longitude in degrees)
+	// of the user, delivered in an ETypeGeolocation event
+	// (see RequestGeolocation()).
+	// ok is false if no (valid) geolocation is available, e.g. if the user
+	// denied the permission, or if this is not an ETypeGeolocation event.
+	Geolocation() (lat, long float64, ok bool)
+
+	// DecodedValue returns the value decoded by the event decoder
+	// of the source component (see Comp.SetEventDecoder()).
+	// nil is returned if the source component has no event decoder.
+	DecodedValue() interface{}
+
 	// Requests the specified window to be reloaded
 	// after processing the current event.
 	// Tip: pass an empty string to reload the current window.
 	ReloadWin(name string)
 
+	// RedirectAfter requests the browser to navigate to the specified URL
+	// after the specified delay (after processing the current event).
+	// Relative URLs are resolved against the current window's URL.
+	// The pending redirect is cancelled if the user clicks on a link
+	// before the delay elapses.
+	// This is useful for "You will be redirected in 5 seconds" screens
+	// (the displayed text can be updated by marking components dirty).
+	//
+	// If a window reload is also requested (see ReloadWin()), the redirect
+	// is not performed.
+	RedirectAfter(url string, delay time.Duration)
+
+	// PreventDefault requests the browser to prevent the default action
+	// of the event. Only honored for ETypeContextMenu events (the browser
+	// waits for their response): suppresses the native context menu, e.g.
+	// to show a custom one instead (positioned using MouseWin()).
+	// By default the default action is performed.
+	// To prevent scrolling by ETypeWheel events, see Comp.SetWheelPreventDefault().
+	//
+	// Note that since context menu events are sent synchronously, the page is
+	// blocked until the response arrives. Also, browsers only allow preventing
+	// the default action from non-passive listeners; Gowut renders handlers as
+	// (non-passive) element attributes.
+	PreventDefault()
+
+	// SetCookie adds a cookie to be set in the browser. The cookie is sent
+	// as a Set-Cookie header of the response of the current event.
+	// This is the way for event handlers to set application cookies
+	// (e.g. preferences), since the response body of events is reserved.
+	SetCookie(cookie *http.Cookie)
+
+	// CaptureComp requests the browser to capture the specified component
+	// as a PNG image and download it with the specified file name
+	// (after processing the current event).
+	//
+	// If the component is rendered as an HTML canvas, it is captured directly.
+	// Else capturing is delegated to the JavaScript gwuCapture(element, callback)
+	// function, which must render the element to a canvas and pass it to callback.
+	// By default gwuCapture() uses the html2canvas library if it is loaded
+	// (include it with Window.AddHeadHtml()), or you can provide your own
+	// implementation by redefining gwuCapture() (also in a head HTML).
+	CaptureComp(c Comp, fileName string)
+
+	// SubmitExternalForm requests the browser to submit a form with the
+	// specified fields to an external URL (after processing the current event),
+	// e.g. to integrate with legacy endpoints which can only be called
+	// by form posts. method is the HTTP method ("POST" or "GET").
+	//
+	// The form is built and submitted by the client (the fields are sent as
+	// hidden inputs), targeting an iframe named "gwu-ExtFormFrame" so the
+	// window is not navigated away. If there is no such iframe in the window,
+	// a hidden one is created. To display the response, add an Iframe with
+	// this name, e.g.:
+	//     ifr := gwu.NewIframe("")
+	//     ifr.SetAttr("name", "gwu-ExtFormFrame")
+	SubmitExternalForm(url, method string, fields map[string]string)
+
+	// RequestGeolocation requests the browser to query the geolocation of
+	// the user (after processing the current event), e.g. for a
+	// "Use my location" button. The browser asks the user for permission.
+	//
+	// The result is delivered in a follow-up ETypeGeolocation event
+	// to the specified component, which can be read by Geolocation().
+	// The event is also delivered if the geolocation is not available
+	// (e.g. the user denied the permission), with ok=false.
+	RequestGeolocation(c Comp)
+
 	// MarkDirty marks components dirty,
 	// causing them to be re-rendered after processing the current event.
 	// Component re-rendering happens without page reload in the browser.
@@ -256,8 +366,27 @@ type Event interface {
 	// marked dirty, the child component will only be re-rendered once.
 	MarkDirty(comps ...Comp)
 
+	// RerenderWindow requests the whole window content to be re-rendered
+	// after processing the current event, without page reload.
+	//
+	// Unlike marking the Window dirty (which makes the client request the
+	// re-rendering of the window in a separate request), the rendered window
+	// content is sent back in the event response itself, and it is spliced
+	// into the page in one step. Components marked dirty are not re-rendered
+	// separately (they are part of the window content).
+	//
+	// Useful when it is simpler to re-render everything than to track
+	// individual dirty components.
+	RerenderWindow()
+
 	// SetFocusedComp sets the component to be focused after processing
 	// the current event.
+	//
+	// The focus action is executed after the dirty components are re-rendered,
+	// so the component may be a new one (e.g. the first field of a newly shown
+	// form, added to a container marked dirty), and it takes precedence over
+	// the previously focused component (which is otherwise restored after
+	// re-rendering).
 	SetFocusedComp(comp Comp)
 
 	// Session returns the current session.
@@ -291,9 +420,17 @@ type eventImpl struct {
 
 	x, y int // Mouse coordinates (relative to component); not part of shared data because they component-relative
 
+	decoded interface{} // Value decoded by the event decoder of the source component
+
 	shared *sharedEvtData // Shared event data
 }
 
+// External form to be submitted by the client.
+type extForm struct {
+	url, method string     // URL and HTTP method of the form
+	fields      url.Values // Fields of the form
+}
+
 // Event data shared between an event and its child events (forks).
 type sharedEvtData struct {
 	server *serverImpl // Server implementation
@@ -301,13 +438,27 @@ type sharedEvtData struct {
 	wx, wy  int      // Mouse coordinates (inside the window)
 	mbtn    MouseBtn // Mouse button
 	modKeys int      // State of the modifier keys
+	wheelDX int      // Horizontal wheel delta in pixels
+	wheelDY int      // Vertical wheel delta in pixels
 	keyCode Key      // Key code
 
-	reload      bool        // Tells if the window has to be reloaded
-	reloadWin   string      // The name of the window to be reloaded
-	dirtyComps  map[ID]Comp // The dirty components
-	focusedComp Comp        // Component to be focused after the event processing
-	session     Session     // Session
+	geoLat, geoLong float64 // Geolocation
+	geoOk           bool    // Tells if geolocation is available
+
+	reload         bool           // Tells if the window has to be reloaded
+	reloadWin      string         // The name of the window to be reloaded
+	dirtyComps     map[ID]Comp    // The dirty components
+	rerenderWin    bool           // Tells if the whole window has to be re-rendered in the response
+	focusedComp    Comp           // Component to be focused after the event processing
+	redirectUrl    string         // URL to navigate to after the event processing
+	redirectDelay  time.Duration  // Delay of the redirect
+	preventDefault bool           // Tells if the default action of the event is to be prevented in the browser
+	cookies        []*http.Cookie // Cookies to be set in the response
+	captureComp    Comp           // Component to be captured as an image
+	captureFile    string         // File name of the captured image
+	extForm        *extForm       // External form to submit
+	geoComp        Comp           // Component to deliver the geolocation to
+	session        Session        // Session
 }
 
 // newEventImpl creates a new eventImpl
@@ -341,6 +492,14 @@ func (e *eventImpl) MouseBtn() MouseBtn {
 	return e.shared.mbtn
 }
 
+func (e *eventImpl) WheelDeltaX() int {
+	return e.shared.wheelDX
+}
+
+func (e *eventImpl) WheelDeltaY() int {
+	return e.shared.wheelDY
+}
+
 func (e *eventImpl) ModKeys() int {
 	return e.shared.modKeys
 }
@@ -353,11 +512,49 @@ func (e *eventImpl) KeyCode() Key {
 	return e.shared.keyCode
 }
 
+func (e *eventImpl) Geolocation() (lat, long float64, ok bool) {
+	return e.shared.geoLat, e.shared.geoLong, e.shared.geoOk
+}
+
+func (e *eventImpl) DecodedValue() interface{} {
+	return e.decoded
+}
+
 func (e *eventImpl) ReloadWin(name string) {
 	e.shared.reload = true
 	e.shared.reloadWin = name
 }
 
+func (e *eventImpl) RedirectAfter(url string, delay time.Duration) {
+	e.shared.redirectUrl = url
+	e.shared.redirectDelay = delay
+}
+
+func (e *eventImpl) PreventDefault() {
+	e.shared.preventDefault = true
+}
+
+func (e *eventImpl) SetCookie(cookie *http.Cookie) {
+	e.shared.cookies = append(e.shared.cookies, cookie)
+}
+
+func (e *eventImpl) SubmitExternalForm(actionUrl, method string, fields map[string]string) {
+	f := &extForm{url: actionUrl, method: method, fields: make(url.Values, len(fields))}
+	for name, value := range fields {
+		f.fields.Set(name, value)
+	}
+	e.shared.extForm = f
+}
+
+func (e *eventImpl) RequestGeolocation(c Comp) {
+	e.shared.geoComp = c
+}
+
+func (e *eventImpl) CaptureComp(c Comp, fileName string) {
+	e.shared.captureComp = c
+	e.shared.captureFile = fileName
+}
+
 func (e *eventImpl) MarkDirty(comps ...Comp) {
 	// We can optimize "on the run" (during dispatching) because we rely on the fact
 	// that if the component tree is modified later by a handler, the Container
@@ -385,6 +582,10 @@ func (e *eventImpl) MarkDirty(comps ...Comp) {
 	}
 }
 
+func (e *eventImpl) RerenderWindow() {
+	e.shared.rerenderWin = true
+}
+
 // dirty returns true if the specified component is already marked dirty.
 // Note that a component being dirty makes all of its descendants dirty, recursively.
 //
@@ -429,6 +630,38 @@ func (e *eventImpl) forkEvent(etype EventType, src Comp) Event {
 		shared: e.shared}
 }
 
+// BroadcastEvent interface defines the context of a broadcast update
+// (see Server.Broadcast()) for a window of a session.
+type BroadcastEvent interface {
+	// Session returns the session of the window.
+	Session() Session
+
+	// Window returns the window being updated.
+	Window() Window
+
+	// MarkDirty marks components dirty, their re-rendering
+	// is pushed to the clients viewing the window.
+	MarkDirty(comps ...Comp)
+}
+
+// BroadcastEvent implementation.
+type broadcastEventImpl struct {
+	win Window     // Window being updated
+	e   *eventImpl // Event implementation, used to collect the dirty components
+}
+
+func (b *broadcastEventImpl) Session() Session {
+	return b.e.shared.session
+}
+
+func (b *broadcastEventImpl) Window() Window {
+	return b.win
+}
+
+func (b *broadcastEventImpl) MarkDirty(comps ...Comp) {
+	b.e.MarkDirty(comps...)
+}
+
 // Handler function wrapper
 type handlerFuncWrapper struct {
 	hf func(e Event) // The handler function to be called as part of implementing the EventHandler interface
@@ -439,6 +672,39 @@ func (hfw handlerFuncWrapper) HandleEvent(e Event) {
 	hfw.hf(e)
 }
 
+// nsEHandler is an event handler belonging to a namespace
+// (see Comp.AddEHandlerNS()).
+type nsEHandler struct {
+	ns      string       // Namespace of the handler
+	handler EventHandler // Wrapped handler
+}
+
+// HandleEvent forwards the call to the wrapped handler.
+func (nh nsEHandler) HandleEvent(e Event) {
+	nh.handler.HandleEvent(e)
+}
+
+// keyEHandler is an ETypeKeyDown event handler which only calls
+// the wrapped handler if the pressed key matches (see Comp.AddKeyHandler()).
+type keyEHandler struct {
+	keyCode Key          // Key code to match
+	modKeys ModKey       // Modifier key mask to match
+	handler EventHandler // Wrapped handler
+}
+
+// HandleEvent calls the wrapped handler if the key code and modifier keys match.
+// If modifier key info is not available (ModKeys() returns -1), only the key code
+// is checked (it is also checked at the client side).
+func (kh keyEHandler) HandleEvent(e Event) {
+	if e.KeyCode() != kh.keyCode {
+		return
+	}
+	if modKeys := e.ModKeys(); modKeys >= 0 && modKeys != int(kh.modKeys) {
+		return
+	}
+	kh.handler.HandleEvent(e)
+}
+
 // Empty Event Handler type.
 type emptyEventHandler int
 
@@ -127,6 +127,16 @@ func (c *expanderImpl) ById(id ID) Comp {
 	return nil
 }
 
+func (c *expanderImpl) children() []Comp {
+	var comps []Comp
+	for _, c2 := range []Comp{c.header, c.content} {
+		if c2 != nil {
+			comps = append(comps, c2)
+		}
+	}
+	return comps
+}
+
 func (c *expanderImpl) Clear() {
 	if c.header != nil {
 		c.header.setParent(nil)
@@ -211,13 +221,13 @@ func (c *expanderImpl) Render(w Writer) {
 	if c.header != nil {
 		c.renderTr(w)
 		c.headerFmt.render(strTDOp, w)
-		c.header.Render(w)
+		renderComp(w, c.header)
 	}
 
 	if c.expanded && c.content != nil {
 		c.renderTr(w)
 		c.contentFmt.render(strTDOp, w)
-		c.content.Render(w)
+		renderComp(w, c.content)
 	}
 
 	w.Write(strTableCl)
@@ -0,0 +1,92 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// FieldSet component interface and implementation.
+
+package gwu
+
+// FieldSet interface defines a container which groups related components
+// (e.g. the radio buttons of a RadioGroup) visually and semantically.
+// It is rendered as an HTML fieldset with a legend, which improves
+// the accessibility (screen readers announce the legend for the grouped
+// components).
+//
+// The text of the FieldSet is the legend. Child components are laid out
+// inside the fieldset according to the layout strategy.
+//
+// Default style classes: "gwu-FieldSet", "gwu-FieldSet-Legend"
+type FieldSet interface {
+	// FieldSet is a Panel.
+	Panel
+
+	// FieldSet has text which is rendered as the legend.
+	HasText
+}
+
+// FieldSet implementation.
+type fieldSetImpl struct {
+	panelImpl   // Panel implementation
+	hasTextImpl // Has text implementation
+}
+
+// NewFieldSet creates a new FieldSet with the specified legend.
+// Default layout strategy is LayoutVertical.
+func NewFieldSet(legend string) FieldSet {
+	c := &fieldSetImpl{panelImpl: newPanelImpl(), hasTextImpl: newHasTextImpl(legend)}
+	c.Style().AddClass("gwu-FieldSet")
+	return c
+}
+
+var (
+	strFieldSetOp = []byte("<fieldset")                            // "<fieldset"
+	strLegendOp   = []byte(`<legend class="gwu-FieldSet-Legend">`) // `<legend class="gwu-FieldSet-Legend">`
+	strLegendCl   = []byte("</legend>")                            // "</legend>"
+	strFieldSetCl = []byte("</fieldset>")                          // "</fieldset>"
+)
+
+func (c *fieldSetImpl) Render(w Writer) {
+	w.Write(strFieldSetOp)
+	c.renderAttrsAndStyle(w)
+	c.renderEHandlers(w)
+	w.Write(strGT)
+
+	if len(c.text) > 0 {
+		w.Write(strLegendOp)
+		c.renderText(w)
+		w.Write(strLegendCl)
+	}
+
+	// The fieldset holds the attributes, an inner table (if any) is plain.
+	switch c.layout {
+	case LayoutNatural:
+		for _, c2 := range c.comps {
+			if c.rendered(c2) {
+				renderComp(w, c2)
+			}
+		}
+	case LayoutHorizontal:
+		w.Write(strTableOp)
+		w.Write(strGT)
+		c.renderCellsHorizontal(w)
+		w.Write(strTableCl)
+	case LayoutVertical:
+		w.Write(strTableOp)
+		w.Write(strGT)
+		c.renderCellsVertical(w)
+		w.Write(strTableCl)
+	}
+
+	w.Write(strFieldSetCl)
+}
@@ -0,0 +1,45 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFieldSetRender(t *testing.T) {
+	fs := NewFieldSet("Size & color")
+	fs.SetLayout(LayoutNatural)
+	group := NewRadioGroup("size")
+	rb1, rb2 := NewRadioButton("Small", group), NewRadioButton("Large", group)
+	fs.Add(rb1)
+	fs.Add(rb2)
+
+	s := renderString(fs)
+	if !strings.HasPrefix(s, "<fieldset ") || !strings.HasSuffix(s, "</fieldset>") {
+		t.Errorf("Not rendered as a fieldset: %s", s)
+	}
+	if !strings.Contains(s, ` id="`+fs.Id().String()+`"`) {
+		t.Errorf("Id not rendered on the fieldset: %s", s)
+	}
+	legend := `><legend class="gwu-FieldSet-Legend">Size &amp; color</legend>`
+	if i := strings.Index(s, legend); i < 0 || i > strings.Index(s, renderString(rb1)) {
+		t.Errorf("Legend not rendered before the children: %s", s)
+	}
+	if !strings.Contains(s, renderString(rb1)+renderString(rb2)+"</fieldset>") {
+		t.Errorf("Children not rendered properly: %s", s)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Gzip compression of responses.
+
+package gwu
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// acceptsGzip tells if the client accepts gzip encoded responses.
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		params := strings.Split(enc, ";")
+		if strings.TrimSpace(params[0]) != "gzip" {
+			continue
+		}
+		// "gzip;q=0" means gzip is not acceptable
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && kv[0] == "q" {
+				if q, err := strconv.ParseFloat(kv[1], 64); err == nil && q == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipRespWriter is an http.ResponseWriter which gzips the response
+// if its size reaches a minimum size. Writing the response header
+// is deferred until the encoding is decided.
+// close() must be called after the response is written.
+type gzipRespWriter struct {
+	http.ResponseWriter
+
+	minSize int          // Minimum size of the response to compress
+	status  int          // Status code of the response, 0 if not set
+	buf     []byte       // Buffered response until it is decided whether to compress it
+	gz      *gzip.Writer // Gzip writer if the response is compressed
+}
+
+// newGzipRespWriter creates a new gzipRespWriter.
+func newGzipRespWriter(w http.ResponseWriter, minSize int) *gzipRespWriter {
+	return &gzipRespWriter{ResponseWriter: w, minSize: minSize}
+}
+
+func (g *gzipRespWriter) WriteHeader(status int) {
+	if g.status == 0 {
+		g.status = status
+	}
+}
+
+func (g *gzipRespWriter) Write(p []byte) (int, error) {
+	if g.gz != nil {
+		return g.gz.Write(p)
+	}
+
+	g.buf = append(g.buf, p...)
+	if len(g.buf) < g.minSize {
+		return len(p), nil
+	}
+
+	h := g.Header()
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	g.writeHeader()
+	g.gz = gzip.NewWriter(g.ResponseWriter)
+	if _, err := g.gz.Write(g.buf); err != nil {
+		return 0, err
+	}
+	g.buf = nil
+	return len(p), nil
+}
+
+// writeHeader writes the response header with the status code (if set).
+func (g *gzipRespWriter) writeHeader() {
+	if g.status != 0 {
+		g.ResponseWriter.WriteHeader(g.status)
+	}
+}
+
+// close completes the response: flushes the gzip writer, or writes
+// the buffered response uncompressed if it did not reach the minimum size.
+func (g *gzipRespWriter) close() error {
+	if g.gz != nil {
+		return g.gz.Close()
+	}
+	g.writeHeader()
+	if len(g.buf) == 0 {
+		return nil
+	}
+	_, err := g.ResponseWriter.Write(g.buf)
+	return err
+}
@@ -0,0 +1,100 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	cases := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=0.8", true},
+		{"gzip;q=0", false},
+		{"gzip; q=0.0, deflate", false},
+		{"x-gzip", false},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", c.accept)
+		if got := acceptsGzip(r); got != c.want {
+			t.Errorf("%q: got %v, want %v", c.accept, got, c.want)
+		}
+	}
+}
+
+func TestGzip(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	s.SetGzip(true, 1024)
+	win := NewWindow("main", "Test")
+	for i := 0; i < 100; i++ {
+		win.Add(NewLabel("Label " + strconv.Itoa(i)))
+	}
+	s.AddWin(win)
+
+	request := func(path, accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", s.AppPath()+path, nil)
+		r.Header.Set("Accept-Encoding", accept)
+		wr := httptest.NewRecorder()
+		s.serveHTTP(wr, r)
+		return wr
+	}
+
+	wr := request("main", "gzip")
+	if enc := wr.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("Got content encoding: %q", enc)
+	}
+	if vary := wr.Header().Get("Vary"); vary != "Accept-Encoding" {
+		t.Errorf("Got vary: %q", vary)
+	}
+	gr, err := gzip.NewReader(wr.Body)
+	if err != nil {
+		t.Fatalf("Invalid gzip response: %v", err)
+	}
+	body, err := ioutil.ReadAll(gr)
+	if err != nil || !strings.Contains(string(body), "Label 99") {
+		t.Errorf("Got body: %s, err: %v", body, err)
+	}
+
+	// Client does not accept gzip
+	wr = request("main", "")
+	if enc := wr.Header().Get("Content-Encoding"); enc != "" || !strings.Contains(wr.Body.String(), "Label 99") {
+		t.Errorf("Got content encoding: %q, body: %s", enc, wr.Body)
+	}
+
+	// Tiny responses are not compressed
+	lid := win.CompAt(0).Id().String()
+	wr = request("main/"+pathRenderComp+"?"+paramCompId+"="+lid, "gzip")
+	if enc := wr.Header().Get("Content-Encoding"); enc != "" || !strings.Contains(wr.Body.String(), "Label 0") {
+		t.Errorf("Got content encoding: %q, body: %s", enc, wr.Body)
+	}
+
+	// Disabled
+	s.SetGzip(false, 0)
+	wr = request("main", "gzip")
+	if enc := wr.Header().Get("Content-Encoding"); enc != "" || wr.Header().Get("Vary") != "" {
+		t.Errorf("Got content encoding: %q", enc)
+	}
+}
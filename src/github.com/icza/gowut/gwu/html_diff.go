@@ -0,0 +1,363 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Diffing of rendered HTML, to update components at client side
+// with minimal DOM operations (see Server.SetDiffRender()).
+
+package gwu
+
+import (
+	"html"
+	"strings"
+	"sync"
+)
+
+// htmlNode is a node of a parsed HTML fragment.
+type htmlNode struct {
+	name     string      // Node name as in the DOM: lowercase tag name, "#text" or "#comment"
+	attrs    [][2]string // Attributes of elements (values unescaped)
+	text     string      // Text of text and comment nodes
+	src      string      // HTML source of the node
+	implicit bool        // Tells if the element is implied (e.g. tbody of a table rows), it has no source
+	children []*htmlNode // Child nodes
+}
+
+// Elements which have no closing tag.
+var htmlVoidTags = map[string]bool{"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true, "param": true, "source": true,
+	"track": true, "wbr": true}
+
+// Elements whose content is raw text (not parsed as HTML).
+var htmlRawTags = map[string]bool{"script": true, "style": true, "textarea": true, "title": true}
+
+// parseHtml parses an HTML fragment, and returns its top-level nodes.
+// This is not a complete HTML parser: it handles what components render,
+// and implies the tbody element of table rows like browsers do.
+func parseHtml(text string) []*htmlNode {
+	root := &htmlNode{}
+	open := []*htmlNode{root} // Stack of open elements
+	starts := []int{0}        // Source start positions of the open elements
+
+	add := func(n *htmlNode) {
+		parent := open[len(open)-1]
+		parent.children = append(parent.children, n)
+	}
+
+	for pos := 0; pos < len(text); {
+		start := pos
+		i := strings.IndexByte(text[pos:], '<')
+		if i != 0 {
+			if i < 0 {
+				i = len(text) - pos
+			}
+			pos += i
+			add(&htmlNode{name: "#text", text: html.UnescapeString(text[start:pos]), src: text[start:pos]})
+			continue
+		}
+
+		if strings.HasPrefix(text[pos:], "<!--") {
+			if end := strings.Index(text[pos+4:], "-->"); end >= 0 {
+				pos += 4 + end + 3
+			} else {
+				pos = len(text)
+			}
+			src := text[start:pos]
+			add(&htmlNode{name: "#comment", text: strings.TrimSuffix(src[4:], "-->"), src: src})
+			continue
+		}
+
+		name, attrs, closing, rest, ok := parseTag(text[pos:])
+		if !ok {
+			pos++
+			add(&htmlNode{name: "#text", text: "<", src: "<"})
+			continue
+		}
+		pos = len(text) - len(rest)
+
+		if closing {
+			// Close the element (and the unclosed inner elements)
+			for j := len(open) - 1; j > 0; j-- {
+				if open[j].name != name {
+					continue
+				}
+				for k := len(open) - 1; k >= j; k-- {
+					if !open[k].implicit {
+						open[k].src = text[starts[k]:pos]
+					}
+				}
+				open, starts = open[:j], starts[:j]
+				break
+			}
+			continue
+		}
+
+		if name == "tr" && open[len(open)-1].name == "table" {
+			tbody := &htmlNode{name: "tbody", implicit: true}
+			add(tbody)
+			open, starts = append(open, tbody), append(starts, start)
+		}
+
+		n := &htmlNode{name: name}
+		for _, attr := range attrs {
+			n.attrs = append(n.attrs, [2]string{attr[0], html.UnescapeString(attr[1])})
+		}
+		add(n)
+
+		switch {
+		case htmlVoidTags[name]:
+			n.src = text[start:pos]
+		case htmlRawTags[name]:
+			end := strings.Index(strings.ToLower(text[pos:]), "</"+name)
+			if end < 0 {
+				end = len(text) - pos
+			}
+			if content := text[pos : pos+end]; content != "" {
+				t := content
+				if name == "textarea" || name == "title" {
+					t = html.UnescapeString(content)
+				}
+				n.children = []*htmlNode{{name: "#text", text: t, src: content}}
+			}
+			pos += end
+			if gt := strings.IndexByte(text[pos:], '>'); gt >= 0 {
+				pos += gt + 1
+			} else {
+				pos = len(text)
+			}
+			n.src = text[start:pos]
+		default:
+			open, starts = append(open, n), append(starts, start)
+		}
+	}
+
+	// Unclosed elements
+	for k := len(open) - 1; k > 0; k-- {
+		if !open[k].implicit {
+			open[k].src = text[starts[k]:]
+		}
+	}
+	return root.children
+}
+
+// attr returns the value of the attribute of the node, and whether it is present.
+func (n *htmlNode) attr(name string) (string, bool) {
+	for _, a := range n.attrs {
+		if a[0] == name {
+			return a[1], true
+		}
+	}
+	return "", false
+}
+
+// key returns the key of the node identifying it among its siblings:
+// the id attribute of the node, or if it has none, the key of its
+// first child element (e.g. of a table cell wrapping a component).
+// Empty string is returned if the node has no key.
+func (n *htmlNode) key() string {
+	for depth := 0; n != nil && depth < 4; depth++ {
+		if id, ok := n.attr("id"); ok {
+			return id
+		}
+		var child *htmlNode
+		for _, c := range n.children {
+			if c.name[0] != '#' {
+				child = c
+				break
+			}
+		}
+		n = child
+	}
+	return ""
+}
+
+// Diff operations.
+const (
+	diffReplace    = "r" // Replace the node with the HTML
+	diffText       = "t" // Set the text of the text (or comment) node
+	diffSetAttr    = "a" // Set the attribute of the element
+	diffRemoveAttr = "d" // Remove the attribute of the element
+	diffInsert     = "i" // Insert the HTML before the child with the index of the last path element
+	diffRemove     = "x" // Remove the node
+)
+
+// diffOp is a DOM operation of a patch.
+type diffOp struct {
+	Op    string `json:"o"`           // Operation, one of the diffXxx constants
+	Path  []int  `json:"p"`           // Path of the target node: child node indices starting from the root
+	Name  string `json:"g,omitempty"` // Node name of the target node (to verify the DOM), empty for insert
+	Attr  string `json:"n,omitempty"` // Attribute name
+	Value string `json:"v,omitempty"` // Attribute value, text or HTML
+}
+
+// diffHtml computes the DOM operations which transform the DOM of the old
+// render of a component to the DOM of the new render. Paths of the operations
+// are valid when the operations are applied in order.
+// ok is false if no patch can be made (e.g. the renders do not consist of
+// a single element with the same tag), the new render has to be sent then.
+func diffHtml(old, new string) (ops []diffOp, ok bool) {
+	oldNodes, newNodes := parseHtml(old), parseHtml(new)
+	if len(oldNodes) != 1 || len(newNodes) != 1 || oldNodes[0].name != newNodes[0].name || oldNodes[0].name[0] == '#' {
+		return nil, false
+	}
+	d := &differ{ops: []diffOp{}, ok: true}
+	d.diff(oldNodes[0], newNodes[0], nil)
+	return d.ops, d.ok
+}
+
+// differ collects the diff operations.
+type differ struct {
+	ops []diffOp // Collected operations
+	ok  bool     // Tells if the patch is valid
+}
+
+// add adds an operation with a copy of path.
+func (d *differ) add(op string, path []int, name, attr, value string) {
+	d.ops = append(d.ops, diffOp{Op: op, Path: append([]int{}, path...), Name: name, Attr: attr, Value: value})
+}
+
+// diff adds the operations which transform the old node to the new node.
+func (d *differ) diff(old, new *htmlNode, path []int) {
+	if old.name != new.name || htmlRawTags[old.name] && old.src != new.src {
+		if new.implicit || len(path) == 0 {
+			d.ok = false
+			return
+		}
+		d.add(diffReplace, path, old.name, "", new.src)
+		return
+	}
+	if old.name[0] == '#' {
+		if old.text != new.text {
+			d.add(diffText, path, old.name, "", new.text)
+		}
+		return
+	}
+
+	for _, a := range new.attrs {
+		if v, ok := old.attr(a[0]); !ok || v != a[1] {
+			d.add(diffSetAttr, path, old.name, a[0], a[1])
+		}
+	}
+	for _, a := range old.attrs {
+		if _, ok := new.attr(a[0]); !ok {
+			d.add(diffRemoveAttr, path, old.name, a[0], "")
+		}
+	}
+
+	d.diffChildren(old.children, new.children, path)
+}
+
+// diffChildren adds the operations which transform the old child nodes
+// to the new child nodes of the node at path. Children are matched by their
+// keys, so inserting or removing keyed children (e.g. components) does not
+// affect the other children.
+func (d *differ) diffChildren(oldCs, newCs []*htmlNode, path []int) {
+	oldKeys, newKeys := map[string]bool{}, map[string]bool{}
+	for _, c := range oldCs {
+		oldKeys[c.key()] = true
+	}
+	for _, c := range newCs {
+		newKeys[c.key()] = true
+	}
+
+	path = append(path, 0)
+	last := len(path) - 1
+	i, j := 0, 0
+	for d.ok && (i < len(oldCs) || j < len(newCs)) {
+		path[last] = j // Old children before i are transformed to new children before j
+		var ko, kn string
+		if i < len(oldCs) {
+			ko = oldCs[i].key()
+		}
+		if j < len(newCs) {
+			kn = newCs[j].key()
+		}
+		switch {
+		case i == len(oldCs) || j < len(newCs) && kn != "" && !oldKeys[kn]:
+			// New child
+			if newCs[j].implicit {
+				d.ok = false
+				return
+			}
+			d.add(diffInsert, path, "", "", newCs[j].src)
+			j++
+		case j == len(newCs) || ko != "" && !newKeys[ko]:
+			// Removed child
+			d.add(diffRemove, path, oldCs[i].name, "", "")
+			i++
+		default:
+			d.diff(oldCs[i], newCs[j], path)
+			i++
+			j++
+		}
+	}
+}
+
+// renderCache caches the last renders of the components of a window
+// re-rendered with diff rendering.
+type renderCache struct {
+	mu      sync.Mutex          // Mutex to synchronize access
+	rev     int                 // Last render revision
+	renders map[ID]cachedRender // Last renders mapped from component id
+}
+
+// cachedRender is a cached render of a component.
+type cachedRender struct {
+	html string // Rendered HTML
+	rev  int    // Render revision
+}
+
+// update stores the new render of the component of the window, and returns
+// the previous render and the revision of the new render.
+// Cached renders of the ancestors and descendants of the component are
+// dropped as they are changed by the new render (so are the renders of
+// components no longer in the window).
+func (rc *renderCache) update(win Window, c Comp, html string) (prev cachedRender, rev int) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.renders == nil {
+		rc.renders = make(map[ID]cachedRender)
+	}
+	for id := range rc.renders {
+		if id == c.Id() {
+			continue
+		}
+		if c2 := win.ById(id); c2 == nil || isAncestor(c, c2) || isAncestor(c2, c) {
+			delete(rc.renders, id)
+		}
+	}
+
+	prev = rc.renders[c.Id()]
+	rc.rev++
+	rc.renders[c.Id()] = cachedRender{html: html, rev: rc.rev}
+	return prev, rc.rev
+}
+
+// clear clears the cache, e.g. when the whole window is rendered.
+func (rc *renderCache) clear() {
+	rc.mu.Lock()
+	rc.renders = nil
+	rc.mu.Unlock()
+}
+
+// isAncestor tells if a is an ancestor of c.
+func isAncestor(a, c Comp) bool {
+	for p := c.Parent(); p != nil; p = p.Parent() {
+		if p.Id() == a.Id() {
+			return true
+		}
+	}
+	return false
+}
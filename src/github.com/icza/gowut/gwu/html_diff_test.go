@@ -0,0 +1,173 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHtml(t *testing.T) {
+	src := `<table id="1"><tr><td><input id="2" value="a&amp;b"></td></tr></table><script>if (a<b) x();</script>`
+	nodes := parseHtml(src)
+	if len(nodes) != 2 {
+		t.Fatalf("Got %d top-level nodes", len(nodes))
+	}
+	table := nodes[0]
+	if table.src != src[:strings.Index(src, "<script>")] {
+		t.Errorf("Got table source: %s", table.src)
+	}
+	tbody := table.children[0]
+	if tbody.name != "tbody" || !tbody.implicit {
+		t.Errorf("Got table child %q, want implicit tbody", tbody.name)
+	}
+	input := tbody.children[0].children[0].children[0]
+	if v, _ := input.attr("value"); input.name != "input" || v != "a&b" || len(input.children) != 0 {
+		t.Errorf("Got input %q with value %q", input.name, v)
+	}
+	if key := tbody.children[0].key(); key != "2" {
+		t.Errorf("Got key %q of the table row, want key of the input", key)
+	}
+	script := nodes[1]
+	if len(script.children) != 1 || script.children[0].text != "if (a<b) x();" {
+		t.Errorf("Got script children %v", script.children)
+	}
+}
+
+func TestDiffHtml(t *testing.T) {
+	cases := []struct {
+		name     string
+		old, new string
+		want     []diffOp
+	}{
+		{"unchanged", `<div id="1">a<b>x</b></div>`, `<div id="1">a<b>x</b></div>`, []diffOp{}},
+		{"attr change", `<div id="1" class="a" title="t">x</div>`, `<div id="1" class="b" style="color:red">x</div>`, []diffOp{
+			{Op: diffSetAttr, Path: []int{}, Name: "div", Attr: "class", Value: "b"},
+			{Op: diffSetAttr, Path: []int{}, Name: "div", Attr: "style", Value: "color:red"},
+			{Op: diffRemoveAttr, Path: []int{}, Name: "div", Attr: "title"},
+		}},
+		{"text change", `<div id="1"><span id="2">a</span></div>`, `<div id="1"><span id="2">a &amp; b</span></div>`, []diffOp{
+			{Op: diffText, Path: []int{0, 0}, Name: "#text", Value: "a & b"},
+		}},
+		{"add", `<div id="1"><span id="2">a</span></div>`, `<div id="1"><span id="2">a</span><span id="3">b</span></div>`, []diffOp{
+			{Op: diffInsert, Path: []int{1}, Value: `<span id="3">b</span>`},
+		}},
+		{"insert in the middle", `<div id="1"><p id="2"></p><p id="4"></p></div>`, `<div id="1"><p id="2"></p><p id="3"></p><p id="4"></p></div>`, []diffOp{
+			{Op: diffInsert, Path: []int{1}, Value: `<p id="3"></p>`},
+		}},
+		{"remove", `<div id="1"><p id="2"></p><p id="3"></p><p id="4"></p></div>`, `<div id="1"><p id="2"></p><p id="4"></p></div>`, []diffOp{
+			{Op: diffRemove, Path: []int{1}, Name: "p"},
+		}},
+		{"remove and add", `<div id="1"><p id="2"></p><p id="3">x</p></div>`, `<div id="1"><p id="3">y</p><p id="5"></p></div>`, []diffOp{
+			{Op: diffRemove, Path: []int{0}, Name: "p"},
+			{Op: diffText, Path: []int{0, 0}, Name: "#text", Value: "y"},
+			{Op: diffInsert, Path: []int{1}, Value: `<p id="5"></p>`},
+		}},
+		{"replace", `<div id="1"><b>x</b></div>`, `<div id="1"><i>x</i></div>`, []diffOp{
+			{Op: diffReplace, Path: []int{0}, Name: "b", Value: `<i>x</i>`},
+		}},
+		{"table rows", `<table id="1"><tr><td><span id="2"></span></td></tr></table>`,
+			`<table id="1"><tr><td><span id="2"></span></td></tr><tr><td><span id="3"></span></td></tr></table>`, []diffOp{
+				{Op: diffInsert, Path: []int{0, 1}, Value: `<tr><td><span id="3"></span></td></tr>`},
+			}},
+	}
+	for _, c := range cases {
+		ops, ok := diffHtml(c.old, c.new)
+		if !ok {
+			t.Errorf("%s: no patch", c.name)
+			continue
+		}
+		if !reflect.DeepEqual(ops, c.want) {
+			t.Errorf("%s: got ops %+v, want %+v", c.name, ops, c.want)
+		}
+	}
+
+	for _, c := range [][2]string{
+		{`<div id="1"></div>`, `<span id="1"></span>`},                      // Root tag changed
+		{`<div id="1"></div>`, `<span id="1_ps"></span><div id="1"></div>`}, // Multiple top-level nodes
+	} {
+		if _, ok := diffHtml(c[0], c[1]); ok {
+			t.Errorf("Got patch for %s -> %s", c[0], c[1])
+		}
+	}
+}
+
+func TestDiffRender(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	s.SetDiffRender(true)
+	sess := s.newSession(nil)
+	win := NewWindow("main", "Test")
+	p := NewPanel()
+	l := NewLabel("one")
+	p.Add(l)
+	win.Add(p)
+	sess.AddWin(win)
+
+	renderComp := func(c Comp, rev string) *httptest.ResponseRecorder {
+		params := url.Values{paramCompId: {c.Id().String()}}
+		if rev != "" {
+			params.Set(paramRenderRev, rev)
+		}
+		r := httptest.NewRequest("POST", s.AppPath()+win.Name()+"/"+pathRenderComp, strings.NewReader(params.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(&http.Cookie{Name: gwuSessidCookie, Value: sess.Id()})
+		wr := httptest.NewRecorder()
+		s.serveHTTP(wr, r)
+		return wr
+	}
+	isPatch := func(wr *httptest.ResponseRecorder) bool {
+		return strings.HasPrefix(wr.Header().Get("Content-Type"), "application/json")
+	}
+
+	wr := renderComp(l, "")
+	rev := wr.Header().Get(hdrRenderRev)
+	if isPatch(wr) || !strings.Contains(wr.Body.String(), ">one<") || rev == "" {
+		t.Fatalf("Got first render %q (rev %q)", wr.Body.String(), rev)
+	}
+
+	l.SetText("two")
+	wr = renderComp(l, rev)
+	var ops []diffOp
+	if !isPatch(wr) || json.Unmarshal(wr.Body.Bytes(), &ops) != nil {
+		t.Fatalf("Got no patch: %q", wr.Body.String())
+	}
+	if want := []diffOp{{Op: diffText, Path: []int{0}, Name: "#text", Value: "two"}}; !reflect.DeepEqual(ops, want) {
+		t.Errorf("Got ops %+v, want %+v", ops, want)
+	}
+
+	// Client with an unknown revision gets the whole component
+	l.SetText("three")
+	if wr = renderComp(l, "x"); isPatch(wr) || !strings.Contains(wr.Body.String(), ">three<") {
+		t.Errorf("Got patch for unknown revision: %q", wr.Body.String())
+	}
+	rev = wr.Header().Get(hdrRenderRev)
+
+	// Rendering the parent changes the DOM of the label, its render is dropped
+	renderComp(p, "")
+	if wr = renderComp(l, rev); isPatch(wr) {
+		t.Errorf("Got patch after the parent was rendered: %q", wr.Body.String())
+	}
+
+	s.SetDiffRender(false)
+	if wr = renderComp(l, wr.Header().Get(hdrRenderRev)); isPatch(wr) || wr.Header().Get(hdrRenderRev) != "" {
+		t.Errorf("Got patch with diff rendering disabled")
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// HtmlSanitizer interface and implementation.
+
+package gwu
+
+import (
+	"html"
+	"strings"
+)
+
+// HtmlSanitizer interface defines a sanitizer which makes untrusted HTML
+// text (e.g. sent by the client) safe to render, preventing (stored) XSS.
+type HtmlSanitizer interface {
+	// Sanitize returns the sanitized version of the specified HTML text.
+	Sanitize(htmlText string) string
+}
+
+// DefaultAllowedTags is the allow-list of the default HTML sanitizer:
+// allowed tag names mapped to their allowed attribute names.
+var DefaultAllowedTags = map[string][]string{
+	"a": {"href", "title"}, "b": nil, "strong": nil, "i": nil, "em": nil, "u": nil, "s": nil,
+	"br": nil, "p": nil, "div": nil, "span": nil, "ul": nil, "ol": nil, "li": nil, "blockquote": nil,
+}
+
+// NewHtmlSanitizer creates a new allow-list based HTML sanitizer.
+// allowed maps the allowed tag names (lowercase) to their allowed attribute
+// names (lowercase); see DefaultAllowedTags for an example.
+//
+// Disallowed tags are removed (keeping their text content), except for
+// script and style elements which are removed entirely. Comments are removed.
+// Text and attribute values are (re-)escaped, and URL attributes (href, src)
+// only allow http, https and mailto URLs (and relative URLs).
+// Unclosed allowed tags are closed, stray closing tags are removed.
+func NewHtmlSanitizer(allowed map[string][]string) HtmlSanitizer {
+	s := &htmlSanitizerImpl{allowed: make(map[string]map[string]bool, len(allowed))}
+	for tag, attrs := range allowed {
+		s.allowed[tag] = make(map[string]bool, len(attrs))
+		for _, attr := range attrs {
+			s.allowed[tag][attr] = true
+		}
+	}
+	return s
+}
+
+// HtmlSanitizer implementation.
+type htmlSanitizerImpl struct {
+	allowed map[string]map[string]bool // Allowed attributes mapped from allowed tags
+}
+
+// Void elements (which have no closing tag).
+var voidTags = map[string]bool{"br": true, "hr": true, "img": true, "wbr": true}
+
+// Elements whose content is also removed if they are not allowed.
+var rawTextTags = map[string]bool{"script": true, "style": true}
+
+func (s *htmlSanitizerImpl) Sanitize(text string) string {
+	var b strings.Builder
+	var open []string // Stack of open allowed tags
+
+	for len(text) > 0 {
+		i := strings.IndexByte(text, '<')
+		if i < 0 {
+			i = len(text)
+		}
+		b.WriteString(html.EscapeString(html.UnescapeString(text[:i])))
+		text = text[i:]
+		if text == "" {
+			break
+		}
+
+		if strings.HasPrefix(text, "<!--") {
+			if end := strings.Index(text, "-->"); end >= 0 {
+				text = text[end+3:]
+			} else {
+				text = ""
+			}
+			continue
+		}
+
+		name, attrs, closing, rest, ok := parseTag(text)
+		if !ok {
+			// Not a tag, just a less than sign
+			b.WriteString("&lt;")
+			text = text[1:]
+			continue
+		}
+		text = rest
+
+		allowedAttrs, allowed := s.allowed[name]
+		switch {
+		case !allowed && !closing && rawTextTags[name]:
+			// Skip the content too
+			if end := strings.Index(strings.ToLower(text), "</"+name); end >= 0 {
+				text = text[end:]
+				if gt := strings.IndexByte(text, '>'); gt >= 0 {
+					text = text[gt+1:]
+				} else {
+					text = ""
+				}
+			} else {
+				text = ""
+			}
+		case !allowed:
+			// Drop the tag, keep its content
+		case closing:
+			// Only close if open, closing the unclosed inner tags too
+			for j := len(open) - 1; j >= 0; j-- {
+				if open[j] == name {
+					for k := len(open) - 1; k >= j; k-- {
+						b.WriteString("</" + open[k] + ">")
+					}
+					open = open[:j]
+					break
+				}
+			}
+		default:
+			b.WriteString("<" + name)
+			for _, attr := range attrs {
+				if !allowedAttrs[attr[0]] {
+					continue
+				}
+				value := html.UnescapeString(attr[1])
+				if (attr[0] == "href" || attr[0] == "src") && !safeUrl(value) {
+					continue
+				}
+				b.WriteString(" " + attr[0] + `="` + html.EscapeString(value) + `"`)
+			}
+			b.WriteString(">")
+			if !voidTags[name] {
+				open = append(open, name)
+			}
+		}
+	}
+
+	for j := len(open) - 1; j >= 0; j-- {
+		b.WriteString("</" + open[j] + ">")
+	}
+	return b.String()
+}
+
+// parseTag parses an HTML tag at the beginning of the text (which starts with '<').
+// The tag name and attribute names are returned in lowercase, attribute values
+// as they appear (without quotes). rest is the text after the tag.
+// ok is false if the text does not start with a (terminated) tag.
+func parseTag(text string) (name string, attrs [][2]string, closing bool, rest string, ok bool) {
+	i := 1
+	if i < len(text) && text[i] == '/' {
+		closing = true
+		i++
+	}
+	start := i
+	for i < len(text) && isTagNameChar(text[i]) {
+		i++
+	}
+	if i == start || !isLetter(text[start]) {
+		return
+	}
+	name = strings.ToLower(text[start:i])
+
+	for {
+		for i < len(text) && (isSpace(text[i]) || text[i] == '/') {
+			i++
+		}
+		if i >= len(text) {
+			return "", nil, false, "", false // Unterminated tag
+		}
+		if text[i] == '>' {
+			return name, attrs, closing, text[i+1:], true
+		}
+
+		// Attribute name
+		start = i
+		for i < len(text) && !isSpace(text[i]) && text[i] != '=' && text[i] != '>' && text[i] != '/' {
+			i++
+		}
+		attr := [2]string{strings.ToLower(text[start:i]), ""}
+		for i < len(text) && isSpace(text[i]) {
+			i++
+		}
+		if i < len(text) && text[i] == '=' {
+			i++
+			for i < len(text) && isSpace(text[i]) {
+				i++
+			}
+			if i < len(text) && (text[i] == '"' || text[i] == '\'') {
+				quote := text[i]
+				i++
+				start = i
+				for i < len(text) && text[i] != quote {
+					i++
+				}
+				attr[1] = text[start:i]
+				i++ // Skip closing quote
+			} else {
+				start = i
+				for i < len(text) && !isSpace(text[i]) && text[i] != '>' {
+					i++
+				}
+				attr[1] = text[start:i]
+			}
+		}
+		attrs = append(attrs, attr)
+	}
+}
+
+// safeUrl tells if the URL is safe to render as a link target:
+// it has http, https or mailto scheme, or it is a relative URL.
+func safeUrl(u string) bool {
+	// Browsers ignore whitespace and control characters in the scheme
+	u = strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(u))
+
+	colon := strings.IndexByte(u, ':')
+	if colon < 0 || strings.IndexAny(u[:colon], "/?#") >= 0 {
+		return true // Relative URL
+	}
+	switch u[:colon] {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isTagNameChar(c byte) bool {
+	return isLetter(c) || c >= '0' && c <= '9' || c == '-'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
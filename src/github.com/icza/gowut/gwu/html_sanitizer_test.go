@@ -0,0 +1,60 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"testing"
+)
+
+func TestHtmlSanitizer(t *testing.T) {
+	s := NewHtmlSanitizer(DefaultAllowedTags)
+	for _, c := range []struct {
+		in, out string
+	}{
+		{"plain & simple", "plain &amp; simple"},
+		{"<b>bold</b> <i>it</i>", "<b>bold</b> <i>it</i>"},
+		{"<B CLASS='x'>bold</B>", "<b>bold</b>"},
+		{"a<br/>b", "a<br>b"},
+		{"<script>alert(1)</script>ok", "ok"},
+		{"<STYLE>p{}</style>ok", "ok"},
+		{"<script>never closed", ""},
+		{"<img src=x onerror=alert(1)>text", "text"},
+		{"<iframe src='evil'>inner</iframe>", "inner"},
+		{"<!-- comment -->x", "x"},
+		{`<a href="http://a.com/?x=1&amp;y=2" onclick="f()">l</a>`, `<a href="http://a.com/?x=1&amp;y=2">l</a>`},
+		{`<a href="/rel">l</a>`, `<a href="/rel">l</a>`},
+		{`<a href="javascript:alert(1)">l</a>`, `<a>l</a>`},
+		{`<a href=" JaVa&#x09;script:alert(1)">l</a>`, `<a>l</a>`},
+		{`<a title='"><script>'>l</a>`, `<a title="&#34;&gt;&lt;script&gt;">l</a>`},
+		{"<b><i>unclosed", "<b><i>unclosed</i></b>"},
+		{"<b><i>x</b>y", "<b><i>x</i></b>y"},
+		{"stray</b>", "stray"},
+		{"1 < 2 <3", "1 &lt; 2 &lt;3"},
+		{"<div", "&lt;div"},
+	} {
+		if got := s.Sanitize(c.in); got != c.out {
+			t.Errorf("Sanitize(%q): expected %q, got %q", c.in, c.out, got)
+		}
+	}
+}
+
+func TestHtmlSanitizerCustom(t *testing.T) {
+	s := NewHtmlSanitizer(map[string][]string{"p": {"class"}})
+	in, out := `<p class="c" id="i"><b>x</b></p>`, `<p class="c">x</p>`
+	if got := s.Sanitize(in); got != out {
+		t.Errorf("Sanitize(%q): expected %q, got %q", in, out, got)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Iframe component interface and implementation.
+
+package gwu
+
+// Iframe interface defines a component which embeds another
+// HTML page (e.g. maps, videos or legacy pages) using an inline frame.
+//
+// The text of the Iframe is its title, which is announced by screen readers
+// (describe the content of the frame).
+//
+// By default the embedded content is sandboxed with all restrictions applied
+// (an empty sandbox attribute is rendered): scripts, forms, popups, plugins
+// etc. are disabled, and the content is treated as being from a unique origin.
+// Lift restrictions selectively with SetSandbox() (e.g. "allow-scripts").
+// Note that allowing both "allow-scripts" and "allow-same-origin" for
+// same-origin content makes the sandbox ineffective, as the embedded page can
+// remove the sandbox attribute. Only disable sandboxing (SetSandboxed(false))
+// for trusted content.
+//
+// All attributes are HTML-escaped.
+//
+// Default style class: "gwu-Iframe"
+type Iframe interface {
+	// Iframe is a component.
+	Comp
+
+	// Iframe has text which is its title.
+	HasText
+
+	// Iframe has URL string which is the source of the embedded page.
+	HasUrl
+
+	// Sandboxed tells if the embedded content is sandboxed.
+	Sandboxed() bool
+
+	// SetSandboxed sets whether the embedded content is sandboxed.
+	SetSandboxed(sandboxed bool)
+
+	// Sandbox returns the sandbox restrictions lifted for the embedded content,
+	// a space separated list of tokens (e.g. "allow-scripts allow-forms").
+	Sandbox() string
+
+	// SetSandbox sets the sandbox restrictions lifted for the embedded content,
+	// a space separated list of tokens (e.g. "allow-scripts allow-forms").
+	// Pass an empty string to apply all restrictions.
+	SetSandbox(sandbox string)
+
+	// Allow returns the permissions policy of the iframe.
+	Allow() string
+
+	// SetAllow sets the permissions policy of the iframe,
+	// the features the embedded content is allowed to use
+	// (e.g. "fullscreen; geolocation").
+	// Pass an empty string to not render the allow attribute.
+	SetAllow(allow string)
+}
+
+// Iframe implementation.
+type iframeImpl struct {
+	compImpl    // Component implementation
+	hasTextImpl // Has text implementation
+	hasUrlImpl  // Has URL implementation
+
+	sandboxed bool   // Tells if the embedded content is sandboxed
+	sandbox   string // Lifted sandbox restrictions
+	allow     string // Permissions policy
+}
+
+// NewIframe creates a new Iframe.
+// The text is used as the title of the iframe.
+// The embedded content is sandboxed with all restrictions applied.
+func NewIframe(text, url string) Iframe {
+	c := &iframeImpl{compImpl: newCompImpl(nil), hasTextImpl: newHasTextImpl(text), hasUrlImpl: newHasUrlImpl(url), sandboxed: true}
+	c.Style().AddClass("gwu-Iframe")
+	return c
+}
+
+func (c *iframeImpl) Sandboxed() bool {
+	return c.sandboxed
+}
+
+func (c *iframeImpl) SetSandboxed(sandboxed bool) {
+	c.sandboxed = sandboxed
+}
+
+func (c *iframeImpl) Sandbox() string {
+	return c.sandbox
+}
+
+func (c *iframeImpl) SetSandbox(sandbox string) {
+	c.sandbox = sandbox
+}
+
+func (c *iframeImpl) Allow() string {
+	return c.allow
+}
+
+func (c *iframeImpl) SetAllow(allow string) {
+	c.allow = allow
+}
+
+var (
+	strIframeOp = []byte("<iframe")   // "<iframe"
+	strIframeCl = []byte("</iframe>") // "</iframe>"
+)
+
+func (c *iframeImpl) Render(w Writer) {
+	w.Write(strIframeOp)
+	writeEscAttr(w, "src", c.url)
+	writeEscAttr(w, "title", c.text)
+	if c.sandboxed {
+		writeEscAttr(w, "sandbox", c.sandbox)
+	}
+	if len(c.allow) > 0 {
+		writeEscAttr(w, "allow", c.allow)
+	}
+	c.renderAttrsAndStyle(w)
+	c.renderEHandlers(w)
+	w.Write(strGT)
+	w.Write(strIframeCl)
+}
+
+// writeEscAttr writes an attribute name-value pair, HTML-escaping the value
+// (unlike Writer.WriteAttr()).
+func writeEscAttr(w Writer, name, value string) {
+	w.Write(strSpace)
+	w.Writes(name)
+	w.Write(strEqQuote)
+	w.Writees(value)
+	w.Write(strQuote)
+}
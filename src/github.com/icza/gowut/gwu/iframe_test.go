@@ -0,0 +1,48 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIframeRender(t *testing.T) {
+	f := NewIframe(`Map of "HQ"`, `https://example.com/map?a=1&b="x"`)
+
+	s := renderString(f)
+	for _, want := range []string{`<iframe src="https://example.com/map?a=1&amp;b=&#34;x&#34;"`,
+		` title="Map of &#34;HQ&#34;"`, ` sandbox=""`, `></iframe>`} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Rendered iframe %s does not contain %s", s, want)
+		}
+	}
+	if strings.Contains(s, "allow=") {
+		t.Errorf("Allow rendered when not set: %s", s)
+	}
+
+	f.SetSandbox("allow-scripts allow-forms")
+	f.SetAllow(`fullscreen; geolocation "self"`)
+	s = renderString(f)
+	if !strings.Contains(s, ` sandbox="allow-scripts allow-forms"`) || !strings.Contains(s, ` allow="fullscreen; geolocation &#34;self&#34;"`) {
+		t.Errorf("Sandbox or allow not rendered properly: %s", s)
+	}
+
+	f.SetSandboxed(false)
+	if s := renderString(f); strings.Contains(s, "sandbox=") {
+		t.Errorf("Sandbox rendered when disabled: %s", s)
+	}
+}
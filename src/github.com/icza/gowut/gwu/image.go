@@ -19,7 +19,10 @@ package gwu
 
 // Image interface defines an image.
 //
-// Default style class: "gwu-Image"
+// Optionally a (low-quality) placeholder image can be set which is displayed
+// until the full image loads, after which the full image fades in.
+//
+// Default style classes: "gwu-Image", "gwu-Image-FadeIn"
 type Image interface {
 	// Image is a component.
 	Comp
@@ -29,6 +32,16 @@ type Image interface {
 
 	// Image has URL string.
 	HasUrl
+
+	// Placeholder returns the URL of the placeholder image.
+	Placeholder() string
+
+	// SetPlaceholder sets the URL of the placeholder image which is
+	// displayed until the full image (specified by the URL) loads.
+	// When the full image is loaded, it replaces the placeholder
+	// and the "gwu-Image-FadeIn" style class is added to fade it in.
+	// Pass an empty string to not use a placeholder.
+	SetPlaceholder(src string)
 }
 
 // Image implementation
@@ -36,25 +49,43 @@ type imageImpl struct {
 	compImpl    // Component implementation
 	hasTextImpl // Has text implementation
 	hasUrlImpl  // Has text implementation
+
+	placeholder string // URL of the placeholder image
 }
 
 // NewImage creates a new Image.
 // The text is used as the alternate text for the image.
 func NewImage(text, url string) Image {
-	c := &imageImpl{newCompImpl(nil), newHasTextImpl(text), newHasUrlImpl(url)}
+	c := &imageImpl{compImpl: newCompImpl(nil), hasTextImpl: newHasTextImpl(text), hasUrlImpl: newHasUrlImpl(url)}
 	c.Style().AddClass("gwu-Image")
 	return c
 }
 
+func (c *imageImpl) Placeholder() string {
+	return c.placeholder
+}
+
+func (c *imageImpl) SetPlaceholder(src string) {
+	c.placeholder = src
+}
+
 var (
-	strImgOp = []byte("<img")   // "<img"
-	strAlt   = []byte(` alt="`) // ` alt="`
-	strImgCl = []byte(`">`)     // `">`
+	strImgOp         = []byte("<img")                        // "<img"
+	strAlt           = []byte(` alt="`)                      // ` alt="`
+	strImgCl         = []byte(`">`)                          // `">`
+	strOnloadImgFull = []byte(` onload="imgLoadFull(this)"`) // ` onload="imgLoadFull(this)"`
 )
 
 func (c *imageImpl) Render(w Writer) {
 	w.Write(strImgOp)
-	c.renderUrl("src", w)
+	if c.placeholder == "" {
+		c.renderUrl("src", w)
+	} else {
+		// To render: <img src="placeholder" data-gwusrc="url" onload="imgLoadFull(this)"...
+		w.WriteAttr("src", c.placeholder)
+		c.renderUrl("data-gwusrc", w)
+		w.Write(strOnloadImgFull)
+	}
 	c.renderAttrsAndStyle(w)
 	c.renderEHandlers(w)
 	w.Write(strAlt)
@@ -0,0 +1,40 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImagePlaceholder(t *testing.T) {
+	img := NewImage("Photo", "/img/full.jpg")
+	if s := renderString(img); !strings.Contains(s, ` src="/img/full.jpg"`) || strings.Contains(s, "onload") {
+		t.Errorf("Unexpected render without placeholder: %s", s)
+	}
+
+	img.SetPlaceholder("/img/tiny.jpg")
+	if img.Placeholder() != "/img/tiny.jpg" {
+		t.Errorf("Got placeholder %q", img.Placeholder())
+	}
+	s := renderString(img)
+	want := `<img src="/img/tiny.jpg" data-gwusrc="/img/full.jpg" onload="imgLoadFull(this)" id="` + img.Id().String() + `"`
+	if !strings.Contains(s, want) {
+		t.Errorf("Rendered output does not contain %s: %s", want, s)
+	}
+
+	checkStaticJsFuncs(t, "imgLoadFull")
+}
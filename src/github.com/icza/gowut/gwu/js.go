@@ -42,10 +42,19 @@ func init() {
 		"',_pMouseBtn='" + paramMouseBtn +
 		"',_pModKeys='" + paramModKeys +
 		"',_pKeyCode='" + paramKeyCode +
+		"',_pWheelDX='" + paramWheelDX +
+		"',_pWheelDY='" + paramWheelDY +
+		"',_pBufChange='" + paramBufChange +
+		"',_pGeoLat='" + paramGeoLat +
+		"',_pGeoLong='" + paramGeoLong +
+		"',_pKeepAlive='" + paramKeepAlive +
+		"',_pRenderRev='" + paramRenderRev +
+		"',_pWsCookies='" + paramWsCookies +
+		"',_pPushSeq='" + paramPushSeq +
 		"';\n" +
 		// Modifier key masks
 		"var _modKeyAlt=" + strconv.Itoa(int(ModKeyAlt)) +
-		",_modKeyCtlr=" + strconv.Itoa(int(ModKeyCtrl)) +
+		",_modKeyCtrl=" + strconv.Itoa(int(ModKeyCtrl)) +
 		",_modKeyMeta=" + strconv.Itoa(int(ModKeyMeta)) +
 		",_modKeyShift=" + strconv.Itoa(int(ModKeyShift)) +
 		";\n" +
@@ -54,9 +63,96 @@ func init() {
 		",_eraReloadWin=" + strconv.Itoa(eraReloadWin) +
 		",_eraDirtyComps=" + strconv.Itoa(eraDirtyComps) +
 		",_eraFocusComp=" + strconv.Itoa(eraFocusComp) +
-		";" +
+		",_eraRedirect=" + strconv.Itoa(eraRedirect) +
+		",_eraCaptureComp=" + strconv.Itoa(eraCaptureComp) +
+		",_eraRerenderWin=" + strconv.Itoa(eraRerenderWin) +
+		",_eraSubmitForm=" + strconv.Itoa(eraSubmitForm) +
+		",_eraGeolocation=" + strconv.Itoa(eraGeolocation) +
+		",_eraLoadScript=" + strconv.Itoa(eraLoadScript) +
+		",_eraPreventDefault=" + strconv.Itoa(eraPreventDefault) +
+		";\n" +
+		// Unknown response code modes
+		"var _unknownRespLog=" + strconv.Itoa(int(UnknownRespLog)) +
+		",_unknownRespIgnore=" + strconv.Itoa(int(UnknownRespIgnore)) +
+		";\n" +
+		// Enabled predicate operators
+		"var _enPredNotEquals=" + strconv.Itoa(int(EnPredNotEquals)) +
+		",_enPredNotEmpty=" + strconv.Itoa(int(EnPredNotEmpty)) +
+		",_enPredEmpty=" + strconv.Itoa(int(EnPredEmpty)) +
+		",_enPredMatches=" + strconv.Itoa(int(EnPredMatches)) +
+		";\n" +
+		// Client side ValueMirror format kinds
+		"var _mirrorFmtUpper=" + strconv.Itoa(int(MirrorFormatUpper)) +
+		",_mirrorFmtLower=" + strconv.Itoa(int(MirrorFormatLower)) +
+		",_mirrorFmtNumber=" + strconv.Itoa(int(MirrorFormatNumber)) +
+		",_mirrorFmtLength=" + strconv.Itoa(int(MirrorFormatLength)) +
+		";\n" +
+		// Response headers of the render revision (diff rendering) and the push sequence number
+		"var _hdrRenderRev='" + hdrRenderRev +
+		"',_hdrPushSeq='" + hdrPushSeq +
+		"';\n" +
+		// App path-relative path of static contents
+		"var _pathStatic='" + pathStatic +
+		"';" +
 		`
 
+// Shadow roots of the ShadowHost components.
+var _shadowRoots = [];
+
+// Returns the element with the specified id, also looking into the shadow roots of ShadowHosts.
+function gwuById(id) {
+	var e = document.getElementById(id);
+	for (var i = 0; !e && i < _shadowRoots.length; i++)
+		e = _shadowRoots[i].getElementById(id);
+	return e;
+}
+
+// Returns the focused element, also looking into the shadow roots of ShadowHosts.
+function activeElem() {
+	var e = document.activeElement;
+	while (e && e.shadowRoot && e.shadowRoot.activeElement)
+		e = e.shadowRoot.activeElement;
+	return e || document.body;
+}
+
+// Attaches shadow roots to the hosts of the not yet attached shadow templates (of ShadowHosts):
+// moves the content of the templates into the shadow roots along with the Gowut style sheets.
+function attachShadows() {
+	// Forget shadow roots of removed (re-rendered) hosts
+	var roots = [];
+	for (var i = 0; i < _shadowRoots.length; i++)
+		if (_shadowRoots[i].host.isConnected)
+			roots.push(_shadowRoots[i]);
+	_shadowRoots = roots;
+	
+	var lists = [document.querySelectorAll("template[data-gwushadow]")];
+	for (var i = 0; i < _shadowRoots.length; i++)
+		lists.push(_shadowRoots[i].querySelectorAll("template[data-gwushadow]"));
+	var links = document.querySelectorAll('link[rel="stylesheet"][href*="' + _pathStatic + '"]');
+	
+	for (var i = 0; i < lists.length; i++)
+		for (var j = 0; j < lists[i].length; j++) {
+			var t = lists[i][j];
+			var host = t.parentNode;
+			if (!host.attachShadow) {
+				// Shadow DOM not supported, render the content without isolation
+				host.replaceChild(t.content, t);
+				continue;
+			}
+			var root = host.shadowRoot || host.attachShadow({mode: "open"});
+			for (var k = 0; k < links.length; k++)
+				root.appendChild(links[k].cloneNode(false));
+			root.appendChild(t.content);
+			host.removeChild(t);
+			_shadowRoots.push(root);
+			// Nested hosts are attached too when the list of this root is processed
+			lists.push(root.querySelectorAll("template[data-gwushadow]"));
+		}
+}
+
+if (document.addEventListener)
+	document.addEventListener("DOMContentLoaded", attachShadows);
+
 function createXmlHttp() {
 	if (window.XMLHttpRequest) // IE7+, Firefox, Chrome, Opera, Safari
 		return new XMLHttpRequest();
@@ -65,16 +161,11 @@ function createXmlHttp() {
 }
 
 // Send event
-function se(event, etype, compId, compValue) {
-	var xhr = createXmlHttp();
-	
-	xhr.onreadystatechange = function() {
-		if (xhr.readyState == 4 && xhr.status == 200)
-			procEresp(xhr);
-	}
-	
-	xhr.open("POST", _pathEvent, true); // asynch call
-	xhr.setRequestHeader("Content-type", "application/x-www-form-urlencoded");
+// extraData is optional, it is appended to the sent data as-is (must be URL-encoded and start with "&").
+function se(event, etype, compId, compValue, extraData) {
+	// Client event interceptor may veto the event
+	if (typeof _seInterceptor == "function" && _seInterceptor(event, etype, compId, compValue) === false)
+		return;
 	
 	var data="";
 	
@@ -84,58 +175,746 @@ function se(event, etype, compId, compValue) {
 		data += "&" + _pCompId + "=" + compId;
 	if (compValue != null)
 		data += "&" + _pCompValue + "=" + compValue;
-	if (document.activeElement.id != null)
-		data += "&" + _pFocCompId + "=" + document.activeElement.id;
+	if (extraData)
+		data += extraData;
+	var active = activeElem();
+	if (active.id != null)
+		data += "&" + _pFocCompId + "=" + active.id;
+	
+	// Flush buffered changes (component values are already URL-encoded)
+	for (var key in _bufChanges)
+		data += "&" + _pBufChange + "=" + _bufChanges[key];
+	_bufChanges = {};
 	
 	if (event != null) {
-		if (event.clientX != null) {
+		// Touch events have no coordinates, the first touch point is used
+		// (touches is empty on touchend, changedTouches holds the lifted point)
+		var touch = event.touches && event.touches.length ? event.touches[0]
+			: event.changedTouches && event.changedTouches.length ? event.changedTouches[0] : null;
+		if (event.clientX != null || touch) {
 			// Mouse data
-			var x = event.clientX, y = event.clientY;
+			var x = touch ? touch.clientX : event.clientX, y = touch ? touch.clientY : event.clientY;
 			data += "&" + _pMouseWX + "=" + x;
 			data += "&" + _pMouseWY + "=" + y;
-			var parent = document.getElementById(compId);
+			var parent = gwuById(compId);
 			do {
 				x -= parent.offsetLeft;
 				y -= parent.offsetTop;
 			} while (parent = parent.offsetParent);
 			data += "&" + _pMouseX + "=" + x;
 			data += "&" + _pMouseY + "=" + y;
-			data += "&" + _pMouseBtn + "=" + (event.button < 4 ? event.button : 1); // IE8 and below uses 4 for middle btn
+			data += "&" + _pMouseBtn + "=" + (touch ? 0 : event.button < 4 ? event.button : 1); // IE8 and below uses 4 for middle btn
 		}
 		
-		var modKeys;
+		var modKeys = 0;
 		modKeys += event.altKey ? _modKeyAlt : 0;
-		modKeys += event.ctlrKey ? _modKeyCtlr : 0;
+		modKeys += event.ctrlKey ? _modKeyCtrl : 0;
 		modKeys += event.metaKey ? _modKeyMeta : 0;
 		modKeys += event.shiftKey ? _modKeyShift : 0;
 		data += "&" + _pModKeys + "=" + modKeys;
 		data += "&" + _pKeyCode + "=" + (event.which ? event.which : event.keyCode);
+		
+		if (event.deltaY != null) {
+			// Wheel data, deltas in lines or pages are converted to pixels
+			var kx = event.deltaMode == 1 ? 16 : event.deltaMode == 2 ? window.innerWidth : 1;
+			var ky = event.deltaMode == 1 ? 16 : event.deltaMode == 2 ? window.innerHeight : 1;
+			data += "&" + _pWheelDX + "=" + Math.round(event.deltaX * kx);
+			data += "&" + _pWheelDY + "=" + Math.round(event.deltaY * ky);
+		}
+	}
+	
+	seBusy(1);
+	if (_ws && !_seSync) {
+		_ws.send(data);
+		_wsPending.push([etype, compId]);
+		return;
+	}
+	seSend(data, etype, compId, 0);
+}
+
+// Number of events in flight (sent but not yet responded).
+var _seInFlight = 0;
+
+// Changes the number of events in flight by delta, and toggles the gwu-Busy
+// style class of the document body accordingly.
+function seBusy(delta) {
+	_seInFlight = Math.max(0, _seInFlight + delta);
+	if (_seInFlight > 0)
+		document.body.classList.add("gwu-Busy");
+	else
+		document.body.classList.remove("gwu-Busy");
+}
+
+// Tells if the event being sent is sent synchronously (by seSync()),
+// and if its response requested to prevent its default action.
+var _seSync = false, _sePreventDefault = false;
+
+// Sends an event synchronously, and prevents its default action if the response
+// requests it (see Event.PreventDefault()). Returns false in that case (so it can be
+// returned from inline event handlers).
+// Used for events whose default action must be decided before the handler returns
+// (e.g. the native context menu of ETypeContextMenu).
+function seSync(event, etype, compId, compValue) {
+	_seSync = true;
+	_sePreventDefault = false;
+	try {
+		se(event, etype, compId, compValue);
+	} finally {
+		_seSync = false;
+	}
+	if (!_sePreventDefault)
+		return true;
+	_sePreventDefault = false;
+	event.preventDefault();
+	return false;
+}
+
+// Delay of the first retry of a failed event in ms, doubled for each further retry.
+var _seRetryDelay = 300;
+
+// Sends the data of an event. Failed requests are retried _seRetries times (see
+// Server.SetEventRetry()) if the event surely or likely did not reach the server
+// (network errors, 502 and 503 statuses), but not after timeouts and other errors
+// (the event might have been handled); if the last attempt fails too,
+// the client event error handler is called (see Server.SetClientEventErrorHandler()).
+function seSend(data, etype, compId, attempt) {
+	var xhr = createXmlHttp(), timedOut = false;
+	
+	xhr.ontimeout = function() {
+		timedOut = true;
+	};
+	xhr.onreadystatechange = function() {
+		if (xhr.readyState != 4)
+			return;
+		if (xhr.status == 200) {
+			_reqFailures = 0;
+			seBusy(-1);
+			procEresp(xhr);
+			return;
+		}
+		// readyState 4 is reached before ontimeout is called, defer the decision
+		setTimeout(function() {
+			var retry = xhr.status == 0 && !timedOut || xhr.status == 502 || xhr.status == 503;
+			if (retry && attempt < _seRetries) {
+				setTimeout(function() {
+					seSend(data, etype, compId, attempt + 1);
+				}, _seRetryDelay << attempt);
+				return;
+			}
+			seBusy(-1);
+			seFailed(etype, compId, xhr.status);
+		}, 0);
 	}
 	
+	xhr.open("POST", _pathEvent, !_seSync); // asynch call (unless sent by seSync())
+	if (_seTimeout > 0 && !_seSync)
+		xhr.timeout = _seTimeout;
+	xhr.setRequestHeader("Content-type", "application/x-www-form-urlencoded");
 	xhr.send(data);
 }
 
+// Handles a failed event (after the retries): calls the client event error handler
+// (see Server.SetClientEventErrorHandler()).
+function seFailed(etype, compId, status) {
+	reqFailed();
+	if (typeof _seErrHandler == "function")
+		_seErrHandler(etype, compId, status);
+}
+
+// WebSocket to send events over (see Window.SetWebSocketEnabled()), null if not open,
+// and the events ([etype, compId]) sent over it waiting for response, in order.
+var _ws = null, _wsPending = [];
+
+// Opens the WebSocket of the window. While it is not open (or if the
+// browser does not support WebSockets), events are sent via XHR.
+function wsOpen() {
+	if (!window.WebSocket)
+		return;
+	var url = new URL(_pathWs, window.location.href);
+	url.protocol = url.protocol == "https:" ? "wss:" : "ws:";
+	if (typeof _pushSeq != "undefined") // Pushed updates are sent over the WebSocket too
+		url.search = _pPushSeq + "=" + _pushSeq;
+	var ws = new WebSocket(url.href);
+	ws.onopen = function() {
+		_ws = ws;
+	};
+	ws.onmessage = function(m) {
+		_wsMsgs.push(m.data);
+		if (_wsMsgs.length == 1) // Else a previous message is being processed
+			wsProcMsg();
+	};
+	ws.onclose = function() {
+		if (_ws == ws) {
+			_ws = null;
+			// No response will arrive
+			var pending = _wsPending;
+			_wsPending = [];
+			for (var i = 0; i < pending.length; i++) {
+				seBusy(-1);
+				seFailed(pending[i][0], pending[i][1], 0);
+			}
+		}
+		// Events are sent via XHR until reopened
+		setTimeout(wsOpen, 5000);
+	};
+}
+
+// Messages received over the WebSocket waiting to be processed, in order.
+var _wsMsgs = [];
+
+// Processes the first received WebSocket message, and then the next ones.
+// If the cookies of a response have to be fetched, the response (and the
+// next messages) are processed when the cookies have arrived.
+function wsProcMsg() {
+	var text = _wsMsgs[0];
+	var next = function() {
+		_wsMsgs.shift();
+		if (_wsMsgs.length > 0)
+			wsProcMsg();
+	};
+	
+	if (text.indexOf("ps:") == 0) {
+		// Pushed update, not a response (text is "ps:seq\nresponse")
+		var nl = text.indexOf("\n");
+		procPush(parseInt(text.substring(3, nl)), text.substring(nl + 1));
+		next();
+		return;
+	}
+	var ev = _wsPending.shift();
+	if (text.indexOf("er:") == 0) {
+		// Error status (text is "er:status")
+		if (ev) {
+			seBusy(-1);
+			seFailed(ev[0], ev[1], parseInt(text.substring(3)));
+		} else
+			reqFailed();
+		next();
+		return;
+	}
+	var done = function(text) {
+		if (ev)
+			seBusy(-1);
+		_reqFailures = 0;
+		procEresp({responseText: text});
+		next();
+	};
+	if (text.indexOf("ck:") == 0) {
+		// Cookies can't be set over a WebSocket, fetch them with a request (text is "ck:token\nresponse")
+		var nl = text.indexOf("\n");
+		var xhr = createXmlHttp();
+		xhr.onreadystatechange = function() {
+			if (xhr.readyState == 4)
+				done(text.substring(nl + 1)); // Even if fetching the cookies failed
+		};
+		xhr.open("GET", _pathWsCookies + "?" + _pWsCookies + "=" + text.substring(3, nl), true);
+		xhr.send(null);
+		return;
+	}
+	done(text);
+}
+
+// Number of consecutive failed requests, and whether reconnecting is in progress.
+var _reqFailures = 0, _reconnecting = false;
+// Failed requests to start reconnecting after, and the min and max delays of polling in ms.
+var _reconnectFailures = 3, _reconnectMinDelay = 1000, _reconnectMaxDelay = 30000;
+
+// Called when a request fails (e.g. the server is unavailable).
+// If auto reconnect is enabled, starts reconnecting after several consecutive failures.
+function reqFailed() {
+	if (!_autoReconnect || _reconnecting || ++_reqFailures < _reconnectFailures)
+		return;
+	_reconnecting = true;
+	reconnect(_reconnectMinDelay);
+}
+
+// Polls the server after the specified delay, doubling the delay until the server
+// is available again (up to a max delay); then the window is reloaded.
+function reconnect(delay) {
+	setTimeout(function() {
+		var xhr = createXmlHttp();
+		xhr.onreadystatechange = function() {
+			if (xhr.readyState != 4)
+				return;
+			if (xhr.status == 200)
+				window.location.reload();
+			else
+				reconnect(Math.min(delay * 2, _reconnectMaxDelay));
+		}
+		xhr.open("GET", _pathSessCheck, true); // asynch call
+		xhr.send();
+	}, delay);
+}
+
+// Buffered changes of components in buffered mode, mapped from "compId_etype".
+// Values are in the form of "etype,compId,compValue", where compValue is URL-encoded.
+var _bufChanges = {};
+
+// Buffer a change, it will be sent along with the next event.
+function sbuf(etype, compId, compValue) {
+	_bufChanges[compId + "_" + etype] = etype + "," + compId + "," + compValue;
+}
+
+// Timers of pending debounced events, mapped from "compId_etype".
+var _seDebTimers = {};
+
+// Send event debounced: the event is only sent if no further event of the same type
+// of the component occurs within the delay (so the last one of rapid events is sent).
+function seDeb(delay, event, etype, compId, compValue) {
+	var key = compId + "_" + etype;
+	clearTimeout(_seDebTimers[key]);
+	_seDebTimers[key] = setTimeout(function() {
+		delete _seDebTimers[key];
+		if (gwuById(compId)) // Component might have been removed meanwhile
+			se(event, etype, compId, compValue);
+	}, delay);
+}
+
+// State of components sending events with a minimum interval, mapped from "compId_etype".
+var _seMinStates = {};
+
+// Send event with a minimum interval between events of the same type of a component.
+// Events within the interval are dropped, except the last one which is sent when the interval elapses.
+function seMin(interval, event, etype, compId, compValue) {
+	var key = compId + "_" + etype;
+	var st = _seMinStates[key];
+	if (!st)
+		st = _seMinStates[key] = {last: 0, timer: null};
+	
+	if (st.timer != null) {
+		// Drop the previous pending event, this one is more recent
+		clearTimeout(st.timer);
+		st.timer = null;
+	}
+	
+	var wait = st.last + interval - new Date().getTime();
+	if (wait <= 0) {
+		st.last = new Date().getTime();
+		se(event, etype, compId, compValue);
+		return;
+	}
+	
+	st.timer = setTimeout(function() {
+		st.timer = null;
+		st.last = new Date().getTime();
+		se(event, etype, compId, compValue);
+	}, wait);
+}
+
+// Drags the divider of a split panel. The new ratio is sent when the drag ends.
+function splitDrag(event, compId, etype, vertical, min, max) {
+	var t = gwuById(compId);
+	var first = t.rows[0].cells[0];
+	var ratio = null;
+	
+	function move(e) {
+		var r = t.getBoundingClientRect();
+		ratio = vertical ? (e.clientY - r.top) / r.height : (e.clientX - r.left) / r.width;
+		ratio = Math.min(max, Math.max(min, ratio));
+		first.style[vertical ? "height" : "width"] = (ratio * 100) + "%";
+		e.preventDefault(); // Prevent text selection
+	}
+	function up() {
+		document.removeEventListener("mousemove", move, true);
+		document.removeEventListener("mouseup", up, true);
+		if (ratio != null)
+			se(null, etype, compId, ratio.toFixed(4));
+	}
+	
+	document.addEventListener("mousemove", move, true);
+	document.addEventListener("mouseup", up, true);
+	event.preventDefault();
+}
+
+// Long polls the server for pushed updates (ids of components to re-render).
+// While the WebSocket of the window is open, updates are pushed over that instead.
+function pollPush() {
+	if (_ws) {
+		setTimeout(pollPush, 1000);
+		return;
+	}
+	var xhr = createXmlHttp();
+	
+	xhr.onreadystatechange = function() {
+		if (xhr.readyState != 4)
+			return;
+		if (xhr.status == 200) {
+			_reqFailures = 0;
+			procPush(parseInt(xhr.getResponseHeader(_hdrPushSeq)), xhr.responseText);
+			pollPush();
+		} else {
+			reqFailed();
+			setTimeout(pollPush, 5000); // Server unavailable, retry later
+		}
+	}
+	
+	xhr.open("POST", _pathPush, true); // asynch call
+	xhr.setRequestHeader("Content-type", "application/x-www-form-urlencoded");
+	xhr.send(_pPushSeq + "=" + _pushSeq);
+}
+
+// Processes a pushed update having the specified push sequence number. Updates
+// already received (e.g. both over the WebSocket and by long polling) are skipped.
+function procPush(seq, text) {
+	if (!(seq > _pushSeq))
+		return;
+	_pushSeq = seq;
+	procEresp({responseText: text});
+}
+
+// Tells if the character ch fits the mask placeholder m of a MaskedInput.
+function maskFits(m, ch) {
+	return m == "9" ? /[0-9]/.test(ch) : m == "a" ? /[A-Za-z]/.test(ch) : m == "*" ? /[A-Za-z0-9]/.test(ch) : false;
+}
+
+// Tells if the mask character m of a MaskedInput is a placeholder.
+function maskIsPh(m) {
+	return m == "9" || m == "a" || m == "*";
+}
+
+// Returns the raw value of a (possibly partially) masked text:
+// the characters fitting the placeholders, without the literals.
+// Literals of the mask may be omitted from the text.
+function maskRawOf(mask, text) {
+	var raw = "";
+	for (var i = 0, j = 0; i < text.length && j < mask.length; i++) {
+		var ch = text.charAt(i);
+		while (j < mask.length && !maskIsPh(mask.charAt(j)) && mask.charAt(j) != ch)
+			j++;
+		if (j >= mask.length)
+			break;
+		if (!maskIsPh(mask.charAt(j)))
+			j++; // Literal typed
+		else if (maskFits(mask.charAt(j), ch)) {
+			raw += ch;
+			j++;
+		}
+	}
+	return raw;
+}
+
+// Applies the mask to the raw value: inserts the literals
+// up to the last placeholder filled by the raw value.
+function maskApply(mask, raw) {
+	var text = "";
+	for (var i = 0, j = 0; i < mask.length && j < raw.length; i++)
+		text += maskIsPh(mask.charAt(i)) ? raw.charAt(j++) : mask.charAt(i);
+	return text;
+}
+
+// Returns the raw value of a MaskedInput.
+function maskRaw(input) {
+	return maskRawOf(input.getAttribute("data-gwumask"), input.value);
+}
+
+// Reformats the text of a MaskedInput as the user types,
+// keeping the caret after the same raw character.
+function maskInput(input) {
+	var mask = input.getAttribute("data-gwumask");
+	var caret = input.selectionStart;
+	var before = maskRawOf(mask, input.value.substring(0, caret)).length;
+	var text = maskApply(mask, maskRaw(input));
+	if (text == input.value)
+		return;
+	input.value = text;
+	
+	var pos = 0;
+	for (var n = 0; pos < text.length && n < before; pos++)
+		if (maskIsPh(mask.charAt(pos)))
+			n++;
+	input.setSelectionRange(pos, pos);
+}
+
+// Returns the rating value pointed by the mouse on the specified star of a Rating.
+function ratingValue(event, star) {
+	var value = 1;
+	for (var s = star.previousSibling; s; s = s.previousSibling)
+		value++;
+	if (star.parentNode.getAttribute("data-gwuhalf") != null) {
+		var rect = star.getBoundingClientRect();
+		if (event.clientX - rect.left < rect.width / 2)
+			value -= 0.5;
+	}
+	return value;
+}
+
+// Previews the rating pointed by the mouse on a Rating,
+// or restores the chosen rating if event is null.
+function ratingHover(event, rating) {
+	var value;
+	if (event) {
+		if (event.target.parentNode != rating)
+			return;
+		value = ratingValue(event, event.target);
+	} else
+		value = parseFloat(rating.getAttribute("data-gwuvalue"));
+	
+	var i = 1;
+	for (var s = rating.firstChild; s; s = s.nextSibling, i++)
+		s.className = "gwu-Rating-Star gwu-Rating-" + (i <= value ? "Full" : i - 0.5 <= value ? "Half" : "Empty");
+}
+
+// Called when a star of a Rating is clicked, sends the chosen rating.
+function ratingClick(event, star, etype) {
+	se(event, etype, star.parentNode.id, ratingValue(event, star));
+	event.stopPropagation();
+}
+
+// Executes a formatting command on the editor of a RichText, from a toolbar button.
+function rtCmd(button, cmd, value) {
+	var editor = button.parentNode.nextSibling;
+	editor.focus();
+	document.execCommand(cmd, false, value);
+}
+
+// Turns the selection of the editor of a RichText into a link, from a toolbar button.
+function rtLink(button) {
+	var url = prompt("Link URL:", "http://");
+	if (url)
+		rtCmd(button, "createLink", url);
+}
+
+// Called when the editor of a RichText loses the focus,
+// sends its HTML content to the server if it has changed.
+function rtChange(editor, compId, etype) {
+	if (editor.innerHTML == editor._gwuHtml)
+		return;
+	editor._gwuHtml = editor.innerHTML;
+	se(null, etype, compId, encodeURIComponent(editor.innerHTML));
+}
+
+// Called when a details element (of a Disclosure) is toggled,
+// reports the new state if it differs from the last known state.
+function detailsToggle(details, etype) {
+	var open = String(details.open);
+	if (details.getAttribute("data-gwuopen") == open)
+		return;
+	details.setAttribute("data-gwuopen", open);
+	se(null, etype, details.id, open);
+}
+
+// Starts in place editing of an editable label (having a data-gwuedit attribute).
+// Enter or losing the focus saves the edited text, Escape cancels the edit.
+function labelEdit(label) {
+	if (label.getAttribute("data-gwuedit") == null || label.firstChild && label.firstChild.tagName == "INPUT")
+		return; // Not editable or already editing
+	
+	var etype = parseInt(label.getAttribute("data-gwuedit"));
+	var text = label.textContent;
+	var input = document.createElement("input");
+	input.type = "text";
+	input.value = text;
+	
+	var done = false;
+	var finish = function(save) {
+		if (done)
+			return;
+		done = true;
+		label.textContent = save ? input.value : text;
+		if (save && input.value != text)
+			se(null, etype, label.id, encodeURIComponent(input.value));
+	};
+	input.onkeydown = function(e) {
+		e = e || window.event;
+		var code = e.which ? e.which : e.keyCode;
+		if (code == 13)
+			finish(true);
+		else if (code == 27)
+			finish(false);
+	};
+	input.onblur = function() {
+		finish(true);
+	};
+	input.onchange = function(e) {
+		// Do not let the label handle the change of the input
+		(e || window.event).cancelBubble = true;
+	};
+	
+	label.textContent = "";
+	label.appendChild(input);
+	input.focus();
+	input.select();
+}
+
+if (document.addEventListener)
+	document.addEventListener("dblclick", function(event) {
+		for (var e = event.target; e != null && e.getAttribute; e = e.parentNode)
+			if (e.getAttribute("data-gwuedit") != null) {
+				labelEdit(e);
+				return;
+			}
+	});
+
+// Tells if a key event matches any of the key code and modifier key mask
+// pairs (specified in a flat array), and prevents its default action if so.
+function keyMatch(event, keys) {
+	var code = event.which ? event.which : event.keyCode;
+	var mods = (event.altKey ? _modKeyAlt : 0) + (event.ctrlKey ? _modKeyCtrl : 0)
+		+ (event.metaKey ? _modKeyMeta : 0) + (event.shiftKey ? _modKeyShift : 0);
+	
+	for (var i = 0; i + 1 < keys.length; i += 2)
+		if (keys[i] == code && keys[i + 1] == mods) {
+			if (event.preventDefault)
+				event.preventDefault();
+			return true;
+		}
+	return false;
+}
+
+// Keydown listeners of window shortcuts, mapped from window id.
+var _keyShortcuts = {};
+
+// Registers the window shortcuts (see Window.AddKeyShortcut()): matching keys
+// pressed anywhere in the document send an etype event to the window.
+// The listener registered by a previous render of the window is replaced.
+function addKeyShortcuts(winId, etype, keys) {
+	if (_keyShortcuts[winId])
+		document.removeEventListener("keydown", _keyShortcuts[winId]);
+	_keyShortcuts[winId] = function(event) {
+		if (keyMatch(event, keys))
+			se(event, etype, winId, "ks");
+	};
+	document.addEventListener("keydown", _keyShortcuts[winId]);
+}
+
+// Called when the placeholder of an image is loaded: loads the full image,
+// and swaps and fades it in when loaded.
+function imgLoadFull(img) {
+	var src = img.getAttribute("data-gwusrc");
+	if (src == null)
+		return; // Full image already swapped in
+	
+	var full = new Image();
+	full.onload = function() {
+		img.removeAttribute("data-gwusrc");
+		img.src = src;
+		img.className += " gwu-Image-FadeIn";
+	};
+	full.src = src;
+}
+
+// Returns the value of a paste event of a table: the row and column of the target
+// cell and the pasted text. The default paste is prevented.
+function pasteGrid(event, table) {
+	var cd = event.clipboardData || window.clipboardData;
+	var text = cd ? cd.getData("text") : "";
+	
+	// Outermost cell of the table containing the target
+	var td = null;
+	for (var e = event.target || event.srcElement; e != null && e != table; e = e.parentNode)
+		if (e.tagName == "TD")
+			td = e;
+	var row = 0, col = 0;
+	if (td != null) {
+		row = td.parentNode.rowIndex;
+		col = td.cellIndex;
+	}
+	
+	if (event.preventDefault)
+		event.preventDefault();
+	return encodeURIComponent(row + "," + col + "," + text);
+}
+
+// Returns the encoded tags of a tag input as an array.
+function tagList(compId) {
+	var list = gwuById(compId).getAttribute("data-gwutags");
+	return list.length > 0 ? list.split(",") : [];
+}
+
+// Handles key down in a tag input: Enter and comma add the entered tag.
+function tagKey(event, compId, etype) {
+	var key = event.key || String.fromCharCode(event.which || event.keyCode);
+	if (key != "Enter" && key != "," && (event.which || event.keyCode) != 13)
+		return;
+	event.preventDefault();
+	
+	var input = event.target || event.srcElement;
+	var tag = input.value.replace(/^\s+|\s+$/g, "");
+	if (tag.length == 0)
+		return;
+	var tags = tagList(compId);
+	tags.push(encodeURIComponent(tag));
+	se(event, etype, compId, encodeURIComponent(tags.join(",")));
+}
+
+// Removes the tag at the specified index of a tag input.
+function tagRemove(compId, etype, idx) {
+	var tags = tagList(compId);
+	tags.splice(idx, 1);
+	se(null, etype, compId, encodeURIComponent(tags.join(",")));
+}
+
+// Returns its argument, used instead of decodeURIComponent() for JSON responses.
+function identity(s) {
+	return s;
+}
+
+// Processes an event response, either a JSON array of action objects
+// (see Server.SetJSONResponses()) or in the legacy format.
 function procEresp(xhr) {
-	var actions = xhr.responseText.split(";");
+	var text = xhr.responseText, json = text.charAt(0) == "[";
+	var actions = json ? JSON.parse(text) : text.split(";");
+	var dec = json ? identity : decodeURIComponent;
 	
 	if (actions.length == 0) {
 		window.alert("No response received!");
 		return;
 	}
+	
+	// Actions which depend on the DOM (focus, capture) are deferred until
+	// the (asynchronous) re-renders of the dirty components are applied.
+	var deferred = [], renders = 0, processed = false;
+	var runDeferred = function() {
+		for (var i = 0; i < deferred.length; i++)
+			deferred[i]();
+		deferred = [];
+	};
+	var rendered = function() {
+		if (--renders == 0 && processed)
+			runDeferred();
+	};
+	
 	for (var i = 0; i < actions.length; i++) {
-		var n = actions[i].split(",");
+		// Action code and arguments
+		var n = json ? [actions[i].type].concat(actions[i].args || []) : actions[i].split(",");
 		
 		switch (parseInt(n[0])) {
 		case _eraDirtyComps:
-			for (var j = 1; j < n.length; j++)
-				rerenderComp(n[j]);
+			renders++;
+			rerenderComps(n.slice(1), rendered);
 			break;
 		case _eraFocusComp:
 			if (n.length > 1)
-				focusComp(parseInt(n[1]))
+				deferred.push(focusComp.bind(null, parseInt(n[1])));
 			break;
 		case _eraNoAction:
 			break;
+		case _eraRedirect:
+			if (n.length > 2)
+				redirectAfter(dec(n[2]), parseInt(n[1]));
+			break;
+		case _eraCaptureComp:
+			if (n.length > 2)
+				deferred.push(captureComp.bind(null, n[1], dec(n[2])));
+			break;
+		case _eraRerenderWin:
+			if (n.length > 2)
+				spliceComp(n[1], dec(n[2]));
+			break;
+		case _eraSubmitForm:
+			if (n.length > 3)
+				submitExtForm(dec(n[1]), dec(n[2]), dec(n[3]));
+			break;
+		case _eraGeolocation:
+			if (n.length > 2)
+				sendGeolocation(n[1], n[2]);
+			break;
+		case _eraPreventDefault:
+			_sePreventDefault = true;
+			break;
+		case _eraLoadScript:
+			if (n.length > 3)
+				loadScript(n[1], n[2], dec(n[3]));
+			break;
 		case _eraReloadWin:
 			if (n.length > 1 && n[1].length > 0)
 				window.location.href = _pathApp + n[1];
@@ -143,39 +922,578 @@ function procEresp(xhr) {
 				window.location.reload(true); // force reload
 			break;
 		default:
-			window.alert("Unknown response code:" + n[0]);
+			if (_unknownResp == _unknownRespLog) {
+				if (window.console)
+					console.log("Unknown response code:" + n[0]);
+			} else if (_unknownResp != _unknownRespIgnore)
+				window.alert("Unknown response code:" + n[0]);
 			break;
 		}
 	}
+	
+	processed = true;
+	if (renders == 0)
+		runDeferred();
+}
+
+// Timer of the pending delayed redirect
+var _redirTimer = null;
+
+// Resolves a URL against the app path (like the reload window action),
+// absolute URLs and URLs with absolute path are returned as-is.
+function appUrl(url) {
+	return /^([a-z][a-z0-9+.-]*:|\/)/i.test(url) ? url : _pathApp + url;
+}
+
+function redirectAfter(url, delay) {
+	cancelRedirect();
+	_redirTimer = setTimeout(function() {
+		_redirTimer = null;
+		window.location.href = url;
+	}, delay);
+}
+
+function cancelRedirect() {
+	if (_redirTimer != null) {
+		clearTimeout(_redirTimer);
+		_redirTimer = null;
+	}
+}
+
+// Clicking on a link cancels the pending redirect
+if (document.addEventListener)
+	document.addEventListener("click", function(event) {
+		for (var e = event.target; e; e = e.parentNode)
+			if (e.tagName == "A") {
+				cancelRedirect();
+				return;
+			}
+	}, true);
+
+// Formats a value with the client side format of a value mirror (see MirrorFormat).
+function mirrorFormat(e, v) {
+	var fmt = e.getAttribute("data-gwufmt").split(","), kind = parseInt(fmt[0]);
+	switch (kind) {
+	case _mirrorFmtUpper:
+		v = v.toUpperCase();
+		break;
+	case _mirrorFmtLower:
+		v = v.toLowerCase();
+		break;
+	case _mirrorFmtNumber:
+		var n = parseFloat(v);
+		v = isNaN(n) ? "-" : n.toFixed(parseInt(fmt[1]));
+		break;
+	case _mirrorFmtLength:
+		v = String(v.length);
+		break;
+	}
+	return e.getAttribute("data-gwufmtpre") + v + e.getAttribute("data-gwufmtsuf");
+}
+
+// Updates the value mirrors (having a client side format) of the source of an input event.
+function updateMirrors(event) {
+	var src = event.target || event.srcElement;
+	if (!src.id || !document.querySelectorAll)
+		return;
+	
+	var es = document.querySelectorAll('[data-gwumirror="' + src.id + '"]');
+	for (var i = 0; i < es.length; i++)
+		es[i].textContent = mirrorFormat(es[i], src.value);
+}
+
+if (document.addEventListener)
+	document.addEventListener("input", updateMirrors);
+
+// Returns the value of the source component of SetEnabledWhen():
+// "true" or "" (the checked state) for check boxes and radio buttons, else the value.
+function enWhenValue(src) {
+	if (src.value === undefined) // Wrapper (e.g. of a CheckBox)
+		src = src.querySelector("input,select,textarea") || src;
+	if (src.type == "checkbox" || src.type == "radio")
+		return src.checked ? "true" : "";
+	return src.value || "";
+}
+
+// Evaluates an enabled predicate (see EnabledPred) on a value.
+function enPredEval(op, operand, v) {
+	switch (op) {
+	case _enPredNotEquals:
+		return v != operand;
+	case _enPredNotEmpty:
+		return v != "";
+	case _enPredEmpty:
+		return v == "";
+	case _enPredMatches:
+		return new RegExp(operand).test(v);
+	}
+	return v == operand;
+}
+
+// Updates the enabled state of the components depending on
+// the value of other components (see SetEnabledWhen()).
+function updateEnabledWhen() {
+	var es = document.querySelectorAll("[data-gwuenwhen]");
+	for (var i = 0; i < es.length; i++) {
+		var src = gwuById(es[i].getAttribute("data-gwuenwhen"));
+		if (!src)
+			continue;
+		var enabled;
+		try {
+			enabled = enPredEval(parseInt(es[i].getAttribute("data-gwuenpred")), es[i].getAttribute("data-gwuenval"), enWhenValue(src));
+		} catch (err) {
+			continue; // Leave the state unchanged if the regular expression is invalid
+		}
+		es[i].disabled = !enabled;
+		var controls = es[i].querySelectorAll("input,select,textarea,button");
+		for (var j = 0; j < controls.length; j++)
+			controls[j].disabled = !enabled;
+	}
+}
+
+if (document.addEventListener) {
+	document.addEventListener("DOMContentLoaded", updateEnabledWhen);
+	document.addEventListener("input", updateEnabledWhen);
+	document.addEventListener("change", updateEnabledWhen);
+}
+
+// Returns the index of the active section of a ScrollSpy
+// (same as scrollSpyActive() at server side).
+function spyActive(tops, scrollTop, offset, bottom) {
+	if (bottom)
+		return tops.length - 1;
+	var active = 0;
+	for (var i = 1; i < tops.length; i++)
+		if (tops[i] <= scrollTop + offset)
+			active = i;
+	return active;
+}
+
+// Scrolls to the specified section of a ScrollSpy.
+function spyScroll(compId, idx) {
+	var s = gwuById(compId + "_s" + idx);
+	if (!s)
+		return;
+	var hdr = gwuById(compId + "_hdr");
+	window.scrollTo(0, s.getBoundingClientRect().top + window.pageYOffset - (hdr ? hdr.offsetHeight : 0));
+}
+
+// Updates the highlighted active sections of the ScrollSpys,
+// and reports changes to the server if there are handlers.
+function updateScrollSpies() {
+	var es = document.querySelectorAll("[data-gwuspy]");
+	var scrollTop = Math.round(window.pageYOffset);
+	var bottom = window.innerHeight + scrollTop >= document.documentElement.scrollHeight - 1;
+	for (var i = 0; i < es.length; i++) {
+		var e = es[i];
+		var hdr = gwuById(e.id + "_hdr");
+		var hh = hdr ? hdr.offsetHeight : 0;
+		var tops = [];
+		for (var s; (s = gwuById(e.id + "_s" + tops.length)); )
+			tops.push(Math.round(s.getBoundingClientRect().top) + scrollTop - hh);
+		if (!tops.length)
+			continue;
+		var active = spyActive(tops, scrollTop, parseInt(e.getAttribute("data-gwuspy")), bottom);
+		if (active == e.getAttribute("data-gwuspyact"))
+			continue;
+		e.setAttribute("data-gwuspyact", active);
+		var links = e.querySelectorAll(".gwu-ScrollSpy-Link");
+		for (var j = 0; j < links.length; j++) {
+			links[j].classList.toggle("gwu-ScrollSpy-Link-Active", j == active);
+			if (j == active)
+				links[j].setAttribute("aria-current", "location");
+			else
+				links[j].removeAttribute("aria-current");
+		}
+		var etype = e.getAttribute("data-gwuspyet");
+		if (etype)
+			se(null, parseInt(etype), e.id, [scrollTop, bottom ? 1 : 0].concat(tops).join(","));
+	}
+}
+
+if (document.addEventListener) {
+	document.addEventListener("DOMContentLoaded", updateScrollSpies);
+	window.addEventListener("scroll", updateScrollSpies);
+	window.addEventListener("resize", updateScrollSpies);
+}
+
+// Renders an element to a canvas, and passes the canvas to callback.
+// Uses html2canvas if loaded, redefine it to use something else.
+function gwuCapture(e, callback) {
+	if (window.html2canvas)
+		html2canvas(e).then(callback);
+	else
+		window.alert("Capturing is not available (html2canvas is not loaded)!");
+}
+
+// Captures a component as a PNG image and downloads it.
+function captureComp(compId, fileName) {
+	var e = gwuById(compId);
+	if (!e)
+		return;
+	if (e.tagName == "CANVAS")
+		downloadCanvas(e, fileName);
+	else
+		gwuCapture(e, function(canvas) {
+			downloadCanvas(canvas, fileName);
+		});
+}
+
+// Submits a form with the specified fields (in URL-encoded query format) to an
+// external URL, targeting the iframe named "gwu-ExtFormFrame" (a hidden one is created if needed).
+function submitExtForm(method, url, fields) {
+	var frameName = "gwu-ExtFormFrame";
+	if (document.getElementsByName(frameName).length == 0) {
+		var ifr = document.createElement("iframe");
+		ifr.name = frameName;
+		ifr.style.display = "none";
+		document.body.appendChild(ifr);
+	}
+	
+	var form = document.createElement("form");
+	form.method = method;
+	form.action = url;
+	form.target = frameName;
+	form.style.display = "none";
+	var pairs = fields.length > 0 ? fields.split("&") : [];
+	for (var i = 0; i < pairs.length; i++) {
+		var kv = pairs[i].split("=");
+		var input = document.createElement("input");
+		input.type = "hidden";
+		input.name = decodeURIComponent(kv[0].replace(/\+/g, " "));
+		input.value = kv.length > 1 ? decodeURIComponent(kv[1].replace(/\+/g, " ")) : "";
+		form.appendChild(input);
+	}
+	document.body.appendChild(form);
+	form.submit();
+	document.body.removeChild(form);
+}
+
+// Queries the geolocation, and sends it in an event of the specified type.
+// If the geolocation is not available (e.g. permission denied), the event is sent without it.
+function sendGeolocation(etype, compId) {
+	var fail = function() {
+		se(null, etype, compId);
+	};
+	if (!navigator.geolocation) {
+		fail();
+		return;
+	}
+	navigator.geolocation.getCurrentPosition(function(pos) {
+		se(null, etype, compId, null, "&" + _pGeoLat + "=" + pos.coords.latitude + "&" + _pGeoLong + "=" + pos.coords.longitude);
+	}, fail);
+}
+
+// Loads a script, and sends an event of the specified type when it is loaded.
+function loadScript(etype, winId, url) {
+	var s = document.createElement("script");
+	s.src = url;
+	s.onload = function() {
+		se(null, etype, winId, encodeURIComponent(url));
+	};
+	document.getElementsByTagName("head")[0].appendChild(s);
+}
+
+// Triggers the download of the content of a canvas as a PNG image.
+function downloadCanvas(canvas, fileName) {
+	var a = document.createElement("a");
+	a.href = canvas.toDataURL("image/png");
+	a.download = fileName;
+	document.body.appendChild(a);
+	a.click();
+	document.body.removeChild(a);
+}
+
+// Returns the scroll positions of the scrolled element and its scrolled
+// descendants (having an id), e.g. of a multi-row ListBox.
+function scrollPositions(e) {
+	var scrolls = [];
+	var es = [e].concat(Array.prototype.slice.call(e.querySelectorAll("[id]")));
+	for (var i = 0; i < es.length; i++)
+		if (es[i].id && (es[i].scrollTop > 0 || es[i].scrollLeft > 0))
+			scrolls.push({id: es[i].id, top: es[i].scrollTop, left: es[i].scrollLeft});
+	return scrolls;
+}
+
+// Restores the scroll positions returned by scrollPositions() on the
+// (re-rendered) elements having the same ids.
+function restoreScrollPositions(scrolls) {
+	for (var i = 0; i < scrolls.length; i++) {
+		var e = gwuById(scrolls[i].id);
+		if (e) {
+			e.scrollTop = scrolls[i].top;
+			e.scrollLeft = scrolls[i].left;
+		}
+	}
 }
 
-function rerenderComp(compId) {
-	var e = document.getElementById(compId);
+// Replaces a component with its (re-)rendered HTML.
+// Scroll positions are preserved.
+function spliceComp(compId, html) {
+	var e = gwuById(compId);
 	if (!e) // Component removed or not visible (e.g. on inactive tab of TabPanel)
 		return;
 	
+	// Remember focused comp which might be replaced here:
+	var focusedCompId = activeElem().id;
+	// Print-static text is rendered before the component, it is also re-rendered:
+	var ps = gwuById(compId + "_ps");
+	if (ps)
+		ps.parentNode.removeChild(ps);
+	// So is the filter input of a filterable ListBox:
+	var flt = gwuById(compId + "_flt");
+	if (flt)
+		flt.parentNode.removeChild(flt);
+	// And the style block of the responsive styles:
+	var media = gwuById(compId + "_media");
+	if (media)
+		media.parentNode.removeChild(media);
+	var scrolls = scrollPositions(e);
+	e.outerHTML = html;
+	attachShadows();
+	restoreScrollPositions(scrolls);
+	focusComp(focusedCompId);
+	updateEnabledWhen();
+	
+	// Inserted JS code is not executed automatically, do it manually:
+	// Have to "re-get" element by compId!
+	runScripts(gwuById(compId));
+}
+
+// CSP nonce of the scripts of the page (see NoncePlaceholder), empty string if there is none.
+var _nonce = document.currentScript && document.currentScript.nonce || "";
+
+// Executes the inserted scripts under the specified element (which are not executed
+// automatically) by replacing them with new script elements having the same code.
+// Unlike eval(), this works under a content security policy (using the nonce of the page).
+function runScripts(e) {
+	var scripts = e.getElementsByTagName("script");
+	for (var i = 0; i < scripts.length; i++) {
+		var old = scripts[i], s = document.createElement("script");
+		s.text = old.text;
+		if (_nonce)
+			s.nonce = _nonce;
+		old.parentNode.replaceChild(s, old);
+	}
+}
+
+// Returns the node at the specified path (child node indices) under root, null if there is no such node.
+function nodeAt(root, path) {
+	var n = root;
+	for (var i = 0; n && i < path.length; i++)
+		n = n.childNodes[path[i]];
+	return n || null;
+}
+
+// Creates a node from HTML.
+function nodeOf(html) {
+	var t = document.createElement("template");
+	t.innerHTML = html;
+	return t.content.firstChild || document.createTextNode("");
+}
+
+// Applies the DOM operations of a diff render to a component (see diffHtml() at server side).
+// Returns false if the DOM of the component does not match the operations.
+function patchComp(compId, ops) {
+	var e = gwuById(compId);
+	if (!e)
+		return false;
+	
+	for (var i = 0; i < ops.length; i++) {
+		var op = ops[i], v = op.v || "";
+		if (op.o == "i") {
+			var parent = nodeAt(e, op.p.slice(0, -1)), idx = op.p[op.p.length - 1];
+			if (!parent || idx > parent.childNodes.length)
+				return false;
+			parent.insertBefore(nodeOf(v), parent.childNodes[idx] || null);
+			continue;
+		}
+		var n = nodeAt(e, op.p);
+		if (!n || n.nodeName.toLowerCase() != op.g)
+			return false;
+		switch (op.o) {
+		case "r":
+			n.parentNode.replaceChild(nodeOf(v), n);
+			break;
+		case "t":
+			n.nodeValue = v;
+			break;
+		case "a":
+		case "d":
+			if (op.o == "a")
+				n.setAttribute(op.n, v);
+			else
+				n.removeAttribute(op.n);
+			// Attributes only hold the initial state of inputs, also update the state:
+			if (op.n == "value" && "value" in n)
+				n.value = v;
+			else if ((op.n == "checked" || op.n == "selected") && op.n in n)
+				n[op.n] = op.o == "a";
+			break;
+		case "x":
+			n.parentNode.removeChild(n);
+			break;
+		default:
+			return false;
+		}
+	}
+	
+	attachShadows();
+	updateEnabledWhen();
+	// Scripts of the component are executed like after a splice:
+	runScripts(e);
+	return true;
+}
+
+// Sequence number of the last re-render request, and the sequence numbers of the
+// last re-render requests of components (mapped from component id).
+var _renderSeq = 0, _renderSeqs = {};
+
+// Tells if the response of the re-render request with the specified sequence number
+// is to be applied to a component: it's not outdated by a later request, and the
+// component was not removed before the response arrived.
+function renderCurrent(compId, seq) {
+	if (_renderSeqs[compId] != seq)
+		return false;
+	delete _renderSeqs[compId];
+	return gwuById(compId) != null;
+}
+
+// Re-renders a component. If full is false and diff rendering is enabled at
+// server side, only the changes are applied (if the client has the previous render).
+// The render is applied asynchronously when the response arrives (the previously
+// focused component is restored, and inserted scripts are executed, see spliceComp()).
+// The optional done function is called when the render is finished (applied or failed).
+function rerenderComp(compId, full, done) {
+	done = done || function() {};
+	var e = gwuById(compId);
+	if (!e) { // Component removed or not visible (e.g. on inactive tab of TabPanel)
+		done();
+		return;
+	}
+	
+	var seq = _renderSeqs[compId] = ++_renderSeq;
 	var xhr = createXmlHttp();
 	
 	xhr.onreadystatechange = function() {
-		if (xhr.readyState == 4 && xhr.status == 200) {
-			// Remember focused comp which might be replaced here:
-			var focusedCompId = document.activeElement.id;
-			e.outerHTML = xhr.responseText;
-			focusComp(focusedCompId);
-			
-			// Inserted JS code is not executed automatically, do it manually:
-			// Have to "re-get" element by compId!
-			var scripts = document.getElementById(compId).getElementsByTagName("script");
-			for (var i = 0; i < scripts.length; i++) {
-				eval(scripts[i].innerText);
-			}
+		if (xhr.readyState != 4)
+			return;
+		if (xhr.status == 200 && renderCurrent(compId, seq)) {
+			var rev = xhr.getResponseHeader(_hdrRenderRev);
+			if ((xhr.getResponseHeader("Content-Type") || "").indexOf("application/json") == 0) {
+				if (!patchComp(compId, JSON.parse(xhr.responseText))) {
+					rerenderComp(compId, true, done); // DOM does not match, fall back to the whole component
+					return;
+				}
+			} else
+				spliceComp(compId, xhr.responseText);
+			var e2 = gwuById(compId);
+			if (e2 && rev)
+				e2.gwuRenderRev = rev;
 		}
+		done();
 	}
 	
-	xhr.open("POST", _pathRenderComp, false); // synch call (if async, browser specific DOM rendering errors may arise)
+	xhr.open("POST", _pathRenderComp, true);
 	xhr.setRequestHeader("Content-type", "application/x-www-form-urlencoded");
 	
-	xhr.send(_pCompId + "=" + compId);
+	var params = _pCompId + "=" + compId;
+	if (!full && e.gwuRenderRev)
+		params += "&" + _pRenderRev + "=" + e.gwuRenderRev;
+	xhr.send(params);
+}
+
+// Re-renders multiple components with one request, applying the renders in order
+// (like rerenderComp() would do one by one).
+// The optional done function is called when all renders are finished (applied or failed),
+// including the fallback re-renders of whole components.
+function rerenderComps(compIds, done) {
+	done = done || function() {};
+	var params = "", seq = ++_renderSeq;
+	for (var i = 0; i < compIds.length; i++) {
+		var e = gwuById(compIds[i]);
+		if (!e) // Component removed or not visible (e.g. on inactive tab of TabPanel)
+			continue;
+		_renderSeqs[compIds[i]] = seq;
+		params += "&" + _pCompId + "=" + compIds[i] + "&" + _pRenderRev + "=" + (e.gwuRenderRev || "");
+	}
+	if (params.length == 0) {
+		done();
+		return;
+	}
+	
+	var xhr = createXmlHttp();
+	
+	xhr.onreadystatechange = function() {
+		if (xhr.readyState != 4)
+			return;
+		var pending = 1; // Pending fallback re-renders, and the loop below
+		var finished = function() {
+			if (--pending == 0)
+				done();
+		};
+		var renders = xhr.status == 200 ? JSON.parse(xhr.responseText) : [];
+		for (var i = 0; i < renders.length; i++) {
+			var r = renders[i];
+			if (!renderCurrent(r.i, seq))
+				continue;
+			if (r.p) {
+				if (!patchComp(r.i, r.p)) {
+					pending++;
+					rerenderComp(r.i, true, finished); // DOM does not match, fall back to the whole component
+					continue;
+				}
+			} else
+				spliceComp(r.i, r.h || "");
+			var e = gwuById(r.i);
+			if (e && r.r)
+				e.gwuRenderRev = r.r;
+		}
+		finished();
+	}
+	
+	xhr.open("POST", _pathRenderComps, true);
+	xhr.setRequestHeader("Content-type", "application/x-www-form-urlencoded");
+	xhr.send(params.substring(1));
+}
+
+// Filters the options of a filterable ListBox: hides the options whose text
+// does not contain the text of the filter input (case-insensitively).
+// Hidden options keep their selection state.
+function lbFilter(input, compId) {
+	var text = input.value.toLowerCase();
+	var select = gwuById(compId);
+	for (var i = 0; i < select.options.length; i++) {
+		var o = select.options[i];
+		o.hidden = text.length > 0 && o.text.toLowerCase().indexOf(text) < 0;
+		o.style.display = o.hidden ? "none" : ""; // Some browsers ignore the hidden attribute of options
+	}
+	// Hide groups with no visible options
+	var groups = select.getElementsByTagName("optgroup");
+	for (var i = 0; i < groups.length; i++) {
+		var visible = false;
+		for (var o = groups[i].firstChild; o; o = o.nextSibling)
+			if (!o.hidden)
+				visible = true;
+		groups[i].style.display = visible ? "" : "none";
+	}
+}
+
+// Prevents selecting more options in a multi-select ListBox than its limit:
+// reverts the selection to the previous one if the limit is exceeded.
+function lbMaxSel(select) {
+	var max = parseInt(select.getAttribute("data-gwumaxsel")), n = 0;
+	for (var i = 0; i < select.options.length; i++)
+		if (select.options[i].selected)
+			n++;
+	
+	for (var i = 0; i < select.options.length; i++) {
+		var o = select.options[i];
+		if (n <= max)
+			o._gwuSel = o.selected;
+		else
+			o.selected = o._gwuSel === undefined ? o.defaultSelected : o._gwuSel;
+	}
 }
 
 // Get selected indices (of an HTML select)
@@ -191,8 +1509,8 @@ function selIdxs(select) {
 
 // Get and update switch button value
 function sbtnVal(event, onBtnId, offBtnId) {
-	var onBtn = document.getElementById(onBtnId);
-	var offBtn = document.getElementById(offBtnId);
+	var onBtn = gwuById(onBtnId);
+	var offBtn = gwuById(offBtnId);
 	
 	if (onBtn == null)
 		return false;
@@ -211,12 +1529,25 @@ function sbtnVal(event, onBtnId, offBtnId) {
 
 function focusComp(compId) {
 	if (compId != null) {
-		var e = document.getElementById(compId);
+		var e = gwuById(compId);
 		if (e) // Else component removed or not visible (e.g. on inactive tab of TabPanel)
 			e.focus();
 	}
 }
 
+// Moves the focus to the specified component (target of a skip link).
+function skipTo(compId) {
+	var e = gwuById(compId);
+	if (e) {
+		if (e.tabIndex < 0 && !e.hasAttribute("tabindex"))
+			e.setAttribute("tabindex", "-1"); // Make it focusable
+		e.focus();
+		if (e.scrollIntoView)
+			e.scrollIntoView();
+	}
+	return false;
+}
+
 function addonload(func) {
 	var oldonload = window.onload;
 	if (typeof window.onload != 'function') {
@@ -245,11 +1576,14 @@ function addonbeforeunload(func) {
 
 var timers = new Object();
 
-function setupTimer(compId, js, timeout, repeat, active, reset) {
+// Sets up (or stops) the timer of a component: fn is called after timeout ms (repeatedly if repeat).
+// fn is a function (and not a string), so timers work without eval (e.g. under a content security policy).
+// Re-rendered timers are only restarted if changed (compared by the source code of fn).
+function setupTimer(compId, fn, timeout, repeat, active, reset) {
 	var timer = timers[compId];
 	
 	if (timer != null) {
-		var changed = timer.js != js || timer.timeout != timeout || timer.repeat != repeat || timer.reset != reset;
+		var changed = String(timer.fn) != String(fn) || timer.timeout != timeout || timer.repeat != repeat || timer.reset != reset;
 		if (!active || changed) {
 			if (timer.repeat)
 				clearInterval(timer.id);
@@ -265,56 +1599,170 @@ function setupTimer(compId, js, timeout, repeat, active, reset) {
 	
 	// Create new timer
 	timers[compId] = timer = new Object();
-	timer.js = js;
+	timer.fn = fn;
 	timer.timeout = timeout;
 	timer.repeat = repeat;
 	timer.reset = reset;
 	
 	// Start the timer
 	if (timer.repeat)
-		timer.id = setInterval(js, timeout);
+		timer.id = setInterval(fn, timeout);
 	else
-		timer.id = setTimeout(js, timeout);
+		timer.id = setTimeout(fn, timeout);
 }
 
+// Sets up an idle timer: fn is called after timeout ms without user activity.
+// User activity restarts the countdown (using the reset param of setupTimer()).
+function setupIdle(compId, fn, timeout) {
+	var timerId = compId + "_idle", reset = 0, last = 0;
+	var onActivity = function() {
+		var now = new Date().getTime();
+		if (now - last < 500) // Don't restart the timer on every mouse move
+			return;
+		last = now;
+		setupTimer(timerId, fn, timeout, false, true, ++reset);
+	};
+	var etypes = ["mousemove", "mousedown", "keydown", "touchstart", "scroll", "wheel"];
+	for (var i = 0; i < etypes.length; i++)
+		document.addEventListener(etypes[i], onActivity, true);
+	onActivity();
+}
+
+// Time of the last user activity (mouse, keyboard, touch, scroll), used by keep-alive SessMonitors.
+var _lastActivity = 0;
+
+if (document.addEventListener) {
+	(function() {
+		var onActivity = function() {
+			_lastActivity = Date.now();
+		};
+		var etypes = ["mousemove", "mousedown", "keydown", "touchstart", "scroll", "wheel"];
+		for (var i = 0; i < etypes.length; i++)
+			document.addEventListener(etypes[i], onActivity, true);
+	})();
+}
+
+// Ids of the SessMonitors whose session check was skipped because the page was hidden.
+var _skippedSessChecks = {};
+
+// Session checks are skipped while the page is hidden (e.g. in a background browser tab),
+// the skipped checks are performed once right away when the page becomes visible.
+if (document.addEventListener)
+	document.addEventListener("visibilitychange", function() {
+		if (document.hidden)
+			return;
+		var skipped = _skippedSessChecks;
+		_skippedSessChecks = {};
+		for (var compId in skipped)
+			checkSession(compId);
+	});
+
 function checkSession(compId) {
-	var e = document.getElementById(compId);
+	var e = gwuById(compId);
 	if (!e) // Component removed or not visible (e.g. on inactive tab of TabPanel)
 		return;
+	if (document.hidden) {
+		_skippedSessChecks[compId] = true;
+		return;
+	}
 	
 	var xhr = createXmlHttp();
 	
 	xhr.onreadystatechange = function() {
 		if (xhr.readyState == 4 && xhr.status == 200) {
 			var timeoutSec = parseFloat(xhr.responseText);
+			var expEtype = e.getAttribute("data-gwuexpet"), expUrl = e.getAttribute("data-gwuexpurl");
+			if (timeoutSec <= 0 && (expEtype || expUrl) && !e.gwuExpSent) {
+				e.gwuExpSent = true; // Report expiry only once
+				if (expEtype) // Server redirects to the URL after calling the handler
+					se(null, parseInt(expEtype), compId, expUrl ? encodeURIComponent(appUrl(expUrl)) : null);
+				else {
+					window.location.href = appUrl(expUrl);
+					return;
+				}
+			}
 			if (timeoutSec < 60)
 				e.classList.add("gwu-SessMonitor-Expired");
 			else
 				e.classList.remove("gwu-SessMonitor-Expired");
 			var cnvtr = window[e.getAttribute("gwuJsFuncName")];
-			e.children[0].innerText = typeof cnvtr === 'function' ? cnvtr(timeoutSec) : convertSessTimeout(timeoutSec);
+			e.children[0].innerText = typeof cnvtr === 'function' ? cnvtr(timeoutSec, e) : convertSessTimeout(timeoutSec, e);
 		}
 	}
 	
-	xhr.open("GET", _pathSessCheck, false); // synch call (else we can't catch connection error)
+	var path = _pathSessCheck;
+	// In keep-alive mode the check extends the session if there was user activity since the last check
+	if (e.hasAttribute("data-gwukeepalive") && _lastActivity > (e.gwuLastCheck || 0))
+		path += "?" + _pKeepAlive + "=1";
+	e.gwuLastCheck = Date.now();
+	
+	xhr.open("GET", path, false); // synch call (else we can't catch connection error)
 	try {
 		xhr.send();
 		e.classList.remove("gwu-SessMonitor-Error");
 	} catch (err) {
 		e.classList.add("gwu-SessMonitor-Error");
-		e.children[0].innerText = "CONN ERR";
+		e.children[0].innerText = e.getAttribute("data-gwutxterr") || "CONN ERR";
 	}
 }
 
-function convertSessTimeout(sec) {
+// Converts the remaining session time to a displayable text, using
+// the texts of the SessMonitor element e (set by SessMonitor.SetTexts()).
+function convertSessTimeout(sec, e) {
+	var txt = function(name, def) {
+		return e && e.getAttribute(name) || def;
+	};
 	if (sec <= 0)
-		return "Expired!";
+		return txt("data-gwutxtexp", "Expired!");
 	else if (sec < 60)
-			return "<1 min";
+			return txt("data-gwutxtlt", "<1 min");
 	else
-		return "~" + Math.round(sec / 60) + " min";
+		return txt("data-gwutxtmin", "~%d min").replace("%d", Math.round(sec / 60));
 }
 
+// Updates the displayed remaining time of a Countdown, and reports reaching zero.
+// The remaining time is always recomputed from the target, so the countdown does not drift.
+function countdownTick(compId, etype) {
+	var e = gwuById(compId);
+	if (!e) // Component removed or not visible (e.g. on inactive tab of TabPanel)
+		return;
+	if (e._gwuTarget == null)
+		e._gwuTarget = new Date().getTime() + parseInt(e.getAttribute("data-gwuremaining"));
+	
+	var sec = Math.max(0, (e._gwuTarget - new Date().getTime()) / 1000);
+	var cnvtr = window[e.getAttribute("gwuJsFuncName")];
+	e.children[0].innerText = typeof cnvtr === 'function' ? cnvtr(sec) : convertCountdown(sec);
+	if (sec > 0)
+		return;
+	
+	e.classList.add("gwu-Countdown-Zero");
+	if (!e.hasAttribute("data-gwuzero")) {
+		e.setAttribute("data-gwuzero", "");
+		se(null, etype, compId);
+	}
+}
+
+function convertCountdown(sec) {
+	sec = Math.ceil(sec);
+	var d = Math.floor(sec / 86400), h = Math.floor(sec % 86400 / 3600), m = Math.floor(sec % 3600 / 60), s = sec % 60;
+	var pad = function(n) { return n < 10 ? "0" + n : "" + n; };
+	return (d > 0 ? d + "d " : "") + pad(h) + ":" + pad(m) + ":" + pad(s);
+}
+
+// Ticks the countdowns right away when the page becomes visible
+// (browsers may throttle the timers of hidden pages).
+if (document.addEventListener)
+	document.addEventListener("visibilitychange", function() {
+		if (document.hidden)
+			return;
+		var es = document.querySelectorAll("[data-gwuremaining]");
+		for (var i = 0; i < es.length; i++) {
+			var timer = timers[es[i].id];
+			if (timer)
+				timer.fn();
+		}
+	});
+
 // INITIALIZATION
 
 addonload(function() {
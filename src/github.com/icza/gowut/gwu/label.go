@@ -17,6 +17,10 @@
 
 package gwu
 
+import (
+	"net/http"
+)
+
 // Label interface defines a component which wraps a text into a component.
 //
 // Default style class: "gwu-Label"
@@ -28,23 +32,96 @@ type Label interface {
 	HasText
 }
 
+// EditableLabel interface defines a Label which can be made editable
+// in place: double-clicking on it turns it into a text input, and the
+// edited text is saved on Enter or when the input loses focus, sending
+// an ETypeChange event with the new text; the label is marked dirty
+// automatically. Escape cancels the edit.
+//
+// Labels created by NewLabel() also implement EditableLabel
+// (not editable by default).
+//
+// Suggested event type to handle changes (if editable): ETypeChange
+//
+// Default style class: "gwu-Label", "gwu-Label-Editable"
+type EditableLabel interface {
+	// EditableLabel is a Label.
+	Label
+
+	// Editable tells if the label is editable in place.
+	Editable() bool
+
+	// SetEditable sets whether the label is editable in place.
+	SetEditable(editable bool)
+}
+
 // Label implementation
 type labelImpl struct {
 	compImpl    // Component implementation
 	hasTextImpl // Has text implementation
+
+	editable bool // Tells if the label is editable in place
 }
 
 // NewLabel creates a new Label.
 func NewLabel(text string) Label {
-	c := &labelImpl{newCompImpl(nil), newHasTextImpl(text)}
+	return newLabelImpl(text)
+}
+
+// NewEditableLabel creates a new EditableLabel which is editable in place.
+func NewEditableLabel(text string) EditableLabel {
+	c := newLabelImpl(text)
+	c.SetEditable(true)
+	return c
+}
+
+// newLabelImpl creates a new labelImpl.
+func newLabelImpl(text string) *labelImpl {
+	c := &labelImpl{compImpl: newCompImpl(nil), hasTextImpl: newHasTextImpl(text)}
 	c.Style().AddClass("gwu-Label")
 	return c
 }
 
+func (c *labelImpl) Editable() bool {
+	return c.editable
+}
+
+func (c *labelImpl) SetEditable(editable bool) {
+	c.editable = editable
+	if editable {
+		c.Style().AddClass("gwu-Label-Editable")
+	} else {
+		c.Style().RemoveClass("gwu-Label-Editable")
+	}
+}
+
+func (c *labelImpl) preprocessEvent(event Event, r *http.Request) {
+	if event.Type() != ETypeChange || !c.editable {
+		return
+	}
+	// Empty text is a valid value, so check whether the value is present
+	r.FormValue(paramCompValue) // Make sure Form is parsed
+	values, present := r.Form[paramCompValue]
+	if !present || len(values) == 0 {
+		return
+	}
+
+	c.text = values[0]
+	event.MarkDirty(c)
+}
+
+var strDataGwuEdit = []byte(` data-gwuedit="`) // ` data-gwuedit="`
+
 func (c *labelImpl) Render(w Writer) {
 	w.Write(strSpanOp)
 	c.renderAttrsAndStyle(w)
 	c.renderEHandlers(w)
+	if c.editable {
+		// Editing is started by a document level double click listener
+		w.Write(strDataGwuEdit)
+		w.Writev(int(ETypeChange))
+		w.Write(strQuote)
+	}
 	w.Write(strGT)
 
 	c.renderText(w)
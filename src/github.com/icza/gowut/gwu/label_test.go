@@ -0,0 +1,79 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestLabelEditSave(t *testing.T) {
+	l := NewLabel("old").(EditableLabel)
+
+	// Not editable: change is ignored
+	e := newEventImpl(ETypeChange, l, nil, nil)
+	l.preprocessEvent(e, newCompValueReq("new"))
+	if l.Text() != "old" || e.shared.dirty(l) {
+		t.Errorf("Change of non-editable label not ignored, text: %q", l.Text())
+	}
+
+	l.SetEditable(true)
+	for _, text := range []string{"new", "", "a, b & <c>"} {
+		e = newEventImpl(ETypeChange, l, nil, nil)
+		l.preprocessEvent(e, newCompValueReq(text))
+		if l.Text() != text {
+			t.Errorf("Got text %q, want %q", l.Text(), text)
+		}
+		if !e.shared.dirty(l) {
+			t.Error("Label not marked dirty")
+		}
+	}
+}
+
+func TestLabelEditCancel(t *testing.T) {
+	l := NewEditableLabel("old")
+	if !l.Editable() {
+		t.Error("New editable label is not editable")
+	}
+
+	// Cancel does not send a value (and native change events of the input are not propagated)
+	e := newEventImpl(ETypeChange, l, nil, nil)
+	l.preprocessEvent(e, &http.Request{Form: url.Values{}})
+	if l.Text() != "old" || e.shared.dirty(l) {
+		t.Errorf("Label changed without value, text: %q", l.Text())
+	}
+}
+
+func TestLabelEditableRender(t *testing.T) {
+	l := NewLabel("x").(EditableLabel)
+	if s := renderString(l); strings.Contains(s, "data-gwuedit") {
+		t.Errorf("Non-editable label rendered as editable: %s", s)
+	}
+
+	l.SetEditable(true)
+	s := renderString(l)
+	if want := ` data-gwuedit="` + ETypeChange.String() + `">x</span>`; !strings.Contains(s, want) || !strings.Contains(s, "gwu-Label-Editable") {
+		t.Errorf("Rendered output does not contain %s: %s", want, s)
+	}
+	checkStaticJsFuncs(t, "labelEdit")
+
+	l.SetEditable(false)
+	if s := renderString(l); strings.Contains(s, "gwu-Label-Editable") {
+		t.Errorf("Editable class not removed: %s", s)
+	}
+}
@@ -99,6 +99,13 @@ func (c *linkImpl) ById(id ID) Comp {
 	return nil
 }
 
+func (c *linkImpl) children() []Comp {
+	if c.comp == nil {
+		return nil
+	}
+	return []Comp{c.comp}
+}
+
 func (c *linkImpl) Clear() {
 	if c.comp != nil {
 		c.comp.setParent(nil)
@@ -141,7 +148,7 @@ func (c *linkImpl) Render(w Writer) {
 	c.renderText(w)
 
 	if c.comp != nil {
-		c.comp.Render(w)
+		renderComp(w, c.comp)
 	}
 
 	w.Write(strACl)
@@ -18,6 +18,8 @@
 package gwu
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -26,9 +28,16 @@ import (
 // ListBox interface defines a component which allows selecting one or multiple values
 // from a predefined list.
 //
+// Optionally the values may have keys (see NewListBoxKV()) which are
+// independent from the displayed texts: e.g. the texts may be localized
+// while the server logic depends on stable keys.
+//
+// A ListBox may be filterable (see SetFilterable()): a text input is rendered
+// before the list, and typing into it hides the non-matching options.
+//
 // Suggested event type to handle changes: ETypeChange
 //
-// Default style class: "gwu-ListBox"
+// Default style classes: "gwu-ListBox", "gwu-ListBox-Filter"
 type ListBox interface {
 	// ListBox is a component
 	Comp
@@ -42,6 +51,19 @@ type ListBox interface {
 	// SetMulti sets whether multiple selections are allowed.
 	SetMulti(multi bool)
 
+	// MaxSelected returns the maximum number of values the user can select
+	// if multiple selections are allowed. 0 means unlimited.
+	MaxSelected() int
+
+	// SetMaxSelected sets the maximum number of values the user can select
+	// if multiple selections are allowed. Pass 0 for unlimited (default).
+	//
+	// Selections exceeding the limit are prevented at the client side,
+	// and are also rejected at the server side: only the first max selected
+	// values (in index order) are kept. Selected disabled options count too.
+	// The selection state set from the server side is not limited.
+	SetMaxSelected(max int)
+
 	// Rows returns the number of displayed rows.
 	Rows() int
 
@@ -52,12 +74,18 @@ type ListBox interface {
 	SetRows(rows int)
 
 	// SelectedValue retruns the first selected value.
+	// If the ListBox has keys, the key of the value is returned.
 	// Empty string is returned if nothing is selected.
 	SelectedValue() string
 
 	// SelectedValues retruns all the selected values.
+	// If the ListBox has keys, the keys of the values are returned.
 	SelectedValues() []string
 
+	// SelectedKeys returns the keys of all the selected values.
+	// If the ListBox has no keys, the values are returned.
+	SelectedKeys() []string
+
 	// Selected tells if the value at index i is selected.
 	Selected(i int) bool
 
@@ -68,15 +96,104 @@ type ListBox interface {
 	// SelectedIndices returns a slice of the indices of the selected values.
 	SelectedIndices() []int
 
+	// SelectedIndicesInOrder returns a slice of the indices of the selected
+	// values in the order they were selected (e.g. to build an ordered
+	// playlist in a multi-select ListBox).
+	// Values selected at once (e.g. by a single event or by
+	// SetSelectionBits()) are ordered by their indices.
+	SelectedIndicesInOrder() []int
+
 	// SetSelected sets the selection state of the value at index i.
+	// An out of range index is ignored.
 	SetSelected(i int, selected bool)
 
+	// SetSelectedErr sets the selection state of the value at index i.
+	// An error is returned if the index is out of range.
+	SetSelectedErr(i int, selected bool) error
+
 	// SetSelectedIndices sets the (only) selected values.
 	// Only values will be selected that are contained in the specified indices slice.
+	// Out of range indices are ignored.
 	SetSelectedIndices(indices []int)
 
 	// ClearSelected deselects all values.
 	ClearSelected()
+
+	// SelectionBits returns the selection states as a packed bitset:
+	// the selection state of the value at index i is bit (i%64) of
+	// the element at index i/64 (bit 0 being the least significant bit).
+	// This is efficient for bulk operations on large multi-selects.
+	SelectionBits() []uint64
+
+	// SetSelectionBits sets the selection states from a packed bitset
+	// (see SelectionBits()). Values whose bit is not present in the bitset
+	// are deselected, and bits beyond the number of values are ignored.
+	SetSelectionBits(bits []uint64)
+
+	// AddGroup appends values grouped under the specified label
+	// (rendered as an HTML optgroup). Values remain indexed in a flat
+	// manner: the first value of the group gets the index following the
+	// last value added before (by NewListBox() or by a previous AddGroup()).
+	// If label is empty, the values are added without a group.
+	// If the ListBox has keys, the values are also used as their keys.
+	AddGroup(label string, values []string)
+
+	// SetValues replaces the values to choose from.
+	//
+	// The selection and disabled states are preserved for the values
+	// which are also present in the new list (matched by string, not
+	// by index), and are cleared for the rest. So if the new list is
+	// shorter, the selection of removed values is lost but the selection
+	// of kept values is preserved, even if their indices change.
+	// If the ListBox is not multi-select, at most the first matching value
+	// remains selected.
+	//
+	// Groups added by AddGroup(), option styles (see SetOptionStyle())
+	// and option attributes (see SetOptionAttr()) are removed.
+	// If the ListBox has keys, the values are also used as their keys.
+	//
+	// The ListBox has to be marked dirty to show the new values in the browser.
+	SetValues(values []string)
+
+	// OptionDisabled tells if the option (value) at index i is disabled.
+	// Returns false for invalid indices.
+	OptionDisabled(i int) bool
+
+	// SetOptionDisabled sets whether the option (value) at index i is disabled.
+	// Invalid indices are ignored.
+	// Disabled options cannot be selected or deselected by the user,
+	// their selection state can only be changed from the server side.
+	SetOptionDisabled(i int, disabled bool)
+
+	// SetOptionStyle returns the Style builder of the option (value) at
+	// index i, e.g. to color-code options. The style is created on first
+	// call, options whose style is never requested are rendered without
+	// style attributes. Responsive styles (media queries) are not supported.
+	// If the index is out of range, a style is returned which is not rendered.
+	SetOptionStyle(i int) Style
+
+	// OptionAttr returns the value of an attribute of the option (value)
+	// at index i. Empty string is returned if the attribute is not set
+	// or the index is out of range.
+	OptionAttr(i int, name string) string
+
+	// SetOptionAttr sets an attribute of the option (value) at index i,
+	// e.g. a title (tooltip) or a data-* attribute. The value is escaped
+	// when rendered. Pass an empty value to remove the attribute.
+	// Out of range indices are ignored.
+	SetOptionAttr(i int, name, value string)
+
+	// Filterable tells if the ListBox is filterable.
+	Filterable() bool
+
+	// SetFilterable sets whether the ListBox is filterable.
+	// A filterable ListBox renders a text input before the list,
+	// and typing into it hides the options whose text does not contain
+	// the typed text (case-insensitively). Filtering is done purely at the
+	// client side, it does not change the selection (hidden options
+	// remain selected) nor the indices of the values.
+	// The typed filter text is cleared when the ListBox is re-rendered.
+	SetFilterable(filterable bool)
 }
 
 // ListBox implementation.
@@ -84,10 +201,27 @@ type listBoxImpl struct {
 	compImpl       // Component implementation
 	hasEnabledImpl // Has enabled implementation
 
-	values   []string // Values to choose from
-	multi    bool     // Allow multiple selection
-	selected []bool   // Array of selection state of the values
-	rows     int      // Number of displayed rows
+	values   []string  // Values to choose from
+	keys     []string  // Optional keys of the values
+	multi    bool      // Allow multiple selection
+	selected []bool    // Array of selection state of the values
+	disabled []bool    // Array of disabled state of the values (options)
+	rows     int       // Number of displayed rows
+	groups   []lbGroup // Groups of values, in the order of values
+
+	filterable  bool // Tells if the ListBox is filterable
+	maxSelected int  // Max number of values the user can select, 0 means unlimited
+
+	order []int // Indices of the selected values in the order of selection
+
+	optStyles map[int]*styleImpl  // Styles of the options, lazily created
+	optAttrs  map[int][][2]string // Attributes (name-value pairs) of the options, lazily created
+}
+
+// A group of values of a ListBox.
+type lbGroup struct {
+	label      string // Label of the group
+	start, end int    // Index of the first value and index after the last value of the group
 }
 
 var (
@@ -96,12 +230,28 @@ var (
 
 // NewListBox creates a new ListBox.
 func NewListBox(values []string) ListBox {
-	c := &listBoxImpl{newCompImpl(strSelidx), newHasEnabledImpl(), values, false, make([]bool, len(values)), 1}
+	c := &listBoxImpl{newCompImpl(strSelidx), newHasEnabledImpl(), values, nil, false, make([]bool, len(values)), make([]bool, len(values)), 1, nil, false, 0, nil, nil, nil}
 	c.AddSyncOnETypes(ETypeChange)
 	c.Style().AddClass("gwu-ListBox")
 	return c
 }
 
+// NewListBoxKV creates a new ListBox whose values have keys:
+// texts are the displayed texts of the values, and keys are
+// rendered as the values of the options.
+// If the lengths of keys and texts differ, the extra elements
+// of the longer one are ignored.
+func NewListBoxKV(keys, texts []string) ListBox {
+	if len(keys) < len(texts) {
+		texts = texts[:len(keys)]
+	} else {
+		keys = keys[:len(texts)]
+	}
+	c := NewListBox(texts).(*listBoxImpl)
+	c.keys = keys
+	return c
+}
+
 func (c *listBoxImpl) Multi() bool {
 	return c.multi
 }
@@ -110,6 +260,17 @@ func (c *listBoxImpl) SetMulti(multi bool) {
 	c.multi = multi
 }
 
+func (c *listBoxImpl) MaxSelected() int {
+	return c.maxSelected
+}
+
+func (c *listBoxImpl) SetMaxSelected(max int) {
+	if max < 0 {
+		max = 0
+	}
+	c.maxSelected = max
+}
+
 func (c *listBoxImpl) Rows() int {
 	return c.rows
 }
@@ -120,16 +281,32 @@ func (c *listBoxImpl) SetRows(rows int) {
 
 func (c *listBoxImpl) SelectedValue() string {
 	if i := c.SelectedIdx(); i >= 0 {
+		if c.keys != nil {
+			return c.keys[i]
+		}
 		return c.values[i]
 	}
 
 	return ""
 }
 
-func (c *listBoxImpl) SelectedValues() (sv []string) {
-	for i, s := range c.selected {
-		if s {
-			sv = append(sv, c.values[i])
+func (c *listBoxImpl) SelectedValues() []string {
+	return c.SelectedKeys()
+}
+
+func (c *listBoxImpl) SelectedKeys() []string {
+	if c.keys != nil {
+		return c.selectedOf(c.keys)
+	}
+	return c.selectedOf(c.values)
+}
+
+// selectedOf returns the elements of the specified slice (keys or values)
+// at the selected indices.
+func (c *listBoxImpl) selectedOf(s []string) (ss []string) {
+	for i, sel := range c.selected {
+		if sel {
+			ss = append(ss, s[i])
 		}
 	}
 	return
@@ -157,8 +334,40 @@ func (c *listBoxImpl) SelectedIndices() (si []int) {
 	return
 }
 
+func (c *listBoxImpl) SelectedIndicesInOrder() []int {
+	return append([]int(nil), c.order...)
+}
+
+// syncOrder updates the selection order: removes the indices of the
+// deselected values, and appends the newly selected ones (in index order).
+func (c *listBoxImpl) syncOrder() {
+	inOrder := make(map[int]bool, len(c.order))
+	order := c.order[:0]
+	for _, i := range c.order {
+		if i >= 0 && i < len(c.selected) && c.selected[i] && !inOrder[i] {
+			order = append(order, i)
+			inOrder[i] = true
+		}
+	}
+	for i, sel := range c.selected {
+		if sel && !inOrder[i] {
+			order = append(order, i)
+		}
+	}
+	c.order = order
+}
+
 func (c *listBoxImpl) SetSelected(i int, selected bool) {
+	c.SetSelectedErr(i, selected)
+}
+
+func (c *listBoxImpl) SetSelectedErr(i int, selected bool) error {
+	if i < 0 || i >= len(c.selected) {
+		return errors.New("Index out of range: " + strconv.Itoa(i))
+	}
 	c.selected[i] = selected
+	c.syncOrder()
+	return nil
 }
 
 func (c *listBoxImpl) SetSelectedIndices(indices []int) {
@@ -169,14 +378,181 @@ func (c *listBoxImpl) SetSelectedIndices(indices []int) {
 
 	// And now select that needs to be selected
 	for _, idx := range indices {
-		c.selected[idx] = true
+		if idx >= 0 && idx < len(c.selected) {
+			c.selected[idx] = true
+		}
 	}
+	// Order of the indices is the selection order
+	c.order = append(c.order[:0], indices...)
+	c.syncOrder()
 }
 
 func (c *listBoxImpl) ClearSelected() {
 	for i, _ := range c.selected {
 		c.selected[i] = false
 	}
+	c.order = c.order[:0]
+}
+
+func (c *listBoxImpl) SelectionBits() []uint64 {
+	bits := make([]uint64, (len(c.selected)+63)/64)
+	for i, s := range c.selected {
+		if s {
+			bits[i/64] |= 1 << uint(i%64)
+		}
+	}
+	return bits
+}
+
+func (c *listBoxImpl) SetSelectionBits(bits []uint64) {
+	for i := range c.selected {
+		c.selected[i] = i/64 < len(bits) && bits[i/64]&(1<<uint(i%64)) != 0
+	}
+	c.syncOrder()
+}
+
+func (c *listBoxImpl) AddGroup(label string, values []string) {
+	start := len(c.values)
+	// Full slice expression so the slice passed to NewListBox() is never modified
+	c.values = append(c.values[:start:start], values...)
+	if c.keys != nil {
+		c.keys = append(c.keys[:start:start], values...)
+	}
+	c.selected = append(c.selected, make([]bool, len(values))...)
+	c.disabled = append(c.disabled, make([]bool, len(values))...)
+	if label != "" && len(values) > 0 {
+		c.groups = append(c.groups, lbGroup{label: label, start: start, end: len(c.values)})
+	}
+}
+
+func (c *listBoxImpl) SetValues(values []string) {
+	selected := make(map[string]bool, len(c.values))
+	disabled := make(map[string]bool, len(c.values))
+	for i, v := range c.values {
+		if c.selected[i] {
+			selected[v] = true
+		}
+		if c.disabled[i] {
+			disabled[v] = true
+		}
+	}
+
+	orderValues := make([]string, len(c.order))
+	for i, idx := range c.order {
+		orderValues[i] = c.values[idx]
+	}
+
+	c.values = values
+	if c.keys != nil {
+		c.keys = values
+	}
+	c.selected = make([]bool, len(values))
+	c.disabled = make([]bool, len(values))
+	c.groups = nil
+	c.optStyles = nil
+	c.optAttrs = nil
+
+	hasSel := false
+	newIdx := make(map[string]int, len(values)) // First selected index of the values
+	for i, v := range values {
+		if selected[v] && (c.multi || !hasSel) {
+			c.selected[i], hasSel = true, true
+			if _, has := newIdx[v]; !has {
+				newIdx[v] = i
+			}
+		}
+		c.disabled[i] = disabled[v]
+	}
+
+	// Keep the selection order of the kept values
+	c.order = c.order[:0]
+	for _, v := range orderValues {
+		if i, has := newIdx[v]; has {
+			c.order = append(c.order, i)
+		}
+	}
+	c.syncOrder()
+}
+
+func (c *listBoxImpl) OptionDisabled(i int) bool {
+	if i < 0 || i >= len(c.disabled) {
+		return false
+	}
+	return c.disabled[i]
+}
+
+func (c *listBoxImpl) SetOptionDisabled(i int, disabled bool) {
+	if i < 0 || i >= len(c.disabled) {
+		return
+	}
+	c.disabled[i] = disabled
+}
+
+func (c *listBoxImpl) SetOptionStyle(i int) Style {
+	if i < 0 || i >= len(c.values) {
+		return newStyleImpl()
+	}
+	if c.optStyles == nil {
+		c.optStyles = make(map[int]*styleImpl)
+	}
+	s := c.optStyles[i]
+	if s == nil {
+		s = newStyleImpl()
+		c.optStyles[i] = s
+	}
+	return s
+}
+
+func (c *listBoxImpl) OptionAttr(i int, name string) string {
+	for _, attr := range c.optAttrs[i] {
+		if attr[0] == name {
+			return attr[1]
+		}
+	}
+	return ""
+}
+
+func (c *listBoxImpl) SetOptionAttr(i int, name, value string) {
+	if i < 0 || i >= len(c.values) {
+		return
+	}
+	if c.optAttrs == nil {
+		c.optAttrs = make(map[int][][2]string)
+	}
+	attrs := c.optAttrs[i]
+	for j, attr := range attrs {
+		if attr[0] == name {
+			if value == "" {
+				attrs = append(attrs[:j], attrs[j+1:]...)
+			} else {
+				attrs[j][1] = value
+			}
+			c.optAttrs[i] = attrs
+			return
+		}
+	}
+	if value != "" {
+		c.optAttrs[i] = append(attrs, [2]string{name, value})
+	}
+}
+
+func (c *listBoxImpl) Filterable() bool {
+	return c.filterable
+}
+
+func (c *listBoxImpl) SetFilterable(filterable bool) {
+	c.filterable = filterable
+}
+
+func (c *listBoxImpl) saveState() interface{} {
+	return append([]int{}, c.SelectedIndices()...) // Empty selection is [], not null
+}
+
+func (c *listBoxImpl) restoreState(data json.RawMessage) {
+	var indices []int
+	if json.Unmarshal(data, &indices) == nil {
+		c.setClientSelected(indices)
+	}
 }
 
 func (c *listBoxImpl) preprocessEvent(event Event, r *http.Request) {
@@ -185,43 +561,146 @@ func (c *listBoxImpl) preprocessEvent(event Event, r *http.Request) {
 		return
 	}
 
-	// Set selected indices
-	c.ClearSelected()
+	var indices []int
 	for _, sidx := range strings.Split(value, ",") {
 		if idx, err := strconv.Atoi(sidx); err == nil {
+			indices = append(indices, idx)
+		}
+	}
+	c.setClientSelected(indices)
+}
+
+// setClientSelected sets the selected indices sent by the client (or restored
+// from the client state). Selection state of disabled options cannot be
+// changed by the client (e.g. a malicious or stale one), and neither the
+// selection of a disabled list box. Invalid indices are dropped, and so are
+// the indices beyond the max selected limit (or beyond the first one if
+// multiple selection is not allowed).
+func (c *listBoxImpl) setClientSelected(indices []int) {
+	if !c.Enabled() {
+		return
+	}
+
+	for i := range c.selected {
+		if !c.disabled[i] {
+			c.selected[i] = false
+		}
+	}
+	for _, idx := range indices {
+		if idx >= 0 && idx < len(c.selected) && !c.disabled[idx] {
 			c.selected[idx] = true
+			if !c.multi {
+				break // Only one value can be selected
+			}
+		}
+	}
+
+	if c.multi && c.maxSelected > 0 {
+		c.limitSelected()
+	}
+	c.syncOrder()
+}
+
+// limitSelected deselects the values selected by the user beyond the max
+// selected limit, in index order. Selected disabled options count first
+// as their selection state cannot be changed by the user.
+func (c *listBoxImpl) limitSelected() {
+	count := 0
+	for i, sel := range c.selected {
+		if sel && c.disabled[i] {
+			count++
+		}
+	}
+	for i, sel := range c.selected {
+		if sel && !c.disabled[i] {
+			if count >= c.maxSelected {
+				c.selected[i] = false
+			} else {
+				count++
+			}
 		}
 	}
 }
 
 var (
-	strSelectOp    = []byte("<select")                      // "<select"
-	strMultiple    = []byte(` multiple="multiple"`)         // ` multiple="multiple"`
-	strOptionOpSel = []byte(`<option selected="selected">`) // `<option selected="selected">`
-	strOptionOp    = []byte("<option>")                     // "<option>"
-	strOptionCl    = []byte("</option>")                    // "</option>"
-	strSelectCl    = []byte("</select>")                    // "</select>"
+	strSelectOp   = []byte("<select")              // "<select"
+	strMultiple   = []byte(` multiple="multiple"`) // ` multiple="multiple"`
+	strOptionOp   = []byte("<option")              // "<option"
+	strSelected   = []byte(` selected="selected"`) // ` selected="selected"`
+	strOptionCl   = []byte("</option>")            // "</option>"
+	strSelectCl   = []byte("</select>")            // "</select>"
+	strOptgroupOp = []byte(`<optgroup label="`)    // `<optgroup label="`
+	strOptgroupCl = []byte("</optgroup>")          // "</optgroup>"
+)
+
+var (
+	strLbFilterOp  = []byte(`<input type="text" class="gwu-ListBox-Filter" id="`) // `<input type="text" class="gwu-ListBox-Filter" id="`
+	strLbFilterMid = []byte(`_flt" oninput="lbFilter(this,`)                      // `_flt" oninput="lbFilter(this,`
+	strLbMaxSelJs  = []byte("lbMaxSel(this)")                                     // "lbMaxSel(this)"
 )
 
 func (c *listBoxImpl) Render(w Writer) {
+	if c.filterable {
+		// To render: <input type="text" class="gwu-ListBox-Filter" id="compId_flt" oninput="lbFilter(this,compId)"/>
+		w.Write(strLbFilterOp)
+		w.Writev(int(c.id))
+		w.Write(strLbFilterMid)
+		w.Writev(int(c.id))
+		w.Write(strSeSuffix)
+		if !c.enabled {
+			w.Write(strDisabled)
+		}
+		w.Write(strInputCl2)
+	}
+
+	c.renderPrintStatic(w, strings.Join(c.selectedOf(c.values), ", "))
+
 	w.Write(strSelectOp)
+	var inputJs []byte
 	if c.multi {
 		w.Write(strMultiple)
+		if c.maxSelected > 0 {
+			w.WriteAttr("data-gwumaxsel", strconv.Itoa(c.maxSelected))
+			inputJs = strLbMaxSelJs
+		}
 	}
 	w.WriteAttr("size", strconv.Itoa(c.rows))
 	c.renderAttrsAndStyle(w)
 	c.renderEnabled(w)
-	c.renderEHandlers(w)
+	c.renderEHandlersInput(w, inputJs)
 	w.Write(strGT)
 
+	groups := c.groups
 	for i, value := range c.values {
+		if len(groups) > 0 && groups[0].start == i {
+			w.Write(strOptgroupOp)
+			w.Writees(groups[0].label)
+			w.Write(strQuote)
+			w.Write(strGT)
+		}
+		w.Write(strOptionOp)
+		if c.keys != nil {
+			writeEscAttr(w, "value", c.keys[i])
+		}
 		if c.selected[i] {
-			w.Write(strOptionOpSel)
-		} else {
-			w.Write(strOptionOp)
+			w.Write(strSelected)
 		}
+		if c.disabled[i] {
+			w.Write(strDisabled)
+		}
+		if s := c.optStyles[i]; s != nil {
+			s.render(w)
+		}
+		for _, attr := range c.optAttrs[i] {
+			writeEscAttr(w, attr[0], attr[1])
+		}
+		w.Write(strGT)
 		w.Writees(value)
 		w.Write(strOptionCl)
+		if len(groups) > 0 && groups[0].end == i+1 {
+			w.Write(strOptgroupCl)
+			groups = groups[1:]
+		}
 	}
 
 	w.Write(strSelectCl)
@@ -0,0 +1,389 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestListBoxPrintStatic(t *testing.T) {
+	win := NewWindow("main", "Test")
+	lb := NewListBox([]string{"one", "<two>", "three"})
+	lb.SetMulti(true)
+	lb.SetSelectedIndices([]int{1, 2})
+	win.Add(lb)
+
+	if s := renderString(lb); strings.Contains(s, "gwu-PrintStatic") {
+		t.Errorf("Print-static text rendered when not enabled: %s", s)
+	}
+
+	win.SetPrintStaticInputs(true)
+	win.Style().SetClass("custom") // Does not affect the setting
+	if s := renderString(win); !win.PrintStaticInputs() || !strings.Contains(s, ` data-gwuprintstatic="1"`) {
+		t.Errorf("Print-static attribute not rendered: %s", s)
+	}
+	s := renderString(lb)
+	want := `<span class="gwu-PrintStatic" id="` + lb.Id().String() + `_ps">&lt;two&gt;, three</span><select`
+	if !strings.HasPrefix(s, want) {
+		t.Errorf("Got: %s, want prefix: %s", s, want)
+	}
+
+	win.SetPrintStaticInputs(false)
+	if win.PrintStaticInputs() {
+		t.Errorf("Print-static inputs not disabled")
+	}
+	if s := renderString(lb); strings.Contains(s, "gwu-PrintStatic") {
+		t.Errorf("Print-static text rendered when disabled: %s", s)
+	}
+}
+
+func TestListBoxSelectionBits(t *testing.T) {
+	values := make([]string, 1000)
+	for i := range values {
+		values[i] = strconv.Itoa(i)
+	}
+	lb := NewListBox(values)
+	lb.SetMulti(true)
+
+	if bits := lb.SelectionBits(); len(bits) != 16 {
+		t.Errorf("Got %d words, want 16", len(bits))
+	}
+
+	// Sparse selection
+	sparse := []int{0, 63, 64, 500, 999}
+	lb.SetSelectedIndices(sparse)
+	bits := lb.SelectionBits()
+	if bits[0] != 1|1<<63 || bits[1] != 1 {
+		t.Errorf("Got bits %x", bits[:2])
+	}
+	lb.ClearSelected()
+	lb.SetSelectionBits(bits)
+	if got := lb.SelectedIndices(); !reflect.DeepEqual(got, sparse) {
+		t.Errorf("Got indices %v, want %v", got, sparse)
+	}
+
+	// Dense selection: all but every 7th
+	var dense []int
+	for i := range values {
+		if i%7 != 0 {
+			dense = append(dense, i)
+		}
+	}
+	lb.SetSelectedIndices(dense)
+	bits = lb.SelectionBits()
+	lb.ClearSelected()
+	lb.SetSelectionBits(bits)
+	if got := lb.SelectedIndices(); !reflect.DeepEqual(got, dense) {
+		t.Errorf("Dense selection not round-tripped, got %d indices, want %d", len(got), len(dense))
+	}
+
+	// Missing words deselect, extra bits are ignored
+	lb.SetSelectionBits([]uint64{1 << 5})
+	if got := lb.SelectedIndices(); !reflect.DeepEqual(got, []int{5}) {
+		t.Errorf("Got indices %v", got)
+	}
+	small := NewListBox([]string{"a", "b"})
+	small.SetSelectionBits([]uint64{^uint64(0), ^uint64(0)})
+	if got := small.SelectedIndices(); !reflect.DeepEqual(got, []int{0, 1}) {
+		t.Errorf("Got indices %v", got)
+	}
+}
+
+func TestListBoxGroups(t *testing.T) {
+	values := []string{"Any"}
+	lb := NewListBox(values)
+	lb.AddGroup("Fruits & more", []string{"apple", "pear"})
+	lb.AddGroup("", []string{"bread"}) // No group
+	lb.AddGroup("Empty", nil)          // No group
+	lb.AddGroup("Drinks", []string{"tea"})
+
+	s := renderString(lb)
+	want := `<option>Any</option>` +
+		`<optgroup label="Fruits &amp; more"><option>apple</option><option>pear</option></optgroup>` +
+		`<option>bread</option>` +
+		`<optgroup label="Drinks"><option>tea</option></optgroup></select>`
+	if !strings.HasSuffix(s, want) {
+		t.Errorf("Got: %s, want suffix: %s", s, want)
+	}
+	if strings.Contains(s, `label=""`) || strings.Contains(s, `label="Empty"`) {
+		t.Errorf("Empty group rendered: %s", s)
+	}
+	if len(values) != 1 {
+		t.Errorf("Values passed to NewListBox() modified: %v", values)
+	}
+
+	// Indices are flat
+	lb.SetMulti(true)
+	lb.(*listBoxImpl).preprocessEvent(newEventImpl(ETypeChange, lb, nil, nil), newCompValueReq("2,4,"))
+	if got := lb.SelectedValues(); !reflect.DeepEqual(got, []string{"pear", "tea"}) {
+		t.Errorf("Got selected values %v", got)
+	}
+}
+
+func TestListBoxOptionDisabled(t *testing.T) {
+	lb := NewListBox([]string{"a", "b", "c"})
+	lb.AddGroup("G", []string{"d"})
+	lb.SetMulti(true)
+	lb.SetOptionDisabled(1, true)
+	lb.SetOptionDisabled(3, true)
+	lb.SetSelected(3, true) // Selected from the server side
+	if !lb.OptionDisabled(1) || lb.OptionDisabled(0) {
+		t.Error("Option disabled state not stored")
+	}
+	// Invalid indices are ignored
+	lb.SetOptionDisabled(-1, true)
+	lb.SetOptionDisabled(4, true)
+	if lb.OptionDisabled(-1) || lb.OptionDisabled(4) {
+		t.Error("Invalid indices not ignored")
+	}
+
+	s := renderString(lb)
+	want := `<option>a</option><option disabled="disabled">b</option><option>c</option>` +
+		`<optgroup label="G"><option selected="selected" disabled="disabled">d</option></optgroup>`
+	if !strings.Contains(s, want) {
+		t.Errorf("Got: %s, want: %s", s, want)
+	}
+
+	// Disabled indices sent by the client are ignored, and disabled options stay selected
+	lb.(*listBoxImpl).preprocessEvent(newEventImpl(ETypeChange, lb, nil, nil), newCompValueReq("0,1,9,-1"))
+	if got := lb.SelectedIndices(); !reflect.DeepEqual(got, []int{0, 3}) {
+		t.Errorf("Got selected indices %v, want [0 3]", got)
+	}
+}
+
+func TestListBoxKV(t *testing.T) {
+	lb := NewListBoxKV([]string{"hu", "en", "x"}, []string{"Magyar", `"English"`})
+	lb.AddGroup("Other", []string{"de"})
+
+	s := renderString(lb)
+	want := `<option value="hu">Magyar</option><option value="en">&#34;English&#34;</option>` +
+		`<optgroup label="Other"><option value="de">de</option></optgroup>`
+	if !strings.Contains(s, want) {
+		t.Errorf("Got: %s, want: %s", s, want)
+	}
+
+	// Indices are posted, keys are returned
+	lb.SetMulti(true)
+	lb.(*listBoxImpl).preprocessEvent(newEventImpl(ETypeChange, lb, nil, nil), newCompValueReq("1,2"))
+	if got := lb.SelectedValue(); got != "en" {
+		t.Errorf("Got selected value %q, want %q", got, "en")
+	}
+	if got := lb.SelectedKeys(); !reflect.DeepEqual(got, []string{"en", "de"}) {
+		t.Errorf("Got selected keys %v", got)
+	}
+	if got := lb.SelectedValues(); !reflect.DeepEqual(got, []string{"en", "de"}) {
+		t.Errorf("Got selected values %v", got)
+	}
+}
+
+func TestListBoxSetValues(t *testing.T) {
+	lb := NewListBox([]string{"a", "b", "c", "d"})
+	lb.AddGroup("G", []string{"e"})
+	lb.SetMulti(true)
+	lb.SetSelectedIndices([]int{1, 3, 4})
+	lb.SetOptionDisabled(2, true)
+
+	// Shorter list: only the selection of the kept values remains
+	lb.SetValues([]string{"d", "c", "x"})
+	if got := lb.SelectedValues(); !reflect.DeepEqual(got, []string{"d"}) {
+		t.Errorf("Got selected values %v, want [d]", got)
+	}
+	if !lb.OptionDisabled(1) || lb.OptionDisabled(0) || lb.OptionDisabled(2) {
+		t.Error("Disabled states not preserved by value")
+	}
+	s := renderString(lb)
+	if strings.Contains(s, "optgroup") || !strings.Contains(s, `<option selected="selected">d</option>`) {
+		t.Errorf("Unexpected render: %s", s)
+	}
+
+	// Single selection keeps the first match only
+	lb.SetMulti(false)
+	lb.SetValues([]string{"d", "d"})
+	if got := lb.SelectedIndices(); !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("Got selected indices %v, want [0]", got)
+	}
+}
+
+func TestListBoxIndexBounds(t *testing.T) {
+	lb := NewListBox([]string{"a", "b", "c"})
+	lb.SetMulti(true)
+
+	// Stale index posted by the client
+	lb.(*listBoxImpl).preprocessEvent(newEventImpl(ETypeChange, lb, nil, nil), newCompValueReq("0,99"))
+	if got := lb.SelectedIndices(); !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("Got selected indices %v, want [0]", got)
+	}
+
+	lb.SetSelected(3, true)
+	lb.SetSelectedIndices([]int{-1, 2, 5})
+	if got := lb.SelectedIndices(); !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("Got selected indices %v, want [2]", got)
+	}
+
+	if err := lb.SetSelectedErr(3, true); err == nil {
+		t.Error("Expected error for out of range index")
+	}
+	if err := lb.SetSelectedErr(1, true); err != nil || !lb.Selected(1) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestListBoxFilterable(t *testing.T) {
+	lb := NewListBox([]string{"Apple", "Banana"})
+	if s := renderString(lb); strings.Contains(s, "lbFilter") {
+		t.Errorf("Filter rendered for non-filterable ListBox: %s", s)
+	}
+
+	lb.SetFilterable(true)
+	id := lb.Id().String()
+	s := renderString(lb)
+	want := `<input type="text" class="gwu-ListBox-Filter" id="` + id + `_flt" oninput="lbFilter(this,` + id + `)"/><select`
+	if !strings.HasPrefix(s, want) {
+		t.Errorf("Got: %s, want prefix: %s", s, want)
+	}
+
+	lb.SetEnabled(false)
+	if s := renderString(lb); !strings.Contains(s, `)" disabled="disabled"/><select`) {
+		t.Errorf("Filter of disabled ListBox not disabled: %s", s)
+	}
+}
+
+func TestListBoxMaxSelected(t *testing.T) {
+	lb := NewListBox([]string{"a", "b", "c", "d", "e"})
+	lb.SetMulti(true)
+	lb.SetMaxSelected(2)
+
+	s := renderString(lb)
+	if !strings.Contains(s, ` data-gwumaxsel="2"`) || !strings.Contains(s, ` oninput="lbMaxSel(this)"`) {
+		t.Errorf("Max selected not rendered: %s", s)
+	}
+	lb.AddEHandlerFunc(func(e Event) {}, ETypeInput)
+	if s := renderString(lb); strings.Count(s, "oninput=") != 1 || !strings.Contains(s, ` oninput="lbMaxSel(this);se(event,`) {
+		t.Errorf("Input handler not rendered after limiting: %s", s)
+	}
+
+	lb.(*listBoxImpl).preprocessEvent(newEventImpl(ETypeChange, lb, nil, nil), newCompValueReq("4,1,3,0"))
+	if got := lb.SelectedIndices(); !reflect.DeepEqual(got, []int{0, 1}) {
+		t.Errorf("Got selected indices %v, want [0 1]", got)
+	}
+
+	// Selected disabled options count first
+	lb.SetSelectedIndices([]int{2})
+	lb.SetOptionDisabled(2, true)
+	lb.(*listBoxImpl).preprocessEvent(newEventImpl(ETypeChange, lb, nil, nil), newCompValueReq("0,1,2"))
+	if got := lb.SelectedIndices(); !reflect.DeepEqual(got, []int{0, 2}) {
+		t.Errorf("Got selected indices %v, want [0 2]", got)
+	}
+
+	lb.SetMaxSelected(0)
+	lb.(*listBoxImpl).preprocessEvent(newEventImpl(ETypeChange, lb, nil, nil), newCompValueReq("0,1,3,4"))
+	if got := lb.SelectedIndices(); len(got) != 5 {
+		t.Errorf("Got selected indices %v, want all", got)
+	}
+}
+
+func TestListBoxSelectedIndicesInOrder(t *testing.T) {
+	lb := NewListBox([]string{"a", "b", "c", "d", "e"})
+	lb.SetMulti(true)
+	post := func(value string) {
+		lb.(*listBoxImpl).preprocessEvent(newEventImpl(ETypeChange, lb, nil, nil), newCompValueReq(value))
+	}
+
+	post("3")
+	post("1,3")
+	post("0,1,3")
+	if got := lb.SelectedIndicesInOrder(); !reflect.DeepEqual(got, []int{3, 1, 0}) {
+		t.Errorf("Got %v, want [3 1 0]", got)
+	}
+	if got := lb.SelectedIndices(); !reflect.DeepEqual(got, []int{0, 1, 3}) {
+		t.Errorf("Got selected indices %v, want [0 1 3]", got)
+	}
+
+	// Deselected ones are removed, reselected ones go to the end
+	post("0,3")
+	post("0,1,3")
+	lb.SetSelected(4, true)
+	if got := lb.SelectedIndicesInOrder(); !reflect.DeepEqual(got, []int{3, 0, 1, 4}) {
+		t.Errorf("Got %v, want [3 0 1 4]", got)
+	}
+
+	lb.SetSelectedIndices([]int{2, 0})
+	if got := lb.SelectedIndicesInOrder(); !reflect.DeepEqual(got, []int{2, 0}) {
+		t.Errorf("Got %v, want [2 0]", got)
+	}
+
+	lb.SetValues([]string{"x", "c", "a"})
+	if got := lb.SelectedIndicesInOrder(); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("Got %v, want [1 2]", got)
+	}
+
+	lb.ClearSelected()
+	if got := lb.SelectedIndicesInOrder(); len(got) != 0 {
+		t.Errorf("Got %v, want []", got)
+	}
+}
+
+func TestListBoxOptionStyle(t *testing.T) {
+	lb := NewListBox([]string{"a", "b", "c"})
+	lb.SetOptionStyle(1).SetBackground("red")
+	lb.SetOptionStyle(5).SetColor("blue") // Out of range, not rendered
+
+	s := renderString(lb)
+	want := `<option>a</option><option style="background:red;">b</option><option>c</option>`
+	if !strings.Contains(s, want) {
+		t.Errorf("Got: %s, want: %s", s, want)
+	}
+	if strings.Count(s, "style=") != 1 || strings.Count(s, "class=") != 1 { // class of the select only
+		t.Errorf("Unexpected style attributes: %s", s)
+	}
+
+	lb.SetOptionStyle(2).AddClass("overdue")
+	if s := renderString(lb); !strings.Contains(s, `<option class="overdue">c</option>`) {
+		t.Errorf("Option class not rendered: %s", s)
+	}
+}
+
+func TestListBoxOptionAttr(t *testing.T) {
+	lb := NewListBox([]string{"a", "b", "c"})
+	lb.SetOptionAttr(1, "title", `Say "hi" <now>`)
+	lb.SetOptionAttr(1, "data-cost", "5")
+	lb.SetOptionAttr(3, "title", "x") // Out of range, ignored
+
+	s := renderString(lb)
+	want := `<option>a</option><option title="Say &#34;hi&#34; &lt;now&gt;" data-cost="5">b</option><option>c</option>`
+	if !strings.Contains(s, want) {
+		t.Errorf("Got: %s, want: %s", s, want)
+	}
+	if strings.Count(s, "title=") != 1 || strings.Count(s, "data-cost=") != 1 {
+		t.Errorf("Option attributes rendered elsewhere: %s", s)
+	}
+	if v := lb.OptionAttr(1, "data-cost"); v != "5" {
+		t.Errorf("Got attribute value %q", v)
+	}
+
+	lb.SetOptionAttr(1, "title", "")
+	if s := renderString(lb); !strings.Contains(s, `<option data-cost="5">b</option>`) {
+		t.Errorf("Option attribute not removed: %s", s)
+	}
+
+	lb.SetValues([]string{"b", "c"})
+	if s := renderString(lb); strings.Contains(s, "data-cost") {
+		t.Errorf("Option attributes kept after SetValues(): %s", s)
+	}
+}
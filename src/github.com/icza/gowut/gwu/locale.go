@@ -0,0 +1,111 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Locale dependent formatting of numbers and dates.
+
+package gwu
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// localeFormat describes how numbers and dates are formatted in a locale.
+type localeFormat struct {
+	decimalSep string // Decimal separator
+	groupSep   string // Digit group (thousands) separator
+	dateTime   string // Layout of date+time values (see time.Time.Format())
+}
+
+// defaultLocaleFormat is the locale independent format, used if no
+// locale or an unknown locale is specified.
+var defaultLocaleFormat = localeFormat{".", "", "2006-01-02 15:04"}
+
+// localeFormats holds the formats of the supported locales, mapped from
+// lowercased language tag (with or without region).
+var localeFormats = map[string]localeFormat{
+	"en":    {".", ",", "01/02/2006 3:04 PM"},
+	"en-gb": {".", ",", "02/01/2006 15:04"},
+	"de":    {",", ".", "02.01.2006 15:04"},
+	"es":    {",", ".", "02/01/2006 15:04"},
+	"fr":    {",", "\u00a0", "02/01/2006 15:04"},
+	"hu":    {",", "\u00a0", "2006. 01. 02. 15:04"},
+	"it":    {",", ".", "02/01/2006 15:04"},
+	"ja":    {".", ",", "2006/01/02 15:04"},
+	"nl":    {",", ".", "02-01-2006 15:04"},
+}
+
+// formatOf returns the format of the specified locale (language tag,
+// e.g. "en-US" or "de"). If the locale with region is not known, the format
+// of its language is returned. If the language is not known either,
+// the locale independent format is returned.
+func formatOf(locale string) localeFormat {
+	tag := strings.ToLower(strings.Replace(locale, "_", "-", -1))
+	if f, ok := localeFormats[tag]; ok {
+		return f
+	}
+	if i := strings.IndexByte(tag, '-'); i > 0 {
+		if f, ok := localeFormats[tag[:i]]; ok {
+			return f
+		}
+	}
+	return defaultLocaleFormat
+}
+
+// FormatNumber formats a number according to the specified locale
+// (language tag, e.g. "en-US" or "de"), using the specified number of
+// decimals. Pass a negative decimals to use the minimum number of decimals
+// necessary to represent the value.
+// Numbers are formatted locale independently (without digit grouping)
+// if locale is empty or unknown.
+func FormatNumber(locale string, f float64, decimals int) string {
+	lf := formatOf(locale)
+	s := strconv.FormatFloat(f, 'f', decimals, 64)
+
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+
+	if lf.groupSep != "" && len(intPart) > 3 {
+		// Insert group separators from the right
+		groups := make([]string, 0, len(intPart)/3+1)
+		first := len(intPart) % 3
+		if first > 0 {
+			groups = append(groups, intPart[:first])
+		}
+		for i := first; i < len(intPart); i += 3 {
+			groups = append(groups, intPart[i:i+3])
+		}
+		intPart = strings.Join(groups, lf.groupSep)
+	}
+
+	if fracPart == "" {
+		return sign + intPart
+	}
+	return sign + intPart + lf.decimalSep + fracPart
+}
+
+// FormatDateTime formats a time (date and time to the minute) according to
+// the specified locale (language tag, e.g. "en-US" or "de").
+// The "2006-01-02 15:04" layout is used if locale is empty or unknown.
+func FormatDateTime(locale string, t time.Time) string {
+	return t.Format(formatOf(locale).dateTime)
+}
@@ -0,0 +1,104 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatNumber(t *testing.T) {
+	cases := []struct {
+		locale   string
+		f        float64
+		decimals int
+		want     string
+	}{
+		{"", 1234567.891, 2, "1234567.89"},
+		{"en-US", 1234567.891, 2, "1,234,567.89"},
+		{"de", 1234567.891, 2, "1.234.567,89"},
+		{"de_AT", -1234.5, -1, "-1.234,5"},
+		{"hu", 123456, 0, "123 456"},
+		{"en", 999, 0, "999"},
+		{"xx", 1234.5, 1, "1234.5"},
+	}
+	for _, c := range cases {
+		if got := FormatNumber(c.locale, c.f, c.decimals); got != c.want {
+			t.Errorf("FormatNumber(%q, %v, %d) = %q, want %q", c.locale, c.f, c.decimals, got, c.want)
+		}
+	}
+}
+
+func TestFormatDateTime(t *testing.T) {
+	tm := time.Date(2016, 3, 9, 14, 5, 0, 0, time.UTC)
+	cases := []struct{ locale, want string }{
+		{"", "2016-03-09 14:05"},
+		{"en-US", "03/09/2016 2:05 PM"},
+		{"en-GB", "09/03/2016 14:05"},
+		{"de", "09.03.2016 14:05"},
+	}
+	for _, c := range cases {
+		if got := FormatDateTime(c.locale, tm); got != c.want {
+			t.Errorf("FormatDateTime(%q) = %q, want %q", c.locale, got, c.want)
+		}
+	}
+}
+
+func TestSessionLocaleRender(t *testing.T) {
+	ns := NewNumberSpinner(12345, 0, 100000)
+	render := func(locale string) string {
+		b := &bytes.Buffer{}
+		ns.Render(newLocaleWriter(b, locale))
+		return b.String()
+	}
+	if s := render("en"); !strings.Contains(s, ">12,345<") {
+		t.Errorf("Got en render: %s", s)
+	}
+	if s := render("de"); !strings.Contains(s, ">12.345<") {
+		t.Errorf("Got de render: %s", s)
+	}
+
+	// Windows are rendered with the locale of the session
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	win.SetPrintStaticInputs(true)
+	win.Add(NewDateTimePicker(time.Date(2016, 3, 9, 14, 5, 0, 0, time.Local)))
+	win.Add(ns)
+	for _, locale := range []string{"en-US", "de-DE"} {
+		sess := s.newSession(nil)
+		sess.SetLocale(locale)
+		sess.AddWin(win)
+
+		r := httptest.NewRequest("GET", s.AppPath()+win.Name(), nil)
+		r.AddCookie(&http.Cookie{Name: gwuSessidCookie, Value: sess.Id()})
+		wr := httptest.NewRecorder()
+		s.serveHTTP(wr, r)
+		doc := wr.Body.String()
+		want := map[string][]string{
+			"en-US": {">12,345<", ">03/09/2016 2:05 PM<"},
+			"de-DE": {">12.345<", ">09.03.2016 14:05<"},
+		}[locale]
+		for _, w := range want {
+			if !strings.Contains(doc, w) {
+				t.Errorf("Locale %s: document does not contain %q", locale, w)
+			}
+		}
+	}
+}
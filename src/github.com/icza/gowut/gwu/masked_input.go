@@ -0,0 +1,214 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// MaskedInput component interface and implementation.
+
+package gwu
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// MaskedInput interface defines a one-line text input which applies a mask
+// pattern (e.g. "(999) 999-9999") to the entered text as the user types.
+//
+// Characters of the mask pattern:
+//
+//	9  a digit (0-9)
+//	a  a letter (a-z, A-Z)
+//	*  a digit or a letter
+//
+// Other characters are literals which are inserted automatically.
+//
+// Only the raw value (the characters entered into the placeholders,
+// without the literals) is sent to the server, e.g. "5551234567"
+// for the masked text "(555) 123-4567".
+//
+// Suggested event type to handle changes: ETypeChange
+//
+// Default style class: "gwu-MaskedInput"
+type MaskedInput interface {
+	// MaskedInput is a component.
+	Comp
+
+	// MaskedInput can be enabled/disabled.
+	HasEnabled
+
+	// Mask returns the mask pattern.
+	Mask() string
+
+	// SetMask sets the mask pattern.
+	// The raw value is truncated if the new mask has fewer placeholders,
+	// and the characters not fitting the new placeholders are dropped.
+	SetMask(pattern string)
+
+	// Value returns the raw value (without the literals of the mask).
+	Value() string
+
+	// SetValue sets the raw value (without the literals of the mask).
+	// Characters not fitting the placeholders of the mask are dropped.
+	SetValue(value string)
+
+	// MaskedValue returns the value with the mask applied,
+	// as it is displayed in the browser.
+	MaskedValue() string
+}
+
+// MaskedInput implementation.
+type maskedInputImpl struct {
+	compImpl       // Component implementation
+	hasEnabledImpl // Has enabled implementation
+
+	mask  string // Mask pattern
+	value string // Raw value
+}
+
+var (
+	strEncURIMaskRaw = []byte("encodeURIComponent(maskRaw(this))") // "encodeURIComponent(maskRaw(this))"
+)
+
+// NewMaskedInput creates a new MaskedInput with the specified mask pattern.
+func NewMaskedInput(pattern string) MaskedInput {
+	c := &maskedInputImpl{compImpl: newCompImpl(strEncURIMaskRaw), hasEnabledImpl: newHasEnabledImpl(), mask: pattern}
+	c.AddSyncOnETypes(ETypeChange)
+	c.Style().AddClass("gwu-MaskedInput")
+	return c
+}
+
+func (c *maskedInputImpl) Mask() string {
+	return c.mask
+}
+
+func (c *maskedInputImpl) SetMask(pattern string) {
+	c.mask = pattern
+	c.SetValue(c.value)
+}
+
+func (c *maskedInputImpl) Value() string {
+	return c.value
+}
+
+func (c *maskedInputImpl) SetValue(value string) {
+	c.value = maskFilter(c.mask, value)
+}
+
+func (c *maskedInputImpl) MaskedValue() string {
+	return maskApply(c.mask, c.value)
+}
+
+// maskFits tells if the character r fits the mask placeholder m.
+// Returns false if m is not a placeholder.
+func maskFits(m byte, r rune) bool {
+	digit := r >= '0' && r <= '9'
+	letter := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+	switch m {
+	case '9':
+		return digit
+	case 'a':
+		return letter
+	case '*':
+		return digit || letter
+	}
+	return false
+}
+
+// maskIsPh tells if the mask character m is a placeholder.
+func maskIsPh(m byte) bool {
+	return m == '9' || m == 'a' || m == '*'
+}
+
+// maskFilter returns the characters of the raw value that fit
+// the placeholders of the mask in order, dropping the rest
+// and the characters exceeding the number of placeholders.
+func maskFilter(mask, value string) string {
+	raw := make([]rune, 0, len(value))
+	j := 0
+	for _, r := range value {
+		for j < len(mask) && !maskIsPh(mask[j]) {
+			j++
+		}
+		if j >= len(mask) {
+			break
+		}
+		if maskFits(mask[j], r) {
+			raw = append(raw, r)
+			j++
+		}
+	}
+	return string(raw)
+}
+
+// maskApply applies the mask to the raw value: inserts the literals
+// of the mask up to the last placeholder filled by the raw value.
+// The raw value is expected to be filtered by maskFilter().
+func maskApply(mask, raw string) string {
+	if raw == "" {
+		return ""
+	}
+	rs := []rune(raw)
+	s := make([]byte, 0, len(mask))
+	j := 0
+	for i := 0; i < len(mask) && j < len(rs); i++ {
+		if maskIsPh(mask[i]) {
+			s = append(s, string(rs[j])...)
+			j++
+		} else {
+			s = append(s, mask[i])
+		}
+	}
+	return string(s)
+}
+
+func (c *maskedInputImpl) saveState() interface{} {
+	return c.value
+}
+
+func (c *maskedInputImpl) restoreState(data json.RawMessage) {
+	var value string
+	if json.Unmarshal(data, &value) == nil {
+		c.SetValue(value)
+	}
+}
+
+func (c *maskedInputImpl) preprocessEvent(event Event, r *http.Request) {
+	// Empty string is a valid value (the input is cleared),
+	// so we have to check whether it is supplied, not just whether its len() > 0
+	value := r.FormValue(paramCompValue)
+	if len(value) > 0 {
+		c.SetValue(value)
+	} else if values, present := r.Form[paramCompValue]; present && len(values) > 0 {
+		c.SetValue(values[0])
+	}
+}
+
+var (
+	strMaskedInputOp = []byte(`<input type="text" value="`) // `<input type="text" value="`
+	strMaskedInputJs = []byte("maskInput(this)")            // "maskInput(this)"
+)
+
+func (c *maskedInputImpl) Render(w Writer) {
+	value := c.MaskedValue()
+	c.renderPrintStatic(w, value)
+
+	w.Write(strMaskedInputOp)
+	w.Writees(value)
+	w.Write(strQuote)
+	writeEscAttr(w, "data-gwumask", c.mask)
+	c.renderAttrsAndStyle(w)
+	c.renderEnabled(w)
+	c.renderEHandlersInput(w, strMaskedInputJs)
+	w.Write(strInputCl2)
+}
@@ -0,0 +1,84 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMaskApply(t *testing.T) {
+	const phone = "(999) 999-9999"
+	cases := []struct {
+		mask, value, raw, masked string
+	}{
+		{phone, "", "", ""},
+		{phone, "5", "5", "(5"},
+		{phone, "555123", "555123", "(555) 123"},
+		{phone, "5551234567", "5551234567", "(555) 123-4567"},
+		{phone, "55512345678", "5551234567", "(555) 123-4567"},
+		{phone, "(555) 12x3", "555123", "(555) 123"},
+		{"aa-**", "ab1c2", "ab1c", "ab-1c"},
+		{"aa-**", "12ab", "ab", "ab"},
+	}
+	for _, c := range cases {
+		raw := maskFilter(c.mask, c.value)
+		if raw != c.raw {
+			t.Errorf("maskFilter(%q, %q) = %q, want %q", c.mask, c.value, raw, c.raw)
+		}
+		if masked := maskApply(c.mask, raw); masked != c.masked {
+			t.Errorf("maskApply(%q, %q) = %q, want %q", c.mask, raw, masked, c.masked)
+		}
+	}
+}
+
+func TestMaskedInput(t *testing.T) {
+	mi := NewMaskedInput("(999) 999-9999")
+	mi.SetValue("5551234567")
+	if got := mi.MaskedValue(); got != "(555) 123-4567" {
+		t.Errorf("Got masked value %q", got)
+	}
+
+	s := renderString(mi)
+	for _, want := range []string{`value="(555) 123-4567"`, `data-gwumask="(999) 999-9999"`,
+		`oninput="maskInput(this)"`, "encodeURIComponent(maskRaw(this))"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Rendered %s does not contain %s", s, want)
+		}
+	}
+
+	// Masking runs before sending input events
+	mi.AddEHandlerFunc(func(e Event) {}, ETypeInput)
+	if s := renderString(mi); strings.Count(s, "oninput=") != 1 || !strings.Contains(s, `oninput="maskInput(this);se(event,`) {
+		t.Errorf("Input handler not rendered after masking: %s", s)
+	}
+
+	// The raw value is sent by the client, it is filtered by the mask
+	mi.(*maskedInputImpl).preprocessEvent(newEventImpl(ETypeChange, mi, nil, nil), newCompValueReq("555x9876543210"))
+	if got := mi.Value(); got != "5559876543" {
+		t.Errorf("Got value %q, want %q", got, "5559876543")
+	}
+	mi.(*maskedInputImpl).preprocessEvent(newEventImpl(ETypeChange, mi, nil, nil), newCompValueReq(""))
+	if got := mi.Value(); got != "" {
+		t.Errorf("Got value %q, want empty", got)
+	}
+
+	mi.SetValue("5551234567")
+	mi.SetMask("999-999")
+	if got, want := mi.MaskedValue(), "555-123"; got != want {
+		t.Errorf("Got masked value %q, want %q", got, want)
+	}
+}
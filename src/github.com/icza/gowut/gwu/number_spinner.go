@@ -0,0 +1,195 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// NumberSpinner component interface and implementation.
+
+package gwu
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// NumberSpinner interface defines a component which holds an int value
+// that can be decremented and incremented by the step using "-" and "+"
+// buttons. This is useful on touch devices where typing is awkward.
+//
+// The value is held and validated at the server side: a button click sends
+// an event to the server which changes the value by the step, clamped
+// to the [min..max] range. The buttons are disabled when the value
+// reaches the bounds.
+//
+// You can register ETypeStateChange event handlers which will be called when
+// the user changes the value by clicking on the buttons. The NumberSpinner
+// is marked dirty automatically.
+//
+// Default style classes: "gwu-NumberSpinner", "gwu-NumberSpinner-Button",
+// "gwu-NumberSpinner-Value"
+type NumberSpinner interface {
+	// NumberSpinner is a component.
+	Comp
+
+	// NumberSpinner can be enabled/disabled.
+	HasEnabled
+
+	// Value returns the value.
+	Value() int
+
+	// SetValue sets the value, clamped to the [min..max] range.
+	SetValue(value int)
+
+	// Min returns the minimum value.
+	Min() int
+
+	// Max returns the maximum value.
+	Max() int
+
+	// SetRange sets the minimum and maximum values.
+	// If min > max, they are swapped.
+	// The value is clamped to the new range.
+	SetRange(min, max int)
+
+	// Step returns the step by which the value is changed.
+	Step() int
+
+	// SetStep sets the step by which the value is changed.
+	// Step must be positive, else it is set to 1.
+	SetStep(step int)
+}
+
+// NumberSpinner implementation.
+type numberSpinnerImpl struct {
+	compImpl       // Component implementation
+	hasEnabledImpl // Has enabled implementation
+
+	value    int // The value
+	min, max int // Min and max values
+	step     int // Step by which the value is changed
+}
+
+// NewNumberSpinner creates a new NumberSpinner.
+// The step is 1.
+func NewNumberSpinner(value, min, max int) NumberSpinner {
+	c := &numberSpinnerImpl{compImpl: newCompImpl(nil), hasEnabledImpl: newHasEnabledImpl(), step: 1}
+	c.SetRange(min, max)
+	c.SetValue(value)
+	c.Style().AddClass("gwu-NumberSpinner")
+	return c
+}
+
+func (c *numberSpinnerImpl) Value() int {
+	return c.value
+}
+
+func (c *numberSpinnerImpl) SetValue(value int) {
+	if value < c.min {
+		value = c.min
+	} else if value > c.max {
+		value = c.max
+	}
+	c.value = value
+}
+
+func (c *numberSpinnerImpl) Min() int {
+	return c.min
+}
+
+func (c *numberSpinnerImpl) Max() int {
+	return c.max
+}
+
+func (c *numberSpinnerImpl) SetRange(min, max int) {
+	if min > max {
+		min, max = max, min
+	}
+	c.min, c.max = min, max
+	c.SetValue(c.value)
+}
+
+func (c *numberSpinnerImpl) Step() int {
+	return c.step
+}
+
+func (c *numberSpinnerImpl) SetStep(step int) {
+	if step <= 0 {
+		step = 1
+	}
+	c.step = step
+}
+
+func (c *numberSpinnerImpl) saveState() interface{} {
+	return c.value
+}
+
+func (c *numberSpinnerImpl) restoreState(data json.RawMessage) {
+	var value int
+	if json.Unmarshal(data, &value) == nil {
+		c.SetValue(value)
+	}
+}
+
+func (c *numberSpinnerImpl) preprocessEvent(event Event, r *http.Request) {
+	if event.Type() != ETypeStateChange || !c.enabled {
+		return
+	}
+
+	// The buttons send the direction only (-1 or 1), the step is applied here.
+	switch r.FormValue(paramCompValue) {
+	case "-1":
+		c.SetValue(c.value - c.step)
+	case "1":
+		c.SetValue(c.value + c.step)
+	default:
+		return
+	}
+	event.MarkDirty(c)
+}
+
+var (
+	strNumSpinBtnOp   = []byte(`<button type="button" class="gwu-NumberSpinner-Button" onclick="se(event,`) // `<button type="button" class="gwu-NumberSpinner-Button" onclick="se(event,`
+	strNumSpinBtnCl   = []byte(`);event.stopPropagation()"`)                                                // `);event.stopPropagation()"`
+	strNumSpinValueOp = []byte(`<span class="gwu-NumberSpinner-Value">`)                                    // `<span class="gwu-NumberSpinner-Value">`
+)
+
+func (c *numberSpinnerImpl) Render(w Writer) {
+	w.Write(strSpanOp)
+	c.renderAttrsAndStyle(w)
+	c.renderEHandlers(w)
+	w.Write(strGT)
+
+	c.renderButton(w, -1, "-", c.value <= c.min)
+
+	w.Write(strNumSpinValueOp)
+	w.Writes(FormatNumber(writerLocale(w), float64(c.value), 0))
+	w.Write(strSpanCl)
+
+	c.renderButton(w, 1, "+", c.value >= c.max)
+
+	w.Write(strSpanCl)
+}
+
+// renderButton renders a button which changes the value in the specified direction.
+func (c *numberSpinnerImpl) renderButton(w Writer, dir int, text string, atBound bool) {
+	// To render: <button type="button" class="gwu-NumberSpinner-Button" onclick="se(event,etype,compId,dir);event.stopPropagation()">text</button>
+	w.Write(strNumSpinBtnOp)
+	w.Writevs(int(ETypeStateChange), strComma, int(c.id), strComma, dir)
+	w.Write(strNumSpinBtnCl)
+	if atBound || !c.enabled {
+		w.Write(strDisabled)
+	}
+	w.Write(strGT)
+	w.Writes(text)
+	w.Write(strButtonCl)
+}
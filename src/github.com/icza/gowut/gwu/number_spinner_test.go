@@ -0,0 +1,73 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strings"
+	"testing"
+)
+
+// spin sends a button click event to the number spinner in the specified direction.
+func spin(ns NumberSpinner, dir string) *eventImpl {
+	e := newEventImpl(ETypeStateChange, ns, nil, nil)
+	ns.preprocessEvent(e, newCompValueReq(dir))
+	return e
+}
+
+func TestNumberSpinnerBounds(t *testing.T) {
+	ns := NewNumberSpinner(8, 0, 10)
+	ns.SetStep(3)
+
+	steps := []struct {
+		dir   string
+		value int
+	}{
+		{"1", 10}, // 8+3 clamped to max
+		{"1", 10}, // Stays at max
+		{"-1", 7},
+		{"-1", 4},
+		{"-1", 1},
+		{"-1", 0},  // 1-3 clamped to min
+		{"-1", 0},  // Stays at min
+		{"5", 0},   // Invalid direction is ignored
+		{"abc", 0}, // Invalid direction is ignored
+	}
+	for i, s := range steps {
+		e := spin(ns, s.dir)
+		if ns.Value() != s.value {
+			t.Errorf("Step %d: got value %d, want %d", i, ns.Value(), s.value)
+		}
+		if dirty := e.shared.dirty(ns); dirty != (s.dir == "1" || s.dir == "-1") {
+			t.Errorf("Step %d: dirty = %v", i, dirty)
+		}
+	}
+}
+
+func TestNumberSpinnerRender(t *testing.T) {
+	ns := NewNumberSpinner(10, 0, 10)
+	id := ns.Id().String()
+
+	s := renderString(ns)
+	for _, want := range []string{
+		`onclick="se(event,` + ETypeStateChange.String() + `,` + id + `,-1);event.stopPropagation()">-</button>`,
+		`<span class="gwu-NumberSpinner-Value">10</span>`,
+		`onclick="se(event,` + ETypeStateChange.String() + `,` + id + `,1);event.stopPropagation()" disabled="disabled">+</button>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Rendered spinner %s does not contain %s", s, want)
+		}
+	}
+}
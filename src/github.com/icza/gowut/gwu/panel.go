@@ -75,6 +75,18 @@ type Panel interface {
 	// Add adds a component to the panel.
 	Add(c Comp)
 
+	// AddIf adds a component to the panel which is only rendered
+	// if the specified predicate returns true. The predicate is evaluated
+	// each time the panel is rendered (or re-rendered), so toggling
+	// the condition and marking the panel dirty shows or hides the component
+	// without having to remove and re-add it.
+	// Useful for feature flags or permission-gated UI.
+	//
+	// AddIf is not part of the Container interface: other containers
+	// place their children at explicit positions (e.g. table cells or tabs);
+	// wrap the child in a Panel to render it conditionally there.
+	AddIf(predicate func() bool, c Comp)
+
 	// Insert inserts a component at the specified index.
 	// Returns true if the index was valid and the component is inserted
 	// successfully, false otherwise. idx=CompsCount() is also allowed
@@ -112,6 +124,7 @@ type panelImpl struct {
 	layout   Layout              // Layout strategy
 	comps    []Comp              // Components added to this panel
 	cellFmts map[ID]*cellFmtImpl // Lazily initialized cell formatters of the child components
+	renderIf map[ID]func() bool  // Lazily initialized render predicates of the child components
 }
 
 // NewPanel creates a new Panel.
@@ -163,10 +176,13 @@ func (c *panelImpl) Remove(c2 Comp) bool {
 		return false
 	}
 
-	// Remove associated cell formatter
+	// Remove associated cell formatter and render predicate
 	if c.cellFmts != nil {
 		delete(c.cellFmts, c2.Id())
 	}
+	if c.renderIf != nil {
+		delete(c.renderIf, c2.Id())
+	}
 
 	c2.setParent(nil)
 	// When removing, also reference must be cleared to allow the comp being gc'ed, also to prevent memory leak.
@@ -198,11 +214,17 @@ func (c *panelImpl) ById(id ID) Comp {
 	return nil
 }
 
+func (c *panelImpl) children() []Comp {
+	return c.comps
+}
+
 func (c *panelImpl) Clear() {
 	// Clear cell formatters
 	if c.cellFmts != nil {
 		c.cellFmts = nil
 	}
+	// Clear render predicates
+	c.renderIf = nil
 
 	for _, c2 := range c.comps {
 		c2.setParent(nil)
@@ -261,6 +283,22 @@ func (c *panelImpl) Add(c2 Comp) {
 	c2.setParent(c)
 }
 
+func (c *panelImpl) AddIf(predicate func() bool, c2 Comp) {
+	c.Add(c2)
+
+	if c.renderIf == nil {
+		c.renderIf = make(map[ID]func() bool)
+	}
+	c.renderIf[c2.Id()] = predicate
+}
+
+// rendered tells if the specified child component is to be rendered:
+// if it has no render predicate or its render predicate returns true.
+func (c *panelImpl) rendered(c2 Comp) bool {
+	predicate := c.renderIf[c2.Id()]
+	return predicate == nil || predicate()
+}
+
 func (c *panelImpl) Insert(c2 Comp, idx int) bool {
 	if idx < 0 || idx > len(c.comps) {
 		return false
@@ -334,7 +372,9 @@ func (c *panelImpl) layoutNatural(w Writer) {
 	w.Write(strGT)
 
 	for _, c2 := range c.comps {
-		c2.Render(w)
+		if c.rendered(c2) {
+			renderComp(w, c2)
+		}
 	}
 
 	w.Write(strSpanCl)
@@ -348,14 +388,23 @@ func (c *panelImpl) layoutHorizontal(w Writer) {
 	c.renderEHandlers(w)
 	w.Write(strGT)
 
+	c.renderCellsHorizontal(w)
+
+	w.Write(strTableCl)
+}
+
+// renderCellsHorizontal renders the cells and the child components
+// of the horizontal layout (without the wrapper table tag).
+func (c *panelImpl) renderCellsHorizontal(w Writer) {
 	c.renderTr(w)
 
 	for _, c2 := range c.comps {
+		if !c.rendered(c2) {
+			continue
+		}
 		c.renderTd(c2, w)
-		c2.Render(w)
+		renderComp(w, c2)
 	}
-
-	w.Write(strTableCl)
 }
 
 // layoutVertical renders the panel and the child components
@@ -366,18 +415,27 @@ func (c *panelImpl) layoutVertical(w Writer) {
 	c.renderEHandlers(w)
 	w.Write(strGT)
 
+	c.renderCellsVertical(w)
+
+	w.Write(strTableCl)
+}
+
+// renderCellsVertical renders the cells and the child components
+// of the vertical layout (without the wrapper table tag).
+func (c *panelImpl) renderCellsVertical(w Writer) {
 	// There is the same TR tag for each cell:
 	trWriter := bytes.NewBuffer(nil)
 	c.renderTr(NewWriter(trWriter))
 	tr := trWriter.Bytes()
 
 	for _, c2 := range c.comps {
+		if !c.rendered(c2) {
+			continue
+		}
 		w.Write(tr)
 		c.renderTd(c2, w)
-		c2.Render(w)
+		renderComp(w, c2)
 	}
-
-	w.Write(strTableCl)
 }
 
 // renderTd renders the formatted HTML TD tag for the specified child component.
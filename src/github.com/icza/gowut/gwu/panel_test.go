@@ -0,0 +1,52 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPanelAddIf(t *testing.T) {
+	for _, layout := range []Layout{LayoutNatural, LayoutHorizontal, LayoutVertical} {
+		p := NewPanel()
+		p.SetLayout(layout)
+		admin := false
+		l := NewLabel("Admin settings")
+		p.AddIf(func() bool { return admin }, l)
+		p.Add(NewLabel("Public"))
+
+		if s := renderString(p); strings.Contains(s, "Admin settings") || !strings.Contains(s, "Public") {
+			t.Errorf("Layout %d: child rendered with false predicate: %s", layout, s)
+		}
+
+		admin = true
+		if s := renderString(p); !strings.Contains(s, "Admin settings") {
+			t.Errorf("Layout %d: child not rendered with true predicate: %s", layout, s)
+		}
+
+		admin = false
+		if s := renderString(p); strings.Contains(s, "Admin settings") {
+			t.Errorf("Layout %d: child rendered after predicate toggled back: %s", layout, s)
+		}
+
+		// Re-adding unconditionally drops the predicate
+		p.Add(l)
+		if s := renderString(p); !strings.Contains(s, "Admin settings") {
+			t.Errorf("Layout %d: predicate kept after re-adding: %s", layout, s)
+		}
+	}
+}
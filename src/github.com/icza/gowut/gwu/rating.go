@@ -0,0 +1,187 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Rating component interface and implementation.
+
+package gwu
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// Rating interface defines a star rating input component:
+// a row of clickable stars. Hovering over the stars previews
+// the rating, clicking on a star chooses the rating and sends an
+// ETypeChange event; the Rating is marked dirty automatically.
+//
+// Optionally half stars can be chosen too (by clicking on the left
+// half of a star).
+//
+// Suggested event type to handle changes: ETypeChange
+//
+// Default style classes: "gwu-Rating", "gwu-Rating-Star",
+// "gwu-Rating-Full", "gwu-Rating-Half", "gwu-Rating-Empty"
+type Rating interface {
+	// Rating is a component.
+	Comp
+
+	// Rating can be enabled/disabled.
+	HasEnabled
+
+	// Max returns the number of stars (the max rating).
+	Max() int
+
+	// Value returns the rating.
+	// 0 is returned if there is no rating chosen.
+	Value() float64
+
+	// SetValue sets the rating.
+	// The value is rounded to the nearest (half) star,
+	// and is clamped between 0 and Max().
+	SetValue(value float64)
+
+	// HalfStars tells if half stars are allowed.
+	HalfStars() bool
+
+	// SetHalfStars sets whether half stars are allowed.
+	// If half stars are disallowed, the current value is
+	// rounded to the nearest star.
+	SetHalfStars(halfStars bool)
+}
+
+// Rating implementation.
+type ratingImpl struct {
+	compImpl       // Component implementation
+	hasEnabledImpl // Has enabled implementation
+
+	max       int     // Number of stars
+	value     float64 // The rating
+	halfStars bool    // Tells if half stars are allowed
+}
+
+// NewRating creates a new Rating with the specified number of stars.
+// The initial rating is 0.
+func NewRating(max int) Rating {
+	if max < 1 {
+		max = 1
+	}
+	c := &ratingImpl{compImpl: newCompImpl(nil), hasEnabledImpl: newHasEnabledImpl(), max: max}
+	c.Style().AddClass("gwu-Rating")
+	return c
+}
+
+func (c *ratingImpl) Max() int {
+	return c.max
+}
+
+func (c *ratingImpl) Value() float64 {
+	return c.value
+}
+
+func (c *ratingImpl) SetValue(value float64) {
+	if math.IsNaN(value) {
+		return
+	}
+	if c.halfStars {
+		value = math.Round(value*2) / 2
+	} else {
+		value = math.Round(value)
+	}
+	c.value = math.Max(0, math.Min(value, float64(c.max)))
+}
+
+func (c *ratingImpl) HalfStars() bool {
+	return c.halfStars
+}
+
+func (c *ratingImpl) SetHalfStars(halfStars bool) {
+	c.halfStars = halfStars
+	c.SetValue(c.value)
+}
+
+func (c *ratingImpl) saveState() interface{} {
+	return c.value
+}
+
+func (c *ratingImpl) restoreState(data json.RawMessage) {
+	var value float64
+	if json.Unmarshal(data, &value) == nil {
+		c.SetValue(value)
+	}
+}
+
+func (c *ratingImpl) preprocessEvent(event Event, r *http.Request) {
+	if event.Type() != ETypeChange || !c.enabled {
+		return
+	}
+
+	value, err := strconv.ParseFloat(r.FormValue(paramCompValue), 64)
+	if err != nil || math.IsNaN(value) {
+		return
+	}
+	c.SetValue(value)
+	event.MarkDirty(c)
+}
+
+var (
+	strRatingHalf   = []byte(` data-gwuhalf="true"`)                                                               // ` data-gwuhalf="true"`
+	strRatingValue  = []byte(` data-gwuvalue="`)                                                                   // ` data-gwuvalue="`
+	strRatingLeave  = []byte(` onmouseleave="ratingHover(null,this)"`)                                             // ` onmouseleave="ratingHover(null,this)"`
+	strRatingFull   = []byte(`<span class="gwu-Rating-Star gwu-Rating-Full"`)                                      // `<span class="gwu-Rating-Star gwu-Rating-Full"`
+	strRatingHalfSt = []byte(`<span class="gwu-Rating-Star gwu-Rating-Half"`)                                      // `<span class="gwu-Rating-Star gwu-Rating-Half"`
+	strRatingEmpty  = []byte(`<span class="gwu-Rating-Star gwu-Rating-Empty"`)                                     // `<span class="gwu-Rating-Star gwu-Rating-Empty"`
+	strRatingStarEv = []byte(` onmousemove="ratingHover(event,this.parentNode)" onclick="ratingClick(event,this,`) // ` onmousemove="ratingHover(event,this.parentNode)" onclick="ratingClick(event,this,`
+	strRatingStar   = []byte(">&#9733;</span>")                                                                    // ">&#9733;</span>"
+)
+
+func (c *ratingImpl) Render(w Writer) {
+	// To render: <span id="compId" class="gwu-Rating" data-gwuvalue="value" onmouseleave="ratingHover(null,this)">stars</span>
+	w.Write(strSpanOp)
+	c.renderAttrsAndStyle(w)
+	c.renderEHandlers(w)
+	if c.halfStars {
+		w.Write(strRatingHalf)
+	}
+	w.Write(strRatingValue)
+	w.Writes(strconv.FormatFloat(c.value, 'f', -1, 64))
+	w.Write(strQuote)
+	if c.enabled {
+		w.Write(strRatingLeave)
+	}
+	w.Write(strGT)
+
+	for i := 1; i <= c.max; i++ {
+		// To render: <span class="gwu-Rating-Star gwu-Rating-Full" onmousemove="ratingHover(event,this.parentNode)" onclick="ratingClick(event,this,etype)">&#9733;</span>
+		switch v := float64(i); {
+		case v <= c.value:
+			w.Write(strRatingFull)
+		case v-0.5 <= c.value:
+			w.Write(strRatingHalfSt)
+		default:
+			w.Write(strRatingEmpty)
+		}
+		if c.enabled {
+			w.Write(strRatingStarEv)
+			w.Writev(int(ETypeChange))
+			w.Write(strSeSuffix)
+		}
+		w.Write(strRatingStar)
+	}
+
+	w.Write(strSpanCl)
+}
@@ -0,0 +1,104 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRatingValue(t *testing.T) {
+	r := NewRating(5)
+	for _, c := range []struct {
+		half       bool
+		set, value float64
+	}{
+		{false, 3, 3}, {false, 3.4, 3}, {false, 3.5, 4}, {false, 9, 5}, {false, -1, 0},
+		{true, 3.5, 3.5}, {true, 3.3, 3.5}, {true, 3.2, 3}, {true, 5.5, 5},
+	} {
+		r.SetHalfStars(c.half)
+		r.SetValue(c.set)
+		if v := r.Value(); v != c.value {
+			t.Errorf("Half: %v, set %v: got %v, want %v", c.half, c.set, v, c.value)
+		}
+	}
+
+	r.SetValue(2.5)
+	r.SetHalfStars(false)
+	if v := r.Value(); v != 3 {
+		t.Errorf("Value not rounded when disabling half stars: %v", v)
+	}
+}
+
+func TestRatingClick(t *testing.T) {
+	r := NewRating(5)
+
+	steps := []struct {
+		half  bool
+		value string
+		want  float64
+		dirty bool
+	}{
+		{false, "4", 4, true},
+		{false, "2.5", 3, true}, // Half stars not allowed
+		{true, "2.5", 2.5, true},
+		{true, "7", 5, true},    // Clamped
+		{true, "x", 5, false},   // Malformed
+		{true, "NaN", 5, false}, // Malformed
+		{false, "", 5, false},   // Missing
+	}
+	for i, s := range steps {
+		r.SetHalfStars(s.half)
+		e := newEventImpl(ETypeChange, r, nil, nil)
+		r.preprocessEvent(e, newCompValueReq(s.value))
+		if v := r.Value(); v != s.want {
+			t.Errorf("Step %d: got value %v, want %v", i, v, s.want)
+		}
+		if dirty := e.shared.dirty(r); dirty != s.dirty {
+			t.Errorf("Step %d: dirty = %v", i, dirty)
+		}
+	}
+
+	r.SetEnabled(false)
+	r.preprocessEvent(newEventImpl(ETypeChange, r, nil, nil), newCompValueReq("1"))
+	if v := r.Value(); v != 5 {
+		t.Errorf("Disabled rating changed value to %v", v)
+	}
+}
+
+func TestRatingRender(t *testing.T) {
+	r := NewRating(3)
+	r.SetHalfStars(true)
+	r.SetValue(1.5)
+	et := ETypeChange.String()
+
+	s := renderString(r)
+	for _, want := range []string{
+		` data-gwuhalf="true" data-gwuvalue="1.5" onmouseleave="ratingHover(null,this)">`,
+		`<span class="gwu-Rating-Star gwu-Rating-Full" onmousemove="ratingHover(event,this.parentNode)" onclick="ratingClick(event,this,` + et + `)">`,
+		`<span class="gwu-Rating-Star gwu-Rating-Half" onmousemove`,
+		`<span class="gwu-Rating-Star gwu-Rating-Empty" onmousemove`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Rendered output does not contain %s: %s", want, s)
+		}
+	}
+
+	r.SetEnabled(false)
+	if s = renderString(r); strings.Contains(s, "onclick") || strings.Contains(s, "onmouse") {
+		t.Errorf("Disabled rating rendered with event handlers: %s", s)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// RichText component interface and implementation.
+
+package gwu
+
+import (
+	"net/http"
+)
+
+// RichText interface defines a rich text editor component:
+// a content editable area with a toolbar for basic formatting
+// (bold, italic and link).
+//
+// The edited HTML text is sent to the server when the editor loses
+// the focus (if it was changed). HTML text coming from the client is
+// untrusted, so it is passed through the sanitizer of the component
+// before it is stored (see HtmlSanitizer). If the sanitizer alters the
+// HTML text, the component is re-rendered to show the sanitized content.
+//
+// Suggested event type to handle changes: ETypeChange
+//
+// Default style classes: "gwu-RichText", "gwu-RichText-Toolbar",
+// "gwu-RichText-Button", "gwu-RichText-Editor"
+type RichText interface {
+	// RichText is a component.
+	Comp
+
+	// RichText can be enabled/disabled.
+	HasEnabled
+
+	// Html returns the (sanitized) HTML text of the editor.
+	Html() string
+
+	// SetHtml sets the HTML text of the editor.
+	// The HTML text set from the server side is trusted,
+	// it is rendered as is (it is not sanitized).
+	SetHtml(html string)
+
+	// Sanitizer returns the sanitizer used to sanitize
+	// the HTML text coming from the client.
+	Sanitizer() HtmlSanitizer
+
+	// SetSanitizer sets the sanitizer used to sanitize
+	// the HTML text coming from the client.
+	// Passing nil restores the default sanitizer.
+	SetSanitizer(sanitizer HtmlSanitizer)
+}
+
+// RichText implementation.
+type richTextImpl struct {
+	compImpl       // Component implementation
+	hasEnabledImpl // Has enabled implementation
+
+	html      string        // HTML text of the editor
+	sanitizer HtmlSanitizer // Sanitizer of the HTML text coming from the client
+}
+
+// defaultSanitizer is the default HtmlSanitizer, using DefaultAllowedTags.
+var defaultSanitizer = NewHtmlSanitizer(DefaultAllowedTags)
+
+// NewRichText creates a new RichText.
+// The HTML text is rendered as is (it is not sanitized).
+func NewRichText(html string) RichText {
+	c := &richTextImpl{compImpl: newCompImpl(nil), hasEnabledImpl: newHasEnabledImpl(),
+		html: html, sanitizer: defaultSanitizer}
+	c.Style().AddClass("gwu-RichText")
+	return c
+}
+
+func (c *richTextImpl) Html() string {
+	return c.html
+}
+
+func (c *richTextImpl) SetHtml(html string) {
+	c.html = html
+}
+
+func (c *richTextImpl) Sanitizer() HtmlSanitizer {
+	return c.sanitizer
+}
+
+func (c *richTextImpl) SetSanitizer(sanitizer HtmlSanitizer) {
+	if sanitizer == nil {
+		sanitizer = defaultSanitizer
+	}
+	c.sanitizer = sanitizer
+}
+
+func (c *richTextImpl) preprocessEvent(event Event, r *http.Request) {
+	if event.Type() != ETypeChange || !c.enabled {
+		return
+	}
+	values, ok := r.Form[paramCompValue]
+	if !ok || len(values) == 0 {
+		return
+	}
+	c.html = c.sanitizer.Sanitize(values[0])
+	// If the sanitizer altered the content, show the sanitized version
+	if c.html != values[0] {
+		event.MarkDirty(c)
+	}
+}
+
+var (
+	strRtToolbarOp = []byte(`<div class="gwu-RichText-Toolbar">`)                                                               // `<div class="gwu-RichText-Toolbar">`
+	strRtButtonOp  = []byte(`<button type="button" class="gwu-RichText-Button" onmousedown="event.preventDefault()" onclick="`) // `<button type="button" class="gwu-RichText-Button" onmousedown="event.preventDefault()" onclick="`
+	strRtBold      = []byte(`rtCmd(this,'bold')"`)                                                                              // `rtCmd(this,'bold')"`
+	strRtItalic    = []byte(`rtCmd(this,'italic')"`)                                                                            // `rtCmd(this,'italic')"`
+	strRtLink      = []byte(`rtLink(this)"`)                                                                                    // `rtLink(this)"`
+	strRtEditorOp  = []byte(`<div class="gwu-RichText-Editor" contenteditable="`)                                               // `<div class="gwu-RichText-Editor" contenteditable="`
+	strRtEditorEv  = []byte(`" onfocus="this._gwuHtml=this.innerHTML" onblur="rtChange(this,`)                                  // `" onfocus="this._gwuHtml=this.innerHTML" onblur="rtChange(this,`
+)
+
+func (c *richTextImpl) Render(w Writer) {
+	// To render: <span id="compId" class="gwu-RichText"><div class="gwu-RichText-Toolbar">buttons</div><div class="gwu-RichText-Editor" contenteditable="true" onfocus="..." onblur="rtChange(this,compId,etype)">html</div></span>
+	w.Write(strSpanOp)
+	c.renderAttrsAndStyle(w)
+	c.renderEHandlers(w)
+	w.Write(strGT)
+
+	w.Write(strRtToolbarOp)
+	c.renderButton(w, strRtBold, "<b>B</b>")
+	c.renderButton(w, strRtItalic, "<i>I</i>")
+	c.renderButton(w, strRtLink, "<u>Link</u>")
+	w.Write(strDivCl)
+
+	w.Write(strRtEditorOp)
+	w.Writev(c.enabled)
+	w.Write(strRtEditorEv)
+	w.Writevs(int(c.id), strComma, int(ETypeChange))
+	w.Write(strSeSuffix)
+	w.Write(strGT)
+	w.Writes(c.html)
+	w.Write(strDivCl)
+
+	w.Write(strSpanCl)
+}
+
+// renderButton renders a toolbar button with the specified onclick handler and HTML label.
+// The onmousedown handler prevents the editor from losing the focus (and the selection).
+func (c *richTextImpl) renderButton(w Writer, onclick []byte, label string) {
+	w.Write(strRtButtonOp)
+	w.Write(onclick)
+	c.renderEnabled(w)
+	w.Write(strGT)
+	w.Writes(label)
+	w.Write(strButtonCl)
+}
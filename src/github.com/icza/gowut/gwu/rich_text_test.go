@@ -0,0 +1,70 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRichTextRender(t *testing.T) {
+	rt := NewRichText("<b>x</b>")
+	id, et := rt.Id().String(), ETypeChange.String()
+
+	s := renderString(rt)
+	for _, want := range []string{
+		`<span id="` + id + `" class="gwu-RichText">`,
+		`onclick="rtCmd(this,'bold')">`,
+		`onclick="rtLink(this)">`,
+		`contenteditable="true" onfocus="this._gwuHtml=this.innerHTML" onblur="rtChange(this,` + id + `,` + et + `)"><b>x</b></div>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Rendered output does not contain %s: %s", want, s)
+		}
+	}
+
+	rt.SetEnabled(false)
+	s = renderString(rt)
+	if !strings.Contains(s, `contenteditable="false"`) || strings.Count(s, ` disabled="disabled"`) != 3 {
+		t.Errorf("Disabled rich text rendered editable: %s", s)
+	}
+}
+
+func TestRichTextChange(t *testing.T) {
+	rt := NewRichText("")
+
+	e := newEventImpl(ETypeChange, rt, nil, nil)
+	rt.preprocessEvent(e, newCompValueReq("<i>ok</i>"))
+	if rt.Html() != "<i>ok</i>" || e.shared.dirty(rt) {
+		t.Errorf("Clean HTML altered or marked dirty: %q", rt.Html())
+	}
+
+	e = newEventImpl(ETypeChange, rt, nil, nil)
+	rt.preprocessEvent(e, newCompValueReq(`x<script>alert(1)</script><img src=a onerror=b>`))
+	if rt.Html() != "x" || !e.shared.dirty(rt) {
+		t.Errorf("Unsafe HTML not sanitized or not marked dirty: %q", rt.Html())
+	}
+
+	rt.SetSanitizer(NewHtmlSanitizer(nil))
+	rt.preprocessEvent(newEventImpl(ETypeChange, rt, nil, nil), newCompValueReq("<b>y</b>"))
+	if rt.Html() != "y" {
+		t.Errorf("Custom sanitizer not used: %q", rt.Html())
+	}
+	rt.SetSanitizer(nil)
+	if rt.Sanitizer() != defaultSanitizer {
+		t.Error("SetSanitizer(nil) did not restore the default sanitizer")
+	}
+}
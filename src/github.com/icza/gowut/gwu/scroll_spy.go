@@ -0,0 +1,339 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// ScrollSpy component interface and implementation.
+
+package gwu
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ScrollSpy interface defines a container for long, documentation-style
+// pages: it displays its sections one below the other, with a navigation
+// (listing the section titles) on the side and an optional header on top.
+// Both the navigation and the header stick to the top of the viewport
+// while scrolling.
+//
+// As the user scrolls, the active section is highlighted in the navigation
+// at client side (scroll-spy). The active section is the last section
+// whose top is scrolled to (or above) the top of the viewport (below the
+// header) plus the offset; if the page is scrolled to the bottom, the last
+// section is the active one. Clicking on a section title in the navigation
+// scrolls to the section.
+//
+// If ETypeStateChange event handlers are registered, the client reports
+// changes of the active section to the server, and the handlers are called
+// with Active() already updated. The ScrollSpy is not marked dirty
+// (it is already up-to-date at client side).
+//
+// Default style classes: "gwu-ScrollSpy", "gwu-ScrollSpy-Header",
+// "gwu-ScrollSpy-Body", "gwu-ScrollSpy-Nav", "gwu-ScrollSpy-Link",
+// "gwu-ScrollSpy-Link-Active", "gwu-ScrollSpy-Content", "gwu-ScrollSpy-Section"
+type ScrollSpy interface {
+	// ScrollSpy is a container.
+	Container
+
+	// Header returns the header component, nil if there is none.
+	Header() Comp
+
+	// SetHeader sets the header component, rendered above the
+	// navigation and the sections. Pass nil to remove the header.
+	SetHeader(header Comp)
+
+	// AddSection adds a new section with the specified title and content.
+	AddSection(title string, content Comp)
+
+	// SectionCount returns the number of sections.
+	SectionCount() int
+
+	// SectionContent returns the content of the section specified by its index.
+	SectionContent(idx int) Comp
+
+	// Active returns the index of the active section.
+	// -1 is returned if there are no sections.
+	Active() int
+
+	// SetActive sets the active section (highlighted in the navigation when
+	// rendered). It does not scroll to the section. Invalid indices are ignored.
+	SetActive(idx int)
+
+	// Offset returns the offset in pixels added to the scroll position
+	// when computing the active section.
+	Offset() int
+
+	// SetOffset sets the offset in pixels added to the scroll position
+	// when computing the active section, so a section becomes active
+	// a little before its top reaches the top of the viewport.
+	// Default is 0.
+	SetOffset(offset int)
+}
+
+// A section of the ScrollSpy.
+type scrollSpySection struct {
+	title   string // Title of the section
+	content Comp   // Content of the section
+}
+
+// ScrollSpy implementation.
+type scrollSpyImpl struct {
+	compImpl // Component implementation
+
+	header   Comp               // Optional header
+	sections []scrollSpySection // Sections
+	active   int                // Index of the active section
+	offset   int                // Offset added to the scroll position
+}
+
+// NewScrollSpy creates a new ScrollSpy.
+func NewScrollSpy() ScrollSpy {
+	c := &scrollSpyImpl{compImpl: newCompImpl(nil), active: -1}
+	c.Style().AddClass("gwu-ScrollSpy")
+	return c
+}
+
+func (c *scrollSpyImpl) Remove(c2 Comp) bool {
+	if c.header != nil && c.header.Equals(c2) {
+		c2.setParent(nil)
+		c.header = nil
+		return true
+	}
+	for i, s := range c.sections {
+		if !s.content.Equals(c2) {
+			continue
+		}
+		c2.setParent(nil)
+		c.sections = append(c.sections[:i], c.sections[i+1:]...)
+		if i < c.active || c.active == len(c.sections) {
+			c.active--
+		}
+		return true
+	}
+	return false
+}
+
+func (c *scrollSpyImpl) ById(id ID) Comp {
+	if c.id == id {
+		return c
+	}
+
+	for _, c2 := range c.children() {
+		if c2.Id() == id {
+			return c2
+		}
+		if c3, isContainer := c2.(Container); isContainer {
+			if c4 := c3.ById(id); c4 != nil {
+				return c4
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *scrollSpyImpl) children() []Comp {
+	comps := make([]Comp, 0, len(c.sections)+1)
+	if c.header != nil {
+		comps = append(comps, c.header)
+	}
+	for _, s := range c.sections {
+		comps = append(comps, s.content)
+	}
+	return comps
+}
+
+func (c *scrollSpyImpl) Clear() {
+	c.SetHeader(nil)
+	for _, s := range c.sections {
+		s.content.setParent(nil)
+	}
+	c.sections = nil
+	c.active = -1
+}
+
+func (c *scrollSpyImpl) Header() Comp {
+	return c.header
+}
+
+func (c *scrollSpyImpl) SetHeader(header Comp) {
+	if c.header != nil {
+		c.header.setParent(nil)
+	}
+	if header != nil {
+		header.makeOrphan()
+		header.setParent(c)
+	}
+	c.header = header
+}
+
+func (c *scrollSpyImpl) AddSection(title string, content Comp) {
+	content.makeOrphan()
+	c.sections = append(c.sections, scrollSpySection{title: title, content: content})
+	content.setParent(c)
+	if c.active < 0 {
+		c.active = 0
+	}
+}
+
+func (c *scrollSpyImpl) SectionCount() int {
+	return len(c.sections)
+}
+
+func (c *scrollSpyImpl) SectionContent(idx int) Comp {
+	if idx < 0 || idx >= len(c.sections) {
+		return nil
+	}
+	return c.sections[idx].content
+}
+
+func (c *scrollSpyImpl) Active() int {
+	return c.active
+}
+
+func (c *scrollSpyImpl) SetActive(idx int) {
+	if idx < 0 || idx >= len(c.sections) {
+		return
+	}
+	c.active = idx
+}
+
+func (c *scrollSpyImpl) Offset() int {
+	return c.offset
+}
+
+func (c *scrollSpyImpl) SetOffset(offset int) {
+	c.offset = offset
+}
+
+// scrollSpyActive returns the index of the active section: the last section
+// whose top is at or above the scroll position plus the offset (the first
+// section if there is none such), or the last section if the page is
+// scrolled to the bottom. Tops are relative to the document, and are
+// already adjusted by the height of the sticky header.
+// This is the same computation spyActive() performs at client side.
+func scrollSpyActive(tops []int, scrollTop, offset int, bottom bool) int {
+	if len(tops) == 0 {
+		return -1
+	}
+	if bottom {
+		return len(tops) - 1
+	}
+	active := 0
+	for i := 1; i < len(tops); i++ {
+		if tops[i] <= scrollTop+offset {
+			active = i
+		}
+	}
+	return active
+}
+
+func (c *scrollSpyImpl) preprocessEvent(event Event, r *http.Request) {
+	if event.Type() != ETypeStateChange {
+		return
+	}
+
+	// Value: "scrollTop,bottom,top0,top1,..." where bottom is 1 if
+	// scrolled to the bottom, and topN is the top of the Nth section.
+	parts := strings.Split(r.FormValue(paramCompValue), ",")
+	if len(parts) < 2 {
+		return
+	}
+	nums := make([]int, len(parts))
+	for i, p := range parts {
+		var err error
+		if nums[i], err = strconv.Atoi(p); err != nil {
+			return
+		}
+	}
+	if len(nums)-2 != len(c.sections) {
+		return // Sections changed meanwhile
+	}
+	c.SetActive(scrollSpyActive(nums[2:], nums[0], c.offset, nums[1] == 1))
+}
+
+var (
+	strScrollSpyHeaderOp  = []byte(`<div class="gwu-ScrollSpy-Header" id="`)                                            // `<div class="gwu-ScrollSpy-Header" id="`
+	strScrollSpyBodyOp    = []byte(`<div class="gwu-ScrollSpy-Body"><div class="gwu-ScrollSpy-Nav" role="navigation">`) // `<div class="gwu-ScrollSpy-Body"><div class="gwu-ScrollSpy-Nav" role="navigation">`
+	strScrollSpyLinkOp    = []byte(`<button type="button" class="gwu-ScrollSpy-Link`)                                   // `<button type="button" class="gwu-ScrollSpy-Link`
+	strScrollSpyLinkAct   = []byte(` gwu-ScrollSpy-Link-Active" aria-current="location`)                                // ` gwu-ScrollSpy-Link-Active" aria-current="location`
+	strScrollSpyLinkOnclk = []byte(`" onclick="spyScroll(`)                                                             // `" onclick="spyScroll(`
+	strScrollSpyContentOp = []byte(`</div><div class="gwu-ScrollSpy-Content">`)                                         // `</div><div class="gwu-ScrollSpy-Content">`
+	strScrollSpySectionOp = []byte(`<div class="gwu-ScrollSpy-Section" id="`)                                           // `<div class="gwu-ScrollSpy-Section" id="`
+	strScrollSpyOffset    = []byte(` data-gwuspy="`)                                                                    // ` data-gwuspy="`
+	strScrollSpyActive    = []byte(` data-gwuspyact="`)                                                                 // ` data-gwuspyact="`
+	strScrollSpyEtype     = []byte(` data-gwuspyet="`)                                                                  // ` data-gwuspyet="`
+	strScrollSpyHdrId     = []byte(`_hdr">`)                                                                            // `_hdr">`
+	strScrollSpySecId     = []byte(`_s`)                                                                                // `_s`
+)
+
+func (c *scrollSpyImpl) Render(w Writer) {
+	w.Write(strDivOp)
+	c.renderAttrsAndStyle(w)
+	c.renderEHandlers(w)
+	w.Write(strScrollSpyOffset)
+	w.Writev(c.offset)
+	w.Write(strQuote)
+	w.Write(strScrollSpyActive)
+	w.Writev(c.active)
+	w.Write(strQuote)
+	if c.handlers[ETypeStateChange] != nil {
+		w.Write(strScrollSpyEtype)
+		w.Writev(int(ETypeStateChange))
+		w.Write(strQuote)
+	}
+	w.Write(strGT)
+
+	if c.header != nil {
+		w.Write(strScrollSpyHeaderOp)
+		w.Writev(int(c.id))
+		w.Write(strScrollSpyHdrId)
+		renderComp(w, c.header)
+		w.Write(strDivCl)
+	}
+
+	// Navigation
+	w.Write(strScrollSpyBodyOp)
+	for i, s := range c.sections {
+		// To render: <button type="button" class="gwu-ScrollSpy-Link" onclick="spyScroll(compId,i)">title</button>
+		w.Write(strScrollSpyLinkOp)
+		if i == c.active {
+			w.Write(strScrollSpyLinkAct)
+		}
+		w.Write(strScrollSpyLinkOnclk)
+		w.Writevs(int(c.id), strComma, i)
+		w.Write(strParenCl)
+		w.Write(strQuote)
+		w.Write(strGT)
+		w.Writees(s.title)
+		w.Write(strButtonCl)
+	}
+
+	// Sections
+	w.Write(strScrollSpyContentOp)
+	for i, s := range c.sections {
+		w.Write(strScrollSpySectionOp)
+		w.Writevs(int(c.id), strScrollSpySecId, i)
+		w.Write(strQuote)
+		w.Write(strGT)
+		renderComp(w, s.content)
+		w.Write(strDivCl)
+	}
+	w.Write(strDivCl)
+	w.Write(strDivCl)
+
+	w.Write(strDivCl)
+}
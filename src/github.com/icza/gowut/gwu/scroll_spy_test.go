@@ -0,0 +1,101 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScrollSpyActive(t *testing.T) {
+	tops := []int{100, 600, 1500}
+	cases := []struct {
+		scrollTop, offset int
+		bottom            bool
+		want              int
+	}{
+		{0, 0, false, 0},    // First section is active before it is reached
+		{599, 0, false, 0},  // Just before the second section
+		{600, 0, false, 1},  // Second section reached
+		{550, 50, false, 1}, // Reached with offset
+		{1499, 0, false, 1},
+		{2000, 0, false, 2},
+		{700, 0, true, 2}, // Scrolled to the bottom
+	}
+	for _, c := range cases {
+		if got := scrollSpyActive(tops, c.scrollTop, c.offset, c.bottom); got != c.want {
+			t.Errorf("scrollTop=%d, offset=%d, bottom=%v: got %d, want %d", c.scrollTop, c.offset, c.bottom, got, c.want)
+		}
+	}
+	if got := scrollSpyActive(nil, 0, 0, false); got != -1 {
+		t.Errorf("Got %d for no sections", got)
+	}
+}
+
+func TestScrollSpyEvent(t *testing.T) {
+	ss := NewScrollSpy()
+	ss.SetOffset(20)
+	ss.AddSection("Intro", NewLabel("i"))
+	ss.AddSection("Usage", NewLabel("u"))
+	ss.AddSection("FAQ", NewLabel("f"))
+
+	send := func(value string, want int) {
+		t.Helper()
+		ss.(*scrollSpyImpl).preprocessEvent(newEventImpl(ETypeStateChange, ss, nil, nil), newCompValueReq(value))
+		if got := ss.Active(); got != want {
+			t.Errorf("Value %q: got active %d, want %d", value, got, want)
+		}
+	}
+	send("400,0,0,420,900", 1)
+	send("0,1,0,420,900", 2)
+	send("399,0,0,420,900", 0)
+	send("400,0,0,420", 0) // Section count mismatch
+	send("x,0,0,420,900", 0)
+}
+
+func TestScrollSpyRender(t *testing.T) {
+	ss := NewScrollSpy()
+	ss.SetHeader(NewLabel("Docs"))
+	ss.AddSection("Intro", NewLabel("i"))
+	ss.AddSection("Usage <b>", NewLabel("u"))
+	ss.SetActive(1)
+	id := ss.Id().String()
+
+	s := renderString(ss)
+	for _, want := range []string{
+		` data-gwuspy="0" data-gwuspyact="1"`,
+		`<div class="gwu-ScrollSpy-Header" id="` + id + `_hdr">`,
+		`class="gwu-ScrollSpy-Link" onclick="spyScroll(` + id + `,0)">Intro</button>`,
+		`class="gwu-ScrollSpy-Link gwu-ScrollSpy-Link-Active" aria-current="location" onclick="spyScroll(` + id + `,1)">Usage &lt;b&gt;</button>`,
+		`<div class="gwu-ScrollSpy-Section" id="` + id + `_s1">`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Render does not contain %s: %s", want, s)
+		}
+	}
+	if strings.Contains(s, "data-gwuspyet") {
+		t.Error("Event type rendered without handlers")
+	}
+
+	ss.AddEHandlerFunc(func(e Event) {}, ETypeStateChange)
+	if s := renderString(ss); !strings.Contains(s, ` data-gwuspyet="`+ETypeStateChange.String()+`"`) {
+		t.Errorf("Event type not rendered: %s", s)
+	}
+
+	if !ss.Remove(ss.SectionContent(1)) || ss.Active() != 0 || ss.SectionCount() != 1 {
+		t.Errorf("Got active %d, sections %d after remove", ss.Active(), ss.SectionCount())
+	}
+}
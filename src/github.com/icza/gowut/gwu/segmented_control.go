@@ -0,0 +1,147 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// SegmentedControl component interface and implementation.
+
+package gwu
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// SegmentedControl interface defines a component for choosing one of a few
+// options, rendered as a row of buttons (segments) where at most one
+// segment is active (selected).
+//
+// Clicking on an inactive segment selects it and sends an ETypeChange event;
+// the SegmentedControl is marked dirty automatically.
+//
+// Suggested event type to handle changes: ETypeChange
+//
+// Default style classes: "gwu-SegmentedControl", "gwu-SegmentedControl-Segment",
+// "gwu-SegmentedControl-Active"
+type SegmentedControl interface {
+	// SegmentedControl is a component.
+	Comp
+
+	// SegmentedControl can be enabled/disabled.
+	HasEnabled
+
+	// Items returns the texts of the segments.
+	Items() []string
+
+	// SelectedIndex returns the index of the selected segment.
+	// -1 is returned if no segment is selected.
+	SelectedIndex() int
+
+	// SetSelectedIndex selects the segment specified by its index.
+	// Pass an index outside of the valid range (e.g. -1)
+	// to deselect the selected segment.
+	SetSelectedIndex(idx int)
+}
+
+// SegmentedControl implementation.
+type segmentedControlImpl struct {
+	compImpl       // Component implementation
+	hasEnabledImpl // Has enabled implementation
+
+	items    []string      // Texts of the segments
+	segments []RadioButton // States of the segments; exclusivity is managed by their RadioGroup
+	group    RadioGroup    // Group of the segments
+}
+
+// NewSegmentedControl creates a new SegmentedControl.
+// The first segment is selected initially (if there are any).
+func NewSegmentedControl(items []string) SegmentedControl {
+	c := &segmentedControlImpl{compImpl: newCompImpl(nil), hasEnabledImpl: newHasEnabledImpl(), items: items}
+	c.group = NewRadioGroup("")
+	c.segments = make([]RadioButton, len(items))
+	for i, item := range items {
+		c.segments[i] = NewRadioButton(item, c.group)
+	}
+	c.SetSelectedIndex(0)
+	c.Style().AddClass("gwu-SegmentedControl")
+	return c
+}
+
+func (c *segmentedControlImpl) Items() []string {
+	return c.items
+}
+
+func (c *segmentedControlImpl) SelectedIndex() int {
+	if sel := c.group.Selected(); sel != nil {
+		for i, s := range c.segments {
+			if s.Equals(sel) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func (c *segmentedControlImpl) SetSelectedIndex(idx int) {
+	if idx >= 0 && idx < len(c.segments) {
+		c.segments[idx].SetState(true)
+	} else if sel := c.group.Selected(); sel != nil {
+		sel.SetState(false)
+	}
+}
+
+func (c *segmentedControlImpl) preprocessEvent(event Event, r *http.Request) {
+	if event.Type() != ETypeChange || !c.enabled {
+		return
+	}
+
+	idx, err := strconv.Atoi(r.FormValue(paramCompValue))
+	if err != nil || idx < 0 || idx >= len(c.segments) {
+		return
+	}
+	c.SetSelectedIndex(idx)
+	event.MarkDirty(c)
+}
+
+var (
+	strSegmentOp       = []byte(`<button type="button" class="gwu-SegmentedControl-Segment`) // `<button type="button" class="gwu-SegmentedControl-Segment`
+	strSegmentActive   = []byte(` gwu-SegmentedControl-Active" aria-pressed="true"`)         // ` gwu-SegmentedControl-Active" aria-pressed="true"`
+	strSegmentInactive = []byte(`" aria-pressed="false" onclick="se(event,`)                 // `" aria-pressed="false" onclick="se(event,`
+	strSegmentClickCl  = []byte(`);event.stopPropagation()"`)                                // `);event.stopPropagation()"`
+)
+
+func (c *segmentedControlImpl) Render(w Writer) {
+	w.Write(strSpanOp)
+	c.renderAttrsAndStyle(w)
+	c.renderEHandlers(w)
+	w.Write(strGT)
+
+	sel := c.SelectedIndex()
+	for i, item := range c.items {
+		// To render: <button type="button" class="gwu-SegmentedControl-Segment" aria-pressed="false" onclick="se(event,etype,compId,idx);event.stopPropagation()">item</button>
+		w.Write(strSegmentOp)
+		if i == sel {
+			w.Write(strSegmentActive)
+		} else {
+			w.Write(strSegmentInactive)
+			w.Writevs(int(ETypeChange), strComma, int(c.id), strComma, i)
+			w.Write(strSegmentClickCl)
+		}
+		c.renderEnabled(w)
+		w.Write(strGT)
+		w.Writees(item)
+		w.Write(strButtonCl)
+	}
+
+	w.Write(strSpanCl)
+}
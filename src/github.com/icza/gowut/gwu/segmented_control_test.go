@@ -0,0 +1,88 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSegmentedControlSelection(t *testing.T) {
+	sc := NewSegmentedControl([]string{"Day", "Week", "Month"})
+	if idx := sc.SelectedIndex(); idx != 0 {
+		t.Errorf("Got initial selected index %d", idx)
+	}
+
+	for _, c := range []struct{ set, want int }{
+		{2, 2}, {1, 1}, {1, 1}, {-1, -1}, {0, 0}, {3, -1},
+	} {
+		sc.SetSelectedIndex(c.set)
+		if idx := sc.SelectedIndex(); idx != c.want {
+			t.Errorf("Set %d: got selected index %d, want %d", c.set, idx, c.want)
+		}
+	}
+
+	if idx := NewSegmentedControl(nil).SelectedIndex(); idx != -1 {
+		t.Errorf("Got selected index %d without items", idx)
+	}
+}
+
+func TestSegmentedControlChange(t *testing.T) {
+	sc := NewSegmentedControl([]string{"Day", "Week", "Month"})
+
+	steps := []struct {
+		value string
+		idx   int
+		dirty bool
+	}{
+		{"2", 2, true},
+		{"0", 0, true},
+		{"3", 0, false},  // Out of range
+		{"-1", 0, false}, // Out of range
+		{"x", 0, false},  // Malformed
+	}
+	for i, s := range steps {
+		e := newEventImpl(ETypeChange, sc, nil, nil)
+		sc.preprocessEvent(e, newCompValueReq(s.value))
+		if idx := sc.SelectedIndex(); idx != s.idx {
+			t.Errorf("Step %d: got selected index %d, want %d", i, idx, s.idx)
+		}
+		if dirty := e.shared.dirty(sc); dirty != s.dirty {
+			t.Errorf("Step %d: dirty = %v", i, dirty)
+		}
+	}
+
+	sc.SetEnabled(false)
+	sc.preprocessEvent(newEventImpl(ETypeChange, sc, nil, nil), newCompValueReq("1"))
+	if idx := sc.SelectedIndex(); idx != 0 {
+		t.Errorf("Disabled control changed selection to %d", idx)
+	}
+}
+
+func TestSegmentedControlRender(t *testing.T) {
+	sc := NewSegmentedControl([]string{"A", "B<"})
+	id := sc.Id().String()
+
+	s := renderString(sc)
+	for _, want := range []string{
+		`<button type="button" class="gwu-SegmentedControl-Segment gwu-SegmentedControl-Active" aria-pressed="true">A</button>`,
+		`<button type="button" class="gwu-SegmentedControl-Segment" aria-pressed="false" onclick="se(event,` + ETypeChange.String() + `,` + id + `,1);event.stopPropagation()">B&lt;</button>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Rendered output does not contain %s: %s", want, s)
+		}
+	}
+}
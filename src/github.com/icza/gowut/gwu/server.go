@@ -19,21 +19,32 @@
 package gwu
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 // Internal path constants.
 const (
-	pathStatic     = "_gwu_static/" // App path-relative path for GWU static contents.
-	pathSessCheck  = "_sess_ch"     // App path-relative path for checking session (without registering access)
-	pathEvent      = "e"            // Window-relative path for sending events
-	pathRenderComp = "rc"           // Window-relative path for rendering a component
+	pathStatic      = "_gwu_static/" // App path-relative path for GWU static contents.
+	pathSessCheck   = "_sess_ch"     // App path-relative path for checking session (without registering access)
+	pathEvent       = "e"            // Window-relative path for sending events
+	pathRenderComp  = "rc"           // Window-relative path for rendering a component
+	pathRenderComps = "rcs"          // Window-relative path for rendering multiple components
+	pathPush        = "push"         // Window-relative path for long-polling pushed updates
+	pathWebSocket   = "ws"           // Window-relative path for opening a WebSocket to send events
+	pathWsCookies   = "_gwu_wsck"    // App path-relative path for fetching cookies of events sent over WebSocket
 )
 
 // Parameters passed between the browser and the server.
@@ -49,16 +60,105 @@ const (
 	paramMouseBtn      = "mb"   // Mouse button
 	paramModKeys       = "mk"   // Modifier key states
 	paramKeyCode       = "kc"   // Key code
+	paramWheelDX       = "wdx"  // Horizontal wheel delta in pixels
+	paramWheelDY       = "wdy"  // Vertical wheel delta in pixels
+	paramBufChange     = "bc"   // Buffered change (of a component in buffered mode), multiple allowed
+	paramGeoLat        = "glat" // Geolocation latitude
+	paramGeoLong       = "glng" // Geolocation longitude
+	paramKeepAlive     = "ka"   // Keep-alive flag of session checks
+	paramRenderRev     = "rrv"  // Render revision of a component at client side (diff rendering)
+	paramWsCookies     = "ck"   // Token of the cookies of an event sent over WebSocket
+	paramPushSeq       = "psq"  // Sequence number of the last push received by the client
 )
 
+// Name of the response header holding the render revision of a
+// re-rendered component (diff rendering).
+const hdrRenderRev = "Gwu-Render-Rev"
+
+// Name of the response header holding the push sequence number of a push response.
+const hdrPushSeq = "Gwu-Push-Seq"
+
 // Event response actions (client actions to take after processing an event).
 const (
-	eraNoAction   = iota // Event processing OK and no action required
-	eraReloadWin         // Window name to be reloaded
-	eraDirtyComps        // There are dirty components which needs to be refreshed
-	eraFocusComp         // Focus a compnent
+	eraNoAction       = iota // Event processing OK and no action required
+	eraReloadWin             // Window name to be reloaded
+	eraDirtyComps            // There are dirty components which needs to be refreshed
+	eraFocusComp             // Focus a compnent
+	eraRedirect              // Navigate to a URL after a delay
+	eraCaptureComp           // Capture a component as an image and download it
+	eraRerenderWin           // Replace the window content with the rendered content sent along
+	eraSubmitForm            // Submit a form to an external URL
+	eraGeolocation           // Query the geolocation and send it in a follow-up event
+	eraLoadScript            // Load a script and send an event when loaded
+	eraPreventDefault        // Prevent the default action of the event
+)
+
+// UnknownRespMode is the type of the client behavior when it receives
+// an event response with an unknown action code. This may happen e.g.
+// during development when the client and server versions mismatch.
+type UnknownRespMode int
+
+// Unknown response code modes.
+const (
+	UnknownRespAuto   UnknownRespMode = iota // Alert in dev mode, log otherwise (this is the default)
+	UnknownRespAlert                         // Alert the unknown code
+	UnknownRespLog                           // Log the unknown code to the browser console
+	UnknownRespIgnore                        // Silently ignore the unknown code
 )
 
+// SecurityHeaders holds the values of the security related HTTP response
+// headers. Empty values are not sent.
+type SecurityHeaders struct {
+	ContentTypeOptions    string // Value of the X-Content-Type-Options header, e.g. "nosniff"
+	FrameOptions          string // Value of the X-Frame-Options header, e.g. "DENY" or "SAMEORIGIN"
+	ReferrerPolicy        string // Value of the Referrer-Policy header, e.g. "same-origin"
+	ContentSecurityPolicy string // Value of the Content-Security-Policy header, may contain NoncePlaceholder
+}
+
+// SessCookieConfig holds the attributes of the session cookie.
+type SessCookieConfig struct {
+	SameSite http.SameSite // SameSite attribute, http.SameSiteDefaultMode means no SameSite attribute
+	Secure   bool          // Secure attribute (only send it over HTTPS), always set in secure (HTTPS) mode
+	HttpOnly bool          // HttpOnly attribute (not accessible from JavaScript)
+}
+
+// NoncePlaceholder is the placeholder of the CSP nonce in the value of
+// SecurityHeaders.ContentSecurityPolicy, e.g. "script-src 'self' 'nonce-{nonce}'".
+// If the policy contains it, a random nonce is generated for each response,
+// it replaces the placeholder, and the script elements of window documents
+// are rendered with it. Scripts of re-rendered components are executed with
+// the nonce of the window document.
+//
+// Note that nonces only cover script elements. Components render their
+// event handlers as inline event handler attributes (e.g. onclick="..."),
+// which browsers block under a policy having a nonce (as 'unsafe-inline'
+// is ignored if a nonce is present), so event handlers do not work under
+// such a policy. The Gowut JavaScript does not evaluate code from strings,
+// so 'unsafe-eval' is not needed.
+const NoncePlaceholder = "{nonce}"
+
+// RenderFilter is a function which may rewrite the rendered HTML of a
+// component before it is sent to the client, e.g. to inject wrapper
+// elements or to rewrite class names. The returned HTML is sent.
+// html must not be modified after the filter returns.
+type RenderFilter func(c Comp, html []byte) []byte
+
+// DefaultSecurityHeaders is a recommended security header configuration.
+// Note that the Gowut JavaScript uses inline event handlers and scripts,
+// so the content security policy must allow inline scripts.
+// It does not evaluate code from strings (timers are set up with functions),
+// so 'unsafe-eval' is not needed.
+var DefaultSecurityHeaders = SecurityHeaders{
+	ContentTypeOptions:    "nosniff",
+	FrameOptions:          "SAMEORIGIN",
+	ReferrerPolicy:        "same-origin",
+	ContentSecurityPolicy: "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self' data:",
+}
+
+// Max duration of a long-polling push request after which
+// it is answered with no action (and the client sends a new one).
+var pushTimeout = 30 * time.Second
+
 // GWU session id cookie name
 const gwuSessidCookie = "gwu-sessid"
 
@@ -75,6 +175,23 @@ type SessionHandler interface {
 	Removed(sess Session)
 }
 
+// Middleware wraps the request handling of the GUI server, e.g. to perform
+// authorization checks, logging or measuring. It returns a handler which
+// should call the next handler to continue processing the request
+// (or respond by itself to stop it).
+type Middleware func(next http.Handler) http.Handler
+
+// sessCtxKey is the request context key of the session of the request.
+type sessCtxKey struct{}
+
+// RequestSession returns the session of a request processed by the GUI
+// server, to be used by session middlewares (see Server.AddSessMiddleware()).
+// nil is returned if the session is not yet resolved.
+func RequestSession(r *http.Request) Session {
+	sess, _ := r.Context().Value(sessCtxKey{}).(Session)
+	return sess
+}
+
 // Function type that handles the application root (when no window name is specified).
 // sess is the shared, public session if no private session is created.
 type AppRootHandlerFunc func(w http.ResponseWriter, r *http.Request, sess Session)
@@ -132,6 +249,37 @@ type Server interface {
 	// AddSHandler adds a new session handler.
 	AddSHandler(handler SessionHandler)
 
+	// AddMiddleware adds a middleware which wraps the handling of all
+	// requests of the app (window documents, events, component
+	// re-renders, session checks etc., but not static files), before
+	// the session of the request is resolved.
+	// Middlewares are called in the order they were added (the first
+	// added middleware is the outermost), before session middlewares
+	// (see AddSessMiddleware()).
+	AddMiddleware(mw Middleware)
+
+	// AddSessMiddleware adds a session middleware which wraps the handling
+	// of the same requests as middlewares (see AddMiddleware()), after the
+	// session of the request is resolved from the session cookie (and
+	// restored from the session store if needed): the session is available
+	// by RequestSession() (it is the public session if the request has no
+	// valid session cookie). Note that the request may still switch to the
+	// public session or create a new session when the requested window is
+	// looked up.
+	// Session middlewares are called in the order they were added, and
+	// without holding the lock of the session.
+	AddSessMiddleware(mw Middleware)
+
+	// SessionStore returns the session store, nil if sessions
+	// are only kept in memory.
+	SessionStore() SessionStore
+
+	// SetSessionStore sets the session store to persist private sessions in
+	// (see SessionStore for what is persisted). Pass nil to only keep
+	// sessions in memory. This is the default.
+	// Should be set before the server is started.
+	SetSessionStore(store SessionStore)
+
 	// SetHeaders sets extra HTTP response headers that are added to all responses.
 	// Supplied values are copied, so changes to the passed map afterwards have no effect.
 	//
@@ -145,6 +293,88 @@ type Server interface {
 	// A copy is returned, so changes to the returned map afterwards have no effect.
 	Headers() map[string][]string
 
+	// SecurityHeaders returns the security headers that are added to all responses.
+	SecurityHeaders() SecurityHeaders
+
+	// SetSecurityHeaders sets the security headers that are added to all responses
+	// (including the responses serving static files).
+	// Empty values are not sent. By default no security headers are sent.
+	//
+	// For example to use the recommended configuration:
+	//     server.SetSecurityHeaders(gwu.DefaultSecurityHeaders)
+	SetSecurityHeaders(sh SecurityHeaders)
+
+	// SessCookieConfig returns the attributes of the session cookie.
+	SessCookieConfig() SessCookieConfig
+
+	// SetSessCookieConfig sets the attributes of the session cookie.
+	// Browsers reject cookies with SameSite=None which are not secure, so an
+	// error is returned (and the config is not changed) if cc.SameSite is
+	// http.SameSiteNoneMode and the cookie would not be secure (cc.Secure
+	// is false and the server is not in secure (HTTPS) mode).
+	// Default is SameSite=Lax and HttpOnly.
+	// Only affects cookies of sessions created after this call.
+	SetSessCookieConfig(cc SessCookieConfig) error
+
+	// RenderFilter returns the render filter, nil if there is none.
+	RenderFilter() RenderFilter
+
+	// SetRenderFilter sets a filter which may rewrite the rendered HTML
+	// before it is sent to the client. Pass nil to remove the filter.
+	//
+	// The filter is called for the units the server renders and sends:
+	// the HTML document of a window (c is the window), components
+	// re-rendered in the browser (c is the re-rendered component) and
+	// windows re-rendered by Event.RerenderWindow(). It is not called
+	// separately for the child components rendered inside these.
+	// The cached document of a prewarmed window (see PrewarmWindow())
+	// is filtered once, when it is rendered.
+	SetRenderFilter(filter RenderFilter)
+
+	// DiffRender tells if diff rendering is enabled.
+	DiffRender() bool
+
+	// SetDiffRender enables or disables diff rendering. If enabled, when a
+	// component of a window of a private session is re-rendered, the server
+	// compares the new render to the previous one, and sends only the DOM
+	// operations (adding and removing nodes, changing attributes and texts)
+	// needed to update the component at client side, instead of replacing
+	// the whole component. This spares bandwidth, and preserves the state of
+	// the unchanged parts (e.g. focus, scroll positions, media playback).
+	//
+	// The last render of each re-rendered component is kept in memory.
+	// If the DOM at client side does not match the previous render (e.g. the
+	// window is open in multiple browser tabs), the client falls back to
+	// requesting the whole component. Components whose render consists of
+	// multiple top-level elements (e.g. print static inputs) are always
+	// sent as a whole.
+	//
+	// Default is false.
+	SetDiffRender(enabled bool)
+
+	// Gzip tells if responses are gzip compressed, and returns the
+	// minimum size of responses to compress.
+	Gzip() (enabled bool, minSize int)
+
+	// SetGzip sets if event responses, component re-renders and window
+	// documents are gzip compressed (if the client accepts gzip encoding).
+	// Responses smaller than minSize bytes are sent uncompressed (the
+	// overhead of compression does not pay off for tiny responses).
+	// Default is disabled, with a minimum size of 1024 bytes.
+	SetGzip(enabled bool, minSize int)
+
+	// JSONResponses tells if event responses are sent in JSON format.
+	JSONResponses() bool
+
+	// SetJSONResponses sets if event responses are sent in JSON format
+	// instead of the legacy delimited text format. In JSON format the
+	// response is an array of action objects, e.g.
+	//     [{"type":2,"args":[12,34]},{"type":3,"args":[56]}]
+	// where type is the action code and args are its arguments (strings
+	// are not URL-encoded). Clients understand both formats.
+	// Default is false.
+	SetJSONResponses(enabled bool)
+
 	// AddStaticDir registers a directory whose content (files) recursively
 	// will be served by the server when requested.
 	// path is an app-path relative path to address a file, dir is the root directory
@@ -165,6 +395,81 @@ type Server interface {
 	// SetTheme sets the default CSS theme of the server.
 	SetTheme(theme string)
 
+	// UnknownRespMode returns the client behavior on unknown event response codes.
+	UnknownRespMode() UnknownRespMode
+
+	// SetUnknownRespMode sets the client behavior on unknown event response codes.
+	// Default is UnknownRespAuto which alerts the code in dev mode (see SetDevMode())
+	// and logs it to the browser console otherwise.
+	// Only affects windows rendered after this call.
+	SetUnknownRespMode(mode UnknownRespMode)
+
+	// DevMode tells if the server is in dev mode.
+	DevMode() bool
+
+	// SetDevMode sets whether the server is in dev mode.
+	// In dev mode problems are reported more loudly to the user,
+	// e.g. unknown event response codes are alerted (see SetUnknownRespMode()).
+	// Default is false (production mode).
+	// Only affects windows rendered after this call.
+	SetDevMode(devMode bool)
+
+	// ClientEventInterceptor returns the client event interceptor.
+	ClientEventInterceptor() string
+
+	// SetClientEventInterceptor sets a client event interceptor: a JavaScript
+	// expression which evaluates to a function, e.g. a function name or
+	// a function literal. The function is called before each event is sent
+	// to the server with the arguments (event, etype, compId, compValue);
+	// it may log the event (e.g. for analytics or debugging), and if it
+	// returns false, the event is not sent.
+	// Pass an empty string to remove the interceptor.
+	// Only affects windows rendered after this call.
+	//
+	// Example:
+	//     s.SetClientEventInterceptor("function(event, etype, compId, compValue) { console.log(etype, compId); }")
+	SetClientEventInterceptor(js string)
+
+	// AutoReconnect tells if clients reconnect automatically
+	// when the server becomes unavailable.
+	AutoReconnect() bool
+
+	// SetAutoReconnect sets whether clients reconnect automatically
+	// when the server becomes unavailable (e.g. it is restarted).
+	// If enabled, after a few consecutive failed requests the client
+	// polls the server (with an exponential backoff) until it is
+	// available again, and then reloads the window.
+	// Default is false. Only affects windows rendered after this call.
+	SetAutoReconnect(autoReconnect bool)
+
+	// EventRetry returns the number of times failed events are retried,
+	// and the timeout of event requests.
+	EventRetry() (retries int, timeout time.Duration)
+
+	// SetEventRetry sets the number of times the client retries an event
+	// if sending it fails due to a network error or a 502 or 503 status,
+	// and the timeout of event requests (0 means no timeout).
+	// Retries are sent with increasing delays. Timed out events and events
+	// failed with other statuses are not retried as they might have been
+	// handled at server side.
+	// Default is 1 retry and no timeout.
+	// Only affects windows rendered after this call.
+	SetEventRetry(retries int, timeout time.Duration)
+
+	// ClientEventErrorHandler returns the client event error handler.
+	ClientEventErrorHandler() string
+
+	// SetClientEventErrorHandler sets a client event error handler:
+	// a JavaScript expression which evaluates to a function, e.g.
+	// a function name or a function literal. The function is called
+	// if sending an event fails (after the retries, see SetEventRetry())
+	// with the arguments (etype, compId, status), where status is the
+	// HTTP status of the last attempt (0 on network errors and timeouts);
+	// e.g. to show a "connection lost" banner.
+	// Pass an empty string to remove the handler.
+	// Only affects windows rendered after this call.
+	SetClientEventErrorHandler(js string)
+
 	// SetLogger sets the logger to be used
 	// to log incoming requests.
 	// Pass nil to disable logging. This is the default.
@@ -189,6 +494,30 @@ type Server interface {
 	// By setting your own hander, you will completely take over the app root.
 	SetAppRootHandler(f AppRootHandlerFunc)
 
+	// Broadcast calls the update function for each window having the specified
+	// name in all sessions (including the public session), and pushes the
+	// re-rendering of the components marked dirty by the update function
+	// to the clients viewing the windows (e.g. to update a live scoreboard).
+	// Only windows having push enabled are pushed to (see Window.SetPushEnabled()).
+	//
+	// The update function is called while holding the lock of the session
+	// of the window, just like event handlers.
+	Broadcast(windowName string, update func(ev BroadcastEvent))
+
+	// PrewarmWindow builds a public window once by calling builder,
+	// adds it to the server (see AddWin()), and renders it in advance.
+	// Requests to the window are served from the rendered document (shell)
+	// without rendering it again, for faster first paint.
+	//
+	// The cached document is dropped when an event is dispatched to the window
+	// or when it is updated by Broadcast(), and it is rendered (and cached)
+	// again on the next request. Server settings affecting the rendered
+	// document (e.g. the theme) should be set before calling this method.
+	//
+	// Returns an error if builder returns a window with a name other than name,
+	// or if AddWin() fails.
+	PrewarmWindow(name string, builder func() Window) error
+
 	// Start starts the GUI server and waits for incoming connections.
 	//
 	// Sessionless window names may be specified as optional parameters
@@ -197,6 +526,19 @@ type Server interface {
 	// Tip: Not passing any window names will start the server silently
 	// without opening any windows.
 	Start(openWins ...string) error
+
+	// Shutdown gracefully shuts down the GUI server started by Start():
+	// closes the WebSocket connections, stops accepting new connections,
+	// and waits for the active requests (events, renders, including events
+	// sent over WebSocket) to complete, and stops removing expired sessions.
+	// Then the functions registered by Session.OnShutdown() are called
+	// for all sessions (including the public session), and private sessions
+	// are saved to the session store (if set, see SetSessionStore()).
+	// Shutdown waits (for requests and for the locks of the sessions) at most
+	// until the context is done, in which case the context's error is returned,
+	// and sessions which could not be locked are skipped.
+	// After Shutdown, Start() returns nil.
+	Shutdown(ctx context.Context) error
 }
 
 // Server implementation.
@@ -210,14 +552,44 @@ type serverImpl struct {
 	appPath            string             // Application path
 	appUrl             string             // Application URL
 	sessions           map[string]Session // Sessions
+	restoring          sessRestores       // Sessions being restored from the session store
+	sessionsMu         sync.Mutex         // Mutex of the sessions and restoring maps
 	certFile, keyFile  string             // Certificate and key files for secure (HTTPS) mode
+	tlsConfig          *tls.Config        // TLS configuration for secure (HTTPS) mode, may be nil
 	sessCreatorNames   map[string]string  // Session creator names
 	sessionHandlers    []SessionHandler   // Registered session handlers
+	sessStore          SessionStore       // Session store to persist sessions in, may be nil
+	middlewares        []Middleware       // Middlewares wrapping request handling
+	sessMiddlewares    []Middleware       // Middlewares wrapping request handling after session resolution
 	theme              string             // Default CSS theme of the server
+	unknownRespMode    UnknownRespMode    // Client behavior on unknown event response codes
+	devMode            bool               // Tells if the server is in dev mode
+	eventInterceptor   string             // Client event interceptor JavaScript expression
+	autoReconnect      bool               // Tells if clients reconnect automatically
+	eventRetries       int                // Number of times failed events are retried by clients
+	eventTimeout       time.Duration      // Timeout of event requests of clients
+	eventErrHandler    string             // Client event error handler JavaScript expression
 	logger             *log.Logger        // Logger.
 	headers            http.Header        // Extra headers that will be added to all responses.
+	securityHeaders    SecurityHeaders    // Security headers that will be added to all responses.
+	sessCookie         SessCookieConfig   // Attributes of the session cookie
+	renderFilter       RenderFilter       // Filter of the rendered HTML, may be nil
+	diffRender         bool               // Tells if diff rendering is enabled
+	jsonResps          bool               // Tells if event responses are sent in JSON format
+	gzip               bool               // Tells if responses are gzip compressed
+	gzipMinSize        int                // Minimum size of responses to compress
+	wsCookies          wsCookieStore      // Cookies of events sent over WebSocket waiting to be fetched
+	shells             map[string][]byte  // Cached rendered documents of prewarmed windows, mapped from window name
+	shellsMu           sync.Mutex         // Mutex of the shells map
 	rootHeads          []string           // Additional head HTML texts of the window list page (app root)
 	appRootHandlerFunc AppRootHandlerFunc // App root handler function
+	httpServer         *http.Server       // HTTP server started by Start(), nil if not started
+	httpServerMu       sync.Mutex         // Mutex of httpServer
+	shutdown           chan struct{}      // Closed when the server is shut down, stops the session cleaner
+	shutdownOnce       sync.Once          // To close shutdown only once
+	wsConns            map[*wsConn]bool   // Open WebSocket connections (hijacked, so not closed by http.Server.Shutdown())
+	wsConnsMu          sync.Mutex         // Mutex of wsConns
+	wsHandlers         sync.WaitGroup     // Running WebSocket handlers
 }
 
 // NewServer creates a new GUI server in HTTP mode.
@@ -236,10 +608,27 @@ func NewServer(appName, addr string) Server {
 // Tip: Pass an empty string as appName to place the GUI server to the root path ("/").
 // Tip: You can use generate_cert.go in crypto/tls to generate
 // a test certificate and key file (cert.pem andkey.pem).
+//
+// Session cookies are sent with the Secure flag in secure mode. Event
+// responses (window reloads, relative redirects) only contain app-relative
+// paths which the browser resolves against the current (https://) URL,
+// so the app can also be served via HTTPS under any app path. Absolute
+// URLs passed to Event.RedirectAfter() are used as-is.
 func NewServerTLS(appName, addr, certFile, keyFile string) Server {
 	return newServerImpl(appName, addr, certFile, keyFile)
 }
 
+// NewServerTLSConfig creates a new GUI server in secure (HTTPS) mode,
+// using the specified TLS configuration, which must provide the certificates
+// (Certificates or GetCertificate). See NewServerTLS() for details.
+func NewServerTLSConfig(appName, addr string, config *tls.Config) Server {
+	s := newServerImpl(appName, addr, "", "")
+	s.secure = true
+	s.appUrl = "https://" + s.addr + s.appPath
+	s.tlsConfig = config
+	return s
+}
+
 // newServerImpl creates a new serverImpl.
 func newServerImpl(appName, addr, certFile, keyFile string) *serverImpl {
 	if addr == "" {
@@ -247,7 +636,9 @@ func newServerImpl(appName, addr, certFile, keyFile string) *serverImpl {
 	}
 
 	s := &serverImpl{sessionImpl: newSessionImpl(false), appName: appName, addr: addr, sessions: make(map[string]Session),
-		sessCreatorNames: make(map[string]string), theme: ThemeDefault}
+		restoring: make(sessRestores), sessCreatorNames: make(map[string]string), theme: ThemeDefault, eventRetries: 1,
+		gzipMinSize: 1024, shutdown: make(chan struct{}), wsConns: make(map[*wsConn]bool),
+		sessCookie: SessCookieConfig{SameSite: http.SameSiteLaxMode, HttpOnly: true}}
 
 	if s.appName == "" {
 		s.appPath = "/"
@@ -292,6 +683,31 @@ func (s *serverImpl) AddSHandler(handler SessionHandler) {
 	s.sessionHandlers = append(s.sessionHandlers, handler)
 }
 
+func (s *serverImpl) AddMiddleware(mw Middleware) {
+	s.middlewares = append(s.middlewares, mw)
+}
+
+func (s *serverImpl) AddSessMiddleware(mw Middleware) {
+	s.sessMiddlewares = append(s.sessMiddlewares, mw)
+}
+
+// chain wraps the handler with the middlewares,
+// the first middleware being the outermost.
+func chain(h http.Handler, mws []Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+func (s *serverImpl) SessionStore() SessionStore {
+	return s.sessStore
+}
+
+func (s *serverImpl) SetSessionStore(store SessionStore) {
+	s.sessStore = store
+}
+
 // newSession creates a new (private) Session.
 // The event is optional. If specified and the current session
 // (as returned by Event.Session()) is private, it will be removed first.
@@ -308,7 +724,9 @@ func (s *serverImpl) newSession(e *eventImpl) Session {
 		e.shared.session = sess
 	}
 	// Store new session
+	s.sessionsMu.Lock()
 	s.sessions[sess.Id()] = sess
+	s.sessionsMu.Unlock()
 
 	log.Println("SESSION created:", sess.Id())
 	if s.logger != nil {
@@ -319,6 +737,7 @@ func (s *serverImpl) newSession(e *eventImpl) Session {
 	for _, handler := range s.sessionHandlers {
 		handler.Created(sess)
 	}
+	s.saveSess(sess)
 
 	return sess
 }
@@ -348,7 +767,14 @@ func (s *serverImpl) removeSess2(sess Session) {
 		for _, handler := range s.sessionHandlers {
 			handler.Removed(sess)
 		}
+		s.sessionsMu.Lock()
 		delete(s.sessions, sess.Id())
+		s.sessionsMu.Unlock()
+		if s.sessStore != nil {
+			if err := s.sessStore.Remove(sess.Id()); err != nil {
+				s.logSessStoreErr("remove", sess.Id(), err)
+			}
+		}
 	}
 }
 
@@ -358,30 +784,115 @@ func (s *serverImpl) removeSess2(sess Session) {
 func (s *serverImpl) addSessCookie(sess Session, w http.ResponseWriter) {
 	// HttpOnly: do not allow non-HTTP access to it (like javascript) to prevent stealing it...
 	// Secure: only send it over HTTPS
+	// SameSite: whether to send it with cross-site requests
 	// MaxAge: to specify the max age of the cookie in seconds, else it's a session cookie and gets deleted after the browser is closed.
-	c := http.Cookie{Name: gwuSessidCookie, Value: sess.Id(), Path: s.appPath, HttpOnly: true, Secure: s.secure,
-		MaxAge: 72 * 60 * 60} // 72 hours max age
+	cc := s.sessCookie
+	c := http.Cookie{Name: gwuSessidCookie, Value: sess.Id(), Path: s.appPath, HttpOnly: cc.HttpOnly,
+		Secure: cc.Secure || s.secure, SameSite: cc.SameSite, MaxAge: 72 * 60 * 60} // 72 hours max age
 	http.SetCookie(w, &c)
 
 	sess.clearNew()
 }
 
 // sessCleaner periodically checks whether private sessions has timed out
-// in an endless loop. If a session has timed out, removes it.
+// until the server is shut down. If a session has timed out, removes it.
 // This method is to start as a new go routine.
 func (s *serverImpl) sessCleaner() {
-	sleep := 10 * time.Second
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	s.removeExpiredSess(time.Now())
 	for {
-		now := time.Now()
+		select {
+		case now := <-ticker.C:
+			s.removeExpiredSess(now)
+		case <-s.shutdown:
+			return
+		}
+	}
+}
 
-		// TODO synchronization?
-		for _, sess := range s.sessions {
-			if now.Sub(sess.Accessed()) > sess.Timeout() {
-				s.removeSess2(sess)
-			}
+// privateSessions returns a snapshot of the private sessions.
+func (s *serverImpl) privateSessions() []Session {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	sessions := make([]Session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	return sessions
+}
+
+// liveSess tells if the specified private session is still stored (not removed).
+func (s *serverImpl) liveSess(sess Session) bool {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	return s.sessions[sess.Id()] == sess
+}
+
+// removeExpiredSess removes the private sessions which have timed out
+// at the specified time (each according to its own timeout).
+func (s *serverImpl) removeExpiredSess(now time.Time) {
+	for _, sess := range s.privateSessions() {
+		if now.Sub(sess.Accessed()) > sess.Timeout() {
+			s.removeSess2(sess)
+		}
+	}
+}
+
+func (s *serverImpl) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() { close(s.shutdown) })
+	s.closeWebSockets()
+
+	s.httpServerMu.Lock()
+	srv := s.httpServer
+	s.httpServerMu.Unlock()
+
+	var err error
+	if srv != nil {
+		err = srv.Shutdown(ctx)
+	}
+	if err2 := s.waitWebSockets(ctx); err == nil {
+		err = err2
+	}
+
+	sessions := append([]Session{s}, s.privateSessions()...)
+	for _, sess := range sessions {
+		if !lockCtx(ctx, sess.rwMutex()) {
+			return ctx.Err()
+		}
+		for _, f := range sess.shutdownFuncs() {
+			f(sess)
 		}
+		s.saveSess(sess)
+		sess.rwMutex().Unlock()
+	}
+
+	return err
+}
 
-		time.Sleep(sleep)
+// lockCtx locks the specified mutex, waiting at most until the context is done.
+// Returns false if the mutex could not be locked.
+func lockCtx(ctx context.Context, mu *sync.RWMutex) bool {
+	if mu.TryLock() {
+		return true
+	}
+	locked := make(chan struct{})
+	go func() {
+		mu.Lock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		return true
+	case <-ctx.Done():
+		// Release the lock when it is eventually acquired
+		go func() {
+			<-locked
+			mu.Unlock()
+		}()
+		return false
 	}
 }
 
@@ -402,14 +913,110 @@ func (s *serverImpl) Headers() map[string][]string {
 	return headers
 }
 
-// addHeaders adds the extra headers to the specified response.
-func (s *serverImpl) addHeaders(w http.ResponseWriter) {
+func (s *serverImpl) SecurityHeaders() SecurityHeaders {
+	return s.securityHeaders
+}
+
+func (s *serverImpl) SetSecurityHeaders(sh SecurityHeaders) {
+	s.securityHeaders = sh
+}
+
+func (s *serverImpl) SessCookieConfig() SessCookieConfig {
+	return s.sessCookie
+}
+
+func (s *serverImpl) SetSessCookieConfig(cc SessCookieConfig) error {
+	if cc.SameSite == http.SameSiteNoneMode && !cc.Secure && !s.secure {
+		return errors.New("SameSite=None session cookie must be secure!")
+	}
+	s.sessCookie = cc
+	return nil
+}
+
+func (s *serverImpl) Gzip() (enabled bool, minSize int) {
+	return s.gzip, s.gzipMinSize
+}
+
+func (s *serverImpl) SetGzip(enabled bool, minSize int) {
+	if minSize < 0 {
+		minSize = 0
+	}
+	s.gzip, s.gzipMinSize = enabled, minSize
+}
+
+func (s *serverImpl) JSONResponses() bool {
+	return s.jsonResps
+}
+
+func (s *serverImpl) SetJSONResponses(enabled bool) {
+	s.jsonResps = enabled
+}
+
+func (s *serverImpl) RenderFilter() RenderFilter {
+	return s.renderFilter
+}
+
+func (s *serverImpl) SetRenderFilter(filter RenderFilter) {
+	s.renderFilter = filter
+}
+
+func (s *serverImpl) DiffRender() bool {
+	return s.diffRender
+}
+
+func (s *serverImpl) SetDiffRender(enabled bool) {
+	s.diffRender = enabled
+}
+
+// renderFiltered calls render (with the locale of the specified session)
+// to render the specified component
+// to w, applying the render filter if there is one.
+func (s *serverImpl) renderFiltered(sess Session, c Comp, w io.Writer, render func(w Writer)) {
+	if s.renderFilter == nil {
+		render(newLocaleWriter(w, sess.Locale()))
+		return
+	}
+	buf := &bytes.Buffer{}
+	render(newLocaleWriter(buf, sess.Locale()))
+	w.Write(s.renderFilter(c, buf.Bytes()))
+}
+
+// renderWin renders the HTML document of the specified window,
+// applying the render filter if there is one.
+// Script elements are rendered with the specified CSP nonce (if not empty).
+func (s *serverImpl) renderWin(sess Session, win Window, w io.Writer, nonce string) {
+	win.renders().clear()
+	s.renderFiltered(sess, win, w, func(w Writer) { win.RenderWin(withNonce(w, nonce), s) })
+}
+
+// addHeaders adds the extra headers and the security headers to the specified response.
+// Returns the CSP nonce of the response, empty string if the content security
+// policy contains no NoncePlaceholder.
+func (s *serverImpl) addHeaders(w http.ResponseWriter) (nonce string) {
 	header := w.Header()
 	for k, v := range s.headers {
 		for _, v2 := range v {
 			header.Add(k, v2)
 		}
 	}
+
+	sh := &s.securityHeaders
+	csp := sh.ContentSecurityPolicy
+	if strings.Contains(csp, NoncePlaceholder) {
+		nonce = genId()
+		csp = strings.Replace(csp, NoncePlaceholder, nonce, -1)
+	}
+	for _, h := range []struct{ name, value string }{
+		{"X-Content-Type-Options", sh.ContentTypeOptions},
+		{"X-Frame-Options", sh.FrameOptions},
+		{"Referrer-Policy", sh.ReferrerPolicy},
+		{"Content-Security-Policy", csp},
+	} {
+		if h.value != "" {
+			header.Set(h.name, h.value)
+		}
+	}
+	return
 }
 
 func (s *serverImpl) AddStaticDir(path, dir string) error {
@@ -451,6 +1058,184 @@ func (s *serverImpl) SetTheme(theme string) {
 	s.theme = theme
 }
 
+func (s *serverImpl) UnknownRespMode() UnknownRespMode {
+	return s.unknownRespMode
+}
+
+func (s *serverImpl) SetUnknownRespMode(mode UnknownRespMode) {
+	s.unknownRespMode = mode
+}
+
+func (s *serverImpl) DevMode() bool {
+	return s.devMode
+}
+
+func (s *serverImpl) SetDevMode(devMode bool) {
+	s.devMode = devMode
+}
+
+func (s *serverImpl) ClientEventInterceptor() string {
+	return s.eventInterceptor
+}
+
+func (s *serverImpl) SetClientEventInterceptor(js string) {
+	s.eventInterceptor = js
+}
+
+func (s *serverImpl) AutoReconnect() bool {
+	return s.autoReconnect
+}
+
+func (s *serverImpl) SetAutoReconnect(autoReconnect bool) {
+	s.autoReconnect = autoReconnect
+}
+
+func (s *serverImpl) EventRetry() (retries int, timeout time.Duration) {
+	return s.eventRetries, s.eventTimeout
+}
+
+func (s *serverImpl) SetEventRetry(retries int, timeout time.Duration) {
+	if retries < 0 {
+		retries = 0
+	}
+	if timeout < 0 {
+		timeout = 0
+	}
+	s.eventRetries, s.eventTimeout = retries, timeout
+}
+
+func (s *serverImpl) ClientEventErrorHandler() string {
+	return s.eventErrHandler
+}
+
+func (s *serverImpl) SetClientEventErrorHandler(js string) {
+	s.eventErrHandler = js
+}
+
+func (s *serverImpl) Broadcast(windowName string, update func(ev BroadcastEvent)) {
+	sessions := append([]Session{&s.sessionImpl}, s.privateSessions()...)
+
+	for _, sess := range sessions {
+		rwMutex := sess.rwMutex()
+		rwMutex.Lock()
+		if win := sess.WinByName(windowName); win != nil {
+			ev := &broadcastEventImpl{win: win, e: newEventImpl(ETypeStateChange, win, s, sess)}
+			update(ev)
+			s.dropShell(sess, win)
+			if win.PushEnabled() && len(ev.e.shared.dirtyComps) > 0 {
+				win.push(ev.e.shared.dirtyComps)
+			}
+		}
+		rwMutex.Unlock()
+	}
+}
+
+func (s *serverImpl) PrewarmWindow(name string, builder func() Window) error {
+	win := builder()
+	if win.Name() != name {
+		return errors.New("Window name mismatch: '" + win.Name() + "' (expected: '" + name + "')!")
+	}
+	if err := s.AddWin(win); err != nil {
+		return err
+	}
+
+	s.shellsMu.Lock()
+	if s.shells == nil {
+		s.shells = make(map[string][]byte)
+	}
+	s.shells[name] = nil // Rendered on first request
+	s.shellsMu.Unlock()
+
+	s.renderShell(win, &bytes.Buffer{}, "")
+	return nil
+}
+
+// renderShell renders a prewarmed window from its cached document, rendering
+// (and caching) it if not yet cached.
+// The cached document is rendered with NoncePlaceholder as the CSP nonce
+// if the content security policy contains it, which is replaced with the
+// specified nonce.
+// Must be called while holding the (read) lock of the public session.
+func (s *serverImpl) renderShell(win Window, w io.Writer, nonce string) {
+	s.shellsMu.Lock()
+	shell := s.shells[win.Name()]
+	s.shellsMu.Unlock()
+
+	if shell == nil {
+		var shellNonce string
+		if strings.Contains(s.securityHeaders.ContentSecurityPolicy, NoncePlaceholder) {
+			shellNonce = NoncePlaceholder
+		}
+		buf := &bytes.Buffer{}
+		s.renderWin(s, win, buf, shellNonce) // Shells are shared, render with the public session
+		shell = buf.Bytes()
+
+		s.shellsMu.Lock()
+		if _, prewarmed := s.shells[win.Name()]; prewarmed {
+			s.shells[win.Name()] = shell
+		}
+		s.shellsMu.Unlock()
+	}
+
+	if nonce != "" {
+		shell = bytes.Replace(shell, []byte(`nonce="`+NoncePlaceholder+`"`), []byte(`nonce="`+nonce+`"`), -1)
+	}
+	w.Write(shell)
+}
+
+// prewarmed tells if the specified window is a prewarmed public window.
+func (s *serverImpl) prewarmed(sess Session, win Window) bool {
+	if sess.Private() {
+		return false
+	}
+	s.shellsMu.Lock()
+	defer s.shellsMu.Unlock()
+	_, prewarmed := s.shells[win.Name()]
+	return prewarmed
+}
+
+// dropShell drops the cached document of a prewarmed window (if it is one).
+func (s *serverImpl) dropShell(sess Session, win Window) {
+	if sess.Private() {
+		return
+	}
+	s.shellsMu.Lock()
+	if _, prewarmed := s.shells[win.Name()]; prewarmed {
+		s.shells[win.Name()] = nil
+	}
+	s.shellsMu.Unlock()
+}
+
+// handlePush handles a long-polling push request: waits for components
+// pushed to the window after the push sequence number sent by the client,
+// and sends the ids of the components to re-render (and the new push
+// sequence number in the hdrPushSeq header).
+// Must be called without holding the lock of the session.
+func (s *serverImpl) handlePush(win Window, wr http.ResponseWriter, r *http.Request) {
+	since, _ := strconv.Atoi(r.FormValue(paramPushSeq))
+	ids, seq := win.waitPush(r.Context(), since, pushTimeout)
+	if r.Context().Err() != nil {
+		return // Client is gone
+	}
+
+	wr.Header().Set(hdrPushSeq, strconv.Itoa(seq))
+	s.writePush(wr, ids)
+}
+
+// writePush writes the response of pushed components: the ids of the components to re-render.
+func (s *serverImpl) writePush(w http.ResponseWriter, ids []ID) {
+	ew := newErespWriter(w, s.jsonResps)
+	defer ew.close()
+	if len(ids) == 0 {
+		return
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = int(id)
+	}
+	ew.action(eraDirtyComps, args...)
+}
+
 func (s *serverImpl) SetLogger(logger *log.Logger) {
 	s.logger = logger
 }
@@ -525,26 +1310,39 @@ func (s *serverImpl) serveStatic(w http.ResponseWriter, r *http.Request) {
 	http.NotFound(w, r)
 }
 
-// serveHTTP handles the incoming requests.
-// Renders of the URL-selected window,
-// and also handles event dispatching.
+// serveHTTP handles the incoming requests through the middlewares.
 func (s *serverImpl) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	chain(http.HandlerFunc(s.serveReq), s.middlewares).ServeHTTP(w, r)
+}
+
+// serveReq handles an incoming request: resolves its session, and
+// serves it through the session middlewares.
+func (s *serverImpl) serveReq(w http.ResponseWriter, r *http.Request) {
 	if s.logger != nil {
 		s.logger.Println("Incoming:", r.URL.Path)
 	}
 
-	s.addHeaders(w)
+	nonce := s.addHeaders(w)
 
 	// Check session
 	var sess Session
 	c, err := r.Cookie(gwuSessidCookie)
 	if err == nil {
-		sess = s.sessions[c.Value]
+		sess = s.sessById(c.Value)
 	}
 	if sess == nil {
 		sess = &s.sessionImpl
 	}
 
+	h := chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.serveSess(sess, nonce, w, r)
+	}), s.sessMiddlewares)
+	h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessCtxKey{}, sess)))
+}
+
+// serveSess serves a request in the specified session:
+// renders the URL-selected window, and also handles event dispatching.
+func (s *serverImpl) serveSess(sess Session, nonce string, w http.ResponseWriter, r *http.Request) {
 	// Parts example: "/appname/winname/e?et=0&cid=1" => {"", "appname", "winname", "e"}
 	parts := strings.Split(r.URL.Path, "/")
 
@@ -569,15 +1367,26 @@ func (s *serverImpl) serveHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(parts) >= 1 && parts[0] == pathSessCheck {
-		// Session check. Must not call sess.acess()
+		// Session check. Must not call sess.acess(), unless it is
+		// a keep-alive check (and the session is not yet expired).
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		sess.rwMutex().RLock()
 		remaining := sess.Timeout() - time.Now().Sub(sess.Accessed())
 		sess.rwMutex().RUnlock()
+		if remaining > 0 && sess.Private() && r.FormValue(paramKeepAlive) == "1" {
+			sess.access()
+			remaining = sess.Timeout()
+		}
 		fmt.Fprintf(w, "%f", remaining.Seconds())
 		return
 	}
 
+	if len(parts) >= 1 && parts[0] == pathWsCookies {
+		// Cookies of an event sent over WebSocket
+		s.serveWsCookies(w, r)
+		return
+	}
+
 	if len(parts) < 1 || parts[0] == "" {
 		// Missing window name, render window list
 		s.appRootHandlerFunc(w, r, sess)
@@ -614,32 +1423,107 @@ func (s *serverImpl) serveHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sess.access()
-
 	var path string
 	if len(parts) >= 2 {
 		path = parts[1]
 	}
 
+	if path == pathPush {
+		// Long-polling push request. Must not call sess.access() (would keep the
+		// session alive forever), and must not hold the session lock while waiting.
+		s.handlePush(win, w, r)
+		return
+	}
+
+	if path == pathWebSocket {
+		// WebSocket to send events over. Must not call sess.access() (events
+		// sent over it do), and must not hold the session lock while open.
+		s.handleWebSocket(sess, win, w, r)
+		return
+	}
+
+	if s.gzip {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if acceptsGzip(r) {
+			gw := newGzipRespWriter(w, s.gzipMinSize)
+			defer gw.close()
+			w = gw
+		}
+	}
+
+	if path == pathEvent {
+		s.serveEvent(sess, win, w, r)
+		return
+	}
+
+	sess.access()
+
 	rwMutex := sess.rwMutex()
 	switch path {
-	case pathEvent:
-		rwMutex.Lock()
-		defer rwMutex.Unlock()
-
-		s.handleEvent(sess, win, w, r)
 	case pathRenderComp:
 		rwMutex.RLock()
 		defer rwMutex.RUnlock()
 
 		// Render just a component
-		s.renderComp(win, w, r)
+		s.renderComp(sess, win, w, r)
+	case pathRenderComps:
+		rwMutex.RLock()
+		defer rwMutex.RUnlock()
+
+		// Render multiple components
+		s.renderComps(sess, win, w, r)
 	default:
 		rwMutex.RLock()
 		defer rwMutex.RUnlock()
 
 		// Render the whole window
-		win.RenderWin(NewWriter(w), s)
+		if s.prewarmed(sess, win) {
+			s.renderShell(win, w, nonce)
+		} else {
+			s.renderWin(sess, win, w, nonce)
+		}
+	}
+}
+
+// serveEvent serves an event request (sent via XHR or over a WebSocket).
+// Must be called without holding the lock of the session.
+func (s *serverImpl) serveEvent(sess Session, win Window, w http.ResponseWriter, r *http.Request) {
+	if parseIntParam(r, paramEventType) == int(ETypeSessExpired) {
+		// Session expiry reported by a SessMonitor. Must not call sess.access()
+		// (would revive the session).
+		s.handleSessExpired(sess, win, w, r)
+		return
+	}
+
+	sess.access()
+
+	rwMutex := sess.rwMutex()
+	rwMutex.Lock()
+	defer rwMutex.Unlock()
+
+	s.dropShell(sess, win)
+	s.handleEvent(sess, win, w, r)
+}
+
+// handleSessExpired handles a session expired event sent by a SessMonitor.
+// If the session is really expired, the event is handled as usual, and after
+// that the session is removed. Else (e.g. the session was kept alive from
+// another browser tab) the event is ignored.
+func (s *serverImpl) handleSessExpired(sess Session, win Window, wr http.ResponseWriter, r *http.Request) {
+	rwMutex := sess.rwMutex()
+	rwMutex.Lock()
+	defer rwMutex.Unlock()
+
+	if time.Now().Sub(sess.Accessed()) <= sess.Timeout() {
+		newErespWriter(wr, s.jsonResps).close() // No action
+		return
+	}
+
+	s.handleEvent(sess, win, wr, r)
+
+	// The handler might have removed the session already
+	if s.liveSess(sess) && time.Now().Sub(sess.Accessed()) > sess.Timeout() {
+		s.removeSess2(sess)
 	}
 }
 
@@ -693,7 +1577,7 @@ func (s *serverImpl) renderWinList(wr http.ResponseWriter, r *http.Request, sess
 }
 
 // renderComp renders just a component.
-func (s *serverImpl) renderComp(win Window, w http.ResponseWriter, r *http.Request) {
+func (s *serverImpl) renderComp(sess Session, win Window, w http.ResponseWriter, r *http.Request) {
 	id, err := AtoID(r.FormValue(paramCompId))
 	if err != nil {
 		http.Error(w, "Invalid component id!", http.StatusBadRequest)
@@ -711,7 +1595,104 @@ func (s *serverImpl) renderComp(win Window, w http.ResponseWriter, r *http.Reque
 	}
 
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8") // We send it as text!
-	comp.Render(NewWriter(w))
+	if !s.diffRender || !sess.Private() {
+		s.renderFiltered(sess, comp, w, func(w Writer) { renderComp(w, comp) })
+		return
+	}
+
+	cr := s.renderCompDiff(sess, win, comp, r.FormValue(paramRenderRev))
+	w.Header().Set(hdrRenderRev, strconv.Itoa(cr.Rev))
+	if cr.Ops != nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(cr.Ops)
+		return
+	}
+	io.WriteString(w, cr.Html)
+}
+
+// compRender is the render of a component sent to the client: either
+// its HTML or the DOM operations of a diff render.
+type compRender struct {
+	Id   ID       `json:"i"`           // Id of the component
+	Html string   `json:"h,omitempty"` // HTML of the component, if Ops is nil
+	Ops  []diffOp `json:"p"`           // DOM operations of a diff render, nil if the HTML is sent (may be empty)
+	Rev  int      `json:"r,omitempty"` // Render revision (diff rendering), 0 if diff rendering is disabled
+}
+
+// renderCompDiff renders a component, and if diff rendering is enabled
+// and the client has the previous render (clientRev is the render revision
+// at client side), computes the DOM operations instead of the HTML.
+func (s *serverImpl) renderCompDiff(sess Session, win Window, comp Comp, clientRev string) compRender {
+	buf := &bytes.Buffer{}
+	s.renderFiltered(sess, comp, buf, func(w Writer) { renderComp(w, comp) })
+	cr := compRender{Id: comp.Id()}
+	if !s.diffRender || !sess.Private() {
+		cr.Html = buf.String()
+		return cr
+	}
+
+	prev, rev := win.renders().update(win, comp, buf.String())
+	cr.Rev = rev
+	// Only send a patch if the client has the previous render
+	if prev.html != "" && clientRev == strconv.Itoa(prev.rev) {
+		if ops, ok := diffHtml(prev.html, buf.String()); ok {
+			if ops == nil {
+				ops = []diffOp{} // Nothing changed, but still a patch
+			}
+			cr.Ops = ops
+			return cr
+		}
+	}
+	cr.Html = buf.String()
+	return cr
+}
+
+// renderComps renders multiple components in one response (e.g. the dirty
+// components of an event), sent as a JSON array of compRender, in the order
+// of the ids.
+// The component ids are sent in multiple paramCompId params, and each may be
+// followed by its render revision at client side in a paramRenderRev param
+// (which is "" if the client does not have one).
+// Components removed meanwhile, duplicates, and components whose ancestor
+// is also rendered are skipped (the render of the ancestor contains them).
+func (s *serverImpl) renderComps(sess Session, win Window, w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	ids, revs := r.Form[paramCompId], r.Form[paramRenderRev]
+
+	comps := make([]Comp, 0, len(ids))
+	for _, v := range ids {
+		id, err := AtoID(v)
+		if err != nil {
+			http.Error(w, "Invalid component id!", http.StatusBadRequest)
+			return
+		}
+		comps = append(comps, win.ById(id))
+	}
+
+	if s.logger != nil {
+		s.logger.Println("\tRendering comps:", ids)
+	}
+
+	renders := make([]compRender, 0, len(comps))
+outer:
+	for i, comp := range comps {
+		if comp == nil {
+			continue
+		}
+		for j, c := range comps {
+			if c != nil && (isAncestor(c, comp) || j < i && c.Id() == comp.Id()) {
+				continue outer
+			}
+		}
+		var rev string
+		if i < len(revs) {
+			rev = revs[i]
+		}
+		renders = append(renders, s.renderCompDiff(sess, win, comp, rev))
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(renders)
 }
 
 // handleEvent handles the event dispatching.
@@ -760,48 +1741,211 @@ func (s *serverImpl) handleEvent(sess Session, win Window, wr http.ResponseWrite
 
 	shared.modKeys = parseIntParam(r, paramModKeys)
 	shared.keyCode = Key(parseIntParam(r, paramKeyCode))
+	if event.etype == ETypeWheel {
+		shared.wheelDX, _ = strconv.Atoi(r.FormValue(paramWheelDX))
+		shared.wheelDY, _ = strconv.Atoi(r.FormValue(paramWheelDY))
+	}
+	if event.etype == ETypeGeolocation {
+		shared.geoLat, shared.geoLong, shared.geoOk = parseGeolocation(r)
+	}
+
+	// Buffered changes sent along with this event are processed first
+	for _, change := range r.Form[paramBufChange] {
+		s.handleBufChange(win, event, change)
+	}
 
 	comp.preprocessEvent(event, r)
+	if decoder := comp.EventDecoder(); decoder != nil {
+		event.decoded = decoder(r)
+	}
 
 	// Dispatch event...
 	comp.dispatchEvent(event)
+	s.saveSess(shared.session)
 
 	// Check if a new session was created during event dispatching
 	if shared.session.New() {
 		s.addSessCookie(shared.session, wr)
 	}
+	// Cookies set by the event handlers
+	for _, cookie := range shared.cookies {
+		http.SetCookie(wr, cookie)
+	}
 
 	// ...and send back the result
-	wr.Header().Set("Content-Type", "text/plain; charset=utf-8") // We send it as text
-	w := NewWriter(wr)
-	hasAction := false
+	ew := newErespWriter(wr, s.jsonResps)
+	defer ew.close()
 	// If we reload, nothing else matters
 	if shared.reload {
-		hasAction = true
-		w.Writevs(eraReloadWin, strComma, shared.reloadWin)
+		ew.action(eraReloadWin, rawArg(shared.reloadWin))
+		return
+	}
+	if shared.rerenderWin {
+		buf := &bytes.Buffer{}
+		win.renders().clear()
+		s.renderFiltered(sess, win, buf, win.Render)
+		ew.action(eraRerenderWin, int(win.Id()), buf.String())
+	} else if len(shared.dirtyComps) > 0 {
+		ids := make([]interface{}, 0, len(shared.dirtyComps))
+		for id, _ := range shared.dirtyComps {
+			ids = append(ids, int(id))
+		}
+		ew.action(eraDirtyComps, ids...)
+	}
+	if shared.focusedComp != nil {
+		ew.action(eraFocusComp, int(shared.focusedComp.Id()))
+		// Also register focusable comp at window
+		win.SetFocusedCompId(shared.focusedComp.Id())
+	}
+	if shared.redirectUrl != "" {
+		ew.action(eraRedirect, int(shared.redirectDelay/time.Millisecond), shared.redirectUrl)
+	}
+	if shared.captureComp != nil {
+		ew.action(eraCaptureComp, int(shared.captureComp.Id()), shared.captureFile)
+	}
+	if f := shared.extForm; f != nil {
+		ew.action(eraSubmitForm, f.method, f.url, f.fields.Encode())
+	}
+	if shared.geoComp != nil {
+		ew.action(eraGeolocation, int(ETypeGeolocation), int(shared.geoComp.Id()))
+	}
+	if shared.preventDefault {
+		ew.action(eraPreventDefault)
+	}
+	for _, scriptUrl := range win.takeScripts() {
+		ew.action(eraLoadScript, int(ETypeScriptLoaded), int(win.Id()), scriptUrl)
+	}
+}
+
+// rawArg is an event response action argument which is sent as-is
+// (not URL-encoded) in the legacy format.
+type rawArg string
+
+// erespWriter writes the actions of an event response,
+// either in the legacy or in JSON format.
+//
+// In the legacy format actions are separated by ';', and the action code
+// and the arguments by ','. String arguments are URL-encoded so they
+// cannot contain the separators.
+type erespWriter struct {
+	w       Writer
+	json    bool // Tells if the JSON format is used
+	actions int  // Number of actions written
+}
+
+// newErespWriter creates a new erespWriter, and sets the content type
+// of the response.
+func newErespWriter(wr http.ResponseWriter, jsonFmt bool) *erespWriter {
+	if jsonFmt {
+		wr.Header().Set("Content-Type", "application/json; charset=utf-8")
 	} else {
-		if len(shared.dirtyComps) > 0 {
-			hasAction = true
-			w.Writev(eraDirtyComps)
-			for id, _ := range shared.dirtyComps {
-				w.Write(strComma)
-				w.Writev(int(id))
+		wr.Header().Set("Content-Type", "text/plain; charset=utf-8") // We send it as text
+	}
+	return &erespWriter{w: NewWriter(wr), json: jsonFmt}
+}
+
+// action writes an action. Arguments must be of type int, string or rawArg.
+func (ew *erespWriter) action(era int, args ...interface{}) {
+	w := ew.w
+	if !ew.json {
+		if ew.actions > 0 {
+			w.Write(strSemicol)
+		}
+		w.Writev(era)
+		for _, arg := range args {
+			w.Write(strComma)
+			switch v := arg.(type) {
+			case string:
+				w.Writes(url.PathEscape(v))
+			case rawArg:
+				w.Writes(string(v))
+			default:
+				w.Writev(v)
 			}
 		}
-		if shared.focusedComp != nil {
-			if hasAction {
-				w.Write(strSemicol)
-			} else {
-				hasAction = true
+		ew.actions++
+		return
+	}
+
+	if ew.actions > 0 {
+		w.Write(strComma)
+	} else {
+		w.Writes("[")
+	}
+	w.Writevs(`{"type":`, era)
+	if len(args) > 0 {
+		w.Writes(`,"args":`)
+		for i, arg := range args {
+			if v, ok := arg.(rawArg); ok {
+				args[i] = string(v)
 			}
-			w.Writevs(eraFocusComp, strComma, int(shared.focusedComp.Id()))
-			// Also register focusable comp at window
-			win.SetFocusedCompId(shared.focusedComp.Id())
 		}
+		data, _ := json.Marshal(args)
+		w.Write(data)
+	}
+	w.Writes("}")
+	ew.actions++
+}
+
+// close writes the end of the response (a no action if no actions were written).
+func (ew *erespWriter) close() {
+	if ew.actions == 0 {
+		ew.action(eraNoAction)
+	}
+	if ew.json {
+		ew.w.Writes("]")
+	}
+}
+
+// handleBufChange processes a buffered change (of a component in buffered mode)
+// which was sent along with the specified event.
+// The change is in the form of "etype,compId,compValue" (already decoded
+// like the other params, compValue may contain commas).
+func (s *serverImpl) handleBufChange(win Window, event *eventImpl, change string) {
+	parts := strings.SplitN(change, ",", 3)
+	if len(parts) != 3 {
+		return
+	}
+	etype, err := strconv.Atoi(parts[0])
+	if err != nil || etype < 0 {
+		return
+	}
+	id, err := AtoID(parts[1])
+	if err != nil {
+		return
+	}
+	value := parts[2]
+
+	comp := win.ById(id)
+	if comp == nil {
+		if s.logger != nil {
+			s.logger.Println("\tBuffered change of comp not found:", id)
+		}
+		return
+	}
+
+	// The buffered event shares the data (session, dirty comps etc.) of the event it was sent with
+	e := &eventImpl{etype: EventType(etype), src: comp, x: -1, y: -1, shared: event.shared}
+	r := &http.Request{Form: url.Values{paramCompValue: {value}}}
+	comp.preprocessEvent(e, r)
+	if decoder := comp.EventDecoder(); decoder != nil {
+		e.decoded = decoder(r)
+	}
+	comp.dispatchEvent(e)
+}
+
+// parseGeolocation parses the geolocation params.
+// ok is false if they are missing or invalid.
+func parseGeolocation(r *http.Request) (lat, long float64, ok bool) {
+	lat, err := strconv.ParseFloat(r.FormValue(paramGeoLat), 64)
+	if err != nil || !(lat >= -90 && lat <= 90) { // Also catches NaN
+		return 0, 0, false
 	}
-	if !hasAction {
-		w.Writev(eraNoAction)
+	long, err = strconv.ParseFloat(r.FormValue(paramGeoLong), 64)
+	if err != nil || !(long >= -180 && long <= 180) {
+		return 0, 0, false
 	}
+	return lat, long, true
 }
 
 // parseIntParam parses an int param.
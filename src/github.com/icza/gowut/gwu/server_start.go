@@ -66,14 +66,19 @@ func (s *serverImpl) Start(openWins ...string) error {
 
 	go s.sessCleaner()
 
+	srv := &http.Server{Addr: s.addr, TLSConfig: s.tlsConfig}
+	s.httpServerMu.Lock()
+	s.httpServer = srv
+	s.httpServerMu.Unlock()
+
 	var err error
 	if s.secure {
-		err = http.ListenAndServeTLS(s.addr, s.certFile, s.keyFile, nil)
+		err = srv.ListenAndServeTLS(s.certFile, s.keyFile)
 	} else {
-		err = http.ListenAndServe(s.addr, nil)
+		err = srv.ListenAndServe()
 	}
 
-	if err != nil {
+	if err != nil && err != http.ErrServerClosed {
 		return err
 	}
 	return nil
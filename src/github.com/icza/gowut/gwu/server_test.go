@@ -0,0 +1,1129 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// checkStaticJsFuncs checks that the static JS defines the specified functions
+// (called by rendered components or by the client processing responses).
+func checkStaticJsFuncs(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if !bytes.Contains(staticJs, []byte("function "+name+"(")) {
+			t.Errorf("Static JS does not define %s()", name)
+		}
+	}
+}
+
+// sendEvent sends an event with the specified params to the window
+// in the specified session, and returns the recorded response.
+func sendEvent(s *serverImpl, sess Session, win Window, params url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", s.AppPath()+win.Name()+"/"+pathEvent, strings.NewReader(params.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	wr := httptest.NewRecorder()
+	s.handleEvent(sess, win, wr, r)
+	return wr
+}
+
+// clickParams returns the event params of a click event of the specified component.
+func clickParams(c Comp) url.Values {
+	return url.Values{paramCompId: {c.Id().String()}, paramEventType: {ETypeClick.String()}}
+}
+
+func TestEventRedirectAfter(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	b := NewButton("Go")
+	b.AddEHandlerFunc(func(e Event) {
+		e.RedirectAfter("/other/page?a=1,b;c", 5*time.Second)
+	}, ETypeClick)
+	win.Add(b)
+
+	wr := sendEvent(s, &s.sessionImpl, win, clickParams(b))
+	if wr.Code != http.StatusOK {
+		t.Fatalf("Unexpected status: %d", wr.Code)
+	}
+	want := strconv.Itoa(eraRedirect) + ",5000,%2Fother%2Fpage%3Fa=1%2Cb%3Bc"
+	if body := wr.Body.String(); body != want {
+		t.Errorf("Got response: %q, want: %q", body, want)
+	}
+}
+
+func TestNewServerTLSConfig(t *testing.T) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	s := NewServerTLSConfig("guitest", "example.com:443", config).(*serverImpl)
+	if !s.Secure() || s.AppUrl() != "https://example.com:443/guitest/" || s.tlsConfig != config {
+		t.Errorf("Got secure: %v, app URL: %s", s.Secure(), s.AppUrl())
+	}
+
+	wr := httptest.NewRecorder()
+	s.addSessCookie(s.newSession(nil), wr)
+	if cookies := wr.Result().Cookies(); len(cookies) != 1 || !cookies[0].Secure {
+		t.Errorf("Got cookies: %v", cookies)
+	}
+}
+
+func TestSessCookieConfig(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	cookie := func() *http.Cookie {
+		wr := httptest.NewRecorder()
+		s.addSessCookie(s.newSession(nil), wr)
+		return wr.Result().Cookies()[0]
+	}
+
+	if c := cookie(); !c.HttpOnly || c.Secure || c.SameSite != http.SameSiteLaxMode {
+		t.Errorf("Got default cookie: %v", c)
+	}
+
+	if err := s.SetSessCookieConfig(SessCookieConfig{SameSite: http.SameSiteNoneMode}); err == nil {
+		t.Errorf("Expected error for insecure SameSite=None")
+	}
+	if err := s.SetSessCookieConfig(SessCookieConfig{SameSite: http.SameSiteNoneMode, Secure: true}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if c := cookie(); c.HttpOnly || !c.Secure || c.SameSite != http.SameSiteNoneMode {
+		t.Errorf("Got cookie: %v", c)
+	}
+
+	// Always secure in secure mode
+	s = NewServerTLSConfig("guitest", "", &tls.Config{}).(*serverImpl)
+	if err := s.SetSessCookieConfig(SessCookieConfig{SameSite: http.SameSiteNoneMode}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if c := cookie(); !c.Secure {
+		t.Errorf("Got cookie: %v", c)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	s.AddWin(NewWindow("main", "Test"))
+	sess := s.newSession(nil)
+
+	var calls []string
+	mw := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if rs := RequestSession(r); rs != nil {
+					name += ":" + rs.Id()
+				}
+				calls = append(calls, name)
+				if r.URL.Query().Get("deny") == name {
+					http.Error(w, "Forbidden", http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	s.AddSessMiddleware(mw("sess"))
+	s.AddMiddleware(mw("a"))
+	s.AddMiddleware(mw("b"))
+
+	request := func(query string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", s.AppPath()+"main"+query, nil)
+		r.AddCookie(&http.Cookie{Name: gwuSessidCookie, Value: sess.Id()})
+		wr := httptest.NewRecorder()
+		s.serveHTTP(wr, r)
+		return wr
+	}
+
+	if wr := request(""); wr.Code != http.StatusOK {
+		t.Errorf("Got status: %d", wr.Code)
+	}
+	if want := []string{"a", "b", "sess:" + sess.Id()}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("Got calls: %v, want: %v", calls, want)
+	}
+
+	calls = nil
+	if wr := request("?deny=b"); wr.Code != http.StatusForbidden {
+		t.Errorf("Got status: %d", wr.Code)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("Got calls: %v, want: %v", calls, want)
+	}
+}
+
+func TestJSONResponses(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	s.SetJSONResponses(true)
+	win := NewWindow("main", "Test")
+	b := NewButton("Go")
+	b.AddEHandlerFunc(func(e Event) {
+		e.RedirectAfter("/other/page?a=1,b;c&w=100%", 5*time.Second)
+		e.SetFocusedComp(b)
+	}, ETypeClick)
+	b.AddEHandlerFunc(func(e Event) {
+		e.RerenderWindow()
+	}, ETypeDblClick)
+	win.Add(b)
+	win.Add(NewLabel("width:100%"))
+
+	wr := sendEvent(s, &s.sessionImpl, win, clickParams(b))
+	if ct := wr.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("Got content type: %s", ct)
+	}
+	var actions []struct {
+		Type int
+		Args []interface{}
+	}
+	if err := json.Unmarshal(wr.Body.Bytes(), &actions); err != nil {
+		t.Fatalf("Invalid JSON response %q: %v", wr.Body.String(), err)
+	}
+	// Strings are not URL-encoded
+	want := []interface{}{float64(5000), "/other/page?a=1,b;c&w=100%"}
+	if len(actions) != 2 || actions[0].Type != eraFocusComp || actions[1].Type != eraRedirect ||
+		!reflect.DeepEqual(actions[1].Args, want) {
+		t.Errorf("Got actions: %v", actions)
+	}
+
+	wr = sendEvent(s, &s.sessionImpl, win, url.Values{paramCompId: {b.Id().String()}, paramEventType: {ETypeDblClick.String()}})
+	if err := json.Unmarshal(wr.Body.Bytes(), &actions); err != nil {
+		t.Fatalf("Invalid JSON response %q: %v", wr.Body.String(), err)
+	}
+	if len(actions) != 1 || actions[0].Type != eraRerenderWin || len(actions[0].Args) != 2 ||
+		!strings.Contains(actions[0].Args[1].(string), ">width:100%<") {
+		t.Errorf("Got actions: %v", actions)
+	}
+
+	// No action
+	wr = sendEvent(s, &s.sessionImpl, win, url.Values{paramCompId: {b.Id().String()}, paramEventType: {ETypeBlur.String()}})
+	if body, want := wr.Body.String(), "[{\"type\":"+strconv.Itoa(eraNoAction)+"}]"; body != want {
+		t.Errorf("Got response: %q, want: %q", body, want)
+	}
+}
+
+func TestStaticJsRedirect(t *testing.T) {
+	checkStaticJsFuncs(t, "redirectAfter", "cancelRedirect")
+}
+
+func TestStaticJsScrollRestore(t *testing.T) {
+	checkStaticJsFuncs(t, "scrollPositions", "restoreScrollPositions")
+}
+
+func TestEventSetCookie(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	b := NewButton("Dark theme")
+	b.AddEHandlerFunc(func(e Event) {
+		e.SetCookie(&http.Cookie{Name: "theme", Value: "dark", Path: "/"})
+	}, ETypeClick)
+	win.Add(b)
+
+	wr := sendEvent(s, &s.sessionImpl, win, clickParams(b))
+	cookies := wr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "theme" || cookies[0].Value != "dark" {
+		t.Errorf("Cookie not set properly: %v", cookies)
+	}
+}
+
+func TestBufferedChanges(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	tb := NewTextBox("")
+	tb.SetBuffered(true)
+	var log []string
+	tb.AddEHandlerFunc(func(e Event) { log = append(log, "change:"+tb.Text()) }, ETypeChange)
+	apply := NewButton("Apply")
+	apply.AddEHandlerFunc(func(e Event) { log = append(log, "apply:"+tb.Text()) }, ETypeClick)
+	win.Add(tb)
+	win.Add(apply)
+
+	// Changes are buffered at the client side:
+	if s := renderString(tb); !strings.Contains(s, ` onchange="sbuf(`+ETypeChange.String()+`,`+tb.Id().String()+`,encodeURIComponent(this.value))"`) {
+		t.Errorf("Buffered change handler not rendered: %s", s)
+	}
+
+	// ...and flushed with the click on Apply:
+	params := clickParams(apply)
+	params.Add(paramBufChange, ETypeChange.String()+","+tb.Id().String()+",Hello, World; 1+1=2 100%")
+	params.Add(paramBufChange, "malformed")
+	sendEvent(s, &s.sessionImpl, win, params)
+
+	want := "Hello, World; 1+1=2 100%"
+	if tb.Text() != want {
+		t.Errorf("Got text %q, want %q", tb.Text(), want)
+	}
+	if len(log) != 2 || log[0] != "change:"+want || log[1] != "apply:"+want {
+		t.Errorf("Unexpected handler calls: %v", log)
+	}
+}
+
+func TestEventCaptureComp(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	chart := NewPanel()
+	b := NewButton("Export")
+	b.AddEHandlerFunc(func(e Event) {
+		e.CaptureComp(chart, "chart 1.png")
+	}, ETypeClick)
+	win.Add(chart)
+	win.Add(b)
+
+	want := strconv.Itoa(eraCaptureComp) + "," + chart.Id().String() + ",chart%201.png"
+	if body := sendEvent(s, &s.sessionImpl, win, clickParams(b)).Body.String(); body != want {
+		t.Errorf("Got response: %q, want: %q", body, want)
+	}
+
+	checkStaticJsFuncs(t, "captureComp", "gwuCapture")
+}
+
+func TestEventDecoder(t *testing.T) {
+	type point struct{ X, Y int }
+
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	tb := NewTextBox("")
+	tb.SetEventDecoder(func(r *http.Request) interface{} {
+		var p point
+		parts := strings.Split(r.FormValue(paramCompValue), ";")
+		if len(parts) == 2 {
+			p.X, _ = strconv.Atoi(parts[0])
+			p.Y, _ = strconv.Atoi(parts[1])
+		}
+		return p
+	})
+	var got interface{}
+	tb.AddEHandlerFunc(func(e Event) { got = e.DecodedValue() }, ETypeChange)
+	win.Add(tb)
+
+	params := url.Values{paramCompId: {tb.Id().String()}, paramEventType: {ETypeChange.String()}, paramCompValue: {"3;4"}}
+	sendEvent(s, &s.sessionImpl, win, params)
+	if p, ok := got.(point); !ok || p != (point{3, 4}) {
+		t.Errorf("Got decoded value %#v, want %#v", got, point{3, 4})
+	}
+}
+
+func TestKeyHandler(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	tb := NewTextBox("")
+	var called []string
+	tb.AddKeyHandler(KeyEnter, 0, handlerFuncWrapper{func(e Event) { called = append(called, "enter") }})
+	tb.AddKeyHandler(KeyEnter, ModKeyCtrl, handlerFuncWrapper{func(e Event) { called = append(called, "ctrl+enter") }})
+	win.Add(tb)
+
+	cases := []struct {
+		keyCode, modKeys string
+		want             string
+	}{
+		{"13", "0", "enter"},
+		{"13", strconv.Itoa(int(ModKeyCtrl)), "ctrl+enter"},
+		{"13", strconv.Itoa(int(ModKeyCtrl | ModKeyAlt)), ""},
+		{"27", "0", ""},
+		{"13", "", "enter,ctrl+enter"}, // Modifier key info not available
+	}
+	for _, c := range cases {
+		called = nil
+		params := url.Values{paramCompId: {tb.Id().String()}, paramEventType: {ETypeKeyDown.String()},
+			paramKeyCode: {c.keyCode}, paramModKeys: {c.modKeys}}
+		sendEvent(s, &s.sessionImpl, win, params)
+		if got := strings.Join(called, ","); got != c.want {
+			t.Errorf("Key %s, mod keys %s: got called %q, want %q", c.keyCode, c.modKeys, got, c.want)
+		}
+	}
+}
+
+func TestCtrlClickModKeys(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	b := NewButton("Go")
+	var ctrl, alt bool
+	modKeys := -1
+	b.AddEHandlerFunc(func(e Event) {
+		modKeys, ctrl, alt = e.ModKeys(), e.ModKey(ModKeyCtrl), e.ModKey(ModKeyAlt)
+	}, ETypeClick)
+	win.Add(b)
+
+	params := clickParams(b)
+	params.Set(paramModKeys, strconv.Itoa(int(ModKeyCtrl)))
+	sendEvent(s, &s.sessionImpl, win, params)
+	if modKeys != int(ModKeyCtrl) || !ctrl || alt {
+		t.Errorf("Got mod keys %d (ctrl: %v, alt: %v)", modKeys, ctrl, alt)
+	}
+}
+
+func TestDblClickEvent(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	l := NewLabel("Item")
+	var got Event
+	l.AddEHandlerFunc(func(e Event) { got = e }, ETypeDblClick)
+	win.Add(l)
+
+	id := l.Id().String()
+	if html := renderString(l); !strings.Contains(html, ` ondblclick="se(event,`+ETypeDblClick.String()+`,`+id+`)"`) {
+		t.Errorf("Double click handler not rendered: %s", html)
+	}
+
+	// Same data as of a click
+	params := url.Values{paramCompId: {id}, paramEventType: {ETypeDblClick.String()},
+		paramMouseWX: {"110"}, paramMouseWY: {"120"}, paramMouseX: {"10"}, paramMouseY: {"20"},
+		paramMouseBtn: {strconv.Itoa(int(MouseBtnLeft))}, paramModKeys: {strconv.Itoa(int(ModKeyShift))}}
+	sendEvent(s, &s.sessionImpl, win, params)
+	if got == nil || got.Type() != ETypeDblClick {
+		t.Fatalf("Double click handler not called")
+	}
+	if x, y := got.Mouse(); x != 10 || y != 20 {
+		t.Errorf("Got mouse %d, %d", x, y)
+	}
+	if x, y := got.MouseWin(); x != 110 || y != 120 {
+		t.Errorf("Got mouse win %d, %d", x, y)
+	}
+	if got.MouseBtn() != MouseBtnLeft || !got.ModKey(ModKeyShift) || got.ModKey(ModKeyCtrl) {
+		t.Errorf("Got mouse btn %d, mod keys %d", got.MouseBtn(), got.ModKeys())
+	}
+}
+
+func TestContextMenuEvent(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	l := NewLabel("Item")
+	prevent := false
+	var x, y int
+	l.AddEHandlerFunc(func(e Event) {
+		x, y = e.MouseWin()
+		if prevent {
+			e.PreventDefault()
+		}
+	}, ETypeContextMenu)
+	win.Add(l)
+
+	id := l.Id().String()
+	if html := renderString(l); !strings.Contains(html, ` oncontextmenu="return seSync(event,`+ETypeContextMenu.String()+`,`+id+`)"`) {
+		t.Errorf("Context menu handler not rendered: %s", html)
+	}
+	if ETypeContextMenu.Category() != ECatGeneral {
+		t.Errorf("Got category %d", ETypeContextMenu.Category())
+	}
+
+	params := url.Values{paramCompId: {id}, paramEventType: {ETypeContextMenu.String()},
+		paramMouseWX: {"110"}, paramMouseWY: {"120"}, paramMouseX: {"10"}, paramMouseY: {"20"}}
+	// Native menu is only suppressed if requested
+	if body := sendEvent(s, &s.sessionImpl, win, params).Body.String(); body != strconv.Itoa(eraNoAction) {
+		t.Errorf("Got response %q", body)
+	}
+	if x != 110 || y != 120 {
+		t.Errorf("Got mouse win %d, %d", x, y)
+	}
+	prevent = true
+	if body := sendEvent(s, &s.sessionImpl, win, params).Body.String(); body != strconv.Itoa(eraPreventDefault) {
+		t.Errorf("Got response %q", body)
+	}
+
+	checkStaticJsFuncs(t, "seSync")
+}
+
+func TestEventTypeValues(t *testing.T) {
+	// Values of existing event types must not change when new ones are added
+	for etype, want := range map[EventType]int{ETypeClick: 0, ETypeFocus: 12, ETypeWinLoad: 13, ETypeWinUnload: 14, ETypeStateChange: 15} {
+		if int(etype) != want {
+			t.Errorf("Got value %d, want %d", etype, want)
+		}
+	}
+	for etype, want := range map[EventType]EventCategory{
+		ETypeFocus: ECatGeneral, ETypePaste: ECatGeneral, ETypeContextMenu: ECatGeneral, ETypeWheel: ECatGeneral, ETypeInput: ECatGeneral,
+		ETypeWinUnload: ECatWindow, ETypeWinIdle: ECatWindow,
+		ETypeStateChange: ECatInternal, ETypeGeolocation: ECatInternal, ETypeScriptLoaded: ECatInternal, ETypeSessExpired: ECatInternal,
+		ETypeInput + 1: ECatUnknown,
+	} {
+		if cat := etype.Category(); cat != want {
+			t.Errorf("Got category %d for %d, want %d", cat, etype, want)
+		}
+	}
+}
+
+func TestWheelEvent(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	p := NewPanel()
+	var dx, dy int
+	p.AddEHandlerFunc(func(e Event) {
+		dx, dy = e.WheelDeltaX(), e.WheelDeltaY()
+	}, ETypeWheel)
+	win.Add(p)
+
+	// Wheel events are sent asynchronously
+	id := p.Id().String()
+	if html := renderString(p); !strings.Contains(html, ` onwheel="se(event,`+ETypeWheel.String()+`,`+id+`)"`) {
+		t.Errorf("Wheel handler not rendered: %s", html)
+	}
+	p.SetWheelPreventDefault(true) // Zoom instead of scrolling the page
+	if html := renderString(p); !strings.Contains(html, ` onwheel="event.preventDefault();se(event,`+ETypeWheel.String()+`,`+id+`)"`) {
+		t.Errorf("Wheel handler not rendered with prevent default: %s", html)
+	}
+
+	params := url.Values{paramCompId: {id}, paramEventType: {ETypeWheel.String()}, paramWheelDX: {"0"}, paramWheelDY: {"-48"}}
+	if body := sendEvent(s, &s.sessionImpl, win, params).Body.String(); body != strconv.Itoa(eraNoAction) {
+		t.Errorf("Got response %q", body)
+	}
+	if dx != 0 || dy != -48 {
+		t.Errorf("Got wheel deltas %d, %d", dx, dy)
+	}
+}
+
+func TestEventFocusAfterRender(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	p := NewPanel()
+	search := NewTextBox("")
+	p.Add(search)
+	show := NewButton("Show form")
+	p.Add(show)
+	win.Add(p)
+
+	var firstField TextBox
+	show.AddEHandlerFunc(func(e Event) {
+		firstField = NewTextBox("")
+		p.Add(firstField)
+		e.MarkDirty(p)
+		e.SetFocusedComp(firstField)
+	}, ETypeClick)
+
+	params := clickParams(show)
+	params.Set(paramFocusedCompId, search.Id().String()) // Search box was focused
+	wr := sendEvent(s, &s.sessionImpl, win, params)
+
+	// Focus action must come after the re-render of the dirty container
+	want := strconv.Itoa(eraDirtyComps) + "," + p.Id().String() + ";" +
+		strconv.Itoa(eraFocusComp) + "," + firstField.Id().String()
+	if body := wr.Body.String(); body != want {
+		t.Errorf("Got response %q, want %q", body, want)
+	}
+	if doc := renderWinString(win, s); !strings.Contains(doc, "var _focCompId='"+firstField.Id().String()+"';") {
+		t.Errorf("New component not registered as focused at the window: %s", doc)
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+
+	// Two sessions, both viewing the "score" window
+	var sessions []Session
+	for i := 0; i < 2; i++ {
+		sess := s.newSession(nil)
+		win := NewWindow("score", "Score")
+		win.SetPushEnabled(true)
+		l := NewLabel("0:0")
+		win.Add(l)
+		sess.AddWin(win)
+		sess.SetAttr("label", l)
+		sessions = append(sessions, sess)
+	}
+	// Public session has another window only
+	s.AddWin(NewWindow("other", "Other"))
+
+	var updated int
+	s.Broadcast("score", func(ev BroadcastEvent) {
+		updated++
+		l := ev.Session().Attr("label").(Label)
+		l.SetText("1:0")
+		ev.MarkDirty(l)
+	})
+	if updated != 2 {
+		t.Errorf("Update called %d times, want 2", updated)
+	}
+
+	for i, sess := range sessions {
+		l := sess.Attr("label").(Label)
+		if l.Text() != "1:0" {
+			t.Errorf("Session %d: got text %q", i, l.Text())
+		}
+
+		// Long-polling push requests of the session, e.g. from 2 browser tabs: both get the update
+		for tab := 0; tab < 2; tab++ {
+			r := httptest.NewRequest("POST", s.AppPath()+"score/"+pathPush, strings.NewReader(paramPushSeq+"=0"))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r.AddCookie(&http.Cookie{Name: gwuSessidCookie, Value: sess.Id()})
+			wr := httptest.NewRecorder()
+			s.serveHTTP(wr, r)
+			if want := strconv.Itoa(eraDirtyComps) + "," + l.Id().String(); wr.Body.String() != want || wr.Header().Get(hdrPushSeq) != "1" {
+				t.Errorf("Session %d, tab %d: got push response %q (seq: %s), want %q", i, tab, wr.Body.String(), wr.Header().Get(hdrPushSeq), want)
+			}
+		}
+	}
+}
+
+func TestWindowWaitPush(t *testing.T) {
+	win := NewWindow("main", "Test")
+	l := NewLabel("x")
+	ctx := context.Background()
+
+	if ids, seq := win.waitPush(ctx, 0, time.Millisecond); len(ids) != 0 || seq != 0 {
+		t.Errorf("Got ids %v (seq: %d) without push", ids, seq)
+	}
+
+	// All waiting push requests are notified
+	done := make(chan []ID)
+	for i := 0; i < 2; i++ {
+		go func() {
+			ids, _ := win.waitPush(ctx, 0, time.Minute)
+			done <- ids
+		}()
+	}
+	win.push(map[ID]Comp{l.Id(): l})
+	for i := 0; i < 2; i++ {
+		select {
+		case ids := <-done:
+			if len(ids) != 1 || ids[0] != l.Id() {
+				t.Errorf("Got ids %v", ids)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Waiting push request not notified")
+		}
+	}
+
+	// Waiting ends when the request is done
+	cctx, cancel := context.WithCancel(ctx)
+	go func() {
+		ids, _ := win.waitPush(cctx, 1, time.Minute)
+		done <- ids
+	}()
+	cancel()
+	select {
+	case ids := <-done:
+		if len(ids) != 0 {
+			t.Errorf("Got ids %v", ids)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Waiting push request not ended by its context")
+	}
+
+	// Missed pushes re-render the whole window
+	for i := 0; i < maxPushes+1; i++ {
+		win.push(map[ID]Comp{l.Id(): l})
+	}
+	if ids, seq := win.waitPush(ctx, 1, time.Minute); len(ids) != 1 || ids[0] != win.Id() || seq != maxPushes+2 {
+		t.Errorf("Got ids %v (seq: %d)", ids, seq)
+	}
+	if ids, _ := win.waitPush(ctx, maxPushes+1, time.Minute); len(ids) != 1 || ids[0] != l.Id() {
+		t.Errorf("Got ids %v", ids)
+	}
+}
+
+func TestEventRerenderWindow(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	l := NewLabel("old")
+	b := NewButton("Go")
+	b.AddEHandlerFunc(func(e Event) {
+		l.SetText("new;1,2")
+		e.MarkDirty(l)
+		e.RerenderWindow()
+	}, ETypeClick)
+	win.Add(l)
+	win.Add(b)
+
+	wr := sendEvent(s, &s.sessionImpl, win, clickParams(b))
+	parts := strings.SplitN(wr.Body.String(), ",", 3)
+	if len(parts) != 3 || parts[0] != strconv.Itoa(eraRerenderWin) || parts[1] != win.Id().String() {
+		t.Fatalf("Unexpected response: %s", wr.Body.String())
+	}
+	if strings.ContainsAny(parts[2], ",;") {
+		t.Errorf("Window content contains action separators: %s", parts[2])
+	}
+	html, err := url.PathUnescape(parts[2])
+	if err != nil {
+		t.Fatalf("Failed to unescape window content: %v", err)
+	}
+	// The full window content, no separate dirty comps (attribute order may vary)
+	if want := renderString(win); len(html) != len(want) || !strings.Contains(html, ` id="`+win.Id().String()+`"`) {
+		t.Errorf("Got window content %s, want %s", html, want)
+	}
+	if !strings.Contains(html, ">new;1,2</span>") || !strings.HasSuffix(html, "Go</button></table>") {
+		t.Errorf("Window content is not up-to-date: %s", html)
+	}
+
+	checkStaticJsFuncs(t, "spliceComp")
+}
+
+func TestEventSubmitExternalForm(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	b := NewButton("Pay")
+	b.AddEHandlerFunc(func(e Event) {
+		e.SubmitExternalForm("https://pay.example.com/start?x=1;y", "POST",
+			map[string]string{"amount": "12,50", "memo": "a b&c=d"})
+	}, ETypeClick)
+	win.Add(b)
+
+	wr := sendEvent(s, &s.sessionImpl, win, clickParams(b))
+	want := strconv.Itoa(eraSubmitForm) + ",POST,https:%2F%2Fpay.example.com%2Fstart%3Fx=1%3By,amount=12%252C50&memo=a+b%2526c%253Dd"
+	if body := wr.Body.String(); body != want {
+		t.Errorf("Got response: %q, want: %q", body, want)
+	}
+
+	checkStaticJsFuncs(t, "submitExtForm")
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	if err := s.AddWin(NewWindow("main", "Test")); err != nil {
+		t.Fatal(err)
+	}
+	s.SetHeaders(map[string][]string{"Gowut-Server": {GowutVersion}})
+
+	request := func(path string, static bool) http.Header {
+		t.Helper()
+		r := httptest.NewRequest("GET", s.AppPath()+path, nil)
+		wr := httptest.NewRecorder()
+		if static {
+			s.serveStatic(wr, r)
+		} else {
+			s.serveHTTP(wr, r)
+		}
+		if wr.Code != http.StatusOK {
+			t.Fatalf("Unexpected status for %s: %d", path, wr.Code)
+		}
+		return wr.Header()
+	}
+
+	if h := request("main", false); h.Get("X-Frame-Options") != "" || h.Get("Content-Security-Policy") != "" {
+		t.Errorf("Security headers sent by default: %v", h)
+	}
+
+	sh := DefaultSecurityHeaders
+	sh.FrameOptions = "DENY"
+	sh.ReferrerPolicy = "" // Not sent
+	s.SetSecurityHeaders(sh)
+	if s.SecurityHeaders() != sh {
+		t.Errorf("Got security headers %v", s.SecurityHeaders())
+	}
+
+	for _, path := range []string{"main", pathStatic + resNameStaticJs} {
+		h := request(path, path != "main")
+		for name, want := range map[string]string{
+			"X-Content-Type-Options":  "nosniff",
+			"X-Frame-Options":         "DENY",
+			"Content-Security-Policy": sh.ContentSecurityPolicy,
+			"Gowut-Server":            GowutVersion,
+		} {
+			if got := h.Get(name); got != want {
+				t.Errorf("%s: got %s header %q, want %q", path, name, got, want)
+			}
+		}
+		if _, ok := h["Referrer-Policy"]; ok {
+			t.Errorf("%s: empty Referrer-Policy header sent", path)
+		}
+	}
+}
+
+func TestDefaultSecurityHeadersCSP(t *testing.T) {
+	csp := DefaultSecurityHeaders.ContentSecurityPolicy
+	var scriptSrc string
+	for _, directive := range strings.Split(csp, ";") {
+		if d := strings.TrimSpace(directive); strings.HasPrefix(d, "script-src ") {
+			scriptSrc = d
+		}
+	}
+	allows := func(source string) bool { return strings.Contains(scriptSrc, source) }
+
+	// Rendered windows use inline scripts and event handler attributes
+	win := NewWindow("main", "Test")
+	b := NewButton("b")
+	b.AddEHandlerFunc(func(e Event) {}, ETypeClick)
+	win.Add(b)
+	if doc := renderWinString(win, newServerImpl("guitest", "", "", "")); (strings.Contains(doc, "<script>") || strings.Contains(doc, ` onclick="`)) && !allows("'unsafe-inline'") {
+		t.Errorf("Inline scripts are used but not allowed by %q", csp)
+	}
+
+	// Code evaluated from strings (eval, Function constructor, string timers) needs 'unsafe-eval'
+	js := regexp.MustCompile(`(?m)^\s*//.*$`).ReplaceAllString(string(staticJs), "") // Without comments
+	if allows("'unsafe-eval'") {
+		return
+	}
+	if loc := regexp.MustCompile(`\beval\(|\bnew Function\(`).FindStringIndex(js); loc != nil {
+		t.Errorf("Static JS evaluates code not allowed by %q: %s", csp, js[loc[0]:loc[1]+30])
+	}
+	// Timer callbacks must be functions: function literals, declared functions or fn params
+	for _, m := range regexp.MustCompile(`\bset(?:Timeout|Interval)\(\s*([^,]+),`).FindAllStringSubmatch(js, -1) {
+		if arg := m[1]; !strings.HasPrefix(arg, "function") && arg != "fn" && !strings.Contains(js, "function "+arg+"(") {
+			t.Errorf("Static JS timer callback may be a string, not allowed by %q: %s", csp, m[0])
+		}
+	}
+}
+
+func TestCSPNonce(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	s.SetSecurityHeaders(SecurityHeaders{ContentSecurityPolicy: "script-src 'self' 'nonce-" + NoncePlaceholder + "'"})
+	win := NewWindow("main", "Test")
+	win.AddEHandlerFunc(func(e Event) {}, ETypeWinLoad)
+	s.AddWin(win)
+	s.PrewarmWindow("pre", func() Window { return NewWindow("pre", "Prewarmed") })
+
+	request := func(name string) (nonce, body string) {
+		wr := httptest.NewRecorder()
+		s.serveHTTP(wr, httptest.NewRequest("GET", s.AppPath()+name, nil))
+		csp := wr.Header().Get("Content-Security-Policy")
+		if !strings.HasPrefix(csp, "script-src 'self' 'nonce-") || strings.Contains(csp, NoncePlaceholder) {
+			t.Fatalf("Got CSP header %q", csp)
+		}
+		return strings.TrimSuffix(csp[len("script-src 'self' 'nonce-"):], "'"), wr.Body.String()
+	}
+
+	for _, name := range []string{"main", "pre", "pre"} {
+		nonce, body := request(name)
+		if strings.Contains(body, "<script>") || strings.Contains(body, NoncePlaceholder) ||
+			!strings.Contains(body, `<script nonce="`+nonce+`">`) || !strings.Contains(body, `.js" nonce="`+nonce+`"></script>`) {
+			t.Errorf("%s: scripts not rendered with nonce %q: %s", name, nonce, body)
+		}
+		if nonce2, _ := request(name); nonce2 == nonce {
+			t.Errorf("%s: same nonce for multiple responses", name)
+		}
+	}
+
+	// No nonce without the placeholder
+	s.SetSecurityHeaders(DefaultSecurityHeaders)
+	wr := httptest.NewRecorder()
+	s.serveHTTP(wr, httptest.NewRequest("GET", s.AppPath()+"main", nil))
+	if body := wr.Body.String(); strings.Contains(body, "nonce") || !strings.Contains(body, "<script>") {
+		t.Errorf("Scripts rendered with nonce: %s", body)
+	}
+
+	checkStaticJsFuncs(t, "runScripts")
+}
+
+func TestPrewarmWindow(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	builds := 0
+	l := NewLabel("initial")
+	builder := func() Window {
+		builds++
+		win := NewWindow("main", "Test")
+		win.Add(l)
+		return win
+	}
+	if err := s.PrewarmWindow("other", builder); err == nil {
+		t.Error("No error for window name mismatch")
+	}
+	builds = 0
+	if err := s.PrewarmWindow("main", builder); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func() string {
+		t.Helper()
+		wr := httptest.NewRecorder()
+		s.serveHTTP(wr, httptest.NewRequest("GET", s.AppPath()+"main", nil))
+		if wr.Code != http.StatusOK {
+			t.Fatalf("Unexpected status: %d", wr.Code)
+		}
+		return wr.Body.String()
+	}
+
+	first := get()
+	if !strings.Contains(first, ">initial</span>") || !strings.HasSuffix(first, "</body></html>") {
+		t.Errorf("Prewarmed window not served: %s", first)
+	}
+	// Served from the shell: changes without an event are not rendered
+	l.SetText("changed")
+	if doc := get(); doc != first {
+		t.Errorf("Shell not served, got: %s", doc)
+	}
+	if builds != 1 {
+		t.Errorf("Builder called %d times, want 1", builds)
+	}
+
+	// Events drop the shell
+	r := httptest.NewRequest("POST", s.AppPath()+"main/"+pathEvent, strings.NewReader(clickParams(l).Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	s.serveHTTP(httptest.NewRecorder(), r)
+	if doc := get(); !strings.Contains(doc, ">changed</span>") {
+		t.Errorf("Shell not dropped, got: %s", doc)
+	}
+	s.Broadcast("main", func(ev BroadcastEvent) { l.SetText("broadcast") })
+	if doc := get(); !strings.Contains(doc, ">broadcast</span>") {
+		t.Errorf("Shell not dropped by broadcast, got: %s", doc)
+	}
+	if builds != 1 {
+		t.Errorf("Builder called %d times, want 1", builds)
+	}
+}
+
+func TestRenderFilter(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	l := NewLabel("text")
+	win.Add(l)
+	s.AddWin(win)
+
+	var filtered []Comp
+	s.SetRenderFilter(func(c Comp, html []byte) []byte {
+		filtered = append(filtered, c)
+		return bytes.Replace(html, []byte("gwu-Label"), []byte("my-Label"), -1)
+	})
+
+	wr := httptest.NewRecorder()
+	s.serveHTTP(wr, httptest.NewRequest("GET", s.AppPath()+"main", nil))
+	if doc := wr.Body.String(); !strings.Contains(doc, `class="my-Label"`) || strings.Contains(doc, `class="gwu-Label"`) {
+		t.Errorf("Window document not filtered: %s", doc)
+	}
+
+	wr = httptest.NewRecorder()
+	r := httptest.NewRequest("POST", s.AppPath()+"main/"+pathRenderComp, strings.NewReader(paramCompId+"="+l.Id().String()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	s.serveHTTP(wr, r)
+	if html := wr.Body.String(); !strings.Contains(html, `class="my-Label"`) {
+		t.Errorf("Re-rendered comp not filtered: %s", html)
+	}
+
+	if len(filtered) != 2 || filtered[0] != win || filtered[1] != l {
+		t.Errorf("Filter called for unexpected comps: %v", filtered)
+	}
+}
+
+func TestEventGeolocation(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	b := NewButton("Use my location")
+	b.AddEHandlerFunc(func(e Event) { e.RequestGeolocation(b) }, ETypeClick)
+	type geo struct {
+		lat, long float64
+		ok        bool
+	}
+	var got []geo
+	b.AddEHandlerFunc(func(e Event) {
+		lat, long, ok := e.Geolocation()
+		got = append(got, geo{lat, long, ok})
+	}, ETypeGeolocation)
+	win.Add(b)
+
+	wr := sendEvent(s, &s.sessionImpl, win, clickParams(b))
+	want := strconv.Itoa(eraGeolocation) + "," + strconv.Itoa(int(ETypeGeolocation)) + "," + b.Id().String()
+	if body := wr.Body.String(); body != want {
+		t.Errorf("Got response: %q, want: %q", body, want)
+	}
+
+	geoParams := func(lat, long string) url.Values {
+		params := url.Values{paramEventType: {strconv.Itoa(int(ETypeGeolocation))}, paramCompId: {b.Id().String()}}
+		if lat != "" {
+			params.Set(paramGeoLat, lat)
+			params.Set(paramGeoLong, long)
+		}
+		return params
+	}
+	sendEvent(s, &s.sessionImpl, win, geoParams("47.4979", "-19.0402"))
+	sendEvent(s, &s.sessionImpl, win, geoParams("", "")) // Permission denied
+	sendEvent(s, &s.sessionImpl, win, geoParams("91", "0"))
+	sendEvent(s, &s.sessionImpl, win, geoParams("NaN", "0"))
+	wantGot := []geo{{47.4979, -19.0402, true}, {}, {}, {}}
+	if !reflect.DeepEqual(got, wantGot) {
+		t.Errorf("Got geolocations %v, want %v", got, wantGot)
+	}
+	if ETypeGeolocation.Category() != ECatInternal {
+		t.Errorf("Got category %v for ETypeGeolocation", ETypeGeolocation.Category())
+	}
+}
+
+func TestWindowLoadScript(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	b := NewButton("Show chart")
+	var loaded []string
+	b.AddEHandlerFunc(func(e Event) {
+		win.LoadScript("/js/chart.js?v=1,2", func(e Event) {
+			loaded = append(loaded, "chart")
+			if e.Src() != win {
+				t.Errorf("Got event source %v, want the window", e.Src())
+			}
+		})
+		win.LoadScript("/js/extra.js", nil)
+	}, ETypeClick)
+	win.Add(b)
+
+	wr := sendEvent(s, &s.sessionImpl, win, clickParams(b))
+	era, etype, winId := strconv.Itoa(eraLoadScript), strconv.Itoa(int(ETypeScriptLoaded)), win.Id().String()
+	want := era + "," + etype + "," + winId + "," + url.PathEscape("/js/chart.js?v=1,2") + ";" +
+		era + "," + etype + "," + winId + ",%2Fjs%2Fextra.js"
+	if body := wr.Body.String(); body != want {
+		t.Errorf("Got response: %q, want: %q", body, want)
+	}
+
+	loadedParams := func(scriptUrl string) url.Values {
+		return url.Values{paramEventType: {etype}, paramCompId: {winId}, paramCompValue: {scriptUrl}}
+	}
+	sendEvent(s, &s.sessionImpl, win, loadedParams("/js/extra.js"))
+	sendEvent(s, &s.sessionImpl, win, loadedParams("/js/chart.js?v=1,2"))
+	sendEvent(s, &s.sessionImpl, win, loadedParams("/js/chart.js?v=1,2")) // Called once only
+	if !reflect.DeepEqual(loaded, []string{"chart"}) {
+		t.Errorf("Got loaded callbacks %v", loaded)
+	}
+
+	checkStaticJsFuncs(t, "loadScript")
+}
+
+func TestRenderComps(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	sess := s.newSession(nil)
+	win := NewWindow("main", "Test")
+	p := NewPanel()
+	l1, l2 := NewLabel("one"), NewLabel("two")
+	p.Add(l2)
+	win.Add(l1)
+	win.Add(p)
+	sess.AddWin(win)
+
+	renderComps := func(params url.Values) []compRender {
+		r := httptest.NewRequest("POST", s.AppPath()+win.Name()+"/"+pathRenderComps, strings.NewReader(params.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(&http.Cookie{Name: gwuSessidCookie, Value: sess.Id()})
+		wr := httptest.NewRecorder()
+		s.serveHTTP(wr, r)
+		var renders []compRender
+		if err := json.Unmarshal(wr.Body.Bytes(), &renders); err != nil {
+			t.Fatalf("Invalid response %q: %v", wr.Body.String(), err)
+		}
+		return renders
+	}
+
+	// Missing, duplicate and descendant components are skipped, the order is kept
+	renders := renderComps(url.Values{paramCompId: {
+		l1.Id().String(), "99999", p.Id().String(), l2.Id().String(), l1.Id().String()}})
+	if len(renders) != 2 || renders[0].Id != l1.Id() || renders[1].Id != p.Id() {
+		t.Fatalf("Got renders: %+v", renders)
+	}
+	if !strings.Contains(renders[0].Html, ">one<") || !strings.Contains(renders[1].Html, ">two<") || renders[0].Rev != 0 {
+		t.Errorf("Got renders: %+v", renders)
+	}
+
+	// With diff rendering, revisions are paired with the ids
+	s.SetDiffRender(true)
+	renders = renderComps(url.Values{paramCompId: {l1.Id().String(), l2.Id().String()}, paramRenderRev: {"", ""}})
+	if len(renders) != 2 || renders[0].Rev == 0 || renders[1].Ops != nil {
+		t.Fatalf("Got renders: %+v", renders)
+	}
+	l1.SetText("three")
+	renders = renderComps(url.Values{paramCompId: {l1.Id().String(), l2.Id().String()},
+		paramRenderRev: {strconv.Itoa(renders[0].Rev), strconv.Itoa(renders[1].Rev)}})
+	if len(renders) != 2 || len(renders[0].Ops) != 1 || renders[0].Ops[0].Value != "three" || renders[1].Ops == nil || len(renders[1].Ops) != 0 {
+		t.Errorf("Got renders: %+v", renders)
+	}
+}
+
+func TestStaticJsAsyncRerender(t *testing.T) {
+	checkStaticJsFuncs(t, "rerenderComp", "rerenderComps")
+}
+
+func TestShutdown(t *testing.T) {
+	// Find a free port
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	s := newServerImpl("shuttest", addr, "", "")
+	var calls []string
+	s.OnShutdown(func(sess Session) { calls = append(calls, "public") })
+	sess := s.newSession(nil)
+	sess.OnShutdown(func(sess Session) { calls = append(calls, "private1") })
+	sess.OnShutdown(func(sess Session) { calls = append(calls, "private2") })
+
+	started := make(chan error, 1)
+	go func() { started <- s.Start() }()
+	for i := 0; ; i++ {
+		if resp, err := http.Get(s.AppUrl()); err == nil {
+			resp.Body.Close()
+			break
+		}
+		if i == 100 {
+			t.Fatalf("Server not started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cleanerDone := make(chan struct{})
+	go func() {
+		s.sessCleaner()
+		close(cleanerDone)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown error: %v", err)
+	}
+	select {
+	case <-cleanerDone:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Session cleaner not stopped")
+	}
+	select {
+	case err := <-started:
+		if err != nil {
+			t.Errorf("Start error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Start did not return")
+	}
+	if want := []string{"public", "private1", "private2"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("Got calls: %v, want: %v", calls, want)
+	}
+}
+
+func TestSessionTimeout(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	guest, admin := s.newSession(nil), s.newSession(nil)
+	guest.SetTimeout(time.Minute)
+	admin.SetTimeout(time.Hour)
+	accessed := time.Now().Add(-30 * time.Second)
+	guest.(*sessionImpl).accessed = accessed
+	admin.(*sessionImpl).accessed = accessed
+
+	// Remaining time reported by the session check
+	remaining := func(sess Session) float64 {
+		r := httptest.NewRequest("GET", s.AppPath()+pathSessCheck, nil)
+		r.AddCookie(&http.Cookie{Name: gwuSessidCookie, Value: sess.Id()})
+		wr := httptest.NewRecorder()
+		s.serveHTTP(wr, r)
+		sec, err := strconv.ParseFloat(wr.Body.String(), 64)
+		if err != nil {
+			t.Fatalf("Invalid session check response %q: %v", wr.Body.String(), err)
+		}
+		return sec
+	}
+	if sec := remaining(guest); sec < 25 || sec > 30 {
+		t.Errorf("Got guest remaining: %f", sec)
+	}
+	if sec := remaining(admin); sec < 3565 || sec > 3570 {
+		t.Errorf("Got admin remaining: %f", sec)
+	}
+
+	s.removeExpiredSess(accessed.Add(30 * time.Minute))
+	if s.sessions[guest.Id()] != nil || s.sessions[admin.Id()] == nil {
+		t.Errorf("Guest session not expired or admin session expired")
+	}
+	s.removeExpiredSess(accessed.Add(2 * time.Hour))
+	if s.sessions[admin.Id()] != nil {
+		t.Errorf("Admin session not expired")
+	}
+}
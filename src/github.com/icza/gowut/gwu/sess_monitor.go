@@ -18,6 +18,8 @@
 package gwu
 
 import (
+	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -25,15 +27,24 @@ import (
 // the session timeout and network connectivity at client side without
 // interacting with the session.
 //
+// The session is checked periodically (see SetInterval()), and once
+// right away when the SessMonitor is rendered (e.g. on page load),
+// regardless of the interval. Checks are skipped while the page is hidden
+// (e.g. in a background browser tab), and one check is performed right
+// away when the page becomes visible again.
+//
 // Default style classes: "gwu-SessMonitor", "gwu-SessMonitor-Expired",
 // ".gwu-SessMonitor-Error"
 type SessMonitor interface {
-	// SessMonitor is a Timer, but it does not generate Events!
+	// SessMonitor is a Timer, but it does not generate Events
+	// (except ETypeSessExpired if an expiry handler is set)!
 	Timer
 
 	// SetJsConverter sets the Javascript function name which converts
-	// a float second time value to a displayable string.
-	// The default value is "convertSessTimeout" whose implementation is:
+	// a float second time value to a displayable string. The function
+	// also receives the SessMonitor element as its second argument.
+	// The default value is "convertSessTimeout" which displays the texts
+	// set by SetTexts(), its implementation with the default texts is:
 	//     function convertSessTimeout(sec) {
 	//         if (sec <= 0)
 	//             return "Expired!";
@@ -47,18 +58,107 @@ type SessMonitor interface {
 	// JsConverter returns the name of the Javascript function which converts
 	// float second time values to displayable strings.
 	JsConverter() string
+
+	// Texts returns the texts displayed by the SessMonitor.
+	Texts() SessMonitorTexts
+
+	// SetTexts sets the texts displayed by the SessMonitor, e.g. to localize
+	// them. Empty fields are replaced by their defaults.
+	SetTexts(texts SessMonitorTexts)
+
+	// Interval returns the interval of the session checks.
+	// This is the same as Timeout().
+	Interval() time.Duration
+
+	// SetInterval sets the interval of the session checks. Shorter intervals
+	// are useful with short session timeouts, longer ones spare requests.
+	// Intervals less than MinSessMonitorInterval are raised to it
+	// (this also applies to SetTimeout()). Default is 1 minute.
+	SetInterval(interval time.Duration)
+
+	// KeepAlive tells if keep-alive mode is enabled.
+	KeepAlive() bool
+
+	// SetKeepAlive enables or disables keep-alive mode. In keep-alive mode
+	// a session check extends the session (resets the session timeout) if
+	// there was user activity (mouse, keyboard, touch, scroll) at the client
+	// since the previous check, so an active user stays logged in without
+	// sending events. Checks without activity do not extend the session,
+	// and expired sessions are not revived.
+	// Default is false (session checks never extend the session).
+	SetKeepAlive(keepAlive bool)
+
+	// SetOnExpired sets a handler to be called when the SessMonitor
+	// detects at client side that the session has expired, e.g. to
+	// clean up resources or to log the expiry. Pass nil to remove the handler.
+	//
+	// The client sends an ETypeSessExpired event to the SessMonitor, which
+	// (unlike other events) does not register a session access, so it does
+	// not revive the session. The handler is called while the session is
+	// still present (so the event has access to it), and the session is
+	// removed right after the handler returns. The handler may reload the
+	// window or redirect the client (e.g. to a login page).
+	// If the session has not expired at server side (e.g. it was kept
+	// alive from another browser tab), the handler is not called.
+	//
+	// Expired sessions are also removed periodically by the server; if the
+	// session is already removed when the event arrives, the window is not
+	// found and the handler is not called. For cleanup that must always
+	// happen, use SessionHandler.Removed() instead.
+	SetOnExpired(handler func(e Event))
+
+	// ExpiredURL returns the URL the browser navigates to when the
+	// session expires, empty string if there is none.
+	ExpiredURL() string
+
+	// SetExpiredURL sets the URL the browser navigates to when the
+	// SessMonitor detects that the session has expired (e.g. a login page),
+	// instead of displaying the expired text. Relative URLs are resolved
+	// against the application path (like when reloading a window).
+	// Pass an empty string to display the expired text (default).
+	//
+	// If an expiry handler is also set (see SetOnExpired()), the browser
+	// navigates after the handler has been called, unless the handler
+	// reloads the window or redirects elsewhere.
+	SetExpiredURL(url string)
+}
+
+// SessMonitorTexts holds the texts displayed by a SessMonitor
+// (with the default JS converter, see SessMonitor.SetJsConverter()).
+type SessMonitorTexts struct {
+	Expired     string // Displayed when the session has expired, default: "Expired!"
+	LessThanMin string // Displayed when less than a minute remains, default: "<1 min"
+	Minutes     string // Displayed otherwise, "%d" is replaced by the remaining minutes (rounded), default: "~%d min"
+	ConnError   string // Displayed on connection error, default: "CONN ERR"
+}
+
+// defaultSessMonitorTexts holds the default texts of SessMonitors.
+var defaultSessMonitorTexts = SessMonitorTexts{
+	Expired:     "Expired!",
+	LessThanMin: "<1 min",
+	Minutes:     "~%d min",
+	ConnError:   "CONN ERR",
 }
 
+// MinSessMonitorInterval is the minimum interval of the session checks
+// of a SessMonitor.
+const MinSessMonitorInterval = time.Second
+
 // SessMonitor implementation
 type sessMonitorImpl struct {
 	timerImpl // Timer implementation
+
+	onExpired  func(e Event)    // Handler to call when the session expired
+	texts      SessMonitorTexts // Displayed texts
+	expiredURL string           // URL to navigate to when the session expired
 }
 
 // NewSessMonitor creates a new SessMonitor.
 // By default it is active repeats with 1 minute timeout duration.
 func NewSessMonitor() SessMonitor {
 	c := &sessMonitorImpl{
-		timerImpl{compImpl: newCompImpl(nil), timeout: time.Minute, active: true, repeat: true},
+		timerImpl: timerImpl{compImpl: newCompImpl(nil), timeout: time.Minute, active: true, repeat: true},
+		texts:     defaultSessMonitorTexts,
 	}
 	c.Style().AddClass("gwu-SessMonitor")
 	c.SetJsConverter("convertSessTimeout")
@@ -73,6 +173,88 @@ func (c *sessMonitorImpl) JsConverter() string {
 	return c.Attr("gwuJsFuncName")
 }
 
+func (c *sessMonitorImpl) Texts() SessMonitorTexts {
+	return c.texts
+}
+
+func (c *sessMonitorImpl) SetTexts(texts SessMonitorTexts) {
+	def := &defaultSessMonitorTexts
+	if texts.Expired == "" {
+		texts.Expired = def.Expired
+	}
+	if texts.LessThanMin == "" {
+		texts.LessThanMin = def.LessThanMin
+	}
+	if texts.Minutes == "" {
+		texts.Minutes = def.Minutes
+	}
+	if texts.ConnError == "" {
+		texts.ConnError = def.ConnError
+	}
+	c.texts = texts
+}
+
+func (c *sessMonitorImpl) Interval() time.Duration {
+	return c.timeout
+}
+
+func (c *sessMonitorImpl) SetInterval(interval time.Duration) {
+	if interval < MinSessMonitorInterval {
+		interval = MinSessMonitorInterval
+	}
+	c.timeout = interval
+}
+
+func (c *sessMonitorImpl) SetTimeout(timeout time.Duration) {
+	c.SetInterval(timeout)
+}
+
+func (c *sessMonitorImpl) KeepAlive() bool {
+	return c.Attr("data-gwukeepalive") != ""
+}
+
+func (c *sessMonitorImpl) SetKeepAlive(keepAlive bool) {
+	if keepAlive {
+		c.SetAttr("data-gwukeepalive", "1")
+	} else {
+		c.SetAttr("data-gwukeepalive", "")
+	}
+}
+
+func (c *sessMonitorImpl) SetOnExpired(handler func(e Event)) {
+	c.onExpired = handler
+	if handler == nil {
+		c.SetAttr("data-gwuexpet", "")
+	} else {
+		c.SetAttr("data-gwuexpet", strconv.Itoa(int(ETypeSessExpired)))
+	}
+}
+
+func (c *sessMonitorImpl) ExpiredURL() string {
+	return c.expiredURL
+}
+
+func (c *sessMonitorImpl) SetExpiredURL(url string) {
+	c.expiredURL = url
+}
+
+func (c *sessMonitorImpl) preprocessEvent(event Event, r *http.Request) {
+	// The client sends the expired URL resolved against the app path.
+	// Redirect set here, so the expiry handler may override it.
+	if event.Type() == ETypeSessExpired && c.expiredURL != "" {
+		if url := r.FormValue(paramCompValue); url != "" {
+			event.RedirectAfter(url, 0)
+		}
+	}
+}
+
+func (c *sessMonitorImpl) dispatchEvent(e Event) {
+	if e.Type() == ETypeSessExpired && c.onExpired != nil {
+		c.onExpired(e)
+	}
+	c.timerImpl.dispatchEvent(e)
+}
+
 var (
 	strEmptySpan     = []byte("<span></span>") // "<span></span>"
 	strJsCheckSessOp = []byte("checkSession(") // "checkSession("
@@ -81,12 +263,21 @@ var (
 func (c *sessMonitorImpl) Render(w Writer) {
 	w.Write(strSpanOp)
 	c.renderAttrsAndStyle(w)
+	if c.texts != defaultSessMonitorTexts {
+		writeEscAttr(w, "data-gwutxtexp", c.texts.Expired)
+		writeEscAttr(w, "data-gwutxtlt", c.texts.LessThanMin)
+		writeEscAttr(w, "data-gwutxtmin", c.texts.Minutes)
+		writeEscAttr(w, "data-gwutxterr", c.texts.ConnError)
+	}
+	if c.expiredURL != "" {
+		writeEscAttr(w, "data-gwuexpurl", c.expiredURL)
+	}
 	c.renderEHandlers(w)
 	w.Write(strGT)
 
 	w.Write(strEmptySpan) // Placeholder for session timeout value
 
-	w.Write(strScriptOp)
+	writeScriptOp(w)
 	c.renderSetupTimerJs(w, strJsCheckSessOp, int(c.id), strParenCl)
 	// Call sess check right away:
 	w.Write(strJsCheckSessOp)
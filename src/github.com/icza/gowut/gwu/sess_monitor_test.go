@@ -0,0 +1,202 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessMonitorInterval(t *testing.T) {
+	sm := NewSessMonitor()
+	if sm.Interval() != time.Minute {
+		t.Errorf("Got default interval %v", sm.Interval())
+	}
+
+	sm.SetInterval(15 * time.Second)
+	id := sm.Id().String()
+	s := renderString(sm)
+	want := `setupTimer(` + id + `,function(){checkSession(` + id + `)},15000,true,true,0);checkSession(` + id + `);`
+	if !strings.Contains(s, want) {
+		t.Errorf("Got: %s, want: %s", s, want)
+	}
+
+	sm.SetInterval(10 * time.Millisecond)
+	if sm.Interval() != MinSessMonitorInterval {
+		t.Errorf("Got interval %v, want %v", sm.Interval(), MinSessMonitorInterval)
+	}
+	sm.SetTimeout(0)
+	if sm.Timeout() != MinSessMonitorInterval {
+		t.Errorf("Got timeout %v, want %v", sm.Timeout(), MinSessMonitorInterval)
+	}
+}
+
+func TestSessMonitorOnExpired(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	sess := s.newSession(nil)
+	win := NewWindow("main", "Test")
+	sm := NewSessMonitor()
+	var expired int
+	sm.SetOnExpired(func(e Event) {
+		expired++
+		if e.Session() != sess {
+			t.Errorf("Handler called with another session")
+		}
+	})
+	win.Add(sm)
+	sess.AddWin(win)
+
+	if want := `data-gwuexpet="` + ETypeSessExpired.String() + `"`; !strings.Contains(renderString(sm), want) {
+		t.Errorf("Rendered SessMonitor does not contain %s", want)
+	}
+
+	sendExpired := func() {
+		params := url.Values{paramCompId: {sm.Id().String()}, paramEventType: {ETypeSessExpired.String()}}
+		r := httptest.NewRequest("POST", s.AppPath()+win.Name()+"/"+pathEvent, strings.NewReader(params.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(&http.Cookie{Name: gwuSessidCookie, Value: sess.Id()})
+		s.serveHTTP(httptest.NewRecorder(), r)
+	}
+
+	// Not yet expired (e.g. accessed from another tab): session must be kept
+	accessed := sess.Accessed()
+	sendExpired()
+	if expired != 0 || s.sessions[sess.Id()] == nil {
+		t.Errorf("Got %d handler calls, session kept: %v", expired, s.sessions[sess.Id()] != nil)
+	}
+	if !sess.Accessed().Equal(accessed) {
+		t.Errorf("Expiry event registered a session access")
+	}
+
+	sess.(*sessionImpl).accessed = time.Now().Add(-sess.Timeout() - time.Second)
+	sendExpired()
+	if expired != 1 || s.sessions[sess.Id()] != nil {
+		t.Errorf("Got %d handler calls, session kept: %v", expired, s.sessions[sess.Id()] != nil)
+	}
+
+	// Session already removed: window is not found, handler is not called
+	sendExpired()
+	if expired != 1 {
+		t.Errorf("Handler called for removed session")
+	}
+
+	sm.SetOnExpired(nil)
+	if strings.Contains(renderString(sm), "data-gwuexpet") {
+		t.Errorf("Expiry event type rendered without handler")
+	}
+}
+
+func TestSessMonitorTexts(t *testing.T) {
+	sm := NewSessMonitor()
+	if s := renderString(sm); strings.Contains(s, "data-gwutxt") {
+		t.Errorf("Default texts rendered: %s", s)
+	}
+
+	sm.SetTexts(SessMonitorTexts{Expired: "Abgelaufen!", Minutes: "~%d Min."})
+	want := SessMonitorTexts{"Abgelaufen!", "<1 min", "~%d Min.", "CONN ERR"}
+	if got := sm.Texts(); got != want {
+		t.Errorf("Got texts %+v, want %+v", got, want)
+	}
+	s := renderString(sm)
+	for _, attr := range []string{` data-gwutxtexp="Abgelaufen!"`, ` data-gwutxtlt="&lt;1 min"`,
+		` data-gwutxtmin="~%d Min."`, ` data-gwutxterr="CONN ERR"`} {
+		if !strings.Contains(s, attr) {
+			t.Errorf("Render does not contain %s: %s", attr, s)
+		}
+	}
+}
+
+func TestSessMonitorKeepAlive(t *testing.T) {
+	sm := NewSessMonitor()
+	if sm.KeepAlive() || strings.Contains(renderString(sm), "data-gwukeepalive") {
+		t.Error("Keep-alive is enabled by default")
+	}
+	sm.SetKeepAlive(true)
+	if !sm.KeepAlive() || !strings.Contains(renderString(sm), ` data-gwukeepalive="1"`) {
+		t.Error("Keep-alive not enabled")
+	}
+
+	s := newServerImpl("guitest", "", "", "")
+	sess := s.newSession(nil)
+	check := func(keepAlive bool) {
+		path := s.AppPath() + pathSessCheck
+		if keepAlive {
+			path += "?" + paramKeepAlive + "=1"
+		}
+		r := httptest.NewRequest("GET", path, nil)
+		r.AddCookie(&http.Cookie{Name: gwuSessidCookie, Value: sess.Id()})
+		s.serveHTTP(httptest.NewRecorder(), r)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	sess.(*sessionImpl).accessed = past
+	check(false)
+	if !sess.Accessed().Equal(past) {
+		t.Error("Session check without keep-alive extended the session")
+	}
+	check(true)
+	if !sess.Accessed().After(past) {
+		t.Error("Keep-alive session check did not extend the session")
+	}
+
+	expired := time.Now().Add(-sess.Timeout() - time.Second)
+	sess.(*sessionImpl).accessed = expired
+	check(true)
+	if !sess.Accessed().Equal(expired) {
+		t.Error("Keep-alive session check revived an expired session")
+	}
+}
+
+func TestSessMonitorExpiredURL(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	sm := NewSessMonitor()
+	win.Add(sm)
+	if strings.Contains(renderString(sm), "data-gwuexpurl") {
+		t.Error("Expired URL rendered by default")
+	}
+
+	sm.SetExpiredURL("login?from=main&x=1")
+	if s := renderString(sm); !strings.Contains(s, ` data-gwuexpurl="login?from=main&amp;x=1"`) {
+		t.Errorf("Expired URL not rendered: %s", s)
+	}
+
+	// With an expiry handler the server redirects after calling the handler
+	var redirect bool
+	sm.SetOnExpired(func(e Event) {
+		if redirect {
+			e.RedirectAfter("/other", 0)
+		}
+	})
+	params := url.Values{paramCompId: {sm.Id().String()}, paramEventType: {ETypeSessExpired.String()},
+		paramCompValue: {"/guitest/login"}}
+	want := strconv.Itoa(eraRedirect) + ",0,%2Fguitest%2Flogin"
+	if body := sendEvent(s, &s.sessionImpl, win, params).Body.String(); body != want {
+		t.Errorf("Got response: %q, want: %q", body, want)
+	}
+	redirect = true
+	want = strconv.Itoa(eraRedirect) + ",0,%2Fother"
+	if body := sendEvent(s, &s.sessionImpl, win, params).Body.String(); body != want {
+		t.Errorf("Got response: %q, want: %q", body, want)
+	}
+
+	checkStaticJsFuncs(t, "checkSession", "appUrl")
+}
@@ -74,9 +74,35 @@ type Session interface {
 	// Timeout returns the session timeout.
 	Timeout() time.Duration
 
-	// SetTimeout sets the session timeout.
+	// SetTimeout sets the session timeout, e.g. to give sessions of
+	// different user roles different lifetimes.
+	// The timeout of new sessions is 30 minutes. The remaining time reported
+	// to SessMonitors is also calculated with the timeout of the session.
 	SetTimeout(timeout time.Duration)
 
+	// Locale returns the locale of the session (language tag, e.g. "en-US").
+	// Empty string means no locale (values are formatted locale independently).
+	Locale() string
+
+	// SetLocale sets the locale of the session (language tag, e.g. "en-US"
+	// or "de"). Components of the windows of the session format their values
+	// (e.g. numbers and dates) according to this locale when rendered,
+	// see FormatNumber() and FormatDateTime().
+	// Windows already rendered have to be reloaded to reflect the change.
+	SetLocale(tag string)
+
+	// OnShutdown registers a function to be called when the server is
+	// shut down (see Server.Shutdown()), e.g. to persist the state of the
+	// session. Functions are called in the order they were registered,
+	// with the lock of the session held.
+	OnShutdown(f func(sess Session))
+
+	// shutdownFuncs returns the functions registered by OnShutdown().
+	shutdownFuncs() []func(sess Session)
+
+	// data returns the persisted part of the session (see SessionStore).
+	data() *SessionData
+
 	// access registers an access to the session.
 	// Implementation locks or the sessions RW mutex.
 	access()
@@ -98,6 +124,8 @@ type sessionImpl struct {
 	windows  map[string]Window      // Windows of the session
 	attrs    map[string]interface{} // Attributes stored in the session
 	timeout  time.Duration          // Session timeout
+	locale   string                 // Locale of the session (language tag)
+	onShut   []func(sess Session)   // Functions to call when the server is shut down
 
 	rwMutex_ *sync.RWMutex // RW mutex to synchronize session (and related Window and component) access
 }
@@ -225,6 +253,31 @@ func (s *sessionImpl) SetTimeout(timeout time.Duration) {
 	s.timeout = timeout
 }
 
+func (s *sessionImpl) Locale() string {
+	return s.locale
+}
+
+func (s *sessionImpl) SetLocale(tag string) {
+	s.locale = tag
+}
+
+func (s *sessionImpl) OnShutdown(f func(sess Session)) {
+	s.onShut = append(s.onShut, f)
+}
+
+func (s *sessionImpl) shutdownFuncs() []func(sess Session) {
+	return s.onShut
+}
+
+func (s *sessionImpl) data() *SessionData {
+	attrs := make(map[string]interface{}, len(s.attrs))
+	for k, v := range s.attrs {
+		attrs[k] = v
+	}
+	return &SessionData{Id: s.id, Created: s.created, Accessed: s.accessed, Timeout: s.timeout,
+		Locale: s.locale, Attrs: attrs}
+}
+
 func (s *sessionImpl) access() {
 	s.rwMutex_.Lock()
 	defer s.rwMutex_.Unlock()
@@ -0,0 +1,164 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Defines the SessionStore type and the persistence of sessions.
+
+package gwu
+
+import (
+	"log"
+	"time"
+)
+
+// SessionData is the persisted part of a session: its metadata and attributes.
+//
+// Windows and components of a session (with their event handlers) are not
+// persisted, they are live objects of the server. When a session is restored
+// from a SessionStore, the Created() method of the registered session
+// handlers is called to reconstruct its windows (the attributes are already
+// restored at that time, so they can be used to rebuild the state).
+type SessionData struct {
+	Id       string                 // Id of the session
+	Created  time.Time              // Creation time
+	Accessed time.Time              // Last accessed time
+	Timeout  time.Duration          // Session timeout
+	Locale   string                 // Locale of the session (language tag)
+	Attrs    map[string]interface{} // Attributes stored in the session
+}
+
+// SessionStore persists private sessions, so they can survive server
+// restarts, or be shared by multiple server instances.
+//
+// Sessions are always kept in memory too; the store is consulted
+// when a client refers to a session which is not in memory.
+// It is the store's responsibility to serialize the session
+// attributes (e.g. with encoding/gob), so attributes stored in sessions
+// must be of types the store is able to serialize.
+//
+// Sessions are saved when they are created and after each processed event,
+// so changes made outside of event handlers (e.g. in the Created() method of
+// session handlers or in Session.OnShutdown() functions) are saved on the
+// next event (or on shutdown). Accesses without events (e.g. rendering)
+// are not saved.
+type SessionStore interface {
+	// Load loads the session with the specified id.
+	// nil must be returned (without an error) if no such session exists.
+	Load(id string) (*SessionData, error)
+
+	// Save saves the specified session.
+	Save(data *SessionData) error
+
+	// Remove removes the session with the specified id.
+	Remove(id string) error
+}
+
+// sessRestore is a session being restored from the session store.
+type sessRestore struct {
+	done chan struct{} // Closed when the restore is finished
+	sess Session       // The restored session, nil if not found; only valid after done is closed
+}
+
+// sessRestores holds the sessions being restored, mapped from their ids.
+type sessRestores map[string]*sessRestore
+
+// sessById returns the private session with the specified id. If it is
+// not in memory, it is loaded from the session store (if set).
+// nil is returned if the session is not found.
+//
+// A session is restored only once even if it is referred by concurrent
+// requests: they wait for the first one to restore it. The sessions mutex
+// is not held while loading the session and calling the session handlers.
+func (s *serverImpl) sessById(id string) Session {
+	s.sessionsMu.Lock()
+	if r := s.restoring[id]; r != nil {
+		s.sessionsMu.Unlock()
+		<-r.done
+		return r.sess
+	}
+	if sess := s.sessions[id]; sess != nil || s.sessStore == nil {
+		s.sessionsMu.Unlock()
+		return sess
+	}
+	r := &sessRestore{done: make(chan struct{})}
+	s.restoring[id] = r
+	s.sessionsMu.Unlock()
+
+	defer func() {
+		s.sessionsMu.Lock()
+		delete(s.restoring, id)
+		s.sessionsMu.Unlock()
+		close(r.done)
+	}()
+
+	r.sess = s.restoreSess(id)
+	return r.sess
+}
+
+// restoreSess loads the session with the specified id from the session store,
+// adds it to the sessions and notifies the session handlers.
+// nil is returned if the session is not found.
+func (s *serverImpl) restoreSess(id string) Session {
+	data, err := s.sessStore.Load(id)
+	if err != nil {
+		s.logSessStoreErr("load", id, err)
+		return nil
+	}
+	if data == nil || data.Id != id || time.Now().Sub(data.Accessed) > data.Timeout {
+		return nil
+	}
+
+	sessImpl := newSessionImpl(true)
+	sessImpl.id, sessImpl.isNew = data.Id, false
+	sessImpl.created, sessImpl.accessed = data.Created, data.Accessed
+	sessImpl.timeout, sessImpl.locale = data.Timeout, data.Locale
+	if data.Attrs != nil {
+		sessImpl.attrs = data.Attrs
+	}
+	sess := &sessImpl
+	s.sessionsMu.Lock()
+	s.sessions[sess.Id()] = sess
+	s.sessionsMu.Unlock()
+
+	log.Println("SESSION restored:", sess.Id())
+	if s.logger != nil {
+		s.logger.Println("SESSION restored:", sess.Id())
+	}
+
+	// Notify session handlers so they can reconstruct the windows
+	for _, handler := range s.sessionHandlers {
+		handler.Created(sess)
+	}
+
+	return sess
+}
+
+// saveSess saves the specified session to the session store (if set).
+// The public session and removed sessions are not saved.
+func (s *serverImpl) saveSess(sess Session) {
+	if s.sessStore == nil || !sess.Private() || !s.liveSess(sess) {
+		return
+	}
+	if err := s.sessStore.Save(sess.data()); err != nil {
+		s.logSessStoreErr("save", sess.Id(), err)
+	}
+}
+
+// logSessStoreErr logs a session store error.
+func (s *serverImpl) logSessStoreErr(op, id string, err error) {
+	log.Printf("Failed to %s session %s: %v", op, id, err)
+	if s.logger != nil {
+		s.logger.Printf("Failed to %s session %s: %v", op, id, err)
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memSessStore is a SessionStore which stores sessions in a map.
+type memSessStore map[string]SessionData
+
+func (m memSessStore) Load(id string) (*SessionData, error) {
+	data, ok := m[id]
+	if !ok {
+		return nil, nil
+	}
+	return &data, nil
+}
+
+func (m memSessStore) Save(data *SessionData) error {
+	m[data.Id] = *data
+	return nil
+}
+
+func (m memSessStore) Remove(id string) error {
+	delete(m, id)
+	return nil
+}
+
+// winSessHandler is a SessionHandler which adds a window
+// to created sessions, titled by the "title" attribute.
+type winSessHandler struct{}
+
+func (winSessHandler) Created(sess Session) {
+	title, _ := sess.Attr("title").(string)
+	win := NewWindow("main", title)
+	b := NewButton("Set")
+	b.AddEHandlerFunc(func(e Event) {
+		e.Session().SetAttr("title", "Restored")
+	}, ETypeClick)
+	win.Add(b)
+	sess.AddWin(win)
+}
+
+func (winSessHandler) Removed(sess Session) {}
+
+func TestSessionStore(t *testing.T) {
+	store := memSessStore{}
+	s := newServerImpl("guitest", "", "", "")
+	s.SetSessionStore(store)
+	s.AddSHandler(winSessHandler{})
+
+	sess := s.newSession(nil)
+	if _, ok := store[sess.Id()]; !ok {
+		t.Fatalf("Created session not saved")
+	}
+	win := sess.WinByName("main")
+	sendEvent(s, sess, win, clickParams(win.CompAt(0)))
+	if title := store[sess.Id()].Attrs["title"]; title != "Restored" {
+		t.Errorf("Got saved title attr: %v", title)
+	}
+
+	// Restart: the session is restored from the store
+	s2 := newServerImpl("guitest", "", "", "")
+	s2.SetSessionStore(store)
+	s2.AddSHandler(winSessHandler{})
+	r := httptest.NewRequest("GET", s2.AppPath()+"main", nil)
+	r.AddCookie(&http.Cookie{Name: gwuSessidCookie, Value: sess.Id()})
+	wr := httptest.NewRecorder()
+	s2.serveHTTP(wr, r)
+	sess2 := s2.sessions[sess.Id()]
+	if sess2 == nil {
+		t.Fatalf("Session not restored")
+	}
+	if sess2.New() || sess2.Attr("title") != "Restored" || sess2.Created() != sess.Created() {
+		t.Errorf("Got new: %v, title attr: %v, created: %v", sess2.New(), sess2.Attr("title"), sess2.Created())
+	}
+	if win := sess2.WinByName("main"); win == nil || win.Text() != "Restored" {
+		t.Errorf("Window not reconstructed: %v", win)
+	}
+
+	// Removed sessions are removed from the store
+	s2.removeSess2(sess2)
+	if _, ok := store[sess.Id()]; ok {
+		t.Errorf("Removed session still in store")
+	}
+
+	// Expired sessions are not restored
+	sess3 := s.newSession(nil)
+	data := store[sess3.Id()]
+	data.Accessed = time.Now().Add(-2 * data.Timeout)
+	store[sess3.Id()] = data
+	if s2.sessById(sess3.Id()) != nil {
+		t.Errorf("Expired session restored")
+	}
+}
+
+// countSessHandler is a SessionHandler which counts created sessions.
+type countSessHandler struct {
+	created *int32
+}
+
+func (h countSessHandler) Created(sess Session) {
+	atomic.AddInt32(h.created, 1)
+}
+
+func (h countSessHandler) Removed(sess Session) {}
+
+func TestSessionStoreConcurrentRestore(t *testing.T) {
+	store := memSessStore{}
+	s := newServerImpl("guitest", "", "", "")
+	s.SetSessionStore(store)
+	sess := s.newSession(nil)
+
+	s2 := newServerImpl("guitest", "", "", "")
+	s2.SetSessionStore(store)
+	var created int32
+	s2.AddSHandler(countSessHandler{&created})
+
+	var wg sync.WaitGroup
+	restored := make([]Session, 10)
+	for i := range restored {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			restored[i] = s2.sessById(sess.Id())
+		}(i)
+	}
+	wg.Wait()
+
+	if created != 1 {
+		t.Errorf("Session restored %d times", created)
+	}
+	for _, r := range restored {
+		if r == nil || r != restored[0] {
+			t.Errorf("Got different restored sessions")
+			break
+		}
+	}
+}
+
+// blockingSessStore is a SessionStore whose Load blocks until release is closed.
+type blockingSessStore struct {
+	memSessStore
+	loading chan struct{} // Signaled when Load is called
+	release chan struct{} // Closed to let Load return
+}
+
+func (b blockingSessStore) Load(id string) (*SessionData, error) {
+	b.loading <- struct{}{}
+	<-b.release
+	return b.memSessStore.Load(id)
+}
+
+// broadcastSessHandler is a SessionHandler which broadcasts when a session is created.
+type broadcastSessHandler struct {
+	s Server
+}
+
+func (h broadcastSessHandler) Created(sess Session) {
+	h.s.Broadcast("main", func(ev BroadcastEvent) {})
+}
+
+func (h broadcastSessHandler) Removed(sess Session) {}
+
+func TestSessionStoreRestoreUnlocked(t *testing.T) {
+	store := memSessStore{}
+	s := newServerImpl("guitest", "", "", "")
+	s.SetSessionStore(store)
+	stored := s.newSession(nil)
+
+	s2 := newServerImpl("guitest", "", "", "")
+	bstore := blockingSessStore{memSessStore: store, loading: make(chan struct{}), release: make(chan struct{})}
+	s2.SetSessionStore(bstore)
+	s2.AddSHandler(broadcastSessHandler{s2})
+	live := s2.newSession(nil)
+
+	done := make(chan Session)
+	go func() { done <- s2.sessById(stored.Id()) }()
+	<-bstore.loading
+
+	// Other sessions are available while loading
+	if s2.sessById(live.Id()) != live {
+		t.Errorf("Live session not found while loading another")
+	}
+
+	// Session handlers may use the sessions (e.g. broadcast) without deadlock
+	close(bstore.release)
+	select {
+	case sess := <-done:
+		if sess == nil || sess.Id() != stored.Id() {
+			t.Errorf("Got restored session %v", sess)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Restoring session deadlocked")
+	}
+}
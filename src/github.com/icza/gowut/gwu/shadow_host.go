@@ -0,0 +1,133 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// ShadowHost component interface and implementation.
+
+package gwu
+
+// ShadowHost interface defines a container which renders its content
+// component into a shadow root (shadow DOM), isolating it from the styles
+// of the embedding page: page styles do not affect the content, and only
+// the Gowut style sheets (the theme) are applied inside the shadow root.
+//
+// The shadow root is attached at the client side by the Gowut JavaScript
+// (when the page is loaded, or when the host is re-rendered); events and
+// re-rendering work for components inside the shadow root too. If the browser
+// does not support shadow DOM, the content is rendered without isolation.
+//
+// Note that styles of the page (including extra CSS added by Window.AddHeadHtml())
+// do not apply inside the shadow root. The responsive styles of components
+// (Style.SetResponsiveWidth(), Comp.SetVisibleAt()) do, as they are rendered
+// along with the components.
+//
+// Default style class: "gwu-ShadowHost"
+type ShadowHost interface {
+	// ShadowHost is a container.
+	Container
+
+	// Content returns the content component.
+	Content() Comp
+
+	// SetContent sets the content component.
+	SetContent(c Comp)
+}
+
+// ShadowHost implementation.
+type shadowHostImpl struct {
+	compImpl // Component implementation
+
+	content Comp // Content component
+}
+
+// NewShadowHost creates a new ShadowHost.
+func NewShadowHost(content Comp) ShadowHost {
+	c := &shadowHostImpl{compImpl: newCompImpl(nil)}
+	if content != nil {
+		c.SetContent(content)
+	}
+	c.Style().AddClass("gwu-ShadowHost")
+	return c
+}
+
+func (c *shadowHostImpl) Remove(c2 Comp) bool {
+	if c.content == nil || !c.content.Equals(c2) {
+		return false
+	}
+	c2.setParent(nil)
+	c.content = nil
+	return true
+}
+
+func (c *shadowHostImpl) ById(id ID) Comp {
+	if c.id == id {
+		return c
+	}
+
+	if c.content != nil {
+		if c.content.Id() == id {
+			return c.content
+		}
+		if c2, isContainer := c.content.(Container); isContainer {
+			return c2.ById(id)
+		}
+	}
+
+	return nil
+}
+
+func (c *shadowHostImpl) children() []Comp {
+	if c.content == nil {
+		return nil
+	}
+	return []Comp{c.content}
+}
+
+func (c *shadowHostImpl) Clear() {
+	if c.content != nil {
+		c.content.setParent(nil)
+		c.content = nil
+	}
+}
+
+func (c *shadowHostImpl) Content() Comp {
+	return c.content
+}
+
+func (c *shadowHostImpl) SetContent(content Comp) {
+	content.makeOrphan()
+	c.content = content
+	content.setParent(c)
+}
+
+var (
+	strShadowTmplOp = []byte("<template data-gwushadow>") // "<template data-gwushadow>"
+	strShadowTmplCl = []byte("</template>")               // "</template>"
+)
+
+func (c *shadowHostImpl) Render(w Writer) {
+	// To render: <div id="compId" class="gwu-ShadowHost"><template data-gwushadow>content</template></div>
+	w.Write(strDivOp)
+	c.renderAttrsAndStyle(w)
+	c.renderEHandlers(w)
+	w.Write(strGT)
+
+	w.Write(strShadowTmplOp)
+	if c.content != nil {
+		renderComp(w, c.content)
+	}
+	w.Write(strShadowTmplCl)
+
+	w.Write(strDivCl)
+}
@@ -0,0 +1,36 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShadowHostRender(t *testing.T) {
+	l := NewLabel("x")
+	sh := NewShadowHost(l)
+
+	want := `<div id="` + sh.Id().String() + `" class="gwu-ShadowHost"><template data-gwushadow><span id="` + l.Id().String() + `"`
+	if s := renderString(sh); !strings.HasPrefix(s, want) || !strings.HasSuffix(s, "</span></template></div>") {
+		t.Errorf("Rendered output does not start with %s: %s", want, s)
+	}
+
+	if sh.ById(l.Id()) != l || !sh.Remove(l) || sh.Content() != nil || l.Parent() != nil {
+		t.Error("Container methods failed")
+	}
+	checkStaticJsFuncs(t, "attachShadows", "gwuById")
+}
@@ -0,0 +1,96 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Spinner component interface and implementation.
+
+package gwu
+
+// Spinner interface defines a component which displays an (inline)
+// loading indicator. It can be placed anywhere in layouts, e.g. inside
+// a panel while its data is being loaded.
+//
+// The spinner is animated with CSS only (no JavaScript timers are involved),
+// so its look can be customized by overriding the "gwu-Spinner" style class
+// (e.g. the border colors).
+//
+// For accessibility the spinner is rendered with role="status" and
+// aria-label="Loading...", the latter can be changed with SetAttr().
+//
+// Default style class: "gwu-Spinner"
+type Spinner interface {
+	// Spinner is a component.
+	Comp
+
+	// Size returns the size (width and height) of the spinner, in pixels.
+	Size() int
+
+	// SetSize sets the size (width and height) of the spinner, in pixels.
+	SetSize(size int)
+
+	// Visible tells if the spinner is visible.
+	Visible() bool
+
+	// SetVisible sets the visibility of the spinner.
+	// A hidden spinner does not take up space in the layout.
+	// Do not forget to mark the spinner dirty after changing its visibility.
+	SetVisible(visible bool)
+}
+
+// Spinner implementation.
+type spinnerImpl struct {
+	compImpl // Component implementation
+
+	size int // Size of the spinner in pixels
+}
+
+// NewSpinner creates a new Spinner.
+// The default size is 16 pixels.
+func NewSpinner() Spinner {
+	c := &spinnerImpl{compImpl: newCompImpl(nil)}
+	c.SetAttr("role", "status")
+	c.SetAttr("aria-label", "Loading...")
+	c.SetSize(16)
+	c.Style().AddClass("gwu-Spinner")
+	return c
+}
+
+func (c *spinnerImpl) Size() int {
+	return c.size
+}
+
+func (c *spinnerImpl) SetSize(size int) {
+	c.size = size
+	c.Style().SetSizePx(size, size)
+}
+
+func (c *spinnerImpl) Visible() bool {
+	return c.Style().Display() != DisplayNone
+}
+
+func (c *spinnerImpl) SetVisible(visible bool) {
+	if visible {
+		c.Style().SetDisplay("")
+	} else {
+		c.Style().SetDisplay(DisplayNone)
+	}
+}
+
+func (c *spinnerImpl) Render(w Writer) {
+	w.Write(strSpanOp)
+	c.renderAttrsAndStyle(w)
+	c.renderEHandlers(w)
+	w.Write(strGT)
+	w.Write(strSpanCl)
+}
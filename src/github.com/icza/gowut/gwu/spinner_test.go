@@ -0,0 +1,46 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSpinnerRender(t *testing.T) {
+	sp := NewSpinner()
+	sp.SetSize(24)
+	sp.Style().SetClass("gwu-Spinner") // Single class for deterministic output
+
+	id := sp.Id().String()
+	got := renderString(sp)
+	// Attributes are rendered from a map, only check the parts
+	for _, want := range []string{`<span `, ` id="` + id + `"`, ` role="status"`, ` aria-label="Loading..."`,
+		` class="gwu-Spinner"`, `width:24px`, `height:24px`, `></span>`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Rendered spinner %s does not contain %s", got, want)
+		}
+	}
+
+	sp.SetVisible(false)
+	if sp.Visible() || !strings.Contains(renderString(sp), "display:none") {
+		t.Errorf("Spinner not hidden")
+	}
+	sp.SetVisible(true)
+	if !sp.Visible() || strings.Contains(renderString(sp), "display:none") {
+		t.Errorf("Spinner not shown")
+	}
+}
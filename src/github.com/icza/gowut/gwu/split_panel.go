@@ -0,0 +1,285 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// SplitPanel component interface and implementation.
+
+package gwu
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// SplitPanel interface defines a container which displays 2 child components
+// separated by a divider which can be dragged to resize them.
+// The children are either side by side (default) or one above the other
+// (vertical split panel).
+//
+// The split ratio is the portion of the space taken up by the first component.
+// When the user finishes dragging the divider, the new ratio is reported
+// to the server (so it can be persisted for example), clamped to the ratio
+// bounds.
+//
+// You can register ETypeStateChange event handlers which will be called when
+// the user changes the split ratio by dragging the divider.
+//
+// Default style classes: "gwu-SplitPanel", "gwu-SplitPanel-Divider",
+// "gwu-SplitPanel-Divider-H", "gwu-SplitPanel-Divider-V"
+type SplitPanel interface {
+	// SplitPanel is a Container.
+	Container
+
+	// First returns the first (left or top) component.
+	First() Comp
+
+	// SetFirst sets the first (left or top) component.
+	SetFirst(c Comp)
+
+	// Second returns the second (right or bottom) component.
+	Second() Comp
+
+	// SetSecond sets the second (right or bottom) component.
+	SetSecond(c Comp)
+
+	// Vertical tells if the children are laid out one above the other.
+	Vertical() bool
+
+	// SetVertical sets whether the children are laid out one above the other.
+	SetVertical(vertical bool)
+
+	// Ratio returns the split ratio, the portion of the space
+	// taken up by the first component (in the range of 0..1).
+	Ratio() float64
+
+	// SetRatio sets the split ratio, the portion of the space
+	// taken up by the first component. The ratio is clamped
+	// to the ratio bounds.
+	SetRatio(ratio float64)
+
+	// RatioBounds returns the minimum and maximum split ratio
+	// that can be set by dragging the divider.
+	RatioBounds() (min, max float64)
+
+	// SetRatioBounds sets the minimum and maximum split ratio
+	// that can be set by dragging the divider. Bounds are clamped
+	// to the range of 0..1, and swapped if min > max.
+	// The ratio is clamped to the new bounds.
+	SetRatioBounds(min, max float64)
+}
+
+// SplitPanel implementation.
+type splitPanelImpl struct {
+	compImpl // Component implementation
+
+	first, second Comp    // First and second components
+	vertical      bool    // Tells if children are laid out one above the other
+	ratio         float64 // Split ratio
+	min, max      float64 // Ratio bounds
+}
+
+// NewSplitPanel creates a new SplitPanel.
+// Default split ratio is 0.5, default ratio bounds are 0.1 and 0.9.
+func NewSplitPanel() SplitPanel {
+	c := &splitPanelImpl{compImpl: newCompImpl(nil), ratio: 0.5, min: 0.1, max: 0.9}
+	c.SetAttr("cellspacing", "0")
+	c.SetAttr("cellpadding", "0")
+	c.Style().AddClass("gwu-SplitPanel")
+	return c
+}
+
+func (c *splitPanelImpl) Remove(c2 Comp) bool {
+	if c.first != nil && c.first.Equals(c2) {
+		c2.setParent(nil)
+		c.first = nil
+		return true
+	}
+
+	if c.second != nil && c.second.Equals(c2) {
+		c2.setParent(nil)
+		c.second = nil
+		return true
+	}
+
+	return false
+}
+
+func (c *splitPanelImpl) ById(id ID) Comp {
+	if c.id == id {
+		return c
+	}
+
+	for _, c2 := range []Comp{c.first, c.second} {
+		if c2 == nil {
+			continue
+		}
+		if c2.Id() == id {
+			return c2
+		}
+		if c3, isContainer := c2.(Container); isContainer {
+			if c4 := c3.ById(id); c4 != nil {
+				return c4
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *splitPanelImpl) children() []Comp {
+	var comps []Comp
+	for _, c2 := range []Comp{c.first, c.second} {
+		if c2 != nil {
+			comps = append(comps, c2)
+		}
+	}
+	return comps
+}
+
+func (c *splitPanelImpl) Clear() {
+	if c.first != nil {
+		c.first.setParent(nil)
+		c.first = nil
+	}
+	if c.second != nil {
+		c.second.setParent(nil)
+		c.second = nil
+	}
+}
+
+func (c *splitPanelImpl) First() Comp {
+	return c.first
+}
+
+func (c *splitPanelImpl) SetFirst(first Comp) {
+	if c.first != nil {
+		c.Remove(c.first)
+	}
+	first.makeOrphan()
+	c.first = first
+	first.setParent(c)
+}
+
+func (c *splitPanelImpl) Second() Comp {
+	return c.second
+}
+
+func (c *splitPanelImpl) SetSecond(second Comp) {
+	if c.second != nil {
+		c.Remove(c.second)
+	}
+	second.makeOrphan()
+	c.second = second
+	second.setParent(c)
+}
+
+func (c *splitPanelImpl) Vertical() bool {
+	return c.vertical
+}
+
+func (c *splitPanelImpl) SetVertical(vertical bool) {
+	c.vertical = vertical
+}
+
+func (c *splitPanelImpl) Ratio() float64 {
+	return c.ratio
+}
+
+func (c *splitPanelImpl) SetRatio(ratio float64) {
+	if ratio < c.min {
+		ratio = c.min
+	} else if ratio > c.max {
+		ratio = c.max
+	}
+	c.ratio = ratio
+}
+
+func (c *splitPanelImpl) RatioBounds() (min, max float64) {
+	return c.min, c.max
+}
+
+func (c *splitPanelImpl) SetRatioBounds(min, max float64) {
+	min, max = math.Max(0, math.Min(1, min)), math.Max(0, math.Min(1, max))
+	if min > max {
+		min, max = max, min
+	}
+	c.min, c.max = min, max
+	c.SetRatio(c.ratio)
+}
+
+func (c *splitPanelImpl) preprocessEvent(event Event, r *http.Request) {
+	if event.Type() != ETypeStateChange {
+		return
+	}
+
+	// Malformed ratios are ignored, valid ones are clamped.
+	ratio, err := strconv.ParseFloat(r.FormValue(paramCompValue), 64)
+	if err != nil || math.IsNaN(ratio) || math.IsInf(ratio, 0) {
+		return
+	}
+	c.SetRatio(ratio)
+}
+
+// formatRatio formats a ratio to be used in JavaScript code.
+func formatRatio(ratio float64) string {
+	return strconv.FormatFloat(ratio, 'f', -1, 64)
+}
+
+var (
+	strSplitDividerH  = []byte(`<td class="gwu-SplitPanel-Divider gwu-SplitPanel-Divider-H" onmousedown="splitDrag(event,`) // `<td class="gwu-SplitPanel-Divider gwu-SplitPanel-Divider-H" onmousedown="splitDrag(event,`
+	strSplitDividerV  = []byte(`<td class="gwu-SplitPanel-Divider gwu-SplitPanel-Divider-V" onmousedown="splitDrag(event,`) // `<td class="gwu-SplitPanel-Divider gwu-SplitPanel-Divider-V" onmousedown="splitDrag(event,`
+	strSplitDividerCl = []byte(`)"></td>`)                                                                                  // `)"></td>`
+)
+
+func (c *splitPanelImpl) Render(w Writer) {
+	w.Write(strTableOp)
+	c.renderAttrsAndStyle(w)
+	c.renderEHandlers(w)
+	w.Write(strGT)
+
+	// First cell has the size, the rest is taken up by the second cell.
+	// splitDrag() relies on the first cell being the first cell of the first row.
+	size := formatRatio(c.ratio*100) + "%"
+	if c.vertical {
+		w.Writess(`<tr><td style="height:`, size, `">`)
+	} else {
+		w.Writess(`<tr><td style="width:`, size, `">`)
+	}
+	if c.first != nil {
+		renderComp(w, c.first)
+	}
+	w.Writes("</td>")
+
+	// To render: <td class="..." onmousedown="splitDrag(event,compId,etype,vertical,min,max)"></td>
+	if c.vertical {
+		w.Writes("</tr><tr>")
+		w.Write(strSplitDividerV)
+	} else {
+		w.Write(strSplitDividerH)
+	}
+	w.Writevs(int(c.id), strComma, int(ETypeStateChange), strComma, c.vertical, strComma, formatRatio(c.min), strComma, formatRatio(c.max))
+	w.Write(strSplitDividerCl)
+
+	if c.vertical {
+		w.Writes("</tr><tr>")
+	}
+	w.Writes("<td>")
+	if c.second != nil {
+		renderComp(w, c.second)
+	}
+	w.Writes("</td></tr>")
+
+	w.Write(strTableCl)
+}
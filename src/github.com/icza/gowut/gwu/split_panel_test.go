@@ -0,0 +1,85 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitPanelRatioEvent(t *testing.T) {
+	sp := NewSplitPanel()
+	sp.SetRatioBounds(0.2, 0.8)
+
+	cases := []struct {
+		payload string
+		ratio   float64
+	}{
+		{"0.3500", 0.35},
+		{"0.9", 0.8},  // Clamped to max
+		{"0.05", 0.2}, // Clamped to min
+		{"-1", 0.2},   // Clamped to min
+		{"abc", 0.2},  // Malformed, ignored
+		{"NaN", 0.2},  // Malformed, ignored
+		{"0.7", 0.7},
+		{"", 0.7}, // Malformed, ignored
+	}
+	for _, c := range cases {
+		sp.preprocessEvent(newEventImpl(ETypeStateChange, sp, nil, nil), newCompValueReq(c.payload))
+		if sp.Ratio() != c.ratio {
+			t.Errorf("Payload %q: got ratio %v, want %v", c.payload, sp.Ratio(), c.ratio)
+		}
+	}
+
+	// Other event types must not change the ratio
+	sp.preprocessEvent(newEventImpl(ETypeClick, sp, nil, nil), newCompValueReq("0.5"))
+	if sp.Ratio() != 0.7 {
+		t.Errorf("Ratio changed by click event: %v", sp.Ratio())
+	}
+}
+
+func TestSplitPanelRatioBounds(t *testing.T) {
+	sp := NewSplitPanel()
+	sp.SetRatio(0.95)
+	if sp.Ratio() != 0.9 {
+		t.Errorf("Got ratio %v, want 0.9", sp.Ratio())
+	}
+
+	sp.SetRatioBounds(1.5, 0.6) // Clamped and swapped
+	if min, max := sp.RatioBounds(); min != 0.6 || max != 1 {
+		t.Errorf("Got bounds %v, %v; want 0.6, 1", min, max)
+	}
+	if sp.Ratio() != 0.9 {
+		t.Errorf("Got ratio %v, want 0.9", sp.Ratio())
+	}
+}
+
+func TestSplitPanelRender(t *testing.T) {
+	sp := NewSplitPanel()
+	sp.SetFirst(NewLabel("left"))
+	sp.SetSecond(NewLabel("right"))
+	sp.SetRatio(0.25)
+
+	s := renderString(sp)
+	want := `<tr><td style="width:25%">`
+	if !strings.Contains(s, want) {
+		t.Errorf("Rendered split panel %s does not contain %s", s, want)
+	}
+	want = `onmousedown="splitDrag(event,` + sp.Id().String() + `,` + ETypeStateChange.String() + `,false,0.1,0.9)"></td><td>`
+	if !strings.Contains(s, want) {
+		t.Errorf("Rendered split panel %s does not contain %s", s, want)
+	}
+}
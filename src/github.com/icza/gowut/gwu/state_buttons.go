@@ -19,6 +19,7 @@
 package gwu
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
 )
@@ -231,6 +232,17 @@ func (c *stateButtonImpl) SetEnabled(enabled bool) {
 	c.hasEnabledImpl.SetEnabled(enabled)
 }
 
+func (c *stateButtonImpl) saveState() interface{} {
+	return c.state
+}
+
+func (c *stateButtonImpl) restoreState(data json.RawMessage) {
+	var state bool
+	if json.Unmarshal(data, &state) == nil {
+		c.SetState(state)
+	}
+}
+
 func (c *stateButtonImpl) State() bool {
 	return c.state
 }
@@ -341,6 +353,17 @@ func (c *switchButtonImpl) SetEnabled(enabled bool) {
 	c.offButton.SetEnabled(enabled)
 }
 
+func (c *switchButtonImpl) saveState() interface{} {
+	return c.state
+}
+
+func (c *switchButtonImpl) restoreState(data json.RawMessage) {
+	var state bool
+	if json.Unmarshal(data, &state) == nil {
+		c.SetState(state)
+	}
+}
+
 func (c *switchButtonImpl) State() bool {
 	return c.state
 }
@@ -407,10 +430,10 @@ func (c *switchButtonImpl) Render(w Writer) {
 	w.Write(strClTr)
 
 	w.Write(strTD50)
-	c.onButton.Render(w)
+	renderComp(w, c.onButton)
 
 	w.Write(strTD50)
-	c.offButton.Render(w)
+	renderComp(w, c.offButton)
 
 	w.Write(strTableCl)
 }
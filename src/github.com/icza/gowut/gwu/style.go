@@ -18,7 +18,9 @@
 package gwu
 
 import (
+	"sort"
 	"strconv"
+	"strings"
 )
 
 // Style attribute constants.
@@ -397,6 +399,9 @@ type Style interface {
 	// SetWidthPx sets the width, in pixels.
 	SetWidthPx(width int) Style
 
+	// SetWidthPct sets the width, in percent.
+	SetWidthPct(width int) Style
+
 	// SetFullWidth sets full width (100%).
 	SetFullWidth() Style
 
@@ -409,9 +414,35 @@ type Style interface {
 	// SetHeightPx sets the height.
 	SetHeightPx(height int) Style
 
+	// SetHeightPct sets the height, in percent.
+	SetHeightPct(height int) Style
+
 	// SetFullHeight sets full height (100%).
 	SetFullHeight() Style
 
+	// ResponsiveWidth returns the responsive widths, mapped from breakpoints.
+	// nil is returned if no responsive widths are set.
+	ResponsiveWidth() map[int]string
+
+	// SetResponsiveWidth sets responsive widths: the keys of the map are
+	// breakpoints (minimum viewport widths in pixels), the values are
+	// the widths (CSS values) to apply if the viewport is at least that wide.
+	// The width of the largest matching breakpoint is applied.
+	// Pass nil to remove responsive widths.
+	//
+	// Responsive widths are rendered as media queries into a style block
+	// scoped to the component (by its id), rendered before the component,
+	// so they only work for component styles (not for cell formatter styles).
+	// The style block requires 'unsafe-inline' in the style-src directive of
+	// the content security policy (which DefaultSecurityHeaders allows).
+	// Note that an explicitly set width (e.g. SetWidth()) is an inline style
+	// which takes precedence, so use the 0 breakpoint for the default width.
+	//
+	// Example:
+	//     // Full width on small screens, half width from 768px, a third from 1200px.
+	//     c.Style().SetResponsiveWidth(map[int]string{0: "100%", 768: "50%", 1200: "33%"})
+	SetResponsiveWidth(widths map[int]string) Style
+
 	// WhiteSpace returns the white space attribute value.
 	WhiteSpace() string
 
@@ -427,11 +458,24 @@ type Style interface {
 
 	// renderAttrs renders the style attributes.
 	renderAttrs(w Writer)
+
+	// renderMedia renders the responsive style attributes as a style block
+	// scoped to the component having the specified id.
+	renderMedia(w Writer, id ID)
+
+	// hasClass tells if the specified style class name is in the class name list.
+	hasClass(class string) bool
 }
 
 type styleImpl struct {
 	classes []string          // Style classes.
 	attrs   map[string]string // Explicitly set style attributes. Lazily initialized.
+
+	// Responsive style attributes: style attribute values mapped from breakpoints,
+	// mapped from style attribute names. Lazily initialized.
+	media map[string]map[int]string
+
+	visMin, visMax int // Viewport width range in which the element is visible, 0 means no bound
 }
 
 // newStyleImpl creates a new styleImpl.
@@ -465,6 +509,15 @@ func (s *styleImpl) RemoveClass(class string) Style {
 	return s
 }
 
+func (s *styleImpl) hasClass(class string) bool {
+	for _, class_ := range s.classes {
+		if class_ == class {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *styleImpl) Get(name string) string {
 	return s.attrs[name]
 }
@@ -755,6 +808,10 @@ func (s *styleImpl) SetHeightPx(height int) Style {
 	return s.SetHeight(strconv.Itoa(height) + "px")
 }
 
+func (s *styleImpl) SetHeightPct(height int) Style {
+	return s.SetHeight(strconv.Itoa(height) + "%")
+}
+
 func (s *styleImpl) SetFullHeight() Style {
 	return s.SetHeight("100%")
 }
@@ -771,10 +828,37 @@ func (s *styleImpl) SetWidthPx(width int) Style {
 	return s.SetWidth(strconv.Itoa(width) + "px")
 }
 
+func (s *styleImpl) SetWidthPct(width int) Style {
+	return s.SetWidth(strconv.Itoa(width) + "%")
+}
+
 func (s *styleImpl) SetFullWidth() Style {
 	return s.SetWidth("100%")
 }
 
+func (s *styleImpl) ResponsiveWidth() map[int]string {
+	return s.media[StWidth]
+}
+
+func (s *styleImpl) SetResponsiveWidth(widths map[int]string) Style {
+	return s.setMedia(StWidth, widths)
+}
+
+// setMedia sets the responsive values of a style attribute, mapped from breakpoints.
+// Pass nil (or an empty map) to remove the responsive values.
+func (s *styleImpl) setMedia(name string, values map[int]string) Style {
+	if len(values) == 0 {
+		delete(s.media, name)
+		return s
+	}
+
+	if s.media == nil {
+		s.media = make(map[string]map[int]string)
+	}
+	s.media[name] = values
+	return s
+}
+
 func (s *styleImpl) WhiteSpace() string {
 	return s.Get(StWhiteSpace)
 }
@@ -793,6 +877,69 @@ func (s *styleImpl) render(w Writer) {
 	}
 }
 
+var (
+	strMediaOp = []byte(`<style id="`) // `<style id="`
+	strMediaId = []byte(`_media">`)    // `_media">`
+	strMediaCl = []byte("</style>")    // "</style>"
+)
+
+// renderMedia renders the responsive style attributes (and the visibility range
+// of the component, see Comp.SetVisibleAt()) as media queries into a style block
+// scoped to the component with the specified id. Component ids are numbers
+// which cannot be used as id selectors (#123), so an attribute selector is used.
+// Nothing is rendered if there are no responsive styles.
+//
+// Example: `<style id="5_media">@media (min-width:768px){[id="5"]{width:50%;}}</style>`
+func (s *styleImpl) renderMedia(w Writer, id ID) {
+	if len(s.media) == 0 && s.visMin == 0 && s.visMax == 0 {
+		return
+	}
+
+	// Collect and sort breakpoints, larger ones must come later to take precedence
+	var bps []int
+	for _, values := range s.media {
+		for bp := range values {
+			bps = append(bps, bp)
+		}
+	}
+	sort.Ints(bps)
+
+	// Also sort attribute names for deterministic output
+	names := make([]string, 0, len(s.media))
+	for name := range s.media {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sel := `){[id="` + id.String() + `"]{`
+	w.Write(strMediaOp)
+	w.Writes(id.String())
+	w.Write(strMediaId)
+	for i, bp := range bps {
+		if i > 0 && bp == bps[i-1] {
+			continue
+		}
+		w.Writevs("@media (min-width:", bp, "px", sel)
+		for _, name := range names {
+			if value, ok := s.media[name][bp]; ok {
+				// Values must not close the style element
+				w.Writess(name, ":", strings.Replace(value, "<", `\3c `, -1))
+				w.Write(strSemicol)
+			}
+		}
+		w.Writes("}}")
+	}
+
+	// Visibility range: hide outside of it
+	if s.visMin > 0 {
+		w.Writevs("@media not all and (min-width:", s.visMin, "px", sel, "display:none !important;}}")
+	}
+	if s.visMax > 0 {
+		w.Writevs("@media not all and (max-width:", s.visMax, "px", sel, "display:none !important;}}")
+	}
+	w.Write(strMediaCl)
+}
+
 func (s *styleImpl) renderClasses(w Writer) {
 	if len(s.classes) > 0 {
 		w.Write(strClass)
@@ -0,0 +1,66 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"bytes"
+	"testing"
+)
+
+// renderStyleString renders the style and returns the result as a string.
+func renderStyleString(s Style) string {
+	b := &bytes.Buffer{}
+	s.render(NewWriter(b))
+	return b.String()
+}
+
+func TestStyleFixedSize(t *testing.T) {
+	s := newStyleImpl()
+	s.SetWidthPct(50)
+	if got, want := renderStyleString(s), ` style="width:50%;"`; got != want {
+		t.Errorf("Got %s, want %s", got, want)
+	}
+
+	s = newStyleImpl()
+	s.SetHeightPct(25)
+	if got, want := renderStyleString(s), ` style="height:25%;"`; got != want {
+		t.Errorf("Got %s, want %s", got, want)
+	}
+}
+
+func TestStyleResponsiveWidth(t *testing.T) {
+	s := newStyleImpl()
+	s.SetResponsiveWidth(map[int]string{1200: "33%", 0: "100%", 768: "50%</style>"})
+
+	renderMedia := func() string {
+		b := &bytes.Buffer{}
+		s.renderMedia(NewWriter(b), 5)
+		return b.String()
+	}
+	want := `<style id="5_media">@media (min-width:0px){[id="5"]{width:100%;}}` +
+		`@media (min-width:768px){[id="5"]{width:50%\3c /style>;}}@media (min-width:1200px){[id="5"]{width:33%;}}</style>`
+	if got := renderMedia(); got != want {
+		t.Errorf("Got %s, want %s", got, want)
+	}
+	if got := renderStyleString(s); got != "" {
+		t.Errorf("Responsive widths rendered into the style attribute: %s", got)
+	}
+
+	s.SetResponsiveWidth(nil)
+	if got := renderMedia(); got != "" {
+		t.Errorf("Got %s, want empty string", got)
+	}
+}
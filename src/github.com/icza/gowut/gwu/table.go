@@ -17,6 +17,12 @@
 
 package gwu
 
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
 // Table interface defines a container which lays out its children
 // using a configurable, flexible table.
 // The size of the table grows dynamically, on demand. However,
@@ -24,6 +30,12 @@ package gwu
 // it is recommended to call EnsureSize to minimize reallocations
 // in the background.
 //
+// Table supports spreadsheet-style paste: if you register ETypePaste
+// event handlers, tab/newline delimited text pasted into a cell of the
+// table is parsed into rows and columns, and is available in the handlers
+// via Pasted(). Paste events are fired by focusable components, so cells
+// should contain e.g. TextBoxes; the default paste is prevented.
+//
 // Default style class: "gwu-Table"
 type Table interface {
 	// Table is a TableView.
@@ -91,6 +103,15 @@ type Table interface {
 	// TrimRow trims the specified row: removes trailing cells that has nil value
 	// by making the row shorter.
 	TrimRow(row int)
+
+	// Pasted returns the data of the last paste (see ETypePaste):
+	// the row and column of the cell the data was pasted into,
+	// and the pasted cells, structure: cells[rowIdx][colIdx].
+	// Note that the column is the index of the cell in the rendered
+	// row, which differs from the column index if col span is used
+	// in the row.
+	// cells is nil if no paste happened yet.
+	Pasted() (row, col int, cells [][]string)
 }
 
 // cellIdx type specifies a cell by its row and col indices.
@@ -105,13 +126,22 @@ type tableImpl struct {
 	comps    [][]Comp                 // Components added to the table. Structure: comps[rowIdx][colIdx]
 	rowFmts  map[int]*cellFmtImpl     // Lazily initialized row formatters of the rows
 	cellFmts map[cellIdx]*cellFmtImpl // Lazily initialized cell formatters of the cells
+
+	pasteRow, pasteCol int        // Cell of the last paste
+	pasteCells         [][]string // Cells of the last paste
 }
 
+var strPasteGridV = []byte("pasteGrid(event,this)") // "pasteGrid(event,this)"
+
 // NewTable creates a new Table.
 // Default horizontal alignment is HADefault,
 // default vertical alignment is VADefault.
 func NewTable() Table {
 	c := &tableImpl{tableViewImpl: newTableViewImpl()}
+	c.valueProviderJs = strPasteGridV
+	// Paste is only synced if ETypePaste handlers are registered (not using AddSyncOnETypes()
+	// which would also register a handler)
+	c.syncOnETypes = map[EventType]bool{ETypePaste: true}
 	c.Style().AddClass("gwu-Table")
 	c.SetCellSpacing(0)
 	c.SetCellPadding(0)
@@ -154,6 +184,18 @@ func (c *tableImpl) ById(id ID) Comp {
 	return nil
 }
 
+func (c *tableImpl) children() []Comp {
+	var comps []Comp
+	for _, rowComps := range c.comps {
+		for _, c2 := range rowComps {
+			if c2 != nil {
+				comps = append(comps, c2)
+			}
+		}
+	}
+	return comps
+}
+
 func (c *tableImpl) Clear() {
 	// Clear row formatters
 	if c.rowFmts != nil {
@@ -367,6 +409,85 @@ func (c *tableImpl) TrimRow(row int) {
 	c.comps[row] = rowComps[:ci.col+1]
 }
 
+func (c *tableImpl) Pasted() (row, col int, cells [][]string) {
+	return c.pasteRow, c.pasteCol, c.pasteCells
+}
+
+func (c *tableImpl) preprocessEvent(event Event, r *http.Request) {
+	if event.Type() != ETypePaste {
+		return
+	}
+
+	// Value format: "row,col,text"
+	parts := strings.SplitN(r.FormValue(paramCompValue), ",", 3)
+	if len(parts) < 3 {
+		return
+	}
+	row, err := strconv.Atoi(parts[0])
+	if err != nil || row < 0 {
+		return
+	}
+	col, err := strconv.Atoi(parts[1])
+	if err != nil || col < 0 {
+		return
+	}
+
+	c.pasteRow, c.pasteCol, c.pasteCells = row, col, parsePasteGrid(parts[2])
+}
+
+// parsePasteGrid parses tab/newline delimited text (as copied from spreadsheets)
+// into a grid of cells, structure: cells[rowIdx][colIdx].
+// Cells enclosed in quotes may contain tabs, newlines and quotes (escaped
+// as 2 quotes). A trailing newline does not start a new row.
+func parsePasteGrid(text string) [][]string {
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	text = strings.TrimSuffix(text, "\n")
+
+	cells := [][]string{}
+	row := []string{}
+	for {
+		var cell string
+		if strings.HasPrefix(text, `"`) {
+			// Quoted cell: find the closing quote (not followed by another quote)
+			var b []byte
+			i := 1
+			for ; i < len(text); i++ {
+				if text[i] == '"' {
+					if i+1 < len(text) && text[i+1] == '"' {
+						i++
+					} else {
+						break
+					}
+				}
+				b = append(b, text[i])
+			}
+			if i < len(text) && (i+1 == len(text) || text[i+1] == '\t' || text[i+1] == '\n') {
+				cell, text = string(b), text[i+1:]
+			}
+		}
+		if cell == "" && !strings.HasPrefix(text, "\t") && !strings.HasPrefix(text, "\n") {
+			// Unquoted cell (or malformed quoted cell which is taken as-is)
+			end := strings.IndexAny(text, "\t\n")
+			if end < 0 {
+				end = len(text)
+			}
+			cell, text = text[:end], text[end:]
+		}
+		row = append(row, cell)
+
+		if text == "" {
+			break
+		}
+		if text[0] == '\n' {
+			cells = append(cells, row)
+			row = []string{}
+		}
+		text = text[1:]
+	}
+
+	return append(cells, row)
+}
+
 func (c *tableImpl) Render(w Writer) {
 	w.Write(strTableOp)
 	c.renderAttrsAndStyle(w)
@@ -382,7 +503,7 @@ func (c *tableImpl) Render(w Writer) {
 			ci.row, ci.col = row, col
 			c.renderTd(ci, w)
 			if c2 != nil {
-				c2.Render(w)
+				renderComp(w, c2)
 			}
 		}
 	}
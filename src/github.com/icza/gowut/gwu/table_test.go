@@ -0,0 +1,78 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePasteGrid(t *testing.T) {
+	cases := []struct {
+		text  string
+		cells [][]string
+	}{
+		{"a", [][]string{{"a"}}},
+		{"a\tb\nc\td\n", [][]string{{"a", "b"}, {"c", "d"}}},
+		{"a\tb\r\nc\td\r\n", [][]string{{"a", "b"}, {"c", "d"}}},
+		{"a\t\tc\n\tx", [][]string{{"a", "", "c"}, {"", "x"}}},
+		{"\"multi\nline\"\t\"say \"\"hi\"\"\"\n\"tab\there\"", [][]string{{"multi\nline", `say "hi"`}, {"tab\there"}}},
+		{"\"\"\tb", [][]string{{"", "b"}}},
+		{"\"unclosed\tb", [][]string{{`"unclosed`, "b"}}},
+		{"1\n\n2", [][]string{{"1"}, {""}, {"2"}}},
+	}
+	for _, c := range cases {
+		if cells := parsePasteGrid(c.text); !reflect.DeepEqual(cells, c.cells) {
+			t.Errorf("Parsing %q: got %q, want %q", c.text, cells, c.cells)
+		}
+	}
+}
+
+func TestTablePaste(t *testing.T) {
+	tbl := NewTable()
+	if _, _, cells := tbl.Pasted(); cells != nil {
+		t.Errorf("Got cells before paste: %q", cells)
+	}
+
+	e := newEventImpl(ETypePaste, tbl, nil, nil)
+	tbl.preprocessEvent(e, newCompValueReq("1,2,a\tb\nc\td"))
+	row, col, cells := tbl.Pasted()
+	if row != 1 || col != 2 || !reflect.DeepEqual(cells, [][]string{{"a", "b"}, {"c", "d"}}) {
+		t.Errorf("Got row: %d, col: %d, cells: %q", row, col, cells)
+	}
+
+	// Malformed values are ignored
+	for _, v := range []string{"", "1,2", "x,2,a", "1,-1,a"} {
+		tbl.preprocessEvent(e, newCompValueReq(v))
+		if r, c, _ := tbl.Pasted(); r != 1 || c != 2 {
+			t.Errorf("Malformed value %q not ignored", v)
+		}
+	}
+}
+
+func TestTablePasteRender(t *testing.T) {
+	tbl := NewTable()
+	if s := renderString(tbl); strings.Contains(s, "onpaste") {
+		t.Errorf("Paste handler rendered without handlers: %s", s)
+	}
+
+	tbl.AddEHandlerFunc(func(e Event) {}, ETypePaste)
+	want := ` onpaste="se(event,` + ETypePaste.String() + `,` + tbl.Id().String() + `,pasteGrid(event,this))"`
+	if s := renderString(tbl); !strings.Contains(s, want) {
+		t.Errorf("Rendered output does not contain %s: %s", want, s)
+	}
+}
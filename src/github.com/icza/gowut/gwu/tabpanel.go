@@ -223,6 +223,12 @@ func (c *tabPanelImpl) ById(id ID) Comp {
 	return nil
 }
 
+func (c *tabPanelImpl) children() []Comp {
+	tabs := c.tabBarImpl.children()
+	// Full slice expression so the slice of the tab bar is never modified
+	return append(tabs[:len(tabs):len(tabs)], c.panelImpl.children()...)
+}
+
 func (c *tabPanelImpl) Clear() {
 	c.tabBarImpl.Clear()
 	c.panelImpl.Clear()
@@ -346,7 +352,7 @@ func (c *tabPanelImpl) Render(w Writer) {
 	case TbPlacementTop:
 		w.Write(strTR)
 		c.tabBarFmt.render(strTDOp, w)
-		c.tabBarImpl.Render(w)
+		renderComp(w, c.tabBarImpl)
 		c.renderTr(w)
 		c.renderContent(w)
 	case TbPlacementBottom:
@@ -354,17 +360,17 @@ func (c *tabPanelImpl) Render(w Writer) {
 		c.renderContent(w)
 		w.Write(strTR)
 		c.tabBarFmt.render(strTDOp, w)
-		c.tabBarImpl.Render(w)
+		renderComp(w, c.tabBarImpl)
 	case TbPlacementLeft:
 		c.renderTr(w)
 		c.tabBarFmt.render(strTDOp, w)
-		c.tabBarImpl.Render(w)
+		renderComp(w, c.tabBarImpl)
 		c.renderContent(w)
 	case TbPlacementRight:
 		c.renderTr(w)
 		c.renderContent(w)
 		c.tabBarFmt.render(strTDOp, w)
-		c.tabBarImpl.Render(w)
+		renderComp(w, c.tabBarImpl)
 	}
 
 	w.Write(strTableCl)
@@ -376,7 +382,7 @@ func (c *tabPanelImpl) renderContent(w Writer) {
 	if c.selected >= 0 {
 		c2 := c.comps[c.selected]
 		c.renderTd(c2, w)
-		c2.Render(w)
+		renderComp(w, c2)
 	} else {
 		w.Write(strTD)
 	}
@@ -0,0 +1,151 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// TagInput component interface and implementation.
+
+package gwu
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TagInput interface defines a component for entering multiple freeform
+// values (e.g. tags or email addresses). The entered values are displayed
+// as removable chips, followed by an input for adding more.
+//
+// Pressing Enter or comma in the input adds the entered text as a new tag,
+// clicking on the "×" of a chip removes the tag. Both send an ETypeChange
+// event with the full list of tags; the TagInput is marked dirty automatically.
+//
+// Suggested event type to handle changes: ETypeChange
+//
+// Default style classes: "gwu-TagInput", "gwu-TagInput-Tag",
+// "gwu-TagInput-Remove", "gwu-TagInput-Input"
+type TagInput interface {
+	// TagInput is a component.
+	Comp
+
+	// Tags returns the tags.
+	Tags() []string
+
+	// SetTags sets the tags.
+	// Empty tags (after trimming spaces) are omitted.
+	SetTags(tags []string)
+}
+
+// TagInput implementation.
+type tagInputImpl struct {
+	compImpl // Component implementation
+
+	tags []string // The tags
+}
+
+// NewTagInput creates a new TagInput.
+func NewTagInput(tags []string) TagInput {
+	c := &tagInputImpl{compImpl: newCompImpl(nil)}
+	c.SetTags(tags)
+	c.Style().AddClass("gwu-TagInput")
+	return c
+}
+
+func (c *tagInputImpl) Tags() []string {
+	return c.tags
+}
+
+func (c *tagInputImpl) SetTags(tags []string) {
+	c.tags = make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			c.tags = append(c.tags, tag)
+		}
+	}
+}
+
+func (c *tagInputImpl) preprocessEvent(event Event, r *http.Request) {
+	if event.Type() != ETypeChange {
+		return
+	}
+	// Empty list is a valid value, so check whether the value is present
+	r.FormValue(paramCompValue) // Make sure Form is parsed
+	values, present := r.Form[paramCompValue]
+	if !present || len(values) == 0 {
+		return
+	}
+
+	c.SetTags(decodeTagList(values[0]))
+	event.MarkDirty(c)
+}
+
+// encodeTagList encodes a list of tags: the URL-encoded tags joined with commas.
+func encodeTagList(tags []string) string {
+	encoded := make([]string, len(tags))
+	for i, tag := range tags {
+		encoded[i] = url.QueryEscape(tag)
+	}
+	return strings.Join(encoded, ",")
+}
+
+// decodeTagList decodes a list of tags encoded by encodeTagList() (or by the client).
+// Malformed tags are omitted.
+func decodeTagList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	var tags []string
+	for _, encoded := range strings.Split(list, ",") {
+		if tag, err := url.QueryUnescape(encoded); err == nil {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+var (
+	strTagOp         = []byte(`<span class="gwu-TagInput-Tag">`)                                        // `<span class="gwu-TagInput-Tag">`
+	strTagRemoveOp   = []byte(`<span class="gwu-TagInput-Remove" onclick="tagRemove(`)                  // `<span class="gwu-TagInput-Remove" onclick="tagRemove(`
+	strTagRemoveCl   = []byte(`)">&times;</span></span>`)                                               // `)">&times;</span></span>`
+	strTagInputOp    = []byte(`<input type="text" class="gwu-TagInput-Input" onkeydown="tagKey(event,`) // `<input type="text" class="gwu-TagInput-Input" onkeydown="tagKey(event,`
+	strTagInputCl    = []byte(`)" onchange="event.stopPropagation()"/>`)                                // `)" onchange="event.stopPropagation()"/>`
+	strDataGwuTagsOp = []byte(` data-gwutags="`)                                                        // ` data-gwutags="`
+)
+
+func (c *tagInputImpl) Render(w Writer) {
+	w.Write(strSpanOp)
+	c.renderAttrsAndStyle(w)
+	c.renderEHandlers(w)
+	// The client needs the current list to send the full list on changes
+	w.Write(strDataGwuTagsOp)
+	w.Writees(encodeTagList(c.tags))
+	w.Write(strQuote)
+	w.Write(strGT)
+
+	// To render: <span class="gwu-TagInput-Tag">tag<span class="gwu-TagInput-Remove" onclick="tagRemove(compId,etype,idx)">&times;</span></span>
+	for i, tag := range c.tags {
+		w.Write(strTagOp)
+		w.Writees(tag)
+		w.Write(strTagRemoveOp)
+		w.Writevs(int(c.id), strComma, int(ETypeChange), strComma, i)
+		w.Write(strTagRemoveCl)
+	}
+
+	// To render: <input type="text" class="gwu-TagInput-Input" onkeydown="tagKey(event,compId,etype)" .../>
+	w.Write(strTagInputOp)
+	w.Writevs(int(c.id), strComma, int(ETypeChange))
+	w.Write(strTagInputCl)
+
+	w.Write(strSpanCl)
+}
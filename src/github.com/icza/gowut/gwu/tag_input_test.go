@@ -0,0 +1,98 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sendTags sends a change event with the specified (client encoded) tag list to the tag input.
+func sendTags(ti TagInput, tags ...string) *eventImpl {
+	encoded := make([]string, len(tags))
+	for i, tag := range tags {
+		// Client uses encodeURIComponent() which encodes space as %20
+		encoded[i] = strings.Replace(url.QueryEscape(tag), "+", "%20", -1)
+	}
+	e := newEventImpl(ETypeChange, ti, nil, nil)
+	ti.preprocessEvent(e, newCompValueReq(strings.Join(encoded, ",")))
+	return e
+}
+
+func TestTagInputAddRemove(t *testing.T) {
+	ti := NewTagInput([]string{"go", " ", "web"})
+	if tags := ti.Tags(); !reflect.DeepEqual(tags, []string{"go", "web"}) {
+		t.Errorf("Got tags %q", tags)
+	}
+
+	steps := []struct {
+		sent, want []string
+	}{
+		{[]string{"go", "web", "a,b"}, []string{"go", "web", "a,b"}},                   // Add with comma
+		{[]string{"go", "web", "a,b", "x y+z"}, []string{"go", "web", "a,b", "x y+z"}}, // Add with space and plus
+		{[]string{"go", "a,b", "x y+z"}, []string{"go", "a,b", "x y+z"}},               // Remove
+		{[]string{"go", "a,b", "x y+z", "  "}, []string{"go", "a,b", "x y+z"}},         // Blank is omitted
+		{[]string{}, []string{}}, // Remove all
+	}
+	for i, s := range steps {
+		e := sendTags(ti, s.sent...)
+		if tags := ti.Tags(); !reflect.DeepEqual(tags, s.want) {
+			t.Errorf("Step %d: got tags %q, want %q", i, tags, s.want)
+		}
+		if !e.shared.dirty(ti) {
+			t.Errorf("Step %d: not marked dirty", i)
+		}
+	}
+
+	// Missing value is ignored
+	ti.SetTags([]string{"a"})
+	e := newEventImpl(ETypeChange, ti, nil, nil)
+	ti.preprocessEvent(e, &http.Request{Form: url.Values{}})
+	if tags := ti.Tags(); !reflect.DeepEqual(tags, []string{"a"}) || e.shared.dirty(ti) {
+		t.Errorf("Got tags %q", tags)
+	}
+}
+
+func TestTagListEncoding(t *testing.T) {
+	tags := []string{"a,b", "x y+z", "%"}
+	if got := decodeTagList(encodeTagList(tags)); !reflect.DeepEqual(got, tags) {
+		t.Errorf("Got %q, want %q", got, tags)
+	}
+	if got := decodeTagList("a,%zz,b"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Malformed tag not omitted: %q", got)
+	}
+}
+
+func TestTagInputRender(t *testing.T) {
+	ti := NewTagInput([]string{"a,b", "<c>"})
+	id := ti.Id().String()
+	et := ETypeChange.String()
+
+	s := renderString(ti)
+	for _, want := range []string{
+		` data-gwutags="a%2Cb,%3Cc%3E"`,
+		`<span class="gwu-TagInput-Tag">a,b<span class="gwu-TagInput-Remove" onclick="tagRemove(` + id + `,` + et + `,0)">&times;</span></span>`,
+		`<span class="gwu-TagInput-Tag">&lt;c&gt;<span class="gwu-TagInput-Remove" onclick="tagRemove(` + id + `,` + et + `,1)">`,
+		`onkeydown="tagKey(event,` + id + `,` + et + `)"`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Rendered output does not contain %s: %s", want, s)
+		}
+	}
+}
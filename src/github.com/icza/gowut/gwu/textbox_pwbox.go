@@ -18,8 +18,10 @@
 package gwu
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
+	"unicode/utf8"
 )
 
 // TextBox interface defines a component for text input purpose.
@@ -175,22 +177,53 @@ func (c *textBoxImpl) SetMaxLength(maxLength int) {
 	}
 }
 
+func (c *textBoxImpl) saveState() interface{} {
+	if c.isPassw {
+		return nil
+	}
+	return c.text
+}
+
+func (c *textBoxImpl) restoreState(data json.RawMessage) {
+	var text string
+	if !c.isPassw && json.Unmarshal(data, &text) == nil {
+		c.setClientText(text)
+	}
+}
+
+// setClientText sets the text sent by the client (or restored from the client state).
+// The text is dropped if the text box is disabled or read-only, or if it is
+// longer than the max length, as the user could not have entered it.
+func (c *textBoxImpl) setClientText(text string) {
+	if !c.Enabled() || c.ReadOnly() {
+		return
+	}
+	if ml := c.MaxLength(); ml >= 0 && utf8.RuneCountInString(text) > ml {
+		return
+	}
+	c.text = text
+}
+
 func (c *textBoxImpl) preprocessEvent(event Event, r *http.Request) {
 	// Empty string for text box is a valid value.
 	// So we have to check whether it is supplied, not just whether its len() > 0
 	value := r.FormValue(paramCompValue)
 	if len(value) > 0 {
-		c.text = value
+		c.setClientText(value)
 	} else {
 		// Empty string might be a valid value, if the component value param is present:
 		values, present := r.Form[paramCompValue] // Form is surely parsed (we called FormValue())
 		if present && len(values) > 0 {
-			c.text = values[0]
+			c.setClientText(values[0])
 		}
 	}
 }
 
 func (c *textBoxImpl) Render(w Writer) {
+	if !c.isPassw {
+		c.renderPrintStatic(w, c.text)
+	}
+
 	if c.rows <= 1 || c.isPassw {
 		c.renderInput(w)
 	} else {
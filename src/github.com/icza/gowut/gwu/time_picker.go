@@ -0,0 +1,191 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// TimePicker component interface and implementation.
+
+package gwu
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// TimePicker interface defines a component for picking a time of the day
+// (hour and minute). It is rendered as an HTML5 time input.
+//
+// Suggested event type to handle changes: ETypeChange
+//
+// Default style class: "gwu-TimePicker"
+type TimePicker interface {
+	// TimePicker is a component.
+	Comp
+
+	// TimePicker can be enabled/disabled.
+	HasEnabled
+
+	// Time returns the picked time (hour and minute).
+	Time() (hour, min int)
+
+	// SetTime sets the picked time (hour and minute).
+	// Invalid values (hour outside of 0..23 or min outside of 0..59)
+	// are ignored.
+	SetTime(hour, min int)
+
+	// Step returns the step of the time input, in seconds.
+	// 0 is returned if no step is set (browser default is used).
+	Step() int
+
+	// SetStep sets the step of the time input, in seconds.
+	// Pass 0 to use the browser default (which is 60 seconds).
+	SetStep(step int)
+}
+
+// TimePicker implementation.
+type timePickerImpl struct {
+	compImpl       // Component implementation
+	hasEnabledImpl // Has enabled implementation
+
+	hour, min int // The picked time
+	step      int // Step of the time input in seconds, 0 if not set
+}
+
+// NewTimePicker creates a new TimePicker.
+func NewTimePicker(hour, min int) TimePicker {
+	c := &timePickerImpl{compImpl: newCompImpl(strEncURIThisV), hasEnabledImpl: newHasEnabledImpl()}
+	c.SetTime(hour, min)
+	c.AddSyncOnETypes(ETypeChange)
+	c.Style().AddClass("gwu-TimePicker")
+	return c
+}
+
+func (c *timePickerImpl) Time() (hour, min int) {
+	return c.hour, c.min
+}
+
+func (c *timePickerImpl) SetTime(hour, min int) {
+	if hour < 0 || hour > 23 || min < 0 || min > 59 {
+		return
+	}
+	c.hour, c.min = hour, min
+}
+
+func (c *timePickerImpl) Step() int {
+	return c.step
+}
+
+func (c *timePickerImpl) SetStep(step int) {
+	if step < 0 {
+		step = 0
+	}
+	c.step = step
+}
+
+func (c *timePickerImpl) saveState() interface{} {
+	return twoDigits(c.hour) + ":" + twoDigits(c.min)
+}
+
+func (c *timePickerImpl) restoreState(data json.RawMessage) {
+	var value string
+	if json.Unmarshal(data, &value) == nil {
+		if hour, min, ok := parseHourMin(value); ok {
+			c.hour, c.min = hour, min
+		}
+	}
+}
+
+func (c *timePickerImpl) preprocessEvent(event Event, r *http.Request) {
+	// Malformed values (including empty string when the input is cleared) are ignored.
+	if hour, min, ok := parseHourMin(r.FormValue(paramCompValue)); ok {
+		c.hour, c.min = hour, min
+	}
+}
+
+// parseHourMin parses a time value in the "HH:MM" format as sent by browsers.
+// The optional seconds part ("HH:MM:SS" or "HH:MM:SS.mmm", sent if the step
+// is less than a minute) is accepted but dropped.
+// ok is false if the value is malformed.
+func parseHourMin(value string) (hour, min int, ok bool) {
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return
+	}
+
+	var err error
+	if hour, err = atoiDigits(parts[0]); err != nil || hour > 23 {
+		return
+	}
+	if min, err = atoiDigits(parts[1]); err != nil || min > 59 {
+		return
+	}
+	if len(parts) == 3 {
+		sec := parts[2]
+		if i := strings.IndexByte(sec, '.'); i >= 0 {
+			sec = sec[:i]
+		}
+		if s, err := atoiDigits(sec); err != nil || s > 59 {
+			return
+		}
+	}
+
+	return hour, min, true
+}
+
+// atoiDigits converts a non-empty string consisting of decimal digits
+// only to an int. Signs and spaces are not allowed (unlike strconv.Atoi()).
+func atoiDigits(s string) (int, error) {
+	if len(s) == 0 || len(s) > 4 {
+		return 0, strconv.ErrSyntax
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, strconv.ErrSyntax
+		}
+	}
+	return strconv.Atoi(s)
+}
+
+// twoDigits formats a non-negative int to at least 2 digits.
+func twoDigits(i int) string {
+	if i < 10 {
+		return "0" + strconv.Itoa(i)
+	}
+	return strconv.Itoa(i)
+}
+
+var (
+	strTimeInputOp = []byte(`<input type="time" value="`) // `<input type="time" value="`
+	strStep        = []byte(` step="`)                    // ` step="`
+	strInputCl2    = []byte("/>")                         // "/>"
+)
+
+func (c *timePickerImpl) Render(w Writer) {
+	value := twoDigits(c.hour) + ":" + twoDigits(c.min)
+	c.renderPrintStatic(w, value)
+
+	w.Write(strTimeInputOp)
+	w.Writes(value)
+	w.Write(strQuote)
+	if c.step > 0 {
+		w.Write(strStep)
+		w.Writev(c.step)
+		w.Write(strQuote)
+	}
+	c.renderAttrsAndStyle(w)
+	c.renderEnabled(w)
+	c.renderEHandlers(w)
+	w.Write(strInputCl2)
+}
@@ -0,0 +1,85 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseHourMin(t *testing.T) {
+	cases := []struct {
+		value     string
+		hour, min int
+		ok        bool
+	}{
+		{"00:00", 0, 0, true},
+		{"09:05", 9, 5, true},
+		{"23:59", 23, 59, true},
+		{"12:30:15", 12, 30, true},
+		{"12:30:15.250", 12, 30, true},
+		{"", 0, 0, false},
+		{"12", 0, 0, false},
+		{"24:00", 0, 0, false},
+		{"12:60", 0, 0, false},
+		{"12:30:60", 0, 0, false},
+		{"-1:30", 0, 0, false},
+		{"+1:30", 0, 0, false},
+		{" 1:30", 0, 0, false},
+		{"1:2:3:4", 0, 0, false},
+		{"ab:cd", 0, 0, false},
+	}
+
+	for _, c := range cases {
+		hour, min, ok := parseHourMin(c.value)
+		if ok != c.ok || ok && (hour != c.hour || min != c.min) {
+			t.Errorf("parseHourMin(%q) = %d, %d, %v; want %d, %d, %v", c.value, hour, min, ok, c.hour, c.min, c.ok)
+		}
+	}
+}
+
+func TestTimePickerPreprocessEvent(t *testing.T) {
+	tp := NewTimePicker(8, 15).(*timePickerImpl)
+
+	tp.preprocessEvent(nil, newCompValueReq("17:45"))
+	if h, m := tp.Time(); h != 17 || m != 45 {
+		t.Errorf("Got %d:%d, want 17:45", h, m)
+	}
+
+	// Malformed values must not change the time:
+	tp.preprocessEvent(nil, newCompValueReq("99:99"))
+	tp.preprocessEvent(nil, newCompValueReq(""))
+	if h, m := tp.Time(); h != 17 || m != 45 {
+		t.Errorf("Got %d:%d, want 17:45", h, m)
+	}
+}
+
+func TestTimePickerRender(t *testing.T) {
+	tp := NewTimePicker(7, 5)
+
+	s := renderString(tp)
+	if !strings.Contains(s, `value="07:05"`) {
+		t.Errorf("Value not rendered properly: %s", s)
+	}
+	if strings.Contains(s, "step=") {
+		t.Errorf("Step rendered when not set: %s", s)
+	}
+
+	tp.SetStep(900)
+	if s := renderString(tp); !strings.Contains(s, ` step="900"`) {
+		t.Errorf("Step not rendered: %s", s)
+	}
+}
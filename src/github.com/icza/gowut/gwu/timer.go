@@ -125,19 +125,22 @@ func (c *timerImpl) Reset() {
 var (
 	strSetupTimerOp = []byte("setupTimer(") // "setupTimer("
 	strJsSendEvtOp  = []byte("se(null,")    // "se(null,"
+	strJsFnOp       = []byte("function(){") // "function(){"
+	strJsFnCl       = []byte("}")           // "}"
 )
 
 // renderSetupTimerJs renders the Javascript code which sets up the timer.
-// js_vs param holds the values which render Javascript code to be scheduled:
-//     setupTimer(compId,"jscode",timeout,repeat,active,reset);
+// js_vs param holds the values which render Javascript code to be scheduled
+// (rendered as a function and not as a string, so no eval is needed):
+//     setupTimer(compId,function(){jscode},timeout,repeat,active,reset);
 func (c *timerImpl) renderSetupTimerJs(w Writer, js_vs ...interface{}) {
 	w.Write(strSetupTimerOp)
 	w.Writev(int(c.id))
 	w.Write(strComma)
 	// js param
-	w.Write(strQuote)
+	w.Write(strJsFnOp)
 	w.Writevs(js_vs...)
-	w.Write(strQuote)
+	w.Write(strJsFnCl)
 	// end of js param
 	w.Write(strComma)
 	w.Writev(int(c.timeout / time.Millisecond))
@@ -156,7 +159,7 @@ func (c *timerImpl) Render(w Writer) {
 	c.renderEHandlers(w)
 	w.Write(strGT)
 
-	w.Write(strScriptOp)
+	writeScriptOp(w)
 	c.renderSetupTimerJs(w, strJsSendEvtOp, int(ETypeStateChange), strComma, int(c.id), strJsFuncCl)
 	w.Write(strScriptCl)
 
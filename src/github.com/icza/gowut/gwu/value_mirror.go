@@ -0,0 +1,188 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// ValueMirror component interface and implementation.
+
+package gwu
+
+import (
+	"strconv"
+)
+
+// ValueMirror interface defines a read-only component which displays
+// the live value of another (source) component as formatted text,
+// e.g. "$1,234.00" reflecting the value of a TextBox.
+//
+// The value of the source is acquired with HasText.Text() if the source
+// has text (e.g. TextBox), or with NumberSpinner.Value() if the source is
+// a NumberSpinner.
+//
+// The ValueMirror is refreshed and marked dirty when the source fires
+// an ETypeChange or ETypeStateChange event, formatting the value with
+// the format function at the server side.
+//
+// Optionally a client side format can be set (see SetClientFormat())
+// with which the ValueMirror is also updated on the client side as
+// the user types, without a server trip.
+//
+// Default style class: "gwu-ValueMirror"
+type ValueMirror interface {
+	// ValueMirror is a component.
+	Comp
+
+	// ValueMirror has text (the formatted value).
+	HasText
+
+	// Source returns the source component.
+	Source() Comp
+
+	// Format returns the format function.
+	Format() func(value string) string
+
+	// SetFormat sets the format function which turns the value
+	// of the source into the displayed text.
+	// Pass nil to display the value as-is.
+	SetFormat(format func(value string) string)
+
+	// ClientFormat returns the client side format.
+	ClientFormat() MirrorFormat
+
+	// SetClientFormat sets the format which formats the value at the
+	// client side, for example:
+	//     MirrorFormat{Kind: MirrorFormatNumber, Decimals: 2, Prefix: "$"}
+	// If set, the ValueMirror is updated on the client side on each input
+	// event of the source, without a server trip; the text is then
+	// overwritten by the server side format on the next change event.
+	// Pass a format of kind MirrorFormatNone to only update at the server side.
+	SetClientFormat(format MirrorFormat)
+
+	// Refresh updates the text from the current value of the source.
+	// It is called automatically when the source changes.
+	Refresh()
+}
+
+// Kind of a client side ValueMirror format.
+type MirrorFormatKind int
+
+// Client side ValueMirror format kinds.
+const (
+	MirrorFormatNone   MirrorFormatKind = iota // No client side format (only updated at the server side)
+	MirrorFormatText                           // The value as-is
+	MirrorFormatUpper                          // The value in upper case
+	MirrorFormatLower                          // The value in lower case
+	MirrorFormatNumber                         // The value as a number with fixed decimals, "-" if not a number
+	MirrorFormatLength                         // The length of the value (number of characters)
+)
+
+// MirrorFormat is a client side format of a ValueMirror.
+// Formats are applied by the Gowut JavaScript without evaluating
+// code, so they also work under a content security policy.
+type MirrorFormat struct {
+	Kind     MirrorFormatKind // Kind of the format
+	Decimals int              // Number of decimals of MirrorFormatNumber
+	Prefix   string           // Text displayed before the formatted value, e.g. "$"
+	Suffix   string           // Text displayed after the formatted value, e.g. " kg"
+}
+
+// ValueMirror implementation.
+type valueMirrorImpl struct {
+	compImpl    // Component implementation
+	hasTextImpl // Has text implementation
+
+	source       Comp                      // The source component
+	format       func(value string) string // Server side format function
+	clientFormat MirrorFormat              // Client side format
+}
+
+// NewValueMirror creates a new ValueMirror bound to the specified source.
+// format may be nil in which case the value is displayed as-is.
+func NewValueMirror(source Comp, format func(value string) string) ValueMirror {
+	c := &valueMirrorImpl{compImpl: newCompImpl(nil), hasTextImpl: newHasTextImpl(""), source: source, format: format}
+	c.Refresh()
+	source.AddEHandlerFunc(func(e Event) {
+		c.Refresh()
+		e.MarkDirty(c)
+	}, ETypeChange, ETypeStateChange)
+	c.Style().AddClass("gwu-ValueMirror")
+	return c
+}
+
+func (c *valueMirrorImpl) Source() Comp {
+	return c.source
+}
+
+func (c *valueMirrorImpl) Format() func(value string) string {
+	return c.format
+}
+
+func (c *valueMirrorImpl) SetFormat(format func(value string) string) {
+	c.format = format
+}
+
+func (c *valueMirrorImpl) ClientFormat() MirrorFormat {
+	return c.clientFormat
+}
+
+func (c *valueMirrorImpl) SetClientFormat(format MirrorFormat) {
+	c.clientFormat = format
+}
+
+func (c *valueMirrorImpl) Refresh() {
+	var value string
+	switch src := c.source.(type) {
+	case HasText:
+		value = src.Text()
+	case NumberSpinner:
+		value = strconv.Itoa(src.Value())
+	}
+
+	if c.format != nil {
+		value = c.format(value)
+	}
+	c.text = value
+}
+
+var (
+	strDataGwuMirror = []byte(` data-gwumirror="`) // ` data-gwumirror="`
+	strDataGwuFmt    = []byte(` data-gwufmt="`)    // ` data-gwufmt="`
+	strDataGwuFmtPre = []byte(` data-gwufmtpre="`) // ` data-gwufmtpre="`
+	strDataGwuFmtSuf = []byte(` data-gwufmtsuf="`) // ` data-gwufmtsuf="`
+)
+
+func (c *valueMirrorImpl) Render(w Writer) {
+	// To render: <span id="compId" data-gwumirror="srcId" data-gwufmt="kind,decimals" data-gwufmtpre="prefix" data-gwufmtsuf="suffix">text</span>
+	w.Write(strSpanOp)
+	c.renderAttrsAndStyle(w)
+	c.renderEHandlers(w)
+	if f := c.clientFormat; f.Kind != MirrorFormatNone {
+		w.Write(strDataGwuMirror)
+		w.Writev(int(c.source.Id()))
+		w.Write(strQuote)
+		w.Write(strDataGwuFmt)
+		w.Writevs(int(f.Kind), strComma, f.Decimals)
+		w.Write(strQuote)
+		w.Write(strDataGwuFmtPre)
+		w.Writees(f.Prefix)
+		w.Write(strQuote)
+		w.Write(strDataGwuFmtSuf)
+		w.Writees(f.Suffix)
+		w.Write(strQuote)
+	}
+	w.Write(strGT)
+
+	c.renderText(w)
+
+	w.Write(strSpanCl)
+}
@@ -0,0 +1,84 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestValueMirrorBinding(t *testing.T) {
+	tb := NewTextBox("12")
+	vm := NewValueMirror(tb, func(value string) string {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "-"
+		}
+		return "$" + strconv.FormatFloat(f, 'f', 2, 64)
+	})
+	if vm.Text() != "$12.00" {
+		t.Errorf("Got initial text %q", vm.Text())
+	}
+
+	// Change event of the source: value is synced, then handlers are dispatched
+	e := newEventImpl(ETypeChange, tb, nil, nil)
+	tb.preprocessEvent(e, newCompValueReq("1234.5"))
+	tb.dispatchEvent(e)
+	if vm.Text() != "$1234.50" {
+		t.Errorf("Got text %q", vm.Text())
+	}
+	if !e.shared.dirty(vm) {
+		t.Error("Value mirror not marked dirty")
+	}
+
+	tb.SetText("abc")
+	vm.Refresh()
+	if vm.Text() != "-" {
+		t.Errorf("Got text %q", vm.Text())
+	}
+
+	// No format: value as-is
+	ns := NewNumberSpinner(3, 0, 10)
+	vm2 := NewValueMirror(ns, nil)
+	e = newEventImpl(ETypeStateChange, ns, nil, nil)
+	ns.preprocessEvent(e, newCompValueReq("1"))
+	ns.dispatchEvent(e)
+	if vm2.Text() != "4" {
+		t.Errorf("Got text %q", vm2.Text())
+	}
+}
+
+func TestValueMirrorRender(t *testing.T) {
+	tb := NewTextBox("1")
+	vm := NewValueMirror(tb, nil)
+	if s := renderString(vm); strings.Contains(s, "data-gwumirror") {
+		t.Errorf("Client format rendered without being set: %s", s)
+	}
+
+	vm.SetClientFormat(MirrorFormat{Kind: MirrorFormatNumber, Decimals: 2, Prefix: "$", Suffix: ` "USD"`})
+	s := renderString(vm)
+	want := ` data-gwumirror="` + tb.Id().String() + `" data-gwufmt="` + strconv.Itoa(int(MirrorFormatNumber)) +
+		`,2" data-gwufmtpre="$" data-gwufmtsuf=" &#34;USD&#34;">1</span>`
+	if !strings.Contains(s, want) {
+		t.Errorf("Rendered output does not contain %s: %s", want, s)
+	}
+
+	vm.SetClientFormat(MirrorFormat{})
+	if s := renderString(vm); strings.Contains(s, "data-gwumirror") {
+		t.Errorf("Client format rendered after being removed: %s", s)
+	}
+}
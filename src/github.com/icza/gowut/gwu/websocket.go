@@ -0,0 +1,454 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// WebSocket transport of events.
+
+package gwu
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GUID used to compute the accept key of the WebSocket handshake (RFC 6455).
+const wsGuid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket frame opcodes.
+const (
+	wsOpCont  = 0x0 // Continuation frame
+	wsOpText  = 0x1 // Text frame
+	wsOpBin   = 0x2 // Binary frame
+	wsOpClose = 0x8 // Connection close
+	wsOpPing  = 0x9 // Ping
+	wsOpPong  = 0xa // Pong
+)
+
+// Max size of a message (event) received over a WebSocket.
+const wsMaxMsgSize = 1 << 20
+
+// Time after which unfetched cookies of events sent over WebSocket are dropped.
+const wsCookiesTimeout = time.Minute
+
+// errWsProtocol is returned if a WebSocket peer violates the protocol.
+var errWsProtocol = errors.New("WebSocket protocol error")
+
+// wsConn is a server side WebSocket connection.
+type wsConn struct {
+	conn net.Conn          // Underlying (hijacked) connection
+	rw   *bufio.ReadWriter // Buffered reader and writer of the connection
+	wmu  sync.Mutex        // Mutex of writing frames (responses and pushes are written concurrently)
+}
+
+// wsAcceptKey computes the accept key for the specified WebSocket key.
+func wsAcceptKey(key string) string {
+	h := sha1.Sum([]byte(key + wsGuid))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// headerHasToken tells if the specified (comma separated list) header
+// contains the specified token (case-insensitive).
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// wsUpgrade performs the WebSocket handshake, and returns the
+// upgraded connection. If the handshake fails, an error response
+// is sent and nil is returned.
+// Cross-origin requests are rejected.
+func wsUpgrade(w http.ResponseWriter, r *http.Request) *wsConn {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != "GET" || !headerHasToken(r.Header, "Connection", "upgrade") ||
+		!headerHasToken(r.Header, "Upgrade", "websocket") || key == "" {
+		http.Error(w, "WebSocket handshake expected", http.StatusBadRequest)
+		return nil
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Unsupported WebSocket version", http.StatusBadRequest)
+		return nil
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || !strings.EqualFold(u.Host, r.Host) {
+			http.Error(w, "Cross-origin WebSocket not allowed", http.StatusForbidden)
+			return nil
+		}
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return nil
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return nil
+	}
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	rw.WriteString(wsAcceptKey(key))
+	rw.WriteString("\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil
+	}
+
+	return &wsConn{conn: conn, rw: rw}
+}
+
+// readMessage reads the next (text or binary) message.
+// Control frames are handled: pings are answered, and io.EOF is
+// returned if the connection is closed by the peer.
+func (c *wsConn) readMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		var hdr [2]byte
+		if _, err := io.ReadFull(c.rw, hdr[:]); err != nil {
+			return nil, err
+		}
+		fin, op := hdr[0]&0x80 != 0, hdr[0]&0x0f
+		if hdr[1]&0x80 == 0 {
+			return nil, errWsProtocol // Client frames must be masked
+		}
+
+		size := uint64(hdr[1] & 0x7f)
+		switch size {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+				return nil, err
+			}
+			size = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+				return nil, err
+			}
+			size = binary.BigEndian.Uint64(ext[:])
+		}
+		if size > wsMaxMsgSize || uint64(len(msg))+size > wsMaxMsgSize {
+			return nil, errWsProtocol
+		}
+
+		var mask [4]byte
+		if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+			return nil, err
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(c.rw, payload); err != nil {
+			return nil, err
+		}
+		for i := range payload {
+			payload[i] ^= mask[i&3]
+		}
+
+		switch op {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil {
+				return nil, err
+			}
+		case wsOpPong:
+		case wsOpClose:
+			c.writeFrame(wsOpClose, nil)
+			return nil, io.EOF
+		case wsOpText, wsOpBin, wsOpCont:
+			if (op == wsOpCont) != (msg != nil) {
+				return nil, errWsProtocol // Continuation must (and only it must) follow a non-final frame
+			}
+			msg = append(msg, payload...)
+			if msg == nil {
+				msg = []byte{}
+			}
+			if fin {
+				return msg, nil
+			}
+		default:
+			return nil, errWsProtocol
+		}
+	}
+}
+
+// writeFrame writes a final, unmasked frame with the specified opcode and payload.
+// It is safe for concurrent use.
+func (c *wsConn) writeFrame(op byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	hdr := make([]byte, 2, 10)
+	hdr[0] = 0x80 | op
+	switch n := len(payload); {
+	case n < 126:
+		hdr[1] = byte(n)
+	case n <= 0xffff:
+		hdr[1] = 126
+		hdr = hdr[:4]
+		binary.BigEndian.PutUint16(hdr[2:], uint16(n))
+	default:
+		hdr[1] = 127
+		hdr = hdr[:10]
+		binary.BigEndian.PutUint64(hdr[2:], uint64(n))
+	}
+	c.rw.Write(hdr)
+	c.rw.Write(payload)
+	return c.rw.Flush()
+}
+
+// close closes the connection.
+func (c *wsConn) close() error {
+	return c.conn.Close()
+}
+
+// wsRespWriter is an http.ResponseWriter which buffers the
+// response of an event sent over WebSocket.
+type wsRespWriter struct {
+	header http.Header  // Response headers
+	status int          // Response status code
+	buf    bytes.Buffer // Response body
+}
+
+func (w *wsRespWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *wsRespWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.buf.Write(p)
+}
+
+func (w *wsRespWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// wsCookies holds the cookies of an event sent over WebSocket
+// until the client fetches them.
+type wsCookies struct {
+	cookies []string  // Values of the Set-Cookie headers
+	created time.Time // Time when the cookies were stored
+}
+
+// wsCookieStore stores cookies of events sent over WebSocket, mapped from token.
+type wsCookieStore struct {
+	mu      sync.Mutex
+	cookies map[string]wsCookies
+}
+
+// put stores the specified cookies, and returns the token to fetch them with.
+// Cookies not fetched for a while are dropped.
+func (st *wsCookieStore) put(cookies []string) string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := time.Now()
+	if st.cookies == nil {
+		st.cookies = make(map[string]wsCookies)
+	}
+	for token, c := range st.cookies {
+		if now.Sub(c.created) > wsCookiesTimeout {
+			delete(st.cookies, token)
+		}
+	}
+	token := genId()
+	st.cookies[token] = wsCookies{cookies: cookies, created: now}
+	return token
+}
+
+// take returns and removes the cookies stored with the specified token.
+func (st *wsCookieStore) take(token string) []string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	c := st.cookies[token]
+	delete(st.cookies, token)
+	return c.cookies
+}
+
+// handleWebSocket upgrades the request to a WebSocket, and handles
+// the events sent over it until the connection is closed.
+// Messages are the same as event request bodies, and the responses
+// are sent back as messages, so the client can process them the same way.
+// If the response of an event sets cookies, the message is prefixed with
+// "ck:token\n", and the client fetches the cookies with the token
+// (cookies can't be set over a WebSocket).
+// If the response status is not OK, the message is "er:status".
+// If push is enabled for the window, pushed updates are also sent over the
+// WebSocket (see pushWebSocket()).
+// Must be called without holding the lock of the session.
+func (s *serverImpl) handleWebSocket(sess Session, win Window, w http.ResponseWriter, r *http.Request) {
+	c := wsUpgrade(w, r)
+	if c == nil {
+		return
+	}
+	if !s.addWebSocket(c) {
+		c.close()
+		return
+	}
+	defer s.removeWebSocket(c)
+
+	if win.PushEnabled() {
+		since, _ := strconv.Atoi(r.FormValue(paramPushSeq))
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go s.pushWebSocket(ctx, c, win, since)
+	}
+
+	for {
+		msg, err := c.readMessage()
+		if err != nil {
+			if err != io.EOF && s.logger != nil {
+				s.logger.Println("WebSocket closed:", err)
+			}
+			return
+		}
+		if sess.Private() && !s.liveSess(sess) {
+			// Session removed, let the client fall back to XHR
+			return
+		}
+
+		// Same request as if the event would be sent via XHR
+		er, err := http.NewRequest("POST", r.URL.String(), bytes.NewReader(msg))
+		if err != nil {
+			return
+		}
+		for k, v := range r.Header {
+			er.Header[k] = v
+		}
+		er.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		er.Host, er.RemoteAddr = r.Host, r.RemoteAddr
+
+		rw := &wsRespWriter{header: http.Header{}}
+		s.serveEvent(sess, win, rw, er)
+
+		var resp []byte
+		if rw.status != 0 && rw.status != http.StatusOK {
+			resp = []byte("er:" + strconv.Itoa(rw.status))
+		} else {
+			if cookies := rw.header["Set-Cookie"]; len(cookies) > 0 {
+				resp = []byte("ck:" + s.wsCookies.put(cookies) + "\n")
+			}
+			resp = append(resp, rw.buf.Bytes()...)
+		}
+		if err := c.writeFrame(wsOpText, resp); err != nil {
+			return
+		}
+	}
+}
+
+// pushWebSocket sends the components pushed to the window after the push
+// sequence number since over the WebSocket, until the context is done.
+// Messages are the same as push responses, prefixed with "ps:seq\n"
+// where seq is the new push sequence number.
+func (s *serverImpl) pushWebSocket(ctx context.Context, c *wsConn, win Window, since int) {
+	for ctx.Err() == nil {
+		ids, seq := win.waitPush(ctx, since, pushTimeout)
+		if len(ids) == 0 {
+			continue
+		}
+		since = seq
+
+		rw := &wsRespWriter{header: http.Header{}}
+		s.writePush(rw, ids)
+		msg := append([]byte("ps:"+strconv.Itoa(seq)+"\n"), rw.buf.Bytes()...)
+		if err := c.writeFrame(wsOpText, msg); err != nil {
+			return
+		}
+	}
+}
+
+// addWebSocket registers an open WebSocket connection (and its handler),
+// so it is closed on shutdown.
+// Returns false if the server is already shut down.
+func (s *serverImpl) addWebSocket(c *wsConn) bool {
+	s.wsConnsMu.Lock()
+	defer s.wsConnsMu.Unlock()
+
+	select {
+	case <-s.shutdown:
+		return false
+	default:
+	}
+	s.wsConns[c] = true
+	s.wsHandlers.Add(1)
+	return true
+}
+
+// removeWebSocket closes and unregisters a WebSocket connection
+// registered by addWebSocket() when its handler returns.
+func (s *serverImpl) removeWebSocket(c *wsConn) {
+	c.close()
+	s.wsConnsMu.Lock()
+	delete(s.wsConns, c)
+	s.wsConnsMu.Unlock()
+	s.wsHandlers.Done()
+}
+
+// closeWebSockets closes the open WebSocket connections. Their handlers
+// return after the events being handled (see waitWebSockets()).
+func (s *serverImpl) closeWebSockets() {
+	s.wsConnsMu.Lock()
+	defer s.wsConnsMu.Unlock()
+
+	for c := range s.wsConns {
+		c.close()
+	}
+}
+
+// waitWebSockets waits for the handlers of the WebSocket connections to return,
+// at most until the context is done (in which case the context's error is returned).
+func (s *serverImpl) waitWebSockets(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wsHandlers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// serveWsCookies sets the cookies of an event sent over WebSocket,
+// identified by the token sent in the response of the event.
+func (s *serverImpl) serveWsCookies(w http.ResponseWriter, r *http.Request) {
+	for _, c := range s.wsCookies.take(r.FormValue(paramWsCookies)) {
+		w.Header().Add("Set-Cookie", c)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,269 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWsAcceptKey(t *testing.T) {
+	// Example of RFC 6455
+	if k := wsAcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); k != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Got: %s", k)
+	}
+}
+
+// wsDial opens a WebSocket to the specified path of the test server.
+func wsDial(t *testing.T, ts *httptest.Server, path, origin string) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := "GET " + path + " HTTP/1.1\r\nHost: " + ts.Listener.Addr().String() +
+		"\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Version: 13" +
+		"\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"
+	if origin != "" {
+		req += "Origin: " + origin + "\r\n"
+	}
+	if _, err := io.WriteString(conn, req+"\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, br, resp
+}
+
+// wsWriteText writes a masked text frame (as clients do).
+func wsWriteText(t *testing.T, conn net.Conn, text string) {
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | wsOpText, 0x80 | 126, 0, 0}
+	binary.BigEndian.PutUint16(frame[2:], uint16(len(text)))
+	frame = append(frame, mask...)
+	for i := 0; i < len(text); i++ {
+		frame = append(frame, text[i]^mask[i&3])
+	}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// wsReadText reads an unmasked text frame (as servers send).
+func wsReadText(t *testing.T, br *bufio.Reader) string {
+	c := &wsConn{rw: bufio.NewReadWriter(br, nil)}
+	var hdr [2]byte
+	if _, err := io.ReadFull(c.rw, hdr[:]); err != nil {
+		t.Fatal(err)
+	}
+	if hdr[0] != 0x80|wsOpText || hdr[1]&0x80 != 0 {
+		t.Fatalf("Unexpected frame header: %x", hdr)
+	}
+	size := int(hdr[1])
+	if size == 126 {
+		var ext [2]byte
+		io.ReadFull(c.rw, ext[:])
+		size = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(c.rw, payload); err != nil {
+		t.Fatal(err)
+	}
+	return string(payload)
+}
+
+func TestWebSocketEvent(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	win.SetWebSocketEnabled(true)
+	l := NewLabel("")
+	b := NewButton("Go")
+	b.AddEHandlerFunc(func(e Event) {
+		l.SetText("clicked")
+		e.MarkDirty(l)
+	}, ETypeClick)
+	win.Add(b)
+	win.Add(l)
+	s.AddWin(win)
+
+	ts := httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	defer ts.Close()
+
+	buf := &bytes.Buffer{}
+	win.(*windowImpl).renderDynJs(NewWriter(buf), s)
+	if !strings.Contains(buf.String(), "wsOpen();") {
+		t.Errorf("WebSocket not opened: %s", buf)
+	}
+
+	conn, br, resp := wsDial(t, ts, "/guitest/main/"+pathWebSocket, "")
+	defer conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Unexpected handshake response: %v", resp)
+	}
+
+	params := clickParams(b)
+	wsWriteText(t, conn, params.Encode())
+	got := wsReadText(t, br)
+
+	// Response must be the same as of XHR
+	l.SetText("")
+	if exp := sendEvent(s, &s.sessionImpl, win, params).Body.String(); got != exp {
+		t.Errorf("Expected: %q, got: %q", exp, got)
+	}
+}
+
+func TestWebSocketPush(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	win.SetPushEnabled(true)
+	win.SetWebSocketEnabled(true)
+	l := NewLabel("")
+	win.Add(l)
+	s.AddWin(win)
+
+	ts := httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	defer ts.Close()
+
+	conn, br, _ := wsDial(t, ts, "/guitest/main/"+pathWebSocket+"?"+paramPushSeq+"=0", "")
+	defer conn.Close()
+
+	// Pushes after the sequence number of the client are sent (even if the sender is not waiting yet)
+	s.Broadcast("main", func(ev BroadcastEvent) {
+		l.SetText("pushed")
+		ev.MarkDirty(l)
+	})
+	want := "ps:1\n" + strconv.Itoa(eraDirtyComps) + "," + l.Id().String()
+	if got := wsReadText(t, br); got != want {
+		t.Errorf("Expected: %q, got: %q", want, got)
+	}
+}
+
+func TestWebSocketCookies(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	b := NewButton("Go")
+	b.AddEHandlerFunc(func(e Event) {
+		e.SetCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	}, ETypeClick)
+	win.Add(b)
+	s.AddWin(win)
+
+	ts := httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	defer ts.Close()
+
+	conn, br, _ := wsDial(t, ts, "/guitest/main/"+pathWebSocket, "")
+	defer conn.Close()
+
+	wsWriteText(t, conn, clickParams(b).Encode())
+	got := wsReadText(t, br)
+	if !strings.HasPrefix(got, "ck:") || !strings.Contains(got, "\n") {
+		t.Fatalf("Cookie token not sent: %q", got)
+	}
+	token := got[3:strings.IndexByte(got, '\n')]
+
+	resp, err := http.Get(ts.URL + "/guitest/" + pathWsCookies + "?" + paramWsCookies + "=" + token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if c := resp.Header.Get("Set-Cookie"); !strings.HasPrefix(c, "theme=dark") {
+		t.Errorf("Cookie not set: %q", c)
+	}
+
+	// Cookies can be fetched only once
+	if len(s.wsCookies.take(token)) != 0 {
+		t.Errorf("Cookies not removed")
+	}
+}
+
+func TestWebSocketCrossOrigin(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	s.AddWin(NewWindow("main", "Test"))
+
+	ts := httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	defer ts.Close()
+
+	conn, _, resp := wsDial(t, ts, "/guitest/main/"+pathWebSocket, "http://evil.example.com")
+	defer conn.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected: %d, got: %d", http.StatusForbidden, resp.StatusCode)
+	}
+}
+
+func TestStaticJsWebSocket(t *testing.T) {
+	checkStaticJsFuncs(t, "wsOpen", "wsProcMsg", "procEresp")
+}
+
+func TestShutdownWebSocket(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	win.SetWebSocketEnabled(true)
+	s.AddWin(win)
+	var calls int
+	s.OnShutdown(func(sess Session) { calls++ })
+
+	ts := httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	defer ts.Close()
+
+	conn, br, resp := wsDial(t, ts, "/guitest/main/"+pathWebSocket, "")
+	defer conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Unexpected handshake response: %v", resp)
+	}
+
+	// Session locks are waited for at most until the context is done
+	s.rwMutex().Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Got error: %v", err)
+	}
+	s.rwMutex().Unlock()
+	if calls != 0 {
+		t.Errorf("Shutdown funcs of locked session called")
+	}
+
+	// WebSocket connections are closed
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := br.ReadByte(); err != io.EOF {
+		t.Errorf("WebSocket not closed: %v", err)
+	}
+	s.wsConnsMu.Lock()
+	if len(s.wsConns) != 0 {
+		t.Errorf("Got %d WebSocket connections", len(s.wsConns))
+	}
+	s.wsConnsMu.Unlock()
+
+	// No new WebSocket connections after shutdown
+	conn2, br2, _ := wsDial(t, ts, "/guitest/main/"+pathWebSocket, "")
+	defer conn2.Close()
+	conn2.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := br2.ReadByte(); err != io.EOF {
+		t.Errorf("WebSocket accepted after shutdown: %v", err)
+	}
+}
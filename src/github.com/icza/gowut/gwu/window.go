@@ -17,6 +17,16 @@
 
 package gwu
 
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
 // The Window interface is the top of the component hierarchy.
 // A Window defines the content seen in the browser window.
 // Multiple windows can be created, but only one is visible
@@ -58,8 +68,171 @@ type Window interface {
 	// If an empty string is set, the server's theme will be used.
 	SetTheme(theme string)
 
+	// PrintStaticInputs tells if input components of the window
+	// are printed as static text.
+	PrintStaticInputs() bool
+
+	// SetPrintStaticInputs sets whether input components of the window
+	// are printed as static text (instead of the input controls).
+	// If enabled, input components (e.g. TextBox, ListBox, TimePicker)
+	// also render their values wrapped in a span which is only visible
+	// when printing (in the print media), and the input controls are hidden
+	// when printing. Password boxes are excluded.
+	//
+	// This is implemented by the "data-gwuprintstatic" attribute of the window
+	// (used by the print CSS), so do not remove it with SetAttr().
+	// Changing this setting affects already rendered components only
+	// after they are re-rendered (or the window is reloaded).
+	SetPrintStaticInputs(printStatic bool)
+
+	// BaseHref returns the base URL of the window document.
+	// Empty string is returned if no base URL is set.
+	BaseHref() string
+
+	// SetBaseHref sets the base URL of the window document,
+	// rendered as a <base href="..."> tag in the HTML head.
+	// Pass an empty string to not render a base tag.
+	//
+	// This is useful if the app is served under a sub-path behind
+	// a reverse proxy. If a base URL is set, the URLs of Gowut
+	// (static resources, window and event paths) are rendered relative
+	// to it (the leading slash of the app path is dropped), so the
+	// base URL must denote the (proxied) location of the server root "/".
+	// E.g. if the app path is "/guitest/" and the proxy serves the server
+	// under "https://example.com/tools/", the base URL must be
+	// "https://example.com/tools/".
+	SetBaseHref(url string)
+
+	// SkipLink returns the target component and the label of the skip link.
+	// nil target is returned if no skip link is set.
+	SkipLink() (target Comp, label string)
+
+	// SetSkipLink sets a "skip to main content" link, which is rendered
+	// as the first element of the document body. The link is visually hidden
+	// until it receives the keyboard focus, and activating it moves the focus
+	// to the target component (which should be the main content of the window).
+	// Pass nil target to remove the skip link.
+	//
+	// Default style class of the link: "gwu-SkipLink"
+	SetSkipLink(target Comp, label string)
+
+	// IdleTimeout returns the idle timeout of the window.
+	IdleTimeout() time.Duration
+
+	// SetIdleTimeout sets the idle timeout of the window, and adds the
+	// specified handler for the ETypeWinIdle event type (if not nil).
+	// An ETypeWinIdle event is generated if there is no user activity
+	// (mouse, keyboard, touch or scroll) in the browser for the idle timeout.
+	// After that the next user activity restarts the countdown.
+	// Useful for auto-logout or screensaver behaviors.
+	// Pass 0 timeout to disable idle events.
+	//
+	// Note that the idle timer is set up when the window is loaded,
+	// so changing the idle timeout requires the window to be reloaded.
+	SetIdleTimeout(timeout time.Duration, handler EventHandler)
+
+	// AddResourceHint adds a resource hint, rendered as a
+	// <link rel="rel" href="href" as="as"> tag in the HTML head
+	// (before the stylesheet and the script of Gowut).
+	// rel is typically "preload", "prefetch" or "preconnect",
+	// as is the type of the resource (e.g. "font", "style", "script",
+	// "image"), it is not rendered if empty.
+	// Font preloads are rendered with the crossorigin attribute
+	// (as required by browsers).
+	AddResourceHint(rel, href, as string)
+
+	// PushEnabled tells if the window receives updates pushed by the server.
+	PushEnabled() bool
+
+	// SetPushEnabled sets whether the window receives updates pushed
+	// by the server (see Server.Broadcast()). If enabled, the browser
+	// keeps a long-polling request open to the server to receive the
+	// ids of the components to re-render (or receives them over the
+	// WebSocket of the window, see SetWebSocketEnabled()).
+	// If the same window is opened multiple times in the same session
+	// (e.g. in multiple browser tabs), all of them receive pushed updates.
+	// Changing this setting requires the window to be reloaded.
+	SetPushEnabled(enabled bool)
+
+	// WebSocketEnabled tells if the window sends events over a WebSocket.
+	WebSocketEnabled() bool
+
+	// SetWebSocketEnabled sets whether the window sends events over a
+	// WebSocket (instead of a new HTTP request per event). The event
+	// payload and the responses are the same as with HTTP requests;
+	// if the browser does not support WebSockets or the connection is
+	// lost, events are sent via HTTP requests.
+	// Note that the WebSocket is bound to the session it is opened in,
+	// so if an event handler changes the session (e.g. logs in the
+	// user), the window should be reloaded.
+	// Changing this setting requires the window to be reloaded.
+	SetWebSocketEnabled(enabled bool)
+
+	// LoadScript requests the browser to load the JavaScript file from the
+	// specified URL (after processing the current event), e.g. to load a
+	// heavy optional library only when it is used. Must be called from an
+	// event handler of a component of the window.
+	//
+	// When the script is loaded, an ETypeScriptLoaded event is sent
+	// to the window, and onloaded is called (if not nil) with this event.
+	//
+	// Dynamically loaded scripts are not part of the window document,
+	// they have to be loaded again after the window is reloaded.
+	LoadScript(url string, onloaded func(e Event))
+
+	// AddKeyShortcut adds a window-wide keyboard shortcut: the handler is
+	// called when the specified key is pressed with the modifier keys matching
+	// the specified modifier key mask exactly, regardless of which component
+	// (if any) has the focus. The default action of the key combination is
+	// prevented at the client.
+	//
+	// If multiple shortcuts are registered for the same key combination,
+	// all of them are called in the order they were added. If a component
+	// registered the same combination with Comp.AddKeyHandler() and it has
+	// the focus, its handler is called first (in a separate event), and then
+	// the window shortcuts.
+	// Changing shortcuts requires the window to be re-rendered.
+	AddKeyShortcut(keyCode Key, modKeys ModKey, handler func(e Event))
+
+	// StateJSON returns the user-visible state of the input components of the
+	// window (texts of text boxes, states of check boxes, selections of list
+	// boxes etc.) as JSON, e.g. to be stored at the client (in localStorage)
+	// and restored later by RestoreState().
+	//
+	// Components are identified by their position in the component tree,
+	// so the state can also be restored into a rebuilt window of the same
+	// structure. The content of password boxes is not included.
+	StateJSON() string
+
+	// RestoreState restores the state of the input components of the window
+	// from JSON returned by StateJSON().
+	// Entries which do not match a component (of the same kind) are ignored.
+	// An error is returned only if stateJSON is not a valid JSON object.
+	//
+	// Restored components are not marked dirty, the window has to be
+	// re-rendered (e.g. by Event.RerenderWindow()) to display the restored state.
+	RestoreState(stateJSON string) error
+
 	// RenderWin renders the window as a complete HTML document.
 	RenderWin(w Writer, s Server)
+
+	// push adds components to be re-rendered at the clients,
+	// and notifies all the waiting push requests.
+	push(comps map[ID]Comp)
+
+	// waitPush waits for components pushed after the push sequence number since,
+	// for at most the specified timeout or until the context is done.
+	// Returns the ids of the pushed components (empty if there are none)
+	// and the current push sequence number.
+	waitPush(ctx context.Context, since int, timeout time.Duration) (ids []ID, seq int)
+
+	// takeScripts returns (and clears) the URLs of the scripts
+	// requested to be loaded by LoadScript().
+	takeScripts() []string
+
+	// renders returns the cache of the last renders of the components
+	// of the window (used by diff rendering).
+	renders() *renderCache
 }
 
 // WinSlice is a slice of windows which implements sort.Interface so it
@@ -83,16 +256,53 @@ type windowImpl struct {
 	panelImpl   // Panel implementation
 	hasTextImpl // Has text implementation
 
-	name          string   // Window name
-	heads         []string // Additional head HTML texts
-	focusedCompId ID       // Id of the last reported focused component
-	theme         string   // CSS theme of the window
+	name          string        // Window name
+	heads         []string      // Additional head HTML texts
+	focusedCompId ID            // Id of the last reported focused component
+	theme         string        // CSS theme of the window
+	baseHref      string        // Base URL of the window document
+	skipTarget    Comp          // Target component of the skip link
+	skipLabel     string        // Label of the skip link
+	idleTimeout   time.Duration // Idle timeout
+	resHints      []resHint     // Resource hints
+
+	scripts      []string                 // URLs of the scripts to be loaded
+	scriptLoaded map[string]func(e Event) // Functions to call when the scripts are loaded, mapped from URL
+
+	pushEnabled bool          // Tells if the window receives pushed updates
+	pushMu      sync.Mutex    // Mutex to synchronize access to the pushes
+	pushSeq     int           // Sequence number of the last push
+	pushes      []pushEntry   // Recent pushes, oldest first
+	pushNotify  chan struct{} // Closed (and replaced) on push to notify all waiting push requests
+
+	wsEnabled bool // Tells if the window sends events over a WebSocket
+
+	shortcuts  []keyEHandler // Window-wide keyboard shortcuts
+	shortcutEv bool          // Tells if the event being processed is a shortcut event
+
+	renders_ renderCache // Last renders of components (used by diff rendering)
+}
+
+// pushEntry is a push of components to be re-rendered at the clients.
+type pushEntry struct {
+	seq int  // Push sequence number
+	ids []ID // Ids of the pushed components
+}
+
+// maxPushes is the max number of recent pushes kept by a window. Clients which
+// missed more pushes than this (e.g. a slow network) re-render the whole window.
+const maxPushes = 64
+
+// resHint describes a resource hint (a link tag in the HTML head).
+type resHint struct {
+	rel, href, as string // Attributes of the link tag
 }
 
 // NewWindow creates a new window.
 // The default layout strategy is LayoutVertical.
 func NewWindow(name, text string) Window {
-	c := &windowImpl{panelImpl: newPanelImpl(), hasTextImpl: newHasTextImpl(text), name: name}
+	c := &windowImpl{panelImpl: newPanelImpl(), hasTextImpl: newHasTextImpl(text), name: name,
+		pushNotify: make(chan struct{})}
 	c.Style().AddClass("gwu-Window")
 	return c
 }
@@ -120,6 +330,146 @@ func (w *windowImpl) RemoveHeadHtml(html string) {
 	}
 }
 
+func (w *windowImpl) AddResourceHint(rel, href, as string) {
+	w.resHints = append(w.resHints, resHint{rel: rel, href: href, as: as})
+}
+
+func (w *windowImpl) PushEnabled() bool {
+	return w.pushEnabled
+}
+
+func (w *windowImpl) SetPushEnabled(enabled bool) {
+	w.pushEnabled = enabled
+}
+
+func (w *windowImpl) WebSocketEnabled() bool {
+	return w.wsEnabled
+}
+
+func (w *windowImpl) SetWebSocketEnabled(enabled bool) {
+	w.wsEnabled = enabled
+}
+
+func (w *windowImpl) ById(id ID) Comp {
+	// Return the window itself (and not the embedded panel) so events
+	// targeting the window are preprocessed by the window.
+	if w.id == id {
+		return w
+	}
+	return w.panelImpl.ById(id)
+}
+
+func (w *windowImpl) LoadScript(url string, onloaded func(e Event)) {
+	w.scripts = append(w.scripts, url)
+	if onloaded != nil {
+		if w.scriptLoaded == nil {
+			w.scriptLoaded = make(map[string]func(e Event))
+		}
+		w.scriptLoaded[url] = onloaded
+	}
+}
+
+func (w *windowImpl) takeScripts() []string {
+	scripts := w.scripts
+	w.scripts = nil
+	return scripts
+}
+
+func (w *windowImpl) AddKeyShortcut(keyCode Key, modKeys ModKey, handler func(e Event)) {
+	w.shortcuts = append(w.shortcuts, keyEHandler{keyCode: keyCode, modKeys: modKeys, handler: handlerFuncWrapper{handler}})
+}
+
+func (w *windowImpl) preprocessEvent(event Event, r *http.Request) {
+	switch event.Type() {
+	case ETypeKeyDown:
+		w.shortcutEv = r.FormValue(paramCompValue) == keyShortcutValue
+		return
+	case ETypeScriptLoaded:
+	default:
+		return
+	}
+	url := r.FormValue(paramCompValue)
+	if onloaded := w.scriptLoaded[url]; onloaded != nil {
+		delete(w.scriptLoaded, url)
+		onloaded(event)
+	}
+}
+
+// keyShortcutValue is the component value sent with window shortcut key events.
+const keyShortcutValue = "ks"
+
+func (w *windowImpl) dispatchEvent(e Event) {
+	if !w.shortcutEv {
+		w.panelImpl.dispatchEvent(e)
+		return
+	}
+	w.shortcutEv = false
+	for _, kh := range w.shortcuts {
+		kh.HandleEvent(e)
+	}
+}
+
+func (w *windowImpl) renders() *renderCache {
+	return &w.renders_
+}
+
+func (w *windowImpl) push(comps map[ID]Comp) {
+	ids := make([]ID, 0, len(comps))
+	for id := range comps {
+		ids = append(ids, id)
+	}
+
+	w.pushMu.Lock()
+	defer w.pushMu.Unlock()
+	w.pushSeq++
+	w.pushes = append(w.pushes, pushEntry{seq: w.pushSeq, ids: ids})
+	if len(w.pushes) > maxPushes {
+		w.pushes = append(w.pushes[:0], w.pushes[len(w.pushes)-maxPushes:]...)
+	}
+	// Notify all waiting push requests (of all browser tabs)
+	close(w.pushNotify)
+	w.pushNotify = make(chan struct{})
+}
+
+func (w *windowImpl) waitPush(ctx context.Context, since int, timeout time.Duration) (ids []ID, seq int) {
+	w.pushMu.Lock()
+	if since == w.pushSeq {
+		notify := w.pushNotify
+		w.pushMu.Unlock()
+
+		timer := time.NewTimer(timeout)
+		select {
+		case <-notify:
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		timer.Stop()
+		w.pushMu.Lock()
+	}
+	defer w.pushMu.Unlock()
+
+	if since == w.pushSeq {
+		return nil, since
+	}
+	if since > w.pushSeq || len(w.pushes) == 0 || since < w.pushes[0].seq-1 {
+		// Unknown sequence number or missed pushes: re-render the whole window
+		return []ID{w.id}, w.pushSeq
+	}
+	added := make(map[ID]bool)
+	for _, p := range w.pushes {
+		if p.seq <= since {
+			continue
+		}
+		for _, id := range p.ids {
+			if !added[id] {
+				added[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, w.pushSeq
+}
+
 func (w *windowImpl) SetFocusedCompId(id ID) {
 	w.focusedCompId = id
 }
@@ -132,6 +482,135 @@ func (s *windowImpl) SetTheme(theme string) {
 	s.theme = theme
 }
 
+// Attribute of the window marking that print-static inputs are enabled.
+const attrPrintStatic = "data-gwuprintstatic"
+
+func (w *windowImpl) PrintStaticInputs() bool {
+	return w.Attr(attrPrintStatic) != ""
+}
+
+func (w *windowImpl) SetPrintStaticInputs(printStatic bool) {
+	if printStatic {
+		w.SetAttr(attrPrintStatic, "1")
+	} else {
+		w.SetAttr(attrPrintStatic, "")
+	}
+}
+
+func (w *windowImpl) BaseHref() string {
+	return w.baseHref
+}
+
+func (w *windowImpl) SetBaseHref(url string) {
+	w.baseHref = url
+}
+
+func (w *windowImpl) SkipLink() (target Comp, label string) {
+	return w.skipTarget, w.skipLabel
+}
+
+func (w *windowImpl) SetSkipLink(target Comp, label string) {
+	w.skipTarget, w.skipLabel = target, label
+}
+
+// renderSkipLink renders the skip link if one is set.
+func (win *windowImpl) renderSkipLink(w Writer) {
+	if win.skipTarget == nil {
+		return
+	}
+	// To render: <a class="gwu-SkipLink" href="#<id>" onclick="return skipTo('<id>')">label</a>
+	// The href is only a fallback, skipTo() also moves the focus (and works with a base URL set).
+	id := win.skipTarget.Id().String()
+	w.Writess(`<a class="gwu-SkipLink" href="#`, id, `" onclick="return skipTo('`, id, `')">`)
+	w.Writees(win.skipLabel)
+	w.Writes("</a>")
+}
+
+func (w *windowImpl) IdleTimeout() time.Duration {
+	return w.idleTimeout
+}
+
+func (w *windowImpl) SetIdleTimeout(timeout time.Duration, handler EventHandler) {
+	if timeout < 0 {
+		timeout = 0
+	}
+	w.idleTimeout = timeout
+	if handler != nil {
+		w.AddEHandler(handler, ETypeWinIdle)
+	}
+}
+
+// stateHolder is implemented by the components whose user-visible state
+// is included in the window state (see Window.StateJSON()).
+type stateHolder interface {
+	// saveState returns the state of the component to be marshaled to JSON.
+	// nil is returned if the component has no state to save.
+	saveState() interface{}
+
+	// restoreState restores the state of the component from its JSON form.
+	// Invalid data (e.g. the state of another kind of component) is ignored.
+	restoreState(data json.RawMessage)
+}
+
+// statePaths maps the components having state in the component tree
+// of c2 to their paths. A path consists of the indices of the components
+// (as returned by Container.children()) from the window, separated by dots.
+func statePaths(c2 Container, prefix string, m map[string]stateHolder) {
+	for i, c := range c2.children() {
+		path := prefix + strconv.Itoa(i)
+		if sh, ok := c.(stateHolder); ok {
+			m[path] = sh
+		}
+		if c3, ok := c.(Container); ok {
+			statePaths(c3, path+".", m)
+		}
+	}
+}
+
+func (win *windowImpl) StateJSON() string {
+	holders := map[string]stateHolder{}
+	statePaths(win, "", holders)
+
+	state := make(map[string]interface{}, len(holders))
+	for path, sh := range holders {
+		if s := sh.saveState(); s != nil {
+			state[path] = s
+		}
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil { // Cannot happen as states are basic types
+		return "{}"
+	}
+	return string(data)
+}
+
+func (win *windowImpl) RestoreState(stateJSON string) error {
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(stateJSON), &state); err != nil {
+		return err
+	}
+
+	holders := map[string]stateHolder{}
+	statePaths(win, "", holders)
+
+	for path, data := range state {
+		if sh := holders[path]; sh != nil {
+			sh.restoreState(data)
+		}
+	}
+	return nil
+}
+
+// appPath returns the app path to be used in the URLs rendered into the window document.
+// If a base URL is set, the app path is returned relative to it.
+func (win *windowImpl) appPath(s Server) string {
+	if win.baseHref != "" {
+		return strings.TrimPrefix(s.AppPath(), "/")
+	}
+	return s.AppPath()
+}
+
 func (c *windowImpl) Render(w Writer) {
 	// Attaching window events is outside of the HTML tag denoted by the window's id.
 	// This means if the window is re-rendered (not reloaded), changed window event handlers
@@ -141,17 +620,41 @@ func (c *windowImpl) Render(w Writer) {
 	// First render window event handlers as window functions.
 	found := false
 	for etype, _ := range c.handlers {
-		if etype.Category() != ECatWindow {
+		etypeFunc := etypeFuncs[etype]
+		if etype.Category() != ECatWindow || etypeFunc == nil {
 			continue
 		}
 
 		if !found {
 			found = true
-			w.Write(strScriptOp)
+			writeScriptOp(w)
 		}
 		// To render       : add<etypeFunc>(function(){se(null,etype,id);});
 		// Example (onload): addonload(function(){se(null,13,4327);});
-		w.Writevs("add", etypeFuncs[etype], "(function(){se(null,", int(etype), ",", int(c.id), ");});")
+		w.Writevs("add", etypeFunc, "(function(){se(null,", int(etype), ",", int(c.id), ");});")
+	}
+	if c.idleTimeout > 0 && len(c.handlers[ETypeWinIdle]) > 0 {
+		if !found {
+			found = true
+			writeScriptOp(w)
+		}
+		// To render: setupIdle(id,function(){se(null,etype,id);},timeoutMs);
+		w.Writevs("setupIdle(", int(c.id), `,function(){se(null,`, int(ETypeWinIdle), ",", int(c.id), `);},`, int(c.idleTimeout/time.Millisecond), ");")
+	}
+	if len(c.shortcuts) > 0 {
+		if !found {
+			found = true
+			writeScriptOp(w)
+		}
+		// To render: addKeyShortcuts(id,etype,[keyCode,modKeys,...]);
+		w.Writevs("addKeyShortcuts(", int(c.id), strComma, int(ETypeKeyDown), ",[")
+		for i, kh := range c.shortcuts {
+			if i > 0 {
+				w.Write(strComma)
+			}
+			w.Writevs(int(kh.keyCode), strComma, int(kh.modKeys))
+		}
+		w.Writes("]);")
 	}
 	if found {
 		w.Write(strScriptCl)
@@ -164,9 +667,18 @@ func (c *windowImpl) Render(w Writer) {
 func (win *windowImpl) RenderWin(w Writer, s Server) {
 	// We could optimize this (store byte slices of static strings)
 	// but windows are rendered "so rarely"...
-	w.Writes(`<html><head><meta http-equiv="content-type" content="text/html; charset=UTF-8"><title>`)
+	w.Writes(`<html><head><meta http-equiv="content-type" content="text/html; charset=UTF-8">`)
+	if win.baseHref != "" {
+		// Base tag must precede all elements having URLs.
+		w.Writes(`<base href="`)
+		w.Writees(win.baseHref)
+		w.Writes(`">`)
+	}
+	w.Writes("<title>")
 	w.Writees(win.text)
-	w.Writess(`</title><link href="`, s.AppPath(), pathStatic)
+	w.Writes("</title>")
+	win.renderResHints(w)
+	w.Writess(`<link href="`, win.appPath(s), pathStatic)
 	if win.theme == "" {
 		w.Writes(resNameStaticCss(s.Theme()))
 	} else {
@@ -174,23 +686,77 @@ func (win *windowImpl) RenderWin(w Writer, s Server) {
 	}
 	w.Writes(`" rel="stylesheet" type="text/css">`)
 	win.renderDynJs(w, s)
-	w.Writess(`<script src="`, s.AppPath(), pathStatic, resNameStaticJs, `"></script>`)
+	w.Writess(`<script src="`, win.appPath(s), pathStatic, resNameStaticJs, `"`)
+	if nonce := writerNonce(w); nonce != "" {
+		w.WriteAttr("nonce", nonce)
+	}
+	w.Writes("></script>")
 	w.Writess(win.heads...)
 	w.Writes("</head><body>")
 
+	win.renderSkipLink(w)
+
 	win.Render(w)
 
 	w.Writes("</body></html>")
 }
 
+// renderResHints renders the resource hints.
+func (win *windowImpl) renderResHints(w Writer) {
+	for _, h := range win.resHints {
+		// To render: <link rel="preload" href="/fonts/x.woff2" as="font" crossorigin>
+		w.Writes("<link")
+		writeEscAttr(w, "rel", h.rel)
+		writeEscAttr(w, "href", h.href)
+		if h.as != "" {
+			writeEscAttr(w, "as", h.as)
+			if h.as == "font" {
+				w.Writes(" crossorigin")
+			}
+		}
+		w.Writes(">")
+	}
+}
+
 // renderDynJs renders the dynamic JavaScript codes of Gowut.
 func (win *windowImpl) renderDynJs(w Writer, s Server) {
-	w.Write(strScriptOp)
-	w.Writess("var _pathApp='", s.AppPath(), "';")
+	writeScriptOp(w)
+	w.Writess("var _pathApp='", win.appPath(s), "';")
 	w.Writess("var _pathSessCheck=_pathApp+'", pathSessCheck, "';")
-	w.Writess("var _pathWin='", s.AppPath(), win.name, "/';")
+	w.Writess("var _pathWin='", win.appPath(s), win.name, "/';")
 	w.Writess("var _pathEvent=_pathWin+'", pathEvent, "';")
 	w.Writess("var _pathRenderComp=_pathWin+'", pathRenderComp, "';")
+	w.Writess("var _pathRenderComps=_pathWin+'", pathRenderComps, "';")
 	w.Writess("var _focCompId='", win.focusedCompId.String(), "';")
+	unknownResp := s.UnknownRespMode()
+	if unknownResp == UnknownRespAuto {
+		if s.DevMode() {
+			unknownResp = UnknownRespAlert
+		} else {
+			unknownResp = UnknownRespLog
+		}
+	}
+	w.Writevs("var _unknownResp=", int(unknownResp), ";")
+	w.Writess("var _pathPush=_pathWin+'", pathPush, "';")
+	w.Writevs("var _autoReconnect=", s.AutoReconnect(), ";")
+	retries, timeout := s.EventRetry()
+	w.Writevs("var _seRetries=", retries, ",_seTimeout=", int(timeout/time.Millisecond), ";")
+	if win.pushEnabled {
+		win.pushMu.Lock()
+		w.Writevs("var _pushSeq=", win.pushSeq, ";")
+		win.pushMu.Unlock()
+		w.Writes("window.addEventListener('load',function(){pollPush();});")
+	}
+	w.Writess("var _pathWs=_pathWin+'", pathWebSocket, "';")
+	w.Writess("var _pathWsCookies=_pathApp+'", pathWsCookies, "';")
+	if win.wsEnabled {
+		w.Writes("window.addEventListener('load',function(){wsOpen();});")
+	}
+	if js := s.ClientEventInterceptor(); js != "" {
+		w.Writess("var _seInterceptor=", js, ";")
+	}
+	if js := s.ClientEventErrorHandler(); js != "" {
+		w.Writess("var _seErrHandler=", js, ";")
+	}
 	w.Write(strScriptCl)
 }
@@ -0,0 +1,337 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"bytes"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// renderWinString renders the window as a complete HTML document
+// and returns the result as a string.
+func renderWinString(win Window, s Server) string {
+	b := &bytes.Buffer{}
+	win.RenderWin(NewWriter(b), s)
+	return b.String()
+}
+
+func TestWindowBaseHref(t *testing.T) {
+	s := NewServer("guitest", "")
+	win := NewWindow("main", "Test")
+
+	doc := renderWinString(win, s)
+	if strings.Contains(doc, "<base") {
+		t.Errorf("Base tag rendered when not set: %s", doc)
+	}
+
+	win.SetBaseHref("https://example.com/tools/")
+	doc = renderWinString(win, s)
+	if !strings.Contains(doc, `<head><meta http-equiv="content-type" content="text/html; charset=UTF-8"><base href="https://example.com/tools/"><title>`) {
+		t.Errorf("Base tag not rendered properly: %s", doc)
+	}
+	if !strings.Contains(doc, "var _pathApp='guitest/';") {
+		t.Errorf("App path not rendered relative to base: %s", doc)
+	}
+}
+
+func TestWindowSkipLink(t *testing.T) {
+	s := NewServer("guitest", "")
+	win := NewWindow("main", "Test")
+	content := NewPanel()
+	win.Add(content)
+
+	if doc := renderWinString(win, s); strings.Contains(doc, "gwu-SkipLink") {
+		t.Errorf("Skip link rendered when not set: %s", doc)
+	}
+
+	win.SetSkipLink(content, "Skip to <content>")
+	id := content.Id().String()
+	want := `<body><a class="gwu-SkipLink" href="#` + id + `" onclick="return skipTo('` + id + `')">Skip to &lt;content&gt;</a>`
+	if doc := renderWinString(win, s); !strings.Contains(doc, want) {
+		t.Errorf("Skip link not rendered properly: %s", doc)
+	}
+}
+
+func TestWindowIdleTimeout(t *testing.T) {
+	win := NewWindow("main", "Test")
+	if s := renderString(win); strings.Contains(s, "setupIdle") {
+		t.Errorf("Idle timer rendered when not set: %s", s)
+	}
+
+	win.SetIdleTimeout(90*time.Second, EmptyEHandler)
+	id := win.Id().String()
+	want := `setupIdle(` + id + `,function(){se(null,` + ETypeWinIdle.String() + `,` + id + `);},90000);`
+	if s := renderString(win); !strings.Contains(s, want) {
+		t.Errorf("Rendered window %s does not contain %s", s, want)
+	}
+	if win.IdleTimeout() != 90*time.Second {
+		t.Errorf("Got idle timeout %v, want %v", win.IdleTimeout(), 90*time.Second)
+	}
+
+	win.SetIdleTimeout(0, nil)
+	if s := renderString(win); strings.Contains(s, "setupIdle") {
+		t.Errorf("Idle timer rendered when disabled: %s", s)
+	}
+}
+
+func TestWindowUnknownRespMode(t *testing.T) {
+	s := NewServer("guitest", "")
+	win := NewWindow("main", "Test")
+
+	if mode := s.UnknownRespMode(); mode != UnknownRespAuto || s.DevMode() {
+		t.Errorf("Got default mode %d, dev mode: %v", mode, s.DevMode())
+	}
+	// Auto mode logs in production and alerts in dev mode
+	for devMode, mode := range map[bool]UnknownRespMode{false: UnknownRespLog, true: UnknownRespAlert} {
+		s.SetDevMode(devMode)
+		want := "var _unknownResp=" + strconv.Itoa(int(mode)) + ";"
+		if doc := renderWinString(win, s); !strings.Contains(doc, want) {
+			t.Errorf("Dynamic JS does not contain %s (dev mode: %v): %s", want, devMode, doc)
+		}
+	}
+	for _, mode := range []UnknownRespMode{UnknownRespAlert, UnknownRespLog, UnknownRespIgnore} {
+		s.SetUnknownRespMode(mode)
+		want := "var _unknownResp=" + strconv.Itoa(int(mode)) + ";"
+		if doc := renderWinString(win, s); !strings.Contains(doc, want) {
+			t.Errorf("Dynamic JS does not contain %s: %s", want, doc)
+		}
+	}
+}
+
+func TestWindowAutoReconnect(t *testing.T) {
+	s := NewServer("guitest", "")
+	win := NewWindow("main", "Test")
+
+	if doc := renderWinString(win, s); !strings.Contains(doc, "var _autoReconnect=false;") {
+		t.Errorf("Auto reconnect not disabled by default: %s", doc)
+	}
+	s.SetAutoReconnect(true)
+	if doc := renderWinString(win, s); !s.AutoReconnect() || !strings.Contains(doc, "var _autoReconnect=true;") {
+		t.Errorf("Auto reconnect not enabled: %s", doc)
+	}
+	checkStaticJsFuncs(t, "reconnect")
+}
+
+func TestWindowResourceHints(t *testing.T) {
+	s := NewServer("guitest", "")
+	win := NewWindow("main", "Test")
+	win.AddResourceHint("preload", "/fonts/a.woff2", "font")
+	win.AddResourceHint("prefetch", "/guitest/_gwu_static/gwu-0001.js", "script")
+	win.AddResourceHint("preconnect", "https://cdn.example.com/?a=1&b=2", "")
+
+	doc := renderWinString(win, s)
+	want := `</title>` +
+		`<link rel="preload" href="/fonts/a.woff2" as="font" crossorigin>` +
+		`<link rel="prefetch" href="/guitest/_gwu_static/gwu-0001.js" as="script">` +
+		`<link rel="preconnect" href="https://cdn.example.com/?a=1&amp;b=2">` +
+		`<link href="/guitest/_gwu_static/`
+	if !strings.Contains(doc, want) {
+		t.Errorf("Document does not contain %s: %s", want, doc)
+	}
+}
+
+func TestWindowClientEventInterceptor(t *testing.T) {
+	s := NewServer("guitest", "")
+	win := NewWindow("main", "Test")
+
+	if doc := renderWinString(win, s); strings.Contains(doc, "_seInterceptor") {
+		t.Errorf("Interceptor rendered without being set: %s", doc)
+	}
+
+	js := "function(event, etype, compId, compValue) { return etype != 1; }"
+	s.SetClientEventInterceptor(js)
+	if s.ClientEventInterceptor() != js {
+		t.Errorf("Got interceptor %q", s.ClientEventInterceptor())
+	}
+	want := "var _seInterceptor=" + js + ";"
+	if doc := renderWinString(win, s); !strings.Contains(doc, want) {
+		t.Errorf("Dynamic JS does not contain %s: %s", want, doc)
+	}
+}
+
+func TestWindowEventRetry(t *testing.T) {
+	s := NewServer("guitest", "")
+	win := NewWindow("main", "Test")
+
+	if retries, timeout := s.EventRetry(); retries != 1 || timeout != 0 {
+		t.Errorf("Got default retry %d, %v", retries, timeout)
+	}
+	if doc := renderWinString(win, s); !strings.Contains(doc, "var _seRetries=1,_seTimeout=0;") || strings.Contains(doc, "_seErrHandler") {
+		t.Errorf("Dynamic JS does not contain the default retry: %s", doc)
+	}
+
+	s.SetEventRetry(3, 10*time.Second)
+	js := "function(etype, compId, status) { showBanner(); }"
+	s.SetClientEventErrorHandler(js)
+	if s.ClientEventErrorHandler() != js {
+		t.Errorf("Got error handler %q", s.ClientEventErrorHandler())
+	}
+	doc := renderWinString(win, s)
+	for _, want := range []string{"var _seRetries=3,_seTimeout=10000;", "var _seErrHandler=" + js + ";"} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("Dynamic JS does not contain %s: %s", want, doc)
+		}
+	}
+	checkStaticJsFuncs(t, "seSend", "seFailed")
+}
+
+func TestStaticJsBusy(t *testing.T) {
+	checkStaticJsFuncs(t, "seBusy")
+	if !strings.Contains(string(staticCss[resNameStaticCss(ThemeDefault)]), ".gwu-Busy {") {
+		t.Errorf("CSS does not contain gwu-Busy")
+	}
+}
+
+func TestWindowStateJSON(t *testing.T) {
+	type form struct {
+		win   Window
+		name  TextBox
+		passw TextBox
+		agree CheckBox
+		lang  ListBox
+		count NumberSpinner
+		phone MaskedInput
+	}
+	newForm := func() *form {
+		f := &form{win: NewWindow("form", "Form"), name: NewTextBox(""), passw: NewPasswBox(""),
+			agree: NewCheckBox("Agree"), lang: NewListBox([]string{"en", "hu", "de"}),
+			count: NewNumberSpinner(1, 0, 10), phone: NewMaskedInput("(999) 999-9999")}
+		p := NewPanel()
+		p.Add(f.name)
+		p.Add(f.passw)
+		t := NewTable()
+		t.Add(f.agree, 0, 0)
+		t.Add(f.lang, 1, 1)
+		p.Add(t)
+		f.win.Add(p)
+		f.win.Add(f.count)
+		f.win.Add(f.phone)
+		return f
+	}
+
+	f := newForm()
+	f.name.SetText(`Bob "B"`)
+	f.passw.SetText("secret")
+	f.agree.SetState(true)
+	f.lang.SetMulti(true)
+	f.lang.SetSelectedIndices([]int{0, 2})
+	f.count.SetValue(7)
+	f.phone.SetValue("5551234567")
+
+	state := f.win.StateJSON()
+	want := `{"0.0":"Bob \"B\"","0.2.0":true,"0.2.1":[0,2],"1":7,"2":"5551234567"}`
+	if state != want {
+		t.Errorf("Got state %s, want %s", state, want)
+	}
+
+	// Restore into a rebuilt window
+	f2 := newForm()
+	f2.lang.SetMulti(true)
+	if err := f2.win.RestoreState(state); err != nil {
+		t.Fatal(err)
+	}
+	if f2.name.Text() != `Bob "B"` || f2.passw.Text() != "" || !f2.agree.State() ||
+		!reflect.DeepEqual(f2.lang.SelectedIndices(), []int{0, 2}) || f2.count.Value() != 7 ||
+		f2.phone.Value() != "5551234567" {
+		t.Errorf("State not restored: %s", f2.win.StateJSON())
+	}
+
+	// Mismatching entries are ignored
+	if err := f2.win.RestoreState(`{"0.0":true,"1":"x","9":1}`); err != nil {
+		t.Error(err)
+	}
+	if f2.name.Text() != `Bob "B"` || f2.count.Value() != 7 {
+		t.Error("Mismatching entries not ignored")
+	}
+	if err := f2.win.RestoreState("not json"); err == nil {
+		t.Error("No error for invalid JSON")
+	}
+}
+
+func TestWindowRestoreStateValidation(t *testing.T) {
+	win := NewWindow("form", "Form")
+	lb := NewListBox([]string{"a", "b", "c", "d"})
+	lb.SetMulti(true)
+	lb.SetMaxSelected(2)
+	lb.SetOptionDisabled(1, true)
+	tb, ro, dis := NewTextBox("x"), NewTextBox("ro"), NewTextBox("dis")
+	tb.SetMaxLength(3)
+	ro.SetReadOnly(true)
+	dis.SetEnabled(false)
+	win.Add(lb)
+	win.Add(tb)
+	win.Add(ro)
+	win.Add(dis)
+
+	// Disabled option and indices beyond the max selected are dropped
+	if err := win.RestoreState(`{"0":[1,0,2,3,9],"1":"toolong","2":"y","3":"z"}`); err != nil {
+		t.Fatal(err)
+	}
+	if got := lb.SelectedIndices(); !reflect.DeepEqual(got, []int{0, 2}) {
+		t.Errorf("Got selected indices: %v", got)
+	}
+	if tb.Text() != "x" || ro.Text() != "ro" || dis.Text() != "dis" {
+		t.Errorf("Got texts: %q, %q, %q", tb.Text(), ro.Text(), dis.Text())
+	}
+
+	if err := win.RestoreState(`{"1":"abc"}`); err != nil || tb.Text() != "abc" {
+		t.Errorf("Got text: %q, err: %v", tb.Text(), err)
+	}
+}
+
+func TestWindowKeyShortcut(t *testing.T) {
+	s := newServerImpl("guitest", "", "", "")
+	win := NewWindow("main", "Test")
+	var saves, escapes, keyDowns int
+	win.AddKeyShortcut(KeyF2, ModKeyCtrl, func(e Event) { saves++ })
+	win.AddKeyShortcut(KeyEscape, 0, func(e Event) { escapes++ })
+	win.AddEHandlerFunc(func(e Event) { keyDowns++ }, ETypeKeyDown)
+
+	str := renderString(win)
+	want := "addKeyShortcuts(" + win.Id().String() + "," + ETypeKeyDown.String() + ",[" +
+		strconv.Itoa(int(KeyF2)) + "," + strconv.Itoa(int(ModKeyCtrl)) + "," + strconv.Itoa(int(KeyEscape)) + ",0]);"
+	if !strings.Contains(str, want) {
+		t.Errorf("Shortcuts not rendered: %s", str)
+	}
+	checkStaticJsFuncs(t, "addKeyShortcuts")
+
+	params := func(keyCode Key, modKeys ModKey, shortcut bool) url.Values {
+		p := url.Values{paramCompId: {win.Id().String()}, paramEventType: {ETypeKeyDown.String()},
+			paramKeyCode: {strconv.Itoa(int(keyCode))}, paramModKeys: {strconv.Itoa(int(modKeys))}}
+		if shortcut {
+			p.Set(paramCompValue, keyShortcutValue)
+		}
+		return p
+	}
+
+	sendEvent(s, &s.sessionImpl, win, params(KeyF2, ModKeyCtrl, true))
+	sendEvent(s, &s.sessionImpl, win, params(KeyF2, 0, true)) // Modifier keys do not match
+	sendEvent(s, &s.sessionImpl, win, params(KeyEscape, 0, true))
+	if saves != 1 || escapes != 1 || keyDowns != 0 {
+		t.Errorf("Got saves: %d, escapes: %d, key downs: %d", saves, escapes, keyDowns)
+	}
+
+	// Regular key down events of the window are not shortcuts
+	sendEvent(s, &s.sessionImpl, win, params(KeyF2, ModKeyCtrl, false))
+	if saves != 1 || keyDowns != 1 {
+		t.Errorf("Got saves: %d, key downs: %d", saves, keyDowns)
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Wizard component interface and implementation.
+
+package gwu
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// StepValidator validates the current step of a Wizard before advancing.
+// The event is the navigation event, it can be used to mark components
+// dirty (e.g. to display validation errors).
+type StepValidator func(e Event) bool
+
+// Wizard interface defines a container which guides the user through an
+// ordered set of steps (e.g. of a multi-step form). Only the content of
+// the current step is displayed, along with a progress indicator
+// (showing the step titles) and Back / Next / Finish navigation buttons.
+//
+// Advancing to the next step (and finishing) is only allowed if the
+// validator of the current step (if any) accepts the step. Going back
+// is always allowed.
+//
+// Navigating generates an ETypeStateChange event; the Wizard is marked
+// dirty automatically if the current step changes. Register ETypeStateChange
+// event handlers to get notified, and use Finished() to detect finishing.
+//
+// Default style classes: "gwu-Wizard", "gwu-Wizard-Progress",
+// "gwu-Wizard-Step", "gwu-Wizard-Step-Done", "gwu-Wizard-Step-Active",
+// "gwu-Wizard-Content", "gwu-Wizard-Nav", "gwu-Wizard-Button"
+type Wizard interface {
+	// Wizard is a container.
+	Container
+
+	// AddStep adds a new step with the specified title and content,
+	// and an optional validator (pass nil if the step needs no validation).
+	AddStep(title string, content Comp, validator StepValidator)
+
+	// StepCount returns the number of steps.
+	StepCount() int
+
+	// StepContent returns the content of the step specified by its index.
+	StepContent(idx int) Comp
+
+	// Step returns the index of the current step.
+	// -1 is returned if there are no steps.
+	Step() int
+
+	// SetStep sets the current step (without validation).
+	// Invalid indices are ignored.
+	SetStep(idx int)
+
+	// Next advances to the next step if the current step is valid.
+	// On the last step this finishes the wizard.
+	// The event is passed to the step validator.
+	// Returns true if the wizard advanced (or finished).
+	Next(e Event) bool
+
+	// Back goes back to the previous step.
+	// Returns true if the current step changed.
+	Back() bool
+
+	// Finished tells if the wizard has been finished
+	// (Next() succeeded on the last step).
+	// Changing the current step resets the finished state.
+	Finished() bool
+}
+
+// A step of the Wizard.
+type wizardStep struct {
+	title     string        // Title of the step
+	content   Comp          // Content of the step
+	validator StepValidator // Optional validator of the step
+}
+
+// Wizard implementation.
+type wizardImpl struct {
+	compImpl // Component implementation
+
+	steps    []wizardStep // Steps of the wizard
+	step     int          // Index of the current step
+	finished bool         // Tells if the wizard has been finished
+}
+
+// NewWizard creates a new Wizard.
+func NewWizard() Wizard {
+	c := &wizardImpl{compImpl: newCompImpl(nil), step: -1}
+	c.Style().AddClass("gwu-Wizard")
+	return c
+}
+
+func (c *wizardImpl) Remove(c2 Comp) bool {
+	for i, s := range c.steps {
+		if !s.content.Equals(c2) {
+			continue
+		}
+		c2.setParent(nil)
+		c.steps = append(c.steps[:i], c.steps[i+1:]...)
+		// Keep the same step current if possible
+		if i < c.step || c.step == len(c.steps) {
+			c.step--
+		}
+		return true
+	}
+	return false
+}
+
+func (c *wizardImpl) ById(id ID) Comp {
+	if c.id == id {
+		return c
+	}
+
+	for _, s := range c.steps {
+		if s.content.Id() == id {
+			return s.content
+		}
+		if c2, isContainer := s.content.(Container); isContainer {
+			if c3 := c2.ById(id); c3 != nil {
+				return c3
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *wizardImpl) children() []Comp {
+	comps := make([]Comp, len(c.steps))
+	for i, s := range c.steps {
+		comps[i] = s.content
+	}
+	return comps
+}
+
+func (c *wizardImpl) Clear() {
+	for _, s := range c.steps {
+		s.content.setParent(nil)
+	}
+	c.steps = nil
+	c.step = -1
+	c.finished = false
+}
+
+func (c *wizardImpl) AddStep(title string, content Comp, validator StepValidator) {
+	content.makeOrphan()
+	c.steps = append(c.steps, wizardStep{title: title, content: content, validator: validator})
+	content.setParent(c)
+	if c.step < 0 {
+		c.step = 0
+	}
+}
+
+func (c *wizardImpl) StepCount() int {
+	return len(c.steps)
+}
+
+func (c *wizardImpl) StepContent(idx int) Comp {
+	if idx < 0 || idx >= len(c.steps) {
+		return nil
+	}
+	return c.steps[idx].content
+}
+
+func (c *wizardImpl) Step() int {
+	return c.step
+}
+
+func (c *wizardImpl) SetStep(idx int) {
+	if idx < 0 || idx >= len(c.steps) {
+		return
+	}
+	c.step = idx
+	c.finished = false
+}
+
+func (c *wizardImpl) Next(e Event) bool {
+	if c.step < 0 || c.finished {
+		return false
+	}
+	if v := c.steps[c.step].validator; v != nil && !v(e) {
+		return false
+	}
+	if c.step == len(c.steps)-1 {
+		c.finished = true
+	} else {
+		c.step++
+	}
+	return true
+}
+
+func (c *wizardImpl) Back() bool {
+	if c.step <= 0 {
+		return false
+	}
+	c.step--
+	c.finished = false
+	return true
+}
+
+func (c *wizardImpl) Finished() bool {
+	return c.finished
+}
+
+// Navigation directions sent as the component value.
+const (
+	wizardBack = -1 // Go back to the previous step
+	wizardNext = 1  // Advance to the next step or finish
+)
+
+func (c *wizardImpl) preprocessEvent(event Event, r *http.Request) {
+	if event.Type() != ETypeStateChange {
+		return
+	}
+
+	dir, err := strconv.Atoi(r.FormValue(paramCompValue))
+	if err != nil {
+		return
+	}
+	var changed bool
+	switch dir {
+	case wizardBack:
+		changed = c.Back()
+	case wizardNext:
+		changed = c.Next(event)
+	}
+	if changed {
+		event.MarkDirty(c)
+	}
+}
+
+var (
+	strWizProgressOp = []byte(`<div class="gwu-Wizard-Progress">`)                                  // `<div class="gwu-Wizard-Progress">`
+	strWizStepOp     = []byte(`<span class="gwu-Wizard-Step`)                                       // `<span class="gwu-Wizard-Step`
+	strWizStepDone   = []byte(` gwu-Wizard-Step-Done">`)                                            // ` gwu-Wizard-Step-Done">`
+	strWizStepActive = []byte(` gwu-Wizard-Step-Active" aria-current="step">`)                      // ` gwu-Wizard-Step-Active" aria-current="step">`
+	strWizContentOp  = []byte(`<div class="gwu-Wizard-Content">`)                                   // `<div class="gwu-Wizard-Content">`
+	strWizNavOp      = []byte(`<div class="gwu-Wizard-Nav">`)                                       // `<div class="gwu-Wizard-Nav">`
+	strWizButtonOp   = []byte(`<button type="button" class="gwu-Wizard-Button" onclick="se(event,`) // `<button type="button" class="gwu-Wizard-Button" onclick="se(event,`
+	strWizButtonCl   = []byte(`);event.stopPropagation()"`)                                         // `);event.stopPropagation()"`
+)
+
+func (c *wizardImpl) Render(w Writer) {
+	w.Write(strDivOp)
+	c.renderAttrsAndStyle(w)
+	c.renderEHandlers(w)
+	w.Write(strGT)
+
+	// Progress indicator
+	w.Write(strWizProgressOp)
+	for i, s := range c.steps {
+		w.Write(strWizStepOp)
+		switch {
+		case i < c.step || c.finished:
+			w.Write(strWizStepDone)
+		case i == c.step:
+			w.Write(strWizStepActive)
+		default:
+			w.Write(strQuote)
+			w.Write(strGT)
+		}
+		w.Writees(s.title)
+		w.Write(strSpanCl)
+	}
+	w.Write(strDivCl)
+
+	// Current step
+	w.Write(strWizContentOp)
+	if c.step >= 0 {
+		renderComp(w, c.steps[c.step].content)
+	}
+	w.Write(strDivCl)
+
+	// Navigation
+	w.Write(strWizNavOp)
+	last := c.step == len(c.steps)-1
+	c.renderButton(w, wizardBack, "Back", c.step <= 0 || c.finished)
+	if last {
+		c.renderButton(w, wizardNext, "Finish", c.finished)
+	} else {
+		c.renderButton(w, wizardNext, "Next", false)
+	}
+	w.Write(strDivCl)
+
+	w.Write(strDivCl)
+}
+
+// renderButton renders a navigation button which navigates in the specified direction.
+func (c *wizardImpl) renderButton(w Writer, dir int, text string, disabled bool) {
+	// To render: <button type="button" class="gwu-Wizard-Button" onclick="se(event,etype,compId,dir);event.stopPropagation()">text</button>
+	w.Write(strWizButtonOp)
+	w.Writevs(int(ETypeStateChange), strComma, int(c.id), strComma, dir)
+	w.Write(strWizButtonCl)
+	if disabled {
+		w.Write(strDisabled)
+	}
+	w.Write(strGT)
+	w.Writes(text)
+	w.Write(strButtonCl)
+}
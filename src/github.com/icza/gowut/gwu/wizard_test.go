@@ -0,0 +1,131 @@
+// Copyright (C) 2013 Andras Belicza. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gwu
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newWizardTest creates a wizard with 3 steps, the second one is valid if valid is true.
+func newWizardTest(valid *bool) Wizard {
+	wz := NewWizard()
+	wz.AddStep("One", NewLabel("1"), nil)
+	wz.AddStep("Two", NewLabel("2"), func(e Event) bool { return *valid })
+	wz.AddStep("Three", NewLabel("3"), nil)
+	return wz
+}
+
+func TestWizardNext(t *testing.T) {
+	valid := false
+	wz := newWizardTest(&valid)
+	if step := wz.Step(); step != 0 {
+		t.Errorf("Got initial step %d", step)
+	}
+
+	next := func(wantStep int, wantDirty bool) {
+		t.Helper()
+		e := newEventImpl(ETypeStateChange, wz, nil, nil)
+		wz.(*wizardImpl).preprocessEvent(e, newCompValueReq(strconv.Itoa(wizardNext)))
+		if step := wz.Step(); step != wantStep {
+			t.Errorf("Got step %d, want %d", step, wantStep)
+		}
+		if dirty := e.shared.dirty(wz); dirty != wantDirty {
+			t.Errorf("Step %d: dirty = %v", wantStep, dirty)
+		}
+	}
+
+	next(1, true)  // No validator
+	next(1, false) // Invalid step
+	valid = true
+	next(2, true)
+	if wz.Finished() {
+		t.Error("Finished before the last step")
+	}
+	next(2, true) // Finish
+	if !wz.Finished() {
+		t.Error("Not finished after the last step")
+	}
+	next(2, false) // Already finished
+}
+
+func TestWizardBack(t *testing.T) {
+	valid := true
+	wz := newWizardTest(&valid)
+	if wz.Back() {
+		t.Error("Went back from the first step")
+	}
+
+	wz.SetStep(2)
+	wz.Next(nil)
+	valid = false // Going back is not validated
+	e := newEventImpl(ETypeStateChange, wz, nil, nil)
+	wz.(*wizardImpl).preprocessEvent(e, newCompValueReq(strconv.Itoa(wizardBack)))
+	if step := wz.Step(); step != 1 || wz.Finished() || !e.shared.dirty(wz) {
+		t.Errorf("Got step %d (finished: %v) after Back", step, wz.Finished())
+	}
+	if !wz.Back() || wz.Step() != 0 {
+		t.Errorf("Got step %d after Back", wz.Step())
+	}
+}
+
+func TestWizardRender(t *testing.T) {
+	valid := true
+	wz := newWizardTest(&valid)
+	wz.SetStep(1)
+	id, et := wz.Id().String(), ETypeStateChange.String()
+
+	s := renderString(wz)
+	for _, want := range []string{
+		`<span class="gwu-Wizard-Step gwu-Wizard-Step-Done">One</span>`,
+		`<span class="gwu-Wizard-Step gwu-Wizard-Step-Active" aria-current="step">Two</span>`,
+		`<span class="gwu-Wizard-Step">Three</span>`,
+		`onclick="se(event,` + et + `,` + id + `,-1);event.stopPropagation()">Back</button>`,
+		`onclick="se(event,` + et + `,` + id + `,1);event.stopPropagation()">Next</button>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Rendered output does not contain %s: %s", want, s)
+		}
+	}
+	// Only the current step is rendered
+	if !strings.Contains(s, ">2</span>") || strings.Contains(s, ">1</span>") || strings.Contains(s, ">3</span>") {
+		t.Errorf("Not only the current step rendered: %s", s)
+	}
+
+	wz.SetStep(2)
+	if s = renderString(wz); !strings.Contains(s, ">Finish</button>") {
+		t.Errorf("Finish button not rendered on the last step: %s", s)
+	}
+}
+
+func TestWizardContainer(t *testing.T) {
+	valid := true
+	wz := newWizardTest(&valid)
+	wz.SetStep(2)
+	c := wz.StepContent(1)
+
+	if wz.ById(c.Id()) != c || wz.ById(wz.Id()) != wz {
+		t.Error("ById() did not find the components")
+	}
+	if !wz.Remove(c) || wz.StepCount() != 2 || wz.Step() != 1 || c.Parent() != nil {
+		t.Errorf("Remove() failed, step count: %d, step: %d", wz.StepCount(), wz.Step())
+	}
+	wz.Clear()
+	if wz.StepCount() != 0 || wz.Step() != -1 {
+		t.Error("Clear() failed")
+	}
+}
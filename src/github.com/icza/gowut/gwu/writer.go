@@ -99,12 +99,61 @@ type Writer interface {
 
 // writerImpl is the implementation of our Writer.
 type writerImpl struct {
-	io.Writer // Writer implementation
+	io.Writer        // Writer implementation
+	locale    string // Locale to format values with
+	nonce     string // CSP nonce of script elements
 }
 
 // NewWriter returns a new Writer, wrapping the specified io.Writer.
 func NewWriter(w io.Writer) Writer {
-	return writerImpl{w}
+	return writerImpl{Writer: w}
+}
+
+// newLocaleWriter returns a new Writer, wrapping the specified io.Writer,
+// which carries the locale to format values with.
+func newLocaleWriter(w io.Writer, locale string) Writer {
+	return writerImpl{Writer: w, locale: locale}
+}
+
+// writerLocale returns the locale carried by the specified Writer,
+// empty string if it carries no locale.
+func writerLocale(w Writer) string {
+	if wi, ok := w.(writerImpl); ok {
+		return wi.locale
+	}
+	return ""
+}
+
+// withNonce returns a Writer which renders script elements with the specified
+// CSP nonce (see NoncePlaceholder). w is returned if nonce is empty.
+func withNonce(w Writer, nonce string) Writer {
+	if nonce == "" {
+		return w
+	}
+	if wi, ok := w.(writerImpl); ok {
+		wi.nonce = nonce
+		return wi
+	}
+	return writerImpl{Writer: w, nonce: nonce}
+}
+
+// writerNonce returns the CSP nonce carried by the specified Writer,
+// empty string if it carries no nonce.
+func writerNonce(w Writer) string {
+	if wi, ok := w.(writerImpl); ok {
+		return wi.nonce
+	}
+	return ""
+}
+
+// writeScriptOp writes the opening tag of a script element,
+// with the CSP nonce carried by the Writer if there is one.
+func writeScriptOp(w Writer) {
+	if nonce := writerNonce(w); nonce != "" {
+		w.Writess(`<script nonce="`, nonce, `">`)
+		return
+	}
+	w.Write(strScriptOp)
 }
 
 func (w writerImpl) Writev(v interface{}) (n int, err error) {